@@ -34,6 +34,7 @@ import (
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers"
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/incluster"
 	"istio.io/istio/pkg/config/analysis/local"
 	"istio.io/istio/pkg/config/analysis/msg"
 	"istio.io/istio/pkg/config/resource"
@@ -102,6 +103,12 @@ func Analyze() *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Watch the current live cluster, printing the new and resolved messages as the configuration changes
+  istioctl analyze --watch --metrics-address :15014
+
+  # Watch the current live cluster, writing the messages to the status of the Istio resources
+  istioctl analyze --watch --write-status --all-namespaces
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -118,11 +125,31 @@ func Analyze() *cobra.Command {
 				return nil
 			}
 
+			if analyzeWatch {
+				if !useKube {
+					return CommandParseError{fmt.Errorf("--watch requires analyzing the live cluster, do not set --use-kube=false")}
+				}
+				if msgOutputFormat != formatting.LogFormat {
+					return CommandParseError{fmt.Errorf("--watch only supports the %s output format", formatting.LogFormat)}
+				}
+				if analyzeWatchInterval <= 0 {
+					return CommandParseError{fmt.Errorf("--watch-interval must be positive")}
+				}
+			} else if analyzeMetricsAddr != "" {
+				return CommandParseError{fmt.Errorf("--metrics-address requires --watch")}
+			} else if analyzeWriteStatus {
+				return CommandParseError{fmt.Errorf("--write-status requires --watch")}
+			}
+
 			readers, err := gatherFiles(cmd, args)
 			if err != nil {
 				return err
 			}
+			if analyzeWriteStatus && len(readers) > 0 {
+				return CommandParseError{fmt.Errorf("--write-status only analyzes the live cluster, do not specify files")}
+			}
 			cancel := make(chan struct{})
+			var statusWriter *incluster.StatusWriter
 
 			// We use the "namespace" arg that's provided as part of root istioctl as a flag for specifying what namespace to use
 			// for file resources that don't have one specified.
@@ -188,6 +215,12 @@ func Analyze() *cobra.Command {
 				if err != nil {
 					return err
 				}
+				if analyzeWriteStatus {
+					// Created before the analysis runs the client, which starts the informers of the status writer.
+					if statusWriter, err = newAnalysisStatusWriter(k, cancel); err != nil {
+						return err
+					}
+				}
 				sa.AddRunningKubeSource(k)
 			}
 
@@ -220,6 +253,10 @@ func Analyze() *cobra.Command {
 				return err
 			}
 
+			if analyzeWatch {
+				return watchAnalysis(cmd, sa, result, statusWriter, cancel)
+			}
+
 			// Maybe output details about which analyzers ran
 			if verbose {
 				fmt.Fprintf(cmd.ErrOrStderr(), "Analyzed resources in %s\n", analyzeTargetAsString())
@@ -243,7 +280,7 @@ func Analyze() *cobra.Command {
 			outputMessages := result.Messages.SetDocRef("istioctl-analyze").FilterOutLowerThan(outputThreshold.Level)
 
 			// Print all the messages to stdout in the specified format
-			output, err := formatting.Print(outputMessages, msgOutputFormat, colorize)
+			output, err := formatting.PrintAnalysis(outputMessages, msgOutputFormat, colorize, result.ExecutedAnalyzers, result.SkippedAnalyzers)
 			if err != nil {
 				return err
 			}
@@ -316,6 +353,16 @@ func Analyze() *cobra.Command {
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
 	analysisCmd.PersistentFlags().BoolVar(&ignoreUnknown, "ignore-unknown", false,
 		"Don't complain about un-parseable input documents, for cases where analyze should run only on k8s compliant inputs.")
+	analysisCmd.PersistentFlags().BoolVar(&analyzeWatch, "watch", false,
+		"Keep analyzing the live cluster, printing the new and resolved messages as the configuration changes, until interrupted.")
+	analysisCmd.PersistentFlags().DurationVar(&analyzeWatchInterval, "watch-interval", 10*time.Second,
+		"The interval between the analyses of the live cluster with --watch.")
+	analysisCmd.PersistentFlags().StringVar(&analyzeMetricsAddr, "metrics-address", "",
+		"The address serving the Prometheus metrics of the message counts by code and namespace on /metrics with --watch, such as ':15014'.")
+	analysisCmd.PersistentFlags().BoolVar(&analyzeWriteStatus, "write-status", false,
+		"Write the messages to the validationMessages of the status of the Istio resources with --watch, as the "+
+			"in-cluster analysis of istiod does. Only set it if the in-cluster analysis of istiod is disabled, as "+
+			"there must be a single writer of those.")
 	return analysisCmd
 }
 
@@ -326,7 +373,7 @@ func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error
 
 		// Handle "-" as stdin as a special case.
 		if f == "-" {
-			if isatty.IsTerminal(os.Stdin.Fd()) && !isJSONorYAMLOutputFormat() {
+			if isatty.IsTerminal(os.Stdin.Fd()) && !isMachineReadableOutputFormat() {
 				fmt.Fprint(cmd.OutOrStdout(), "Reading from stdin:\n")
 			}
 			r = os.Stdin
@@ -454,6 +501,6 @@ func analyzeTargetAsString() string {
 }
 
 // TODO: Refactor output writer so that it is smart enough to know when to output what.
-func isJSONorYAMLOutputFormat() bool {
-	return msgOutputFormat == formatting.JSONFormat || msgOutputFormat == formatting.YAMLFormat
+func isMachineReadableOutputFormat() bool {
+	return msgOutputFormat != formatting.LogFormat
 }
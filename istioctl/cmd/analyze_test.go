@@ -15,6 +15,10 @@
 package cmd
 
 import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -63,3 +67,66 @@ func TestNoErrorIfMessageLevelsBelowThreshold(t *testing.T) {
 
 	g.Expect(err).To(BeNil())
 }
+
+func TestAnalysisWatcherUpdate(t *testing.T) {
+	g := NewWithT(t)
+
+	bubble := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		diag.MockResource("SoapBubble"),
+		"the bubble is too big",
+	)
+	castle := diag.NewMessage(
+		diag.NewMessageType(diag.Warning, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+
+	out := &bytes.Buffer{}
+	w := newAnalysisWatcher(out)
+
+	g.Expect(w.update(diag.Messages{bubble})).To(Succeed())
+	g.Expect(out.String()).To(Equal("New: Error [B1] (SoapBubble) Explosion accident: the bubble is too big\n"))
+
+	out.Reset()
+	g.Expect(w.update(diag.Messages{bubble})).To(Succeed())
+	g.Expect(out.String()).To(BeEmpty())
+
+	out.Reset()
+	g.Expect(w.update(diag.Messages{castle})).To(Succeed())
+	g.Expect(out.String()).To(Equal("New: Warning [C1] (GrandCastle) Collapse danger: the castle is too old\n" +
+		"Resolved: Error [B1] (SoapBubble) Explosion accident: the bubble is too big\n"))
+	g.Expect(w.counts).To(Equal(map[messageCountKey]int{{code: "C1"}: 1}))
+}
+
+func TestAnalysisMetrics(t *testing.T) {
+	g := NewWithT(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(BeNil())
+	stop := make(chan struct{})
+	defer close(stop)
+	g.Expect(serveAnalysisMetrics(listener, stop)).To(Succeed())
+
+	scrape := func() string {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+		g.Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		g.Expect(err).To(BeNil())
+		return string(body)
+	}
+
+	castle := diag.NewMessage(
+		diag.NewMessageType(diag.Warning, "M1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+	w := newAnalysisWatcher(io.Discard)
+	g.Expect(w.update(diag.Messages{castle})).To(Succeed())
+	g.Expect(scrape()).To(ContainSubstring(`istioctl_analysis_messages{code="M1",namespace=""} 1`))
+
+	// The count of the resolved messages is reset.
+	g.Expect(w.update(nil)).To(Succeed())
+	g.Expect(scrape()).To(ContainSubstring(`istioctl_analysis_messages{code="M1",namespace=""} 0`))
+}
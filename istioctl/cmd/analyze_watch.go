@@ -0,0 +1,246 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	ocprom "contrib.go.opencensus.io/exporter/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/util/formatting"
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	istiostatus "istio.io/istio/pilot/pkg/status"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/incluster"
+	"istio.io/istio/pkg/config/analysis/local"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	analyzeWatch         bool
+	analyzeWatchInterval time.Duration
+	analyzeMetricsAddr   string
+	analyzeWriteStatus   bool
+
+	analysisCodeLabel      = monitoring.MustCreateLabel("code")
+	analysisNamespaceLabel = monitoring.MustCreateLabel("namespace")
+	analysisMessageCnt     = monitoring.NewGauge(
+		"istioctl_analysis_messages",
+		"The number of analysis messages of the live analysis session, by message code and namespace.",
+		monitoring.WithLabels(analysisCodeLabel, analysisNamespaceLabel),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(analysisMessageCnt)
+}
+
+// messageCountKey is the labels of the message count metric.
+type messageCountKey struct {
+	code      string
+	namespace string
+}
+
+// analysisWatcher prints the messages added and resolved since the previous analysis.
+type analysisWatcher struct {
+	out      io.Writer
+	previous diag.Messages
+	// counts are the message counts last recorded, so that the counts of resolved messages are reset to 0.
+	counts map[messageCountKey]int
+}
+
+func newAnalysisWatcher(out io.Writer) *analysisWatcher {
+	return &analysisWatcher{
+		out:    out,
+		counts: map[messageCountKey]int{},
+	}
+}
+
+// watchAnalysis reanalyzes the live cluster every --watch-interval until interrupted. The initial result is printed as
+// added messages. The messages are also written to the status of the resources if statusWriter is not nil.
+func watchAnalysis(cmd *cobra.Command, sa *local.IstiodAnalyzer, initial local.AnalysisResult,
+	statusWriter *incluster.StatusWriter, cancel chan struct{},
+) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	defer close(cancel)
+
+	if analyzeMetricsAddr != "" {
+		listener, err := net.Listen("tcp", analyzeMetricsAddr)
+		if err != nil {
+			return fmt.Errorf("unable to listen on %s for metrics: %v", analyzeMetricsAddr, err)
+		}
+		if err := serveAnalysisMetrics(listener, cancel); err != nil {
+			return err
+		}
+	}
+
+	w := newAnalysisWatcher(cmd.OutOrStdout())
+	if err := w.update(initial.Messages); err != nil {
+		return err
+	}
+	if statusWriter != nil {
+		statusWriter.Write(initial.Messages)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "Watching %s for changes every %v. Press Ctrl+C to stop.\n",
+		analyzeTargetAsString(), analyzeWatchInterval)
+
+	t := time.NewTicker(analyzeWatchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			result, err := sa.ReAnalyze(cancel)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Analysis failed: %v\n", err)
+				continue
+			}
+			if err := w.update(result.Messages); err != nil {
+				return err
+			}
+			if statusWriter != nil {
+				statusWriter.Write(result.Messages)
+			}
+		case <-signals:
+			return nil
+		}
+	}
+}
+
+// newAnalysisStatusWriter returns a status writer of the analysis messages to the Istio resources of the cluster. Its
+// informers are started when the client is run, and the status is written until stop is closed.
+func newAnalysisStatusWriter(client kube.Client, stop <-chan struct{}) (*incluster.StatusWriter, error) {
+	store, err := crdclient.NewForSchemas(client, crdclient.Option{
+		Revision:     "default",
+		DomainSuffix: "cluster.local",
+		Identifier:   "analysis-status",
+	}, collections.Pilot)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write the status of the resources: %v", err)
+	}
+	go store.Run(stop)
+	manager := istiostatus.NewManager(store)
+	go func() {
+		// The store must be synced to get the resources whose status is written.
+		if kube.WaitForCacheSync(stop, store.HasSynced) {
+			manager.Start(stop)
+		}
+	}()
+	return incluster.NewStatusWriter(manager), nil
+}
+
+// update prints the messages added and resolved since the last update, and records the message counts.
+func (w *analysisWatcher) update(msgs diag.Messages) error {
+	msgs = msgs.SetDocRef("istioctl-analyze").FilterOutLowerThan(outputThreshold.Level)
+	added, resolved := diffMessages(w.previous, msgs)
+	w.previous = msgs
+
+	for _, m := range added {
+		if err := w.print("New:", m); err != nil {
+			return err
+		}
+	}
+	for _, m := range resolved {
+		if err := w.print("Resolved:", m); err != nil {
+			return err
+		}
+	}
+	w.recordCounts(msgs)
+	return nil
+}
+
+func (w *analysisWatcher) print(prefix string, m diag.Message) error {
+	output, err := formatting.Print(diag.Messages{m}, formatting.LogFormat, colorize)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w.out, prefix, output)
+	return err
+}
+
+func (w *analysisWatcher) recordCounts(msgs diag.Messages) {
+	counts := map[messageCountKey]int{}
+	for _, m := range msgs {
+		key := messageCountKey{code: m.Type.Code()}
+		if m.Resource != nil {
+			key.namespace = m.Resource.Origin.Namespace().String()
+		}
+		counts[key]++
+	}
+	for key := range w.counts {
+		if _, f := counts[key]; !f {
+			analysisMessageCnt.With(analysisCodeLabel.Value(key.code), analysisNamespaceLabel.Value(key.namespace)).Record(0)
+		}
+	}
+	for key, n := range counts {
+		analysisMessageCnt.With(analysisCodeLabel.Value(key.code), analysisNamespaceLabel.Value(key.namespace)).Record(float64(n))
+	}
+	w.counts = counts
+}
+
+// diffMessages returns the messages of current that are not in previous, and the messages of previous that are not
+// in current. As when deduplicating messages, two messages are the same if they have the same string representation.
+func diffMessages(previous, current diag.Messages) (added, resolved diag.Messages) {
+	prev := map[string]bool{}
+	for _, m := range previous {
+		prev[m.String()] = true
+	}
+	cur := map[string]bool{}
+	for _, m := range current {
+		cur[m.String()] = true
+		if !prev[m.String()] {
+			added = append(added, m)
+		}
+	}
+	for _, m := range previous {
+		if !cur[m.String()] {
+			resolved = append(resolved, m)
+		}
+	}
+	return added, resolved
+}
+
+// serveAnalysisMetrics serves the Prometheus metrics of the analysis on the listener until stop is closed. The
+// exporter reads the registered views, including the message counts, when scraped.
+func serveAnalysisMetrics(listener net.Listener, stop <-chan struct{}) error {
+	exporter, err := ocprom.NewExporter(ocprom.Options{Registry: prometheus.NewRegistry()})
+	if err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("could not set up prometheus exporter: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	go func() {
+		<-stop
+		_ = server.Close()
+	}()
+	return nil
+}
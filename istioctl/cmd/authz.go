@@ -18,18 +18,36 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/istio/istioctl/pkg/authz"
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/istioctl/pkg/util/handlers"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/pkg/log"
 )
 
-var configDumpFile string
+var (
+	configDumpFile string
+
+	authzCheckFrom        string
+	authzCheckTrustDomain string
+	authzCheckPort        int
+	authzCheckTCP         bool
+	authzCheckMethod      string
+	authzCheckPath        string
+	authzCheckHeaders     []string
+	authzCheckClaims      []string
+)
+
+// authzCheckRequestFlags are the flags describing a request, which is then checked against the policies.
+var authzCheckRequestFlags = []string{"from", "port", "tcp", "method", "path", "header", "jwt-claims"}
 
 var checkCmd = &cobra.Command{
 	Use:   "check [<type>/]<name>[.<namespace>]",
@@ -39,7 +57,11 @@ the Envoy configuration of the pod. The command is especially useful for inspect
 the policy propagation from Istiod to Envoy and the final AuthorizationPolicy list merged
 from multiple sources (mesh-level, namespace-level and workload-level).
 
-The command also supports reading from a standalone config dump file with flag -f.`,
+The command also supports reading from a standalone config dump file with flag -f.
+
+If a request is described with the flags --from, --port, --method, --path, --header, --jwt-claims
+or --tcp, the command instead evaluates the RBAC filters of the Envoy configuration against the
+request, as Envoy would, and prints the decision and the policy rules matching the request.`,
 	Example: `  # Check AuthorizationPolicy applied to pod httpbin-88ddbcfdd-nt5jb:
   istioctl x authz check httpbin-88ddbcfdd-nt5jb
 
@@ -47,7 +69,14 @@ The command also supports reading from a standalone config dump file with flag -
   istioctl x authz check deployment/productpage-v1
 
   # Check AuthorizationPolicy from Envoy config dump file:
-  istioctl x authz check -f httpbin_config_dump.json`,
+  istioctl x authz check -f httpbin_config_dump.json
+
+  # Check whether a GET request from the sleep workload to /status on port 8000 of httpbin is allowed:
+  istioctl x authz check httpbin-88ddbcfdd-nt5jb --from deployment/sleep.default --port 8000 --method GET --path /status
+
+  # Check a plaintext request with a JWT and a header from a config dump file:
+  istioctl x authz check -f httpbin_config_dump.json --port 8000 --path /admin --header x-env=prod \
+    --jwt-claims iss=https://accounts.example.com --jwt-claims sub=alice --jwt-claims groups=admin`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) > 1 {
 			cmd.Println(cmd.UsageString())
@@ -57,6 +86,7 @@ The command also supports reading from a standalone config dump file with flag -
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var configDump *configdump.Wrapper
+		var destinationIP string
 		var err error
 		if configDumpFile != "" {
 			configDump, err = getConfigDumpFromFile(configDumpFile)
@@ -78,6 +108,13 @@ The command also supports reading from a standalone config dump file with flag -
 			if err != nil {
 				return fmt.Errorf("failed to get config dump from pod %s in %s", podName, podNamespace)
 			}
+			if isAuthzCheckRequest(cmd) {
+				pod, err := kubeClient.Kube().CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				destinationIP = pod.Status.PodIP
+			}
 		} else {
 			return fmt.Errorf("expecting pod name or config dump, found: %d", len(args))
 		}
@@ -86,11 +123,113 @@ The command also supports reading from a standalone config dump file with flag -
 		if err != nil {
 			return err
 		}
-		analyzer.Print(cmd.OutOrStdout())
+		if !isAuthzCheckRequest(cmd) {
+			analyzer.Print(cmd.OutOrStdout())
+			return nil
+		}
+
+		req, err := authzCheckRequest(destinationIP)
+		if err != nil {
+			return err
+		}
+		result, err := analyzer.Check(req)
+		if err != nil {
+			return err
+		}
+		authz.PrintCheckResult(cmd.OutOrStdout(), result)
 		return nil
 	},
 }
 
+func isAuthzCheckRequest(cmd *cobra.Command) bool {
+	for _, name := range authzCheckRequestFlags {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// authzCheckRequest returns the request described by the flags, sent to the destination IP if known.
+func authzCheckRequest(destinationIP string) (*authz.Request, error) {
+	if authzCheckPort < 0 || authzCheckPort > 65535 {
+		return nil, fmt.Errorf("invalid port %d", authzCheckPort)
+	}
+	req := &authz.Request{
+		DestinationIP:   net.ParseIP(destinationIP),
+		DestinationPort: uint32(authzCheckPort),
+		HTTP:            !authzCheckTCP,
+	}
+	if req.HTTP {
+		req.Method = strings.ToUpper(authzCheckMethod)
+		req.Path = authzCheckPath
+		headers, err := parseKeyValues(authzCheckHeaders, "header")
+		if err != nil {
+			return nil, err
+		}
+		req.Headers = map[string][]string{}
+		for k, v := range headers {
+			req.Headers[strings.ToLower(k)] = v
+		}
+		if req.Claims, err = parseKeyValues(authzCheckClaims, "JWT claim"); err != nil {
+			return nil, err
+		}
+	} else if len(authzCheckHeaders) > 0 || len(authzCheckClaims) > 0 {
+		return nil, fmt.Errorf("--header and --jwt-claims are not supported with --tcp")
+	}
+
+	if authzCheckFrom == "" {
+		return req, nil
+	}
+	if strings.HasPrefix(authzCheckFrom, spiffe.URIPrefix) {
+		req.Principal = authzCheckFrom
+		return req, nil
+	}
+	// The source is a workload, whose identity is the one of its service account.
+	kubeClient, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+	podName, podNamespace, err := handlers.InferPodInfoFromTypedResource(authzCheckFrom,
+		handlers.HandleNamespace(namespace, defaultNamespace),
+		kubeClient.UtilFactory())
+	if err != nil {
+		return nil, err
+	}
+	pod, err := kubeClient.Kube().CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	trustDomain := authzCheckTrustDomain
+	if trustDomain == "" {
+		meshConfig, err := getMeshConfig(kubeClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the trust domain, set it with --trust-domain: %v", err)
+		}
+		trustDomain = meshConfig.GetTrustDomain()
+	}
+	serviceAccount := pod.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	req.Principal = fmt.Sprintf("%s%s/ns/%s/sa/%s", spiffe.URIPrefix, trustDomain, podNamespace, serviceAccount)
+	req.SourceIP = net.ParseIP(pod.Status.PodIP)
+	return req, nil
+}
+
+// parseKeyValues parses the key=value pairs. The values of a repeated key are collected in order.
+func parseKeyValues(pairs []string, kind string) (map[string][]string, error) {
+	values := map[string][]string{}
+	for _, pair := range pairs {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || k == "" {
+			return nil, fmt.Errorf("invalid %s %q, expected <name>=<value>", kind, pair)
+		}
+		values[k] = append(values[k], v)
+	}
+	return values, nil
+}
+
 func getConfigDumpFromFile(filename string) (*configdump.Wrapper, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -156,4 +295,21 @@ func AuthZ() *cobra.Command {
 func init() {
 	checkCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"The json file with Envoy config dump to be checked")
+	checkCmd.PersistentFlags().StringVar(&authzCheckFrom, "from", "",
+		"The source of the request, as a workload [<type>/]<name>[.<namespace>] or a SPIFFE identity. "+
+			"If unset, the request is not over mTLS")
+	checkCmd.PersistentFlags().StringVar(&authzCheckTrustDomain, "trust-domain", "",
+		"The trust domain of the identity of the --from workload. Defaults to the trust domain of the mesh config")
+	checkCmd.PersistentFlags().IntVar(&authzCheckPort, "port", 0,
+		"The destination port of the request. If unset, the filter chains not bound to a port are checked")
+	checkCmd.PersistentFlags().BoolVar(&authzCheckTCP, "tcp", false,
+		"Check a TCP connection rather than a HTTP request")
+	checkCmd.PersistentFlags().StringVar(&authzCheckMethod, "method", "GET",
+		"The method of the HTTP request")
+	checkCmd.PersistentFlags().StringVar(&authzCheckPath, "path", "/",
+		"The path of the HTTP request")
+	checkCmd.PersistentFlags().StringArrayVar(&authzCheckHeaders, "header", nil,
+		"A header of the HTTP request, as <name>=<value>. Can be repeated. The host header is the authority of the request")
+	checkCmd.PersistentFlags().StringArrayVar(&authzCheckClaims, "jwt-claims", nil,
+		"A claim of the validated JWT of the HTTP request, as <name>=<value>. Can be repeated, a repeated claim is a list")
 }
@@ -0,0 +1,241 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/security/pkg/pki/ca"
+	caserver "istio.io/istio/security/pkg/server/ca"
+)
+
+func caCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Manage the Istio CA",
+		Long:  `Commands to manage the certificates issued by the Istio CA of istiod.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("unknown subcommand %q", args[0])
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			return nil
+		},
+	}
+	cmd.AddCommand(caRevokeCommand())
+	cmd.AddCommand(caCertificatesCommand())
+	return cmd
+}
+
+func caCertificatesCommand() *cobra.Command {
+	var (
+		opts         clioptions.ControlPlaneOptions
+		identity     string
+		node         string
+		since        time.Duration
+		outputFormat string
+	)
+	cmd := &cobra.Command{
+		Use:   "certificates",
+		Short: "List the certificates recently issued by the Istio CA",
+		Long: `List the unexpired certificates recently issued by the CA of each istiod instance, with the pod and node
+that requested them. Each istiod only keeps the latest certificates it has issued, up to CA_INVENTORY_SIZE.`,
+		Example: `  # List the certificates issued to a service account
+  istioctl experimental ca certificates --identity spiffe://cluster.local/ns/default/sa/httpbin
+
+  # List the certificates requested from a node in the last hour
+  istioctl experimental ca certificates --node worker-1 --since 1h`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("certificates takes no arguments")
+			}
+			if outputFormat != summaryOutput && outputFormat != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected one of %s|%s", outputFormat, summaryOutput, jsonOutput)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			if identity != "" {
+				query.Set("identity", identity)
+			}
+			if node != "" {
+				query.Set("node", node)
+			}
+			if since > 0 {
+				query.Set("since", since.String())
+			}
+			path := "/debug/certz"
+			if len(query) > 0 {
+				path += "?" + query.Encode()
+			}
+			res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, path)
+			if err != nil {
+				return err
+			}
+			return writeIssuedCertificates(cmd.OutOrStdout(), res, outputFormat)
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().StringVar(&identity, "identity", "", "Only list the certificates issued to this SPIFFE identity")
+	cmd.PersistentFlags().StringVar(&node, "node", "", "Only list the certificates requested from this node")
+	cmd.PersistentFlags().DurationVar(&since, "since", 0, "Only list the certificates issued in this last duration")
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|short")
+	return cmd
+}
+
+// issuedCertificate is a certificate issued by the CA of an istiod instance.
+type issuedCertificate struct {
+	caserver.IssuedCertificate
+	Istiod string `json:"istiod"`
+}
+
+func writeIssuedCertificates(out io.Writer, input map[string][]byte, outputFormat string) error {
+	var certs []issuedCertificate
+	for istiod, b := range input {
+		var parsed []caserver.IssuedCertificate
+		if err := json.Unmarshal(b, &parsed); err != nil {
+			return fmt.Errorf("failed to parse the certificates issued by %s: %v", istiod, err)
+		}
+		for _, c := range parsed {
+			certs = append(certs, issuedCertificate{IssuedCertificate: c, Istiod: istiod})
+		}
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].IssuedAt.After(certs[j].IssuedAt)
+	})
+	if outputFormat == jsonOutput {
+		b, err := json.MarshalIndent(certs, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintln(out, string(b))
+		return nil
+	}
+	w := new(tabwriter.Writer).Init(out, 0, 8, 3, ' ', 0)
+	_, _ = fmt.Fprintln(w, "IDENTITY\tSERIAL\tPOD\tNODE\tISSUED\tEXPIRES\tISTIOD")
+	for _, c := range certs {
+		pod := ""
+		if c.PodName != "" {
+			pod = c.PodName + "." + c.PodNamespace
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", strings.Join(c.Identities, ","), c.SerialNumber, pod, c.NodeName,
+			c.IssuedAt.UTC().Format(time.RFC3339), c.ExpiresAt.UTC().Format(time.RFC3339), c.Istiod)
+	}
+	return w.Flush()
+}
+
+func caRevokeCommand() *cobra.Command {
+	var (
+		serials    []string
+		identities []string
+		reason     string
+	)
+	cmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke certificates issued by the Istio CA",
+		Long: `Revoke certificates issued by the Istio CA, by serial number or by SPIFFE identity.
+
+Revoking an identity revokes all the certificates issued to it until now, while the certificates issued
+afterwards are valid. The revocations are stored in the istio-ca-revocations secret of the Istio namespace,
+from which istiod distributes a CRL to the proxies when ENABLE_CA_CRL is set.
+
+The certificates of an identity are found in the istio-ca-issued-<istiod pod> secrets, where each istiod saves
+every minute the certificates it has issued since ENABLE_CA_CRL was set. Certificates issued before, or by an
+istiod which stopped less than a minute after issuing them, are not revoked and remain valid until they expire.`,
+		Example: `  # Revoke a certificate by its hex encoded serial number, as printed by "openssl x509 -serial"
+  istioctl experimental ca revoke --serial 6E:A3:7B:4C:1A:2F
+
+  # Revoke the certificates issued so far to a service account
+  istioctl experimental ca revoke --identity spiffe://cluster.local/ns/default/sa/httpbin --reason "key compromise"`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("revoke takes no arguments")
+			}
+			if len(serials) == 0 && len(identities) == 0 {
+				return fmt.Errorf("at least one of --serial and --identity must be set")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+			return revokeCertificates(context.Background(), client.CoreV1(), istioNamespace, serials, identities, reason, cmd.OutOrStdout())
+		},
+	}
+	cmd.PersistentFlags().StringSliceVar(&serials, "serial", nil, "Hex encoded serial numbers of the certificates to revoke")
+	cmd.PersistentFlags().StringSliceVar(&identities, "identity", nil, "SPIFFE identities whose certificates to revoke")
+	cmd.PersistentFlags().StringVar(&reason, "reason", "", "Reason for the revocation, recorded in the revocation list")
+	return cmd
+}
+
+func revokeCertificates(ctx context.Context, client corev1.CoreV1Interface, namespace string, serials, identities []string,
+	reason string, w io.Writer,
+) error {
+	now := time.Now()
+	var revoked []string
+	err := ca.UpdateRevocationList(ctx, client, namespace, func(l *ca.RevocationList) (bool, error) {
+		// The update may be retried on conflict, start over each time.
+		revoked = nil
+		for _, s := range serials {
+			added, err := l.RevokeSerialNumber(s, now, reason)
+			if err != nil {
+				return false, err
+			}
+			if added {
+				revoked = append(revoked, "certificate "+s)
+			}
+		}
+		for _, id := range identities {
+			if err := l.RevokeIdentity(id, now, reason); err != nil {
+				return false, err
+			}
+			revoked = append(revoked, "identity "+id)
+		}
+		return len(revoked) > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update secret %s/%s: %v", namespace, ca.RevocationSecret, err)
+	}
+	if len(revoked) == 0 {
+		_, _ = fmt.Fprintln(w, "Certificates already revoked")
+		return nil
+	}
+	for _, r := range revoked {
+		_, _ = fmt.Fprintf(w, "Revoked %s\n", r)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/security/pkg/pki/ca"
+)
+
+func TestRevokeCertificates(t *testing.T) {
+	client := fake.NewSimpleClientset().CoreV1()
+	ctx := context.Background()
+	identity := "spiffe://cluster.local/ns/default/sa/httpbin"
+
+	var out bytes.Buffer
+	if err := revokeCertificates(ctx, client, "istio-system", []string{"6E:A3:7B"}, []string{identity}, "key compromise", &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Revoked certificate 6E:A3:7B\nRevoked identity " + identity + "\n"; out.String() != want {
+		t.Fatalf("expected output %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := revokeCertificates(ctx, client, "istio-system", []string{"6ea37b"}, nil, "", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "already revoked") {
+		t.Fatalf("expected certificate to be already revoked, got %q", out.String())
+	}
+
+	if err := revokeCertificates(ctx, client, "istio-system", nil, []string{"httpbin"}, "", &out); err == nil {
+		t.Fatalf("expected invalid identity to be rejected")
+	}
+
+	l, err := ca.LoadRevocationList(ctx, client, "istio-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Revoked) != 2 || l.Revoked[0].SerialNumber != "6ea37b" || l.Revoked[1].Identity != identity ||
+		l.Revoked[1].Reason != "key compromise" {
+		t.Fatalf("unexpected revocation list %+v", l.Revoked)
+	}
+}
+
+func TestWriteIssuedCertificates(t *testing.T) {
+	input := map[string][]byte{
+		"istiod-1.istio-system": []byte(`[{"identities":["spiffe://cluster.local/ns/default/sa/httpbin"],"serialNumber":"6ea37b",` +
+			`"ttl":"24h0m0s","issuedAt":"2023-01-02T10:00:00Z","expiresAt":"2023-01-03T10:00:00Z",` +
+			`"podName":"httpbin-1","podNamespace":"default","nodeName":"worker-1"}]`),
+		"istiod-2.istio-system": []byte(`[{"identities":["spiffe://cluster.local/ns/default/sa/sleep"],"serialNumber":"1f",` +
+			`"ttl":"24h0m0s","issuedAt":"2023-01-02T11:00:00Z","expiresAt":"2023-01-03T11:00:00Z"}]`),
+	}
+	var out bytes.Buffer
+	if err := writeIssuedCertificates(&out, input, summaryOutput); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 certificates, got %q", out.String())
+	}
+	// The most recently issued certificates are listed first.
+	for i, want := range [][]string{
+		{"IDENTITY", "SERIAL", "POD", "NODE", "ISSUED", "EXPIRES", "ISTIOD"},
+		{"spiffe://cluster.local/ns/default/sa/sleep", "1f", "2023-01-02T11:00:00Z", "2023-01-03T11:00:00Z", "istiod-2.istio-system"},
+		{
+			"spiffe://cluster.local/ns/default/sa/httpbin", "6ea37b", "httpbin-1.default", "worker-1",
+			"2023-01-02T10:00:00Z", "2023-01-03T10:00:00Z", "istiod-1.istio-system",
+		},
+	} {
+		if got := strings.Fields(lines[i]); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("line %d: expected %v, got %v", i, want, got)
+		}
+	}
+
+	if err := writeIssuedCertificates(&out, map[string][]byte{"istiod": []byte("invalid")}, summaryOutput); err == nil {
+		t.Fatal("expected invalid response to be rejected")
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/istioctl/pkg/writer/compare"
 	"istio.io/istio/istioctl/pkg/writer/envoy/clusters"
 	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
 	"istio.io/istio/pilot/pkg/model"
@@ -1217,6 +1218,88 @@ func rootCACompareConfigCmd() *cobra.Command {
 	return rootCACompareConfigCmd
 }
 
+func diffConfigCmd() *cobra.Command {
+	diffConfigCmd := &cobra.Command{
+		Use:   "diff [<type>/]<name-1>[.<namespace-1>] [[<type>/]<name-2>[.<namespace-2>]]",
+		Short: "Diff the configuration of two proxies",
+		Long: `Diff the listeners, routes, clusters and endpoints of two proxies, or of a proxy and a saved Envoy config dump.
+The resources are compared by name, ignoring their version information and last update time, to explain why a
+proxy behaves differently from another one, for example from another replica of the same workload.
+The command exits with code 80 when the configurations differ.`,
+		Example: `  # Diff the configuration of two replicas of a workload.
+  istioctl proxy-config diff productpage-v1-bb8d5cbc7-k7qbm productpage-v1-bb8d5cbc7-wz6cx.default
+
+  # Diff the configuration saved earlier with the current configuration of a pod.
+  kubectl exec productpage-v1-bb8d5cbc7-k7qbm -c istio-proxy -- curl -s 'localhost:15000/config_dump?include_eds' > before.json
+  istioctl proxy-config diff productpage-v1-bb8d5cbc7-k7qbm --file before.json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if !(len(args) == 2 && configDumpFile == "") && !(len(args) == 1 && configDumpFile != "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("diff requires 2 pods, or a pod and the --file parameter")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var fromName, toName string
+			var fromDump, toDump []byte
+			var err error
+			if configDumpFile != "" {
+				// The config dump file is compared with the current configuration of the pod.
+				fromName = configDumpFile
+				if fromDump, err = readFile(configDumpFile); err != nil {
+					return err
+				}
+			} else {
+				if fromName, fromDump, err = podConfigDump(args[0]); err != nil {
+					return err
+				}
+			}
+			if toName, toDump, err = podConfigDump(args[len(args)-1]); err != nil {
+				return err
+			}
+
+			comparator, err := compare.NewProxyComparator(c.OutOrStdout(), fromName, fromDump, toName, toDump)
+			if err != nil {
+				return err
+			}
+			same, err := comparator.Diff()
+			if err != nil {
+				return err
+			}
+			if !same {
+				return ProxyConfigDiffersError{}
+			}
+			return nil
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+
+	diffConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
+		"Envoy config dump JSON file to compare with the configuration of the pod")
+	diffConfigCmd.Long += "\n\n" + ExperimentalMsg
+	return diffConfigCmd
+}
+
+// ProxyConfigDiffersError indicates that the configurations compared by proxy-config diff differ.
+type ProxyConfigDiffersError struct{}
+
+func (ProxyConfigDiffersError) Error() string {
+	return "the configurations of the proxies differ"
+}
+
+// podConfigDump returns the name and the config dump, including the endpoints, of the pod.
+func podConfigDump(arg string) (string, []byte, error) {
+	podName, podNamespace, err := getPodName(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	dump, err := extractConfigDump(podName, podNamespace, true)
+	if err != nil {
+		return "", nil, err
+	}
+	return podName + "." + podNamespace, dump, nil
+}
+
 func proxyConfig() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "proxy-config",
@@ -1239,6 +1322,7 @@ func proxyConfig() *cobra.Command {
 	configCmd.AddCommand(edsConfigCmd())
 	configCmd.AddCommand(secretConfigCmd())
 	configCmd.AddCommand(rootCACompareConfigCmd())
+	configCmd.AddCommand(diffConfigCmd())
 
 	return configCmd
 }
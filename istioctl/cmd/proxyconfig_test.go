@@ -17,6 +17,8 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -41,6 +43,24 @@ func TestProxyConfig(t *testing.T) {
 		"details-v1-5b7f94f9bc-wp5tb": util.ReadFile(t, "../pkg/writer/envoy/logging/testdata/logging.txt"),
 		"httpbin-794b576b6c-qx6pf":    []byte("{}"),
 	}
+	configDump := func(cluster string) []byte {
+		return []byte(`{"configs": [
+  {"@type": "type.googleapis.com/envoy.admin.v3.ListenersConfigDump"},
+  {"@type": "type.googleapis.com/envoy.admin.v3.RoutesConfigDump"},
+  {"@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump", "dynamicActiveClusters": [
+    {"cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster", "name": "` + cluster + `"}}
+  ]}
+]}`)
+	}
+	diffConfig := map[string][]byte{
+		"productpage-v1-bb8d5cbc7-k7qbm": configDump("outbound|9080||reviews.default.svc.cluster.local"),
+		"productpage-v1-bb8d5cbc7-wz6cx": configDump("outbound|9080||reviews.default.svc.cluster.local"),
+		"reviews-v1-5b7f94f9bc-wp5tb":    configDump("outbound|9080||ratings.default.svc.cluster.local"),
+	}
+	configDumpFile := filepath.Join(t.TempDir(), "config_dump.json")
+	if err := os.WriteFile(configDumpFile, diffConfig["productpage-v1-bb8d5cbc7-wz6cx"], 0o644); err != nil {
+		t.Fatal(err)
+	}
 	cases := []execTestCase{
 		{
 			args:           strings.Split("proxy-config", " "),
@@ -114,6 +134,30 @@ func TestProxyConfig(t *testing.T) {
 			expectedString: "unable to retrieve Pod: pods \"invalid\" not found",
 			wantException:  true, // "istioctl proxy-config endpoint invalid" should fail
 		},
+		{ // diff missing pod
+			args:           strings.Split("proxy-config diff invalid", " "),
+			expectedString: "diff requires 2 pods, or a pod and the --file parameter",
+			wantException:  true, // "istioctl proxy-config diff invalid" should fail
+		},
+		{ // diff same configurations
+			execClientConfig: diffConfig,
+			args:             strings.Split("proxy-config diff productpage-v1-bb8d5cbc7-k7qbm productpage-v1-bb8d5cbc7-wz6cx", " "),
+		},
+		{ // diff configuration saved to a file
+			execClientConfig: diffConfig,
+			args:             strings.Split("proxy-config diff productpage-v1-bb8d5cbc7-k7qbm --file "+configDumpFile, " "),
+		},
+		{ // diff different configurations
+			execClientConfig: diffConfig,
+			args:             strings.Split("proxy-config diff productpage-v1-bb8d5cbc7-k7qbm reviews-v1-5b7f94f9bc-wp5tb", " "),
+			expectedString:   "the configurations of the proxies differ",
+			wantException:    true,
+		},
+		{ // diff invalid
+			args:           strings.Split("proxy-config diff invalid invalid-2", " "),
+			expectedString: "unable to retrieve Pod: pods \"invalid\" not found",
+			wantException:  true, // "istioctl proxy-config diff invalid invalid-2" should fail
+		},
 		{ // supplying nonexistent deployment name should result in error
 			args:           strings.Split("proxy-config clusters deployment/random-gibberish", " "),
 			expectedString: `"deployment/random-gibberish" does not refer to a pod`,
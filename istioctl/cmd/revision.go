@@ -111,6 +111,7 @@ func revisionCommand() *cobra.Command {
 
 	revisionCmd.AddCommand(revisionListCommand())
 	revisionCmd.AddCommand(revisionDescribeCommand())
+	revisionCmd.AddCommand(revisionCompareCommand())
 	revisionCmd.AddCommand(tagCommand())
 	return revisionCmd
 }
@@ -0,0 +1,243 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/istioctl/pkg/writer/compare"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/kube/labels"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+var revisionCompareSelector string
+
+func revisionCompareCommand() *cobra.Command {
+	compareCmd := &cobra.Command{
+		Use:   "compare <old-revision> <new-revision>",
+		Short: "Compare the configuration sent to a workload by the control planes of two revisions",
+		Long: `Compare the listeners, routes, clusters and endpoints sent by the control planes of two revisions to the
+same proxy, to find out the impact of an upgrade before moving the namespaces to the new revision.
+
+The proxy is the first one of the namespace, by workload, connected to the old revision, unless the pods are selected
+with --selector. Its configuration is read from the debug config dump of the istiod of the old revision, and the
+configuration the new revision would send to it is generated by an istiod of the new revision from the node of the
+proxy bootstrap, so the proxy does not need to be connected to the new revision.
+
+In addition to the differences between the resources, the changes likely to change how the traffic is handled are
+reported, such as the removed routes and the changed TLS settings.`,
+		Example: `  # Compare the configuration sent by the revisions 1-16 and 1-17 to a proxy of the default namespace
+  istioctl x revision compare 1-16 1-17 -n default
+
+  # Compare the configuration sent by the revisions 1-16 and 1-17 to a proxy of the productpage workload
+  istioctl x revision compare 1-16 1-17 -n bookinfo -l app=productpage`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("exactly 2 revisions should be specified")
+			}
+			for _, rev := range args {
+				if errs := validation.IsDNS1123Label(rev); len(errs) > 0 {
+					return fmt.Errorf("%s - invalid revision format: %v", rev, errs)
+				}
+			}
+			if args[0] == args[1] {
+				return fmt.Errorf("the revisions to compare should be different")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return compareRevisions(cmd.OutOrStdout(), args[0], args[1], handlers.HandleNamespace(namespace, defaultNamespace))
+		},
+	}
+	compareCmd.Flags().StringVarP(&revisionCompareSelector, "selector", "l", "",
+		"Label selector of the pods of the workload to compare the configuration of")
+	return compareCmd
+}
+
+func compareRevisions(w io.Writer, oldRev, newRev, ns string) error {
+	oldProxies, err := revisionProxies(oldRev)
+	if err != nil {
+		return err
+	}
+	client, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	pods, err := client.Kube().CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: revisionCompareSelector})
+	if err != nil {
+		return err
+	}
+	pod, err := selectSampleProxy(pods.Items, ns, oldRev, oldProxies)
+	if err != nil {
+		return err
+	}
+	proxyID := pod + "." + ns
+
+	oldDump, err := revisionConfigDump(oldRev, proxyID, oldProxies[proxyID])
+	if err != nil {
+		return err
+	}
+	node, err := proxyNode(pod, ns)
+	if err != nil {
+		return err
+	}
+	newDump, err := revisionConfigDumpPreview(newRev, node)
+	if err != nil {
+		return err
+	}
+	comparator, err := compare.NewProxyComparator(w, "revision "+oldRev, oldDump, "revision "+newRev, newDump)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Comparing the configuration of %s from revision %s with the one generated by revision %s.\n\n",
+		proxyID, oldRev, newRev)
+	if _, err := comparator.Diff(); err != nil {
+		return err
+	}
+	changes, err := comparator.BehaviorChanges()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "\nNo behavior changes found.")
+		return nil
+	}
+	fmt.Fprintln(w, "\nBehavior changes:")
+	for _, change := range changes {
+		fmt.Fprintf(w, "  - %s\n", change)
+	}
+	return nil
+}
+
+// revisionProxies returns the IDs of the proxies connected to the istiods of the revision, with the name of the
+// istiod each one is connected to.
+func revisionProxies(rev string) (map[string]string, error) {
+	client, err := kubeClientWithRevision(kubeconfig, configContext, rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	statuses, err := client.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/syncz")
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the istiod of revision %s: %v", rev, err)
+	}
+	proxies := map[string]string{}
+	for istiod, status := range statuses {
+		var ss []*xds.SyncStatus
+		if err := json.Unmarshal(status, &ss); err != nil {
+			return nil, fmt.Errorf("failed to parse the sync status of %s: %v", istiod, err)
+		}
+		for _, s := range ss {
+			proxies[s.ProxyID] = istiod
+		}
+	}
+	return proxies, nil
+}
+
+// revisionConfigDump returns the config dump, including the endpoints, of the proxy from the istiod it is connected to.
+func revisionConfigDump(rev, proxyID, istiod string) ([]byte, error) {
+	client, err := kubeClientWithRevision(kubeconfig, configContext, rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	path := fmt.Sprintf("/debug/config_dump?proxyID=%s&include_eds=true", proxyID)
+	dumps, err := client.AllDiscoveryDo(context.TODO(), istioNamespace, path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the istiod of revision %s: %v", rev, err)
+	}
+	dump, f := dumps[istiod]
+	if !f {
+		return nil, fmt.Errorf("%s did not return the config dump of %s", istiod, proxyID)
+	}
+	return dump, nil
+}
+
+// proxyNode returns the base64url encoded JSON of the node of the bootstrap of the proxy of the pod, which identifies
+// the proxy to istiod.
+func proxyNode(pod, ns string) (string, error) {
+	dump, err := extractConfigDump(pod, ns, false)
+	if err != nil {
+		return "", err
+	}
+	w := &configdump.Wrapper{}
+	if err := w.UnmarshalJSON(dump); err != nil {
+		return "", fmt.Errorf("failed to parse the config dump of %s.%s: %v", pod, ns, err)
+	}
+	bootstrap, err := w.GetBootstrapConfigDump()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the bootstrap of %s.%s: %v", pod, ns, err)
+	}
+	node, err := protomarshal.Marshal(bootstrap.GetBootstrap().GetNode())
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(node), nil
+}
+
+// revisionConfigDumpPreview returns the config dump, including the endpoints, generated by an istiod of the revision
+// for the proxy of the node, which does not need to be connected to it.
+func revisionConfigDumpPreview(rev, node string) ([]byte, error) {
+	client, err := kubeClientWithRevision(kubeconfig, configContext, rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	dumps, err := client.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/config_dump_preview?include_eds=true&node="+node)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query the istiod of revision %s: %v", rev, err)
+	}
+	istiods := make([]string, 0, len(dumps))
+	for istiod := range dumps {
+		istiods = append(istiods, istiod)
+	}
+	if len(istiods) == 0 {
+		return nil, fmt.Errorf("no istiod of revision %s returned the configuration of the proxy", rev)
+	}
+	// All the istiods of a revision generate the same configuration, use the first one for a stable output.
+	sort.Strings(istiods)
+	return dumps[istiods[0]], nil
+}
+
+// selectSampleProxy returns the name of the pod of the proxy connected to the old revision to compare the
+// configuration of: the first one, by name, of the first workload by canonical name and revision.
+func selectSampleProxy(pods []v1.Pod, ns, oldRev string, oldProxies map[string]string) (string, error) {
+	var sample, sampleWorkload string
+	for _, pod := range pods {
+		if _, f := oldProxies[pod.Name+"."+ns]; !f {
+			continue
+		}
+		name, version := labels.CanonicalService(pod.Labels, pod.Name)
+		workload := name + "/" + version
+		if sample == "" || workload < sampleWorkload || (workload == sampleWorkload && pod.Name < sample) {
+			sample, sampleWorkload = pod.Name, workload
+		}
+	}
+	if sample == "" {
+		return "", fmt.Errorf("no proxy of the namespace %s is connected to the revision %s", ns, oldRev)
+	}
+	return sample, nil
+}
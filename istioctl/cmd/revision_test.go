@@ -16,9 +16,12 @@ package cmd
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/api/operator/v1alpha1"
 )
@@ -120,3 +123,60 @@ func TestGetEnabledComponentsFromIOPSpec(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectSampleProxy(t *testing.T) {
+	pod := func(name string, labels map[string]string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+	}
+	productpage := map[string]string{"app": "productpage", "version": "v1"}
+	reviews := map[string]string{"app": "reviews", "version": "v1"}
+	pods := []v1.Pod{
+		pod("reviews-v1-b", reviews),
+		pod("reviews-v1-a", reviews),
+		pod("productpage-v1-b", productpage),
+		pod("productpage-v1-a", productpage),
+		pod("ratings-v1-a", map[string]string{"app": "ratings"}),
+	}
+	for _, tc := range []struct {
+		name       string
+		oldProxies []string
+		pod        string
+		err        string
+	}{
+		{
+			name:       "first proxy of the first workload",
+			oldProxies: []string{"reviews-v1-a.default", "productpage-v1-b.default", "productpage-v1-a.default"},
+			pod:        "productpage-v1-a",
+		},
+		{
+			name:       "only the proxies connected to the old revision",
+			oldProxies: []string{"reviews-v1-b.default", "ratings-v1-a.default"},
+			pod:        "ratings-v1-a",
+		},
+		{
+			name:       "no proxy connected to the old revision",
+			oldProxies: []string{"details-v1-a.default"},
+			err:        "no proxy of the namespace default is connected to the revision old",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			oldProxies := map[string]string{}
+			for _, p := range tc.oldProxies {
+				oldProxies[p] = "istiod"
+			}
+			got, err := selectSampleProxy(pods, "default", "old", oldProxies)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected the error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.pod {
+				t.Errorf("expected the pod %s, got %s", tc.pod, got)
+			}
+		})
+	}
+}
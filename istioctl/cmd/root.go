@@ -239,6 +239,8 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(preCheck())
 	experimentalCmd.AddCommand(statsConfigCmd())
 	experimentalCmd.AddCommand(checkInjectCommand())
+	experimentalCmd.AddCommand(simulateCmd())
+	experimentalCmd.AddCommand(caCommand())
 
 	analyzeCmd := Analyze()
 	hideInheritedFlags(analyzeCmd, FlagIstioNamespace)
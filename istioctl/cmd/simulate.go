@@ -0,0 +1,249 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/simulate"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/simulation"
+)
+
+type simulateArgs struct {
+	configDumpFile string
+	configFiles    []string
+
+	proxyType   string
+	proxyIP     string
+	proxyLabels string
+
+	address  string
+	port     int
+	host     string
+	path     string
+	headers  []string
+	protocol string
+	tls      string
+	sni      string
+	mode     string
+
+	method    string
+	principal string
+	sourceIP  string
+}
+
+func simulateCmd() *cobra.Command {
+	args := &simulateArgs{}
+	cmd := &cobra.Command{
+		Use:   "simulate [<type>/]<name>[.<namespace>]",
+		Short: "Trace a request through the configuration of a proxy",
+		Long: `Simulate traces a hypothetical request through the xDS configuration of a proxy, without sending
+any traffic. It prints the listener, filter chain, route and cluster that Envoy would apply to the request,
+and the decision of the authorization policies of the filter chain for the request.
+
+The configuration can be read from a running pod, from a standalone config dump file with flag -f,
+or generated offline from local Istio and Kubernetes YAML files with flag --config.`,
+		Example: `  # Trace an HTTP request from the productpage pod to reviews:
+  istioctl x simulate productpage-v1-123456-abcde --host reviews --port 9080 --path /reviews/0
+
+  # Trace a request using an Envoy config dump file:
+  istioctl x simulate -f productpage_config_dump.json --host reviews --port 9080
+
+  # Trace a request for a sidecar in namespace default, generating its config from local files:
+  istioctl x simulate --config services.yaml --config virtualservices.yaml -n default \
+    --proxy-labels app=productpage --host reviews --port 9080 --header end-user=jason
+
+  # Check whether a mTLS request from the sleep service account is allowed by the reviews pod:
+  istioctl x simulate reviews-v1-123456-abcde --mode inbound --tls mtls --port 9080 --path /admin \
+    --method POST --principal spiffe://cluster.local/ns/default/sa/sleep`,
+		Args: func(cmd *cobra.Command, posArgs []string) error {
+			if len(posArgs) > 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires only [<type>/]<name>[.<namespace>]")
+			}
+			if len(posArgs) == 0 && args.configDumpFile == "" && len(args.configFiles) == 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting pod name, config dump file, or config files")
+			}
+			if args.port == 0 {
+				return fmt.Errorf("--port is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, posArgs []string) error {
+			call, err := args.call()
+			if err != nil {
+				return err
+			}
+			res, err := args.resources(posArgs)
+			if err != nil {
+				return err
+			}
+			peer, err := args.peer()
+			if err != nil {
+				return err
+			}
+			report, err := simulate.Run(res, call, peer)
+			if err != nil {
+				return fmt.Errorf("simulation failed: %v", err)
+			}
+			return report.Print(cmd.OutOrStdout())
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&args.configDumpFile, "file", "f", "",
+		"The json file with Envoy config dump to simulate against")
+	cmd.PersistentFlags().StringSliceVar(&args.configFiles, "config", nil,
+		"Istio and Kubernetes YAML files to generate the proxy configuration from, instead of reading it from a proxy")
+	cmd.PersistentFlags().StringVar(&args.proxyType, "proxy-type", string(model.SidecarProxy),
+		"Type of the proxy to generate configuration for when using --config, one of sidecar or router")
+	cmd.PersistentFlags().StringVar(&args.proxyIP, "proxy-ip", "",
+		"IP address of the proxy to generate configuration for when using --config")
+	cmd.PersistentFlags().StringVar(&args.proxyLabels, "proxy-labels", "",
+		"Labels of the proxy to generate configuration for when using --config, split multiple labels by commas")
+	cmd.PersistentFlags().StringVar(&args.address, "address", "",
+		"Destination IP address of the request. Defaults to an address not matching any service")
+	cmd.PersistentFlags().IntVar(&args.port, "port", 0, "Destination port of the request")
+	cmd.PersistentFlags().StringVar(&args.host, "host", "", "Host header (or SNI, for TLS requests) of the request")
+	cmd.PersistentFlags().StringVar(&args.path, "path", "/", "Path of the request")
+	cmd.PersistentFlags().StringArrayVar(&args.headers, "header", nil, "Header of the request, in the form key=value")
+	cmd.PersistentFlags().StringVar(&args.protocol, "protocol", string(simulation.HTTP),
+		"Protocol of the request, one of http, http2 or tcp")
+	cmd.PersistentFlags().StringVar(&args.tls, "tls", string(simulation.Plaintext),
+		"TLS mode of the request, one of plaintext, tls or mtls")
+	cmd.PersistentFlags().StringVar(&args.sni, "sni", "", "SNI of the request. Defaults to the host for TLS requests")
+	cmd.PersistentFlags().StringVar(&args.mode, "mode", string(simulation.CallModeOutbound),
+		"How the request reaches the proxy, one of outbound, inbound or gateway")
+	cmd.PersistentFlags().StringVar(&args.method, "method", http.MethodGet,
+		"Method of the request, to evaluate the authorization policies")
+	cmd.PersistentFlags().StringVar(&args.principal, "principal", "",
+		"SPIFFE identity of the client of a mTLS request, to evaluate the authorization policies, "+
+			"for example spiffe://cluster.local/ns/default/sa/sleep")
+	cmd.PersistentFlags().StringVar(&args.sourceIP, "source-ip", "",
+		"IP address of the client of the request, to evaluate the authorization policies")
+	return cmd
+}
+
+func (a *simulateArgs) call() (simulation.Call, error) {
+	call := simulation.Call{
+		Address:    a.address,
+		Port:       a.port,
+		Path:       a.path,
+		HostHeader: a.host,
+		Headers:    http.Header{},
+		Sni:        a.sni,
+	}
+	switch p := simulation.Protocol(a.protocol); p {
+	case simulation.HTTP, simulation.HTTP2, simulation.TCP:
+		call.Protocol = p
+	default:
+		return call, fmt.Errorf("unknown protocol %q", a.protocol)
+	}
+	switch t := simulation.TLSMode(a.tls); t {
+	case simulation.Plaintext, simulation.TLS, simulation.MTLS:
+		call.TLS = t
+	default:
+		return call, fmt.Errorf("unknown tls mode %q", a.tls)
+	}
+	switch m := simulation.CallMode(a.mode); m {
+	case simulation.CallModeOutbound, simulation.CallModeInbound, simulation.CallModeGateway:
+		call.CallMode = m
+	default:
+		return call, fmt.Errorf("unknown mode %q", a.mode)
+	}
+	for _, h := range a.headers {
+		k, v, ok := strings.Cut(h, "=")
+		if !ok {
+			return call, fmt.Errorf("invalid header %q, expected key=value", h)
+		}
+		call.Headers.Add(k, v)
+	}
+	return call, nil
+}
+
+func (a *simulateArgs) peer() (simulate.Peer, error) {
+	peer := simulate.Peer{Principal: a.principal, Method: a.method}
+	if a.sourceIP != "" {
+		if peer.SourceIP = net.ParseIP(a.sourceIP); peer.SourceIP == nil {
+			return peer, fmt.Errorf("invalid source IP %q", a.sourceIP)
+		}
+	}
+	return peer, nil
+}
+
+func (a *simulateArgs) resources(posArgs []string) (*simulate.Resources, error) {
+	if len(a.configFiles) > 0 {
+		var input []string
+		for _, f := range a.configFiles {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				return nil, err
+			}
+			input = append(input, string(b))
+		}
+		var labels map[string]string
+		if a.proxyLabels != "" {
+			ls, err := metav1.ParseToLabelSelector(a.proxyLabels)
+			if err != nil {
+				return nil, err
+			}
+			labels = ls.MatchLabels
+		}
+		nodeType := model.NodeType(a.proxyType)
+		if !model.IsApplicationNodeType(nodeType) {
+			return nil, fmt.Errorf("unknown proxy type %q", a.proxyType)
+		}
+		return simulate.FromYAML(strings.Join(input, "\n---\n"), simulate.ProxyOptions{
+			Namespace: handlers.HandleNamespace(namespace, defaultNamespace),
+			Labels:    labels,
+			IP:        a.proxyIP,
+			Type:      nodeType,
+		})
+	}
+
+	var cd *configdump.Wrapper
+	var err error
+	if a.configDumpFile != "" {
+		cd, err = getConfigDumpFromFile(a.configDumpFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config dump from file %s: %s", a.configDumpFile, err)
+		}
+	} else {
+		kubeClient, err := kubeClient(kubeconfig, configContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create k8s client: %w", err)
+		}
+		podName, podNamespace, err := handlers.InferPodInfoFromTypedResource(posArgs[0],
+			handlers.HandleNamespace(namespace, defaultNamespace),
+			kubeClient.UtilFactory())
+		if err != nil {
+			return nil, err
+		}
+		cd, err = getConfigDumpFromPod(podName, podNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config dump from pod %s in %s", podName, podNamespace)
+		}
+	}
+	return simulate.FromConfigDump(cd)
+}
@@ -0,0 +1,296 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"text/tabwriter"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	sm "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/pkg/log"
+)
+
+const (
+	// virtualInboundListener is the listener of the inbound traffic of the sidecars.
+	virtualInboundListener = "virtualInbound"
+
+	// The attributes of the JWT of the request, in the dynamic metadata of the Istio authn filter.
+	attrRequestPrincipal = "request.auth.principal"
+	attrRequestAudiences = "request.auth.audiences"
+	attrRequestPresenter = "request.auth.presenter"
+	attrRequestClaims    = "request.auth.claims"
+)
+
+// Request is a hypothetical request to a workload, checked against its authorization policies.
+type Request struct {
+	// Principal is the SPIFFE identity of the client, or empty if the request is not over mTLS.
+	Principal string
+	SourceIP  net.IP
+	// DestinationIP and DestinationPort are the address of the workload the request is sent to. If the port is 0, the
+	// filter chains not bound to a port are checked.
+	DestinationIP   net.IP
+	DestinationPort uint32
+	// ServerName is the SNI of the TLS connection.
+	ServerName string
+
+	// HTTP is true for a HTTP request, otherwise the request is a TCP connection.
+	HTTP    bool
+	Method  string
+	Path    string
+	Headers map[string][]string
+	// Claims are the claims of the JWT of the request, if any.
+	Claims map[string][]string
+
+	metadata map[string]any
+}
+
+// Decision is the result of the authorization of a request.
+type Decision string
+
+const (
+	DecisionAllow Decision = "ALLOW"
+	DecisionDeny  Decision = "DENY"
+	// DecisionCustom means the request is allowed only if allowed by the external authorizer of a CUSTOM policy.
+	DecisionCustom Decision = "CUSTOM"
+)
+
+// Match is a rule of an AuthorizationPolicy matching the request.
+type Match struct {
+	// Action is ALLOW, DENY, CUSTOM or AUDIT.
+	Action string
+	Policy string
+	Rule   string
+	// DryRun is true if the policy is in dry-run mode, and so has no effect on the decision.
+	DryRun bool
+}
+
+// CheckResult is the result of the check of a request.
+type CheckResult struct {
+	// FilterChain is the name of the filter chain the request was checked against.
+	FilterChain string
+	Decision    Decision
+	// Reason explains the decision.
+	Reason string
+	// Matches are the rules matching the request, in the order they were evaluated. The rules after the one denying
+	// the request are not evaluated.
+	Matches []Match
+}
+
+// Check evaluates the RBAC filters generated from the AuthorizationPolicy against the request, with the semantics of
+// Envoy. The filter chain is the inbound one of the destination port of the request, preferring the chain of the
+// transport (mTLS or plaintext) and protocol of the request.
+func (a *Analyzer) Check(req *Request) (*CheckResult, error) {
+	listeners, err := a.listeners()
+	if err != nil {
+		return nil, err
+	}
+	fc := selectFilterChain(parse(listeners), req)
+	if fc == nil {
+		return nil, fmt.Errorf("no inbound filter chain found for port %d", req.DestinationPort)
+	}
+	return evaluate(fc, req), nil
+}
+
+// CheckFilterChain evaluates the RBAC filters of the filter chain against the request, for the callers which already
+// selected the filter chain the request is sent to.
+func CheckFilterChain(fc *listener.FilterChain, req *Request) *CheckResult {
+	return evaluate(parseFilterChain(fc), req)
+}
+
+func selectFilterChain(listeners []*parsedListener, req *Request) *filterChain {
+	// The sidecars receive all the inbound traffic on the virtual inbound listener, gateways on a listener per port.
+	for _, l := range listeners {
+		if l.name == virtualInboundListener {
+			listeners = []*parsedListener{l}
+			break
+		}
+	}
+	var selected *filterChain
+	best := -1
+	for _, l := range listeners {
+		for _, fc := range l.filterChains {
+			port := fc.destinationPort
+			if port == 0 && l.name != virtualInboundListener {
+				port = l.port
+			}
+			score := 0
+			switch {
+			case port != 0 && port != req.DestinationPort:
+				continue
+			case port != 0:
+				score += 4
+			}
+			if (req.Principal != "") == (fc.transportProtocol == "tls") {
+				score += 2
+			}
+			if fc.http == req.HTTP {
+				score++
+			}
+			if score > best {
+				selected, best = fc, score
+			}
+		}
+	}
+	return selected
+}
+
+func evaluate(fc *filterChain, req *Request) *CheckResult {
+	req.metadata = map[string]any{sm.AuthnFilterName: req.authnMetadata()}
+	result := &CheckResult{FilterChain: fc.name, Decision: DecisionAllow}
+	custom := ""
+
+	type rbacFilter struct {
+		rules, shadowRules *rbacpb.RBAC
+		shadowPrefix       string
+	}
+	var filters []rbacFilter
+	if req.HTTP && fc.http {
+		for _, f := range fc.rbacHTTP {
+			filters = append(filters, rbacFilter{f.GetRules(), f.GetShadowRules(), f.GetShadowRulesStatPrefix()})
+		}
+	} else {
+		for _, f := range fc.rbacTCP {
+			filters = append(filters, rbacFilter{f.GetRules(), f.GetShadowRules(), f.GetShadowRulesStatPrefix()})
+		}
+	}
+
+	for _, f := range filters {
+		if f.shadowRules != nil {
+			names := req.matchedPolicies(f.shadowRules)
+			if f.shadowPrefix == authzmodel.RBACExtAuthzShadowRulesStatPrefix {
+				// The shadow rules of the CUSTOM policies select the requests sent to the external authorizer.
+				result.addMatches("CUSTOM", names, false)
+				if len(names) > 0 && custom == "" {
+					custom = names[0]
+				}
+			} else {
+				result.addMatches(actionName(f.shadowRules.GetAction()), names, true)
+			}
+		}
+		if f.rules == nil {
+			continue
+		}
+		names := req.matchedPolicies(f.rules)
+		action := f.rules.GetAction()
+		result.addMatches(actionName(action), names, false)
+		switch {
+		case action == rbacpb.RBAC_DENY && len(names) > 0:
+			policy, rule := extractName(names[0])
+			result.Decision = DecisionDeny
+			result.Reason = fmt.Sprintf("denied by the DENY policy %s, rule %s", policy, rule)
+			return result
+		case action == rbacpb.RBAC_ALLOW && len(names) == 0:
+			result.Decision = DecisionDeny
+			result.Reason = "no ALLOW policy matched the request"
+			return result
+		}
+	}
+
+	if custom != "" {
+		policy, rule := extractName(custom)
+		result.Decision = DecisionCustom
+		result.Reason = fmt.Sprintf("sent to the external authorizer by the CUSTOM policy %s, rule %s, "+
+			"and allowed by the other policies", policy, rule)
+		return result
+	}
+	for _, m := range result.Matches {
+		if m.Action == "ALLOW" && !m.DryRun {
+			result.Reason = fmt.Sprintf("allowed by the ALLOW policy %s, rule %s", m.Policy, m.Rule)
+			return result
+		}
+	}
+	result.Reason = "no DENY policy matched the request and no ALLOW policy applies to the workload"
+	return result
+}
+
+func (c *CheckResult) addMatches(action string, names []string, dryRun bool) {
+	for _, name := range names {
+		policy, rule := extractName(name)
+		c.Matches = append(c.Matches, Match{Action: action, Policy: policy, Rule: rule, DryRun: dryRun})
+	}
+}
+
+// actionName returns the name of the action of the AuthorizationPolicy generating the RBAC rules.
+func actionName(action rbacpb.RBAC_Action) string {
+	if action == rbacpb.RBAC_LOG {
+		return "AUDIT"
+	}
+	return action.String()
+}
+
+// authnMetadata returns the dynamic metadata of the Istio authn filter for the JWT of the request. The claims are
+// lists of strings, and only the first audience is set, as in the filter.
+func (r *Request) authnMetadata() map[string]any {
+	if len(r.Claims) == 0 {
+		return nil
+	}
+	md := map[string]any{}
+	first := func(claim string) string {
+		if values := r.Claims[claim]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if iss, sub := first("iss"), first("sub"); iss != "" && sub != "" {
+		md[attrRequestPrincipal] = iss + "/" + sub
+	}
+	if aud := first("aud"); aud != "" {
+		md[attrRequestAudiences] = aud
+	}
+	if azp := first("azp"); azp != "" {
+		md[attrRequestPresenter] = azp
+	}
+	claims := map[string]any{}
+	for name, values := range r.Claims {
+		list := make([]any, 0, len(values))
+		for _, v := range values {
+			list = append(list, v)
+		}
+		claims[name] = list
+	}
+	md[attrRequestClaims] = claims
+	return md
+}
+
+// PrintCheckResult prints the decision, and the rules matching the request.
+func PrintCheckResult(writer io.Writer, result *CheckResult) {
+	buf := strings.Builder{}
+	buf.WriteString(fmt.Sprintf("Checked against the filter chain %q.\n\n", result.FilterChain))
+	if len(result.Matches) > 0 {
+		buf.WriteString("ACTION\tAuthorizationPolicy\tRULE\tMODE\n")
+		for _, m := range result.Matches {
+			mode := "enforced"
+			if m.DryRun {
+				mode = "dry-run"
+			}
+			buf.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s\n", m.Action, m.Policy, m.Rule, mode))
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(fmt.Sprintf("DECISION: %s (%s)\n", result.Decision, result.Reason))
+
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 3, ' ', 0)
+	if _, err := fmt.Fprint(w, buf.String()); err != nil {
+		log.Errorf("failed to print output: %s", err)
+	}
+	_ = w.Flush()
+}
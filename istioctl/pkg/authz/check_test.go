@@ -0,0 +1,245 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	authzpb "istio.io/api/security/v1beta1"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+func rbacRules(t *testing.T, action rbacpb.RBAC_Action, policy string, rules ...string) *rbacpb.RBAC {
+	t.Helper()
+	out := &rbacpb.RBAC{Action: action, Policies: map[string]*rbacpb.Policy{}}
+	for i, rule := range rules {
+		r := &authzpb.Rule{}
+		if err := protomarshal.ApplyYAML(rule, r); err != nil {
+			t.Fatal(err)
+		}
+		m, err := authzmodel.New(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated, err := m.Generate(false, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Policies[fmt.Sprintf("ns[foo]-policy[%s]-rule[%d]", policy, i)] = generated
+	}
+	return out
+}
+
+func inboundListener(t *testing.T, filters ...*rbachttp.RBAC) *listener.Listener {
+	t.Helper()
+	var httpFilters []*hcm.HttpFilter
+	for _, f := range filters {
+		httpFilters = append(httpFilters, &hcm.HttpFilter{
+			Name:       wellknown.HTTPRoleBasedAccessControl,
+			ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: protoconv.MessageToAny(f)},
+		})
+	}
+	chain := func(name, transport string) *listener.FilterChain {
+		return &listener.FilterChain{
+			Name: name,
+			FilterChainMatch: &listener.FilterChainMatch{
+				DestinationPort:   wrapperspb.UInt32(8000),
+				TransportProtocol: transport,
+			},
+			Filters: []*listener.Filter{{
+				Name: wellknown.HTTPConnectionManager,
+				ConfigType: &listener.Filter_TypedConfig{
+					TypedConfig: protoconv.MessageToAny(&hcm.HttpConnectionManager{HttpFilters: httpFilters}),
+				},
+			}},
+		}
+	}
+	return &listener.Listener{
+		Name:         virtualInboundListener,
+		FilterChains: []*listener.FilterChain{chain("0.0.0.0_8000_tls", "tls"), chain("0.0.0.0_8000", "raw_buffer")},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	audit := &rbachttp.RBAC{Rules: rbacRules(t, rbacpb.RBAC_LOG, "audit", `
+to:
+- operation:
+    paths: ["/admin*"]`)}
+	deny := &rbachttp.RBAC{
+		Rules: rbacRules(t, rbacpb.RBAC_DENY, "deny-admin", `
+to:
+- operation:
+    paths: ["/admin*"]
+when:
+- key: request.auth.claims[groups]
+  notValues: ["admin"]`),
+		ShadowRules: rbacRules(t, rbacpb.RBAC_DENY, "deny-dry-run", `
+to:
+- operation:
+    methods: ["DELETE"]`),
+		ShadowRulesStatPrefix: authzmodel.RBACShadowRulesDenyStatPrefix,
+	}
+	allow := &rbachttp.RBAC{Rules: rbacRules(t, rbacpb.RBAC_ALLOW, "allow",
+		`
+from:
+- source:
+    principals: ["cluster.local/ns/foo/sa/sleep"]
+to:
+- operation:
+    methods: ["GET", "DELETE"]
+    ports: ["8000"]`,
+		`
+from:
+- source:
+    ipBlocks: ["10.0.0.0/8"]
+to:
+- operation:
+    hosts: ["httpbin.foo*"]`)}
+	listeners := parse([]*listener.Listener{inboundListener(t, audit, deny, allow)})
+
+	sleep := "spiffe://cluster.local/ns/foo/sa/sleep"
+	cases := []struct {
+		name     string
+		req      Request
+		chain    string
+		decision Decision
+		reason   string
+		matches  []Match
+	}{
+		{
+			name:     "allowed principal",
+			req:      Request{Principal: sleep, HTTP: true, Method: "GET", Path: "/status?x=1"},
+			chain:    "0.0.0.0_8000_tls",
+			decision: DecisionAllow,
+			reason:   "allowed by the ALLOW policy allow.foo, rule 0",
+			matches:  []Match{{Action: "ALLOW", Policy: "allow.foo", Rule: "0"}},
+		},
+		{
+			name:     "dry-run deny",
+			req:      Request{Principal: sleep, HTTP: true, Method: "DELETE", Path: "/status"},
+			chain:    "0.0.0.0_8000_tls",
+			decision: DecisionAllow,
+			reason:   "allowed by the ALLOW policy allow.foo, rule 0",
+			matches: []Match{
+				{Action: "DENY", Policy: "deny-dry-run.foo", Rule: "0", DryRun: true},
+				{Action: "ALLOW", Policy: "allow.foo", Rule: "0"},
+			},
+		},
+		{
+			name:     "denied path",
+			req:      Request{Principal: sleep, HTTP: true, Method: "GET", Path: "/admin/users"},
+			chain:    "0.0.0.0_8000_tls",
+			decision: DecisionDeny,
+			reason:   "denied by the DENY policy deny-admin.foo, rule 0",
+			matches: []Match{
+				{Action: "AUDIT", Policy: "audit.foo", Rule: "0"},
+				{Action: "DENY", Policy: "deny-admin.foo", Rule: "0"},
+			},
+		},
+		{
+			name: "admin claim",
+			req: Request{
+				Principal: sleep, HTTP: true, Method: "GET", Path: "/admin/users",
+				Claims: map[string][]string{"iss": {"issuer"}, "sub": {"alice"}, "groups": {"dev", "admin"}},
+			},
+			chain:    "0.0.0.0_8000_tls",
+			decision: DecisionAllow,
+			reason:   "allowed by the ALLOW policy allow.foo, rule 0",
+			matches: []Match{
+				{Action: "AUDIT", Policy: "audit.foo", Rule: "0"},
+				{Action: "ALLOW", Policy: "allow.foo", Rule: "0"},
+			},
+		},
+		{
+			name: "allowed ip and host",
+			req: Request{
+				SourceIP: net.ParseIP("10.1.2.3"), HTTP: true, Method: "POST", Path: "/",
+				Headers: map[string][]string{"host": {"HTTPBIN.foo.svc"}},
+			},
+			chain:    "0.0.0.0_8000",
+			decision: DecisionAllow,
+			reason:   "allowed by the ALLOW policy allow.foo, rule 1",
+			matches:  []Match{{Action: "ALLOW", Policy: "allow.foo", Rule: "1"}},
+		},
+		{
+			name:     "no allow policy matched",
+			req:      Request{SourceIP: net.ParseIP("192.168.0.1"), HTTP: true, Method: "GET", Path: "/"},
+			chain:    "0.0.0.0_8000",
+			decision: DecisionDeny,
+			reason:   "no ALLOW policy matched the request",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := tc.req
+			req.DestinationPort = 8000
+			fc := selectFilterChain(listeners, &req)
+			if fc == nil || fc.name != tc.chain {
+				t.Fatalf("expected the filter chain %s, got %+v", tc.chain, fc)
+			}
+			result := evaluate(fc, &req)
+			if result.Decision != tc.decision || result.Reason != tc.reason {
+				t.Errorf("expected %s (%s), got %s (%s)", tc.decision, tc.reason, result.Decision, result.Reason)
+			}
+			if !reflect.DeepEqual(result.Matches, tc.matches) {
+				t.Errorf("expected the matches %+v, got %+v", tc.matches, result.Matches)
+			}
+		})
+	}
+}
+
+func TestCheckCustom(t *testing.T) {
+	custom := &rbachttp.RBAC{
+		ShadowRules: rbacRules(t, rbacpb.RBAC_DENY, "ext-authz", `
+to:
+- operation:
+    paths: ["/api/*"]`),
+		ShadowRulesStatPrefix: authzmodel.RBACExtAuthzShadowRulesStatPrefix,
+	}
+	listeners := parse([]*listener.Listener{inboundListener(t, custom)})
+
+	req := &Request{HTTP: true, Method: "GET", Path: "/api/users", DestinationPort: 8000}
+	result := evaluate(selectFilterChain(listeners, req), req)
+	if result.Decision != DecisionCustom {
+		t.Fatalf("expected the request to be sent to the external authorizer, got %+v", result)
+	}
+
+	out := &bytes.Buffer{}
+	PrintCheckResult(out, result)
+	for _, want := range []string{"CUSTOM   ext-authz.foo", "DECISION: CUSTOM"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the output:\n%s", want, out.String())
+		}
+	}
+
+	req = &Request{HTTP: true, Method: "GET", Path: "/status", DestinationPort: 8000}
+	if result := evaluate(selectFilterChain(listeners, req), req); result.Decision != DecisionAllow {
+		t.Fatalf("expected the request to be allowed, got %+v", result)
+	}
+}
@@ -40,11 +40,17 @@ const (
 var re = regexp.MustCompile(`ns\[(.+)\]-policy\[(.+)\]-rule\[(.+)\]`)
 
 type filterChain struct {
-	rbacHTTP []*rbachttp.RBAC
-	rbacTCP  []*rbactcp.RBAC
+	name              string
+	destinationPort   uint32
+	transportProtocol string
+	http              bool
+	rbacHTTP          []*rbachttp.RBAC
+	rbacTCP           []*rbactcp.RBAC
 }
 
 type parsedListener struct {
+	name         string
+	port         uint32
 	filterChains []*filterChain
 }
 
@@ -80,40 +86,51 @@ func getHTTPFilterConfig(filter *hcm.HttpFilter, out proto.Message) error {
 func parse(listeners []*listener.Listener) []*parsedListener {
 	var parsedListeners []*parsedListener
 	for _, l := range listeners {
-		parsed := &parsedListener{}
+		parsed := &parsedListener{
+			name: l.GetName(),
+			port: l.GetAddress().GetSocketAddress().GetPortValue(),
+		}
 		for _, fc := range l.FilterChains {
-			parsedFC := &filterChain{}
-			for _, filter := range fc.Filters {
-				switch filter.Name {
-				case wellknown.HTTPConnectionManager, "envoy.http_connection_manager":
-					if cm := getHTTPConnectionManager(filter); cm != nil {
-						for _, httpFilter := range cm.GetHttpFilters() {
-							switch httpFilter.GetName() {
-							case wellknown.HTTPRoleBasedAccessControl:
-								rbacHTTP := &rbachttp.RBAC{}
-								if err := getHTTPFilterConfig(httpFilter, rbacHTTP); err != nil {
-									log.Errorf("found RBAC HTTP filter but failed to parse: %s", err)
-								} else {
-									parsedFC.rbacHTTP = append(parsedFC.rbacHTTP, rbacHTTP)
-								}
-							}
+			parsed.filterChains = append(parsed.filterChains, parseFilterChain(fc))
+		}
+		parsedListeners = append(parsedListeners, parsed)
+	}
+	return parsedListeners
+}
+
+func parseFilterChain(fc *listener.FilterChain) *filterChain {
+	parsedFC := &filterChain{
+		name:              fc.GetName(),
+		destinationPort:   fc.GetFilterChainMatch().GetDestinationPort().GetValue(),
+		transportProtocol: fc.GetFilterChainMatch().GetTransportProtocol(),
+	}
+	for _, filter := range fc.Filters {
+		switch filter.Name {
+		case wellknown.HTTPConnectionManager, "envoy.http_connection_manager":
+			parsedFC.http = true
+			if cm := getHTTPConnectionManager(filter); cm != nil {
+				for _, httpFilter := range cm.GetHttpFilters() {
+					switch httpFilter.GetName() {
+					case wellknown.HTTPRoleBasedAccessControl:
+						rbacHTTP := &rbachttp.RBAC{}
+						if err := getHTTPFilterConfig(httpFilter, rbacHTTP); err != nil {
+							log.Errorf("found RBAC HTTP filter but failed to parse: %s", err)
+						} else {
+							parsedFC.rbacHTTP = append(parsedFC.rbacHTTP, rbacHTTP)
 						}
 					}
-				case wellknown.RoleBasedAccessControl:
-					rbacTCP := &rbactcp.RBAC{}
-					if err := getFilterConfig(filter, rbacTCP); err != nil {
-						log.Errorf("found RBAC network filter but failed to parse: %s", err)
-					} else {
-						parsedFC.rbacTCP = append(parsedFC.rbacTCP, rbacTCP)
-					}
 				}
 			}
-
-			parsed.filterChains = append(parsed.filterChains, parsedFC)
+		case wellknown.RoleBasedAccessControl:
+			rbacTCP := &rbactcp.RBAC{}
+			if err := getFilterConfig(filter, rbacTCP); err != nil {
+				log.Errorf("found RBAC network filter but failed to parse: %s", err)
+			} else {
+				parsedFC.rbacTCP = append(parsedFC.rbacTCP, rbacTCP)
+			}
 		}
-		parsedListeners = append(parsedListeners, parsed)
 	}
-	return parsedListeners
+	return parsedFC
 }
 
 func extractName(name string) (string, string) {
@@ -0,0 +1,308 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+
+	authzmatcher "istio.io/istio/pilot/pkg/security/authz/matcher"
+	"istio.io/pkg/log"
+)
+
+// This file evaluates the Envoy RBAC policies generated from the AuthorizationPolicy against a request, following the
+// semantics of the Envoy RBAC filter.
+
+// matchedPolicies returns the sorted names of the policies of the RBAC rules matching the request.
+func (r *Request) matchedPolicies(rules *rbacpb.RBAC) []string {
+	var names []string
+	for name, policy := range rules.GetPolicies() {
+		if r.matchPolicy(policy) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matchPolicy returns true if any permission and any principal of the policy match the request.
+func (r *Request) matchPolicy(policy *rbacpb.Policy) bool {
+	permitted := false
+	for _, p := range policy.GetPermissions() {
+		if r.matchPermission(p) {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		return false
+	}
+	for _, p := range policy.GetPrincipals() {
+		if r.matchPrincipal(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Request) matchPermission(p *rbacpb.Permission) bool {
+	switch rule := p.GetRule().(type) {
+	case *rbacpb.Permission_Any:
+		return rule.Any
+	case *rbacpb.Permission_AndRules:
+		for _, p := range rule.AndRules.GetRules() {
+			if !r.matchPermission(p) {
+				return false
+			}
+		}
+		return true
+	case *rbacpb.Permission_OrRules:
+		for _, p := range rule.OrRules.GetRules() {
+			if r.matchPermission(p) {
+				return true
+			}
+		}
+		return false
+	case *rbacpb.Permission_NotRule:
+		return !r.matchPermission(rule.NotRule)
+	case *rbacpb.Permission_Header:
+		return r.matchHeader(rule.Header)
+	case *rbacpb.Permission_UrlPath:
+		return r.matchPath(rule.UrlPath)
+	case *rbacpb.Permission_DestinationIp:
+		return matchCIDR(rule.DestinationIp, r.DestinationIP)
+	case *rbacpb.Permission_DestinationPort:
+		return r.DestinationPort != 0 && rule.DestinationPort == r.DestinationPort
+	case *rbacpb.Permission_DestinationPortRange:
+		port := int32(r.DestinationPort)
+		return r.DestinationPort != 0 && port >= rule.DestinationPortRange.GetStart() && port < rule.DestinationPortRange.GetEnd()
+	case *rbacpb.Permission_Metadata:
+		return r.matchMetadata(rule.Metadata)
+	case *rbacpb.Permission_RequestedServerName:
+		return matchString(rule.RequestedServerName, r.ServerName)
+	default:
+		log.Warnf("unsupported RBAC permission %T, assuming it does not match", rule)
+		return false
+	}
+}
+
+func (r *Request) matchPrincipal(p *rbacpb.Principal) bool {
+	switch id := p.GetIdentifier().(type) {
+	case *rbacpb.Principal_Any:
+		return id.Any
+	case *rbacpb.Principal_AndIds:
+		for _, p := range id.AndIds.GetIds() {
+			if !r.matchPrincipal(p) {
+				return false
+			}
+		}
+		return true
+	case *rbacpb.Principal_OrIds:
+		for _, p := range id.OrIds.GetIds() {
+			if r.matchPrincipal(p) {
+				return true
+			}
+		}
+		return false
+	case *rbacpb.Principal_NotId:
+		return !r.matchPrincipal(id.NotId)
+	case *rbacpb.Principal_Authenticated_:
+		// Only the requests over mTLS are authenticated.
+		if r.Principal == "" {
+			return false
+		}
+		return id.Authenticated.GetPrincipalName() == nil || matchString(id.Authenticated.GetPrincipalName(), r.Principal)
+	case *rbacpb.Principal_SourceIp:
+		return matchCIDR(id.SourceIp, r.SourceIP)
+	case *rbacpb.Principal_DirectRemoteIp:
+		return matchCIDR(id.DirectRemoteIp, r.SourceIP)
+	case *rbacpb.Principal_RemoteIp:
+		return matchCIDR(id.RemoteIp, r.SourceIP)
+	case *rbacpb.Principal_Header:
+		return r.matchHeader(id.Header)
+	case *rbacpb.Principal_UrlPath:
+		return r.matchPath(id.UrlPath)
+	case *rbacpb.Principal_Metadata:
+		return r.matchMetadata(id.Metadata)
+	default:
+		log.Warnf("unsupported RBAC principal %T, assuming it does not match", id)
+		return false
+	}
+}
+
+// header returns the value of the header, with the values of a repeated header joined by commas as in Envoy.
+func (r *Request) header(name string) (string, bool) {
+	name = strings.ToLower(name)
+	switch name {
+	case ":method":
+		return r.Method, r.Method != ""
+	case ":path":
+		return r.Path, r.Path != ""
+	case ":authority", "host":
+		if values, f := r.Headers["host"]; f {
+			return strings.Join(values, ","), true
+		}
+		values, f := r.Headers[":authority"]
+		return strings.Join(values, ","), f
+	}
+	values, f := r.Headers[name]
+	return strings.Join(values, ","), f
+}
+
+func (r *Request) matchHeader(m *routepb.HeaderMatcher) bool {
+	if !r.HTTP {
+		return false
+	}
+	value, present := r.header(m.GetName())
+	if !present && m.GetTreatMissingHeaderAsEmpty() {
+		present = true
+	}
+	matched := false
+	switch spec := m.GetHeaderMatchSpecifier().(type) {
+	case *routepb.HeaderMatcher_PresentMatch:
+		matched = present == spec.PresentMatch
+	case *routepb.HeaderMatcher_ExactMatch:
+		matched = present && value == spec.ExactMatch
+	case *routepb.HeaderMatcher_PrefixMatch:
+		matched = present && strings.HasPrefix(value, spec.PrefixMatch)
+	case *routepb.HeaderMatcher_SuffixMatch:
+		matched = present && strings.HasSuffix(value, spec.SuffixMatch)
+	case *routepb.HeaderMatcher_ContainsMatch:
+		matched = present && strings.Contains(value, spec.ContainsMatch)
+	case *routepb.HeaderMatcher_SafeRegexMatch:
+		matched = present && matchRegex(spec.SafeRegexMatch.GetRegex(), value)
+	case *routepb.HeaderMatcher_StringMatch:
+		matched = present && matchString(spec.StringMatch, value)
+	case nil:
+		matched = present
+	default:
+		log.Warnf("unsupported header matcher %T, assuming it does not match", spec)
+	}
+	return matched != m.GetInvertMatch()
+}
+
+func (r *Request) matchPath(m *matcher.PathMatcher) bool {
+	if !r.HTTP {
+		return false
+	}
+	// The path is matched without the query and the fragment.
+	path := r.Path
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	return matchString(m.GetPath(), path)
+}
+
+func (r *Request) matchMetadata(m *matcher.MetadataMatcher) bool {
+	var value any = r.metadata[m.GetFilter()]
+	for _, segment := range m.GetPath() {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			value = nil
+			break
+		}
+		value = fields[segment.GetKey()]
+	}
+	return matchValue(m.GetValue(), value) != m.GetInvert()
+}
+
+// matchValue matches a metadata value, which is nil, a string, a bool, a float64, a []any or a map[string]any.
+func matchValue(m *matcher.ValueMatcher, value any) bool {
+	switch pattern := m.GetMatchPattern().(type) {
+	case *matcher.ValueMatcher_NullMatch_:
+		return value == nil
+	case *matcher.ValueMatcher_PresentMatch:
+		return (value != nil) == pattern.PresentMatch
+	case *matcher.ValueMatcher_StringMatch:
+		s, ok := value.(string)
+		return ok && matchString(pattern.StringMatch, s)
+	case *matcher.ValueMatcher_BoolMatch:
+		b, ok := value.(bool)
+		return ok && b == pattern.BoolMatch
+	case *matcher.ValueMatcher_DoubleMatch:
+		d, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		switch dm := pattern.DoubleMatch.GetMatchPattern().(type) {
+		case *matcher.DoubleMatcher_Exact:
+			return d == dm.Exact
+		case *matcher.DoubleMatcher_Range:
+			return d >= dm.Range.GetStart() && d < dm.Range.GetEnd()
+		}
+		return false
+	case *matcher.ValueMatcher_ListMatch:
+		list, ok := value.([]any)
+		if !ok {
+			return false
+		}
+		for _, v := range list {
+			if matchValue(pattern.ListMatch.GetOneOf(), v) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchString(m *matcher.StringMatcher, value string) bool {
+	if m == nil {
+		return false
+	}
+	match, err := authzmatcher.MatchString(m, value)
+	if err != nil {
+		log.Warn(err)
+	}
+	return match
+}
+
+// matchRegex returns true if the RE2 regex matches the whole value.
+func matchRegex(regex, value string) bool {
+	re, err := regexp.Compile("^(?:" + regex + ")$")
+	if err != nil {
+		log.Warnf("invalid regex %q: %v", regex, err)
+		return false
+	}
+	return re.MatchString(value)
+}
+
+func matchCIDR(cidr *core.CidrRange, ip net.IP) bool {
+	if ip == nil || cidr == nil {
+		return false
+	}
+	prefix := net.ParseIP(cidr.GetAddressPrefix())
+	if prefix == nil {
+		return false
+	}
+	bits := 32
+	if prefix.To4() == nil {
+		bits = 128
+	}
+	length := bits
+	if cidr.GetPrefixLen() != nil {
+		length = int(cidr.GetPrefixLen().GetValue())
+	}
+	network := &net.IPNet{IP: prefix, Mask: net.CIDRMask(length, bits)}
+	return network.Contains(ip)
+}
@@ -0,0 +1,261 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulate traces a hypothetical request through the xDS configuration of a proxy, reporting
+// which listener, filter chain, route, cluster and RBAC policies would apply to it.
+// Note: this is still under active development and is not ready for real use.
+package simulate
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	"istio.io/istio/istioctl/pkg/authz"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/test"
+)
+
+// Resources holds the xDS resources of a single proxy that a request is traced through.
+type Resources struct {
+	Listeners []*listener.Listener
+	Clusters  []*cluster.Cluster
+	Routes    []*route.RouteConfiguration
+}
+
+// ProxyOptions describes the source workload when generating configuration from local files.
+type ProxyOptions struct {
+	Namespace string
+	Labels    map[string]string
+	IP        string
+	Type      model.NodeType
+}
+
+// Peer describes the attributes of the request not in the simulation.Call, used to evaluate the authorization
+// policies.
+type Peer struct {
+	// Principal is the SPIFFE identity of the client of a mTLS request.
+	Principal string
+	SourceIP  net.IP
+	// Method is the method of a HTTP request. Defaults to GET.
+	Method string
+}
+
+// Report is the outcome of tracing a single request.
+type Report struct {
+	simulation.Result
+	// RBAC is the authorization decision of the RBAC filters of the matched filter chain for the request.
+	RBAC *authz.CheckResult
+}
+
+// FromConfigDump extracts the dynamic listeners, clusters and routes from an Envoy config dump.
+func FromConfigDump(cd *configdump.Wrapper) (*Resources, error) {
+	res := &Resources{}
+	listenerDump, err := cd.GetDynamicListenerDump(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dynamic listener dump: %v", err)
+	}
+	for _, l := range listenerDump.GetDynamicListeners() {
+		if l.GetActiveState().GetListener() == nil {
+			continue
+		}
+		l.ActiveState.Listener.TypeUrl = v3.ListenerType
+		lis := &listener.Listener{}
+		if err := l.ActiveState.Listener.UnmarshalTo(lis); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal listener %v: %v", l.Name, err)
+		}
+		res.Listeners = append(res.Listeners, lis)
+	}
+	clusterDump, err := cd.GetDynamicClusterDump(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dynamic cluster dump: %v", err)
+	}
+	for _, c := range clusterDump.GetDynamicActiveClusters() {
+		cl := &cluster.Cluster{}
+		if err := c.Cluster.UnmarshalTo(cl); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal cluster: %v", err)
+		}
+		res.Clusters = append(res.Clusters, cl)
+	}
+	routeDump, err := cd.GetDynamicRouteDump(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dynamic route dump: %v", err)
+	}
+	for _, r := range routeDump.GetDynamicRouteConfigs() {
+		rc := &route.RouteConfiguration{}
+		if err := r.RouteConfig.UnmarshalTo(rc); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal route: %v", err)
+		}
+		res.Routes = append(res.Routes, rc)
+	}
+	return res, nil
+}
+
+// FromYAML generates the resources istiod would send to the proxy described by opts, using the
+// Istio configuration and Kubernetes objects in input. No cluster connection is required.
+func FromYAML(input string, opts ProxyOptions) (*Resources, error) {
+	configs, kubeObjects, err := splitInput(input)
+	if err != nil {
+		return nil, err
+	}
+	res := &Resources{}
+	err = test.Wrap(func(t test.Failer) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+			Configs:                configs,
+			KubernetesObjectString: kubeObjects,
+		})
+		proxy := &model.Proxy{
+			Type:            opts.Type,
+			ConfigNamespace: opts.Namespace,
+			Labels:          opts.Labels,
+			Metadata:        &model.NodeMetadata{Labels: opts.Labels},
+		}
+		if opts.IP != "" {
+			proxy.IPAddresses = []string{opts.IP}
+		}
+		proxy = s.SetupProxy(proxy)
+		res.Listeners = s.Listeners(proxy)
+		res.Clusters = s.Clusters(proxy)
+		res.Routes = s.RoutesFromListeners(proxy, res.Listeners)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate configuration: %v", err)
+	}
+	return res, nil
+}
+
+// splitInput separates Istio configuration from other Kubernetes objects, as they are consumed by
+// different parts of the fake discovery server.
+func splitInput(input string) ([]config.Config, string, error) {
+	var configs []config.Config
+	var kubeDocs []string
+	for _, doc := range strings.Split(input, "\n---") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		parsed, _, err := crd.ParseInputs(doc)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(parsed) == 0 {
+			kubeDocs = append(kubeDocs, doc)
+			continue
+		}
+		for _, c := range parsed {
+			if c.Namespace == "" {
+				c.Namespace = "default"
+			}
+			// Short host names are resolved against the domain, so it must match the fake registries.
+			c.Domain = constants.DefaultClusterLocalDomain
+			configs = append(configs, c)
+		}
+	}
+	return configs, strings.Join(kubeDocs, "\n---\n"), nil
+}
+
+// Run traces the call through the resources, and evaluates the authorization policies of the matched filter chain.
+func Run(res *Resources, call simulation.Call, peer Peer) (*Report, error) {
+	report := &Report{}
+	err := test.Wrap(func(t test.Failer) {
+		sim := simulation.NewSimulationFromResources(t, res.Listeners, res.Clusters, res.Routes)
+		report.Result = sim.Run(call)
+		if report.FilterChainMatched == "" {
+			return
+		}
+		if l := xdstest.ExtractListener(report.ListenerMatched, res.Listeners); l != nil {
+			report.RBAC = authz.CheckFilterChain(matchedFilterChain(l, report.FilterChainMatched), authzRequest(call, peer))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func matchedFilterChain(l *listener.Listener, name string) *listener.FilterChain {
+	if l.GetDefaultFilterChain().GetName() == name {
+		return l.GetDefaultFilterChain()
+	}
+	return xdstest.ExtractFilterChain(name, l)
+}
+
+// authzRequest returns the request of the call, as seen by the RBAC filters.
+func authzRequest(call simulation.Call, peer Peer) *authz.Request {
+	call = call.FillDefaults()
+	req := &authz.Request{
+		SourceIP:        peer.SourceIP,
+		DestinationIP:   net.ParseIP(call.Address),
+		DestinationPort: uint32(call.Port),
+		ServerName:      call.Sni,
+		HTTP:            call.Protocol == simulation.HTTP || call.Protocol == simulation.HTTP2,
+		Method:          peer.Method,
+		Path:            call.Path,
+		Headers:         map[string][]string{},
+	}
+	// Only the mTLS requests have a peer identity.
+	if call.TLS == simulation.MTLS {
+		req.Principal = peer.Principal
+	}
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	for k, v := range call.Headers {
+		req.Headers[strings.ToLower(k)] = v
+	}
+	return req
+}
+
+// Print writes a human readable form of the report.
+func (r *Report) Print(writer io.Writer) error {
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 3, ' ', 0)
+	row := func(k, v string) {
+		if v == "" {
+			v = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", k, v)
+	}
+	row("LISTENER", r.ListenerMatched)
+	row("FILTER CHAIN", r.FilterChainMatched)
+	row("ROUTE CONFIG", r.RouteConfigMatched)
+	row("VIRTUAL HOST", r.VirtualHostMatched)
+	row("ROUTE", r.RouteMatched)
+	row("CLUSTER", r.ClusterMatched)
+	row("RBAC", r.rbacDecision())
+	if r.Error != nil {
+		row("ERROR", r.Error.Error())
+	}
+	return w.Flush()
+}
+
+func (r *Report) rbacDecision() string {
+	if r.RBAC == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s)", r.RBAC.Decision, r.RBAC.Reason)
+}
@@ -0,0 +1,235 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/simulation"
+)
+
+const simulateConfig = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  clusterIP: 10.0.0.1
+  ports:
+  - name: http
+    port: 9080
+  selector:
+    app: reviews
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: details
+  namespace: default
+spec:
+  hosts:
+  - details.example.com
+  endpoints:
+  - address: 1.1.1.1
+  location: MESH_INTERNAL
+  resolution: STATIC
+  ports:
+  - name: http
+    number: 9080
+    protocol: HTTP
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews
+  http:
+  - name: jason
+    match:
+    - headers:
+        end-user:
+          exact: jason
+    route:
+    - destination:
+        host: reviews
+        subset: v2
+  - name: catch-all
+    route:
+    - destination:
+        host: reviews
+        subset: v1
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: deny-admin
+  namespace: default
+spec:
+  action: DENY
+  rules:
+  - to:
+    - operation:
+        paths: ["/admin"]
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: allow-sleep
+  namespace: default
+spec:
+  action: ALLOW
+  rules:
+  - from:
+    - source:
+        principals: ["cluster.local/ns/default/sa/sleep"]
+`
+
+func TestSimulate(t *testing.T) {
+	outbound, err := FromYAML(simulateConfig, ProxyOptions{Namespace: "default", Type: model.SidecarProxy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbound, err := FromYAML(simulateConfig, ProxyOptions{
+		Namespace: "default",
+		Type:      model.SidecarProxy,
+		IP:        "1.1.1.1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		res       *Resources
+		call      simulation.Call
+		peer      Peer
+		cluster   string
+		route     string
+		rbac      string
+		wantError error
+	}{
+		{
+			name:    "default route",
+			res:     outbound,
+			call:    simulation.Call{Port: 9080, HostHeader: "reviews", Protocol: simulation.HTTP},
+			cluster: "outbound|9080|v1|reviews.default.svc.cluster.local",
+			route:   "catch-all",
+		},
+		{
+			name: "header match",
+			res:  outbound,
+			call: simulation.Call{
+				Port:       9080,
+				HostHeader: "reviews",
+				Protocol:   simulation.HTTP,
+				Headers:    http.Header{"End-User": []string{"jason"}},
+			},
+			cluster: "outbound|9080|v2|reviews.default.svc.cluster.local",
+			route:   "jason",
+		},
+		{
+			name: "unknown host",
+			res:  outbound,
+			call: simulation.Call{Port: 9080, HostHeader: "ratings", Protocol: simulation.HTTP},
+			// Falls back to passthrough
+			cluster: "PassthroughCluster",
+		},
+		{
+			name: "inbound rbac denied",
+			res:  inbound,
+			call: simulation.Call{
+				Address:  "1.1.1.1",
+				Port:     9080,
+				Path:     "/admin",
+				Protocol: simulation.HTTP,
+				TLS:      simulation.MTLS,
+				CallMode: simulation.CallModeInbound,
+			},
+			peer:    Peer{Principal: "spiffe://cluster.local/ns/default/sa/sleep"},
+			cluster: "inbound|9080||",
+			rbac:    "DENY (denied by the DENY policy deny-admin.default, rule 0)",
+		},
+		{
+			name: "inbound rbac allowed",
+			res:  inbound,
+			call: simulation.Call{
+				Address:  "1.1.1.1",
+				Port:     9080,
+				Path:     "/status",
+				Protocol: simulation.HTTP,
+				TLS:      simulation.MTLS,
+				CallMode: simulation.CallModeInbound,
+			},
+			peer:    Peer{Principal: "spiffe://cluster.local/ns/default/sa/sleep"},
+			cluster: "inbound|9080||",
+			rbac:    "ALLOW (allowed by the ALLOW policy allow-sleep.default, rule 0)",
+		},
+		{
+			name: "inbound rbac no allow policy matched",
+			res:  inbound,
+			call: simulation.Call{
+				Address:  "1.1.1.1",
+				Port:     9080,
+				Path:     "/status",
+				Protocol: simulation.HTTP,
+				TLS:      simulation.MTLS,
+				CallMode: simulation.CallModeInbound,
+			},
+			peer:    Peer{Principal: "spiffe://cluster.local/ns/default/sa/other"},
+			cluster: "inbound|9080||",
+			rbac:    "DENY (no ALLOW policy matched the request)",
+		},
+		{
+			name:      "no listener",
+			res:       &Resources{},
+			call:      simulation.Call{Port: 9080, Protocol: simulation.HTTP},
+			wantError: simulation.ErrNoListener,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Run(tt.res, tt.call, tt.peer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Error != tt.wantError {
+				t.Fatalf("want error %v, got %v", tt.wantError, report.Error)
+			}
+			if report.ClusterMatched != tt.cluster {
+				t.Errorf("want cluster %q, got %q", tt.cluster, report.ClusterMatched)
+			}
+			if tt.route != "" && report.RouteMatched != tt.route {
+				t.Errorf("want route %q, got %q", tt.route, report.RouteMatched)
+			}
+			if report.rbacDecision() != tt.rbac {
+				t.Errorf("want rbac %q, got %q", tt.rbac, report.rbacDecision())
+			}
+			out := &bytes.Buffer{}
+			if err := report.Print(out); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out.String(), "LISTENER") {
+				t.Errorf("unexpected output: %v", out.String())
+			}
+		})
+	}
+}
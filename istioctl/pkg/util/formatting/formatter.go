@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/mattn/go-isatty"
@@ -30,13 +31,15 @@ import (
 
 // Formatting options for Messages
 const (
-	LogFormat  = "log"
-	JSONFormat = "json"
-	YAMLFormat = "yaml"
+	LogFormat   = "log"
+	JSONFormat  = "json"
+	YAMLFormat  = "yaml"
+	SARIFFormat = "sarif"
+	JUnitFormat = "junit"
 )
 
 var (
-	MsgOutputFormatKeys = []string{LogFormat, JSONFormat, YAMLFormat}
+	MsgOutputFormatKeys = []string{LogFormat, JSONFormat, YAMLFormat, SARIFFormat, JUnitFormat}
 	MsgOutputFormats    = make(map[string]bool)
 	termEnvVar          = env.Register("TERM", "", "Specifies terminal type.  Use 'dumb' to suppress color output")
 )
@@ -49,6 +52,12 @@ func init() {
 
 // Print output messages in the specified format with color options
 func Print(ms diag.Messages, format string, colorize bool) (string, error) {
+	return PrintAnalysis(ms, format, colorize, nil, nil)
+}
+
+// PrintAnalysis outputs the messages like Print. The formats reporting each analyzer, such as JUnit, also report the
+// executed analyzers without messages, and the skipped ones.
+func PrintAnalysis(ms diag.Messages, format string, colorize bool, executed, skipped []string) (string, error) {
 	switch format {
 	case LogFormat:
 		return printLog(ms, colorize), nil
@@ -56,6 +65,10 @@ func Print(ms diag.Messages, format string, colorize bool) (string, error) {
 		return printJSON(ms)
 	case YAMLFormat:
 		return printYAML(ms)
+	case SARIFFormat:
+		return printSARIF(ms)
+	case JUnitFormat:
+		return printJUnit(ms, executed, skipped)
 	default:
 		return "", fmt.Errorf("invalid format, expected one of %v but got %q", MsgOutputFormatKeys, format)
 	}
@@ -79,6 +92,26 @@ func printYAML(ms diag.Messages) (string, error) {
 	return string(yamlOutput), err
 }
 
+// location returns the file and line of the origin of the message, if known. The line is 0 if unknown.
+func location(m diag.Message) (string, int) {
+	if m.Resource == nil || m.Resource.Origin.Reference() == nil {
+		return "", 0
+	}
+	loc := m.Resource.Origin.Reference().String()
+	if m.Line != 0 {
+		loc = m.ReplaceLine(loc)
+	}
+	i := strings.LastIndex(loc, ":")
+	if i < 0 {
+		return loc, 0
+	}
+	line, err := strconv.Atoi(strings.TrimSpace(loc[i+1:]))
+	if err != nil {
+		return loc, 0
+	}
+	return loc[:i], line
+}
+
 // Formatting options for Message
 var (
 	colorPrefixes = map[diag.Level]string{
@@ -15,11 +15,14 @@
 package formatting
 
 import (
+	"encoding/json"
 	"testing"
 
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/legacy/source/kube"
+	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/url"
 )
 
@@ -151,4 +154,129 @@ func TestFormatter_PrintEmpty(t *testing.T) {
 
 	yamlOutput, _ := Print(msgs, YAMLFormat, false)
 	g.Expect(yamlOutput).To(Equal("[]\n"))
+
+	sarifOutput, _ := Print(msgs, SARIFFormat, false)
+	g.Expect(sarifOutput).To(ContainSubstring(`"results": []`))
+}
+
+func fileResource(name, file string, line int) *resource.Instance {
+	return &resource.Instance{
+		Metadata: resource.Metadata{
+			FullName: resource.NewShortOrFullName("default", name),
+		},
+		Origin: &kube.Origin{
+			Kind:     "VirtualService",
+			FullName: resource.NewShortOrFullName("default", name),
+			Ref:      &kube.Position{Filename: file, Line: line},
+		},
+	}
+}
+
+func TestFormatter_PrintSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		fileResource("bubble", "config/bubble.yaml", 3),
+		"the bubble is too big",
+	)
+	firstMsg.Line = 7
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+	thirdMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		nil,
+		"the bubble is gone",
+	)
+
+	output, err := Print(diag.Messages{firstMsg, secondMsg, thirdMsg}, SARIFFormat, false)
+	g.Expect(err).To(BeNil())
+
+	log := sarifLog{}
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Runs).To(HaveLen(1))
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Rules).To(Equal([]sarifRule{
+		{ID: "B1", HelpURI: url.ConfigAnalysis + "/b1/", DefaultConfiguration: sarifConfiguration{Level: "error"}},
+		{ID: "C1", HelpURI: url.ConfigAnalysis + "/c1/", DefaultConfiguration: sarifConfiguration{Level: "note"}},
+	}))
+	g.Expect(run.Results).To(Equal([]sarifResult{
+		{
+			RuleID:  "B1",
+			Level:   "error",
+			Message: sarifMessage{Text: "Explosion accident: the bubble is too big"},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: "config/bubble.yaml"},
+					Region:           &sarifRegion{StartLine: 7},
+				},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "VirtualService default/bubble", Kind: "resource"}},
+			}},
+		},
+		{
+			RuleID:    "C1",
+			RuleIndex: 1,
+			Level:     "note",
+			Message:   sarifMessage{Text: "Collapse danger: the castle is too old"},
+			Locations: []sarifLocation{{
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "GrandCastle", Kind: "resource"}},
+			}},
+		},
+		{
+			RuleID:  "B1",
+			Level:   "error",
+			Message: sarifMessage{Text: "Explosion accident: the bubble is gone"},
+		},
+	}))
+}
+
+func TestFormatter_PrintJUnit(t *testing.T) {
+	g := NewWithT(t)
+
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		fileResource("bubble", "bubble.yaml", 3),
+		"the bubble is too big",
+	)
+	firstMsg.Analyzer = "bubble.Analyzer"
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+	secondMsg.Analyzer = "castle.Analyzer"
+	thirdMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Warning, "D1", "Flood: %v"),
+		diag.MockResource("Moat"),
+		"the moat is too deep",
+	)
+
+	output, err := PrintAnalysis(diag.Messages{firstMsg, secondMsg, thirdMsg}, JUnitFormat, false,
+		[]string{"bubble.Analyzer", "castle.Analyzer", "clean.Analyzer", "skipped.Analyzer"}, []string{"skipped.Analyzer"})
+	g.Expect(err).To(BeNil())
+
+	expectedOutput := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="istioctl analyze" tests="5" failures="2" skipped="1">
+	<testsuite name="istioctl analyze" tests="5" failures="2" skipped="1">
+		<testcase name="bubble.Analyzer" classname="istioctl analyze">
+			<failure message="1 issue(s) found" type="Error">Error [B1] (VirtualService default/bubble bubble.yaml:3) Explosion accident: the bubble is too big</failure>
+		</testcase>
+		<testcase name="castle.Analyzer" classname="istioctl analyze">
+			<system-out>Info [C1] (GrandCastle) Collapse danger: the castle is too old</system-out>
+		</testcase>
+		<testcase name="clean.Analyzer" classname="istioctl analyze"></testcase>
+		<testcase name="skipped.Analyzer" classname="istioctl analyze">
+			<skipped message="the analyzer was skipped as its input collections are not available"></skipped>
+		</testcase>
+		<testcase name="D1" classname="istioctl analyze">
+			<failure message="1 issue(s) found" type="Warning">Warning [D1] (Moat) Flood: the moat is too deep</failure>
+		</testcase>
+	</testsuite>
+</testsuites>`
+
+	g.Expect(output).To(Equal(expectedOutput))
 }
@@ -0,0 +1,152 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+)
+
+const junitSuiteName = "istioctl analyze"
+
+// The JUnit XML report of the analysis, with a test case per analyzer. The analyzers reporting warnings or errors fail.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func printJUnit(ms diag.Messages, executed, skipped []string) (string, error) {
+	// The messages not attributed to an analyzer are reported under their code.
+	byAnalyzer := map[string]diag.Messages{}
+	for _, m := range ms {
+		name := m.Analyzer
+		if name == "" {
+			name = m.Type.Code()
+		}
+		byAnalyzer[name] = append(byAnalyzer[name], m)
+	}
+
+	names := append([]string{}, executed...)
+	listed := map[string]bool{}
+	for _, name := range executed {
+		listed[name] = true
+	}
+	var unlisted []string
+	for name := range byAnalyzer {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	names = append(names, unlisted...)
+
+	isSkipped := map[string]bool{}
+	for _, name := range skipped {
+		isSkipped[name] = true
+	}
+
+	suite := junitTestSuite{Name: junitSuiteName}
+	for _, name := range names {
+		tc := junitTestCase{Name: name, ClassName: junitSuiteName}
+		msgs := byAnalyzer[name]
+		switch {
+		case len(msgs) > 0:
+			tc.Failure, tc.SystemOut = junitResult(msgs)
+		case isSkipped[name]:
+			tc.Skipped = &junitSkipped{Message: "the analyzer was skipped as its input collections are not available"}
+		}
+		suite.Tests++
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		if tc.Skipped != nil {
+			suite.Skipped++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	out, err := xml.MarshalIndent(junitTestSuites{
+		Name:     junitSuiteName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Suites:   []junitTestSuite{suite},
+	}, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(out), nil
+}
+
+// junitResult returns the failure of the warning and error messages, and the output of the info messages, of an
+// analyzer. Each message is rendered as in the log format, with the file and line of its origin, if known.
+func junitResult(ms diag.Messages) (*junitFailure, string) {
+	var failures, infos []string
+	worst := diag.Info
+	for _, m := range ms {
+		line := render(m, false)
+		if m.Type.Level().IsWorseThanOrEqualTo(diag.Warning) {
+			failures = append(failures, line)
+			if m.Type.Level().IsWorseThanOrEqualTo(worst) {
+				worst = m.Type.Level()
+			}
+		} else {
+			infos = append(infos, line)
+		}
+	}
+	out := strings.Join(infos, "\n")
+	if len(failures) == 0 {
+		return nil, out
+	}
+	return &junitFailure{
+		Message: fmt.Sprintf("%d issue(s) found", len(failures)),
+		Type:    worst.String(),
+		Text:    strings.Join(failures, "\n"),
+	}, out
+}
@@ -0,0 +1,157 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"fmt"
+	"sort"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// BehaviorChanges returns the differences between the two proxies likely to change how their traffic is handled:
+// the listeners, filter chains, clusters, route configurations, virtual hosts and routes removed, and the changed TLS
+// settings of the clusters and filter chains. The changes are sorted.
+func (c *ProxyComparator) BehaviorChanges() ([]string, error) {
+	var changes []string
+
+	fromListeners, err := dynamicListeners(c.from)
+	if err != nil {
+		return nil, err
+	}
+	toListeners, err := dynamicListeners(c.to)
+	if err != nil {
+		return nil, err
+	}
+	for name, f := range fromListeners {
+		t, ok := toListeners[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("listener %s removed", name))
+			continue
+		}
+		changes = append(changes, listenerChanges(f.(*listener.Listener), t.(*listener.Listener))...)
+	}
+
+	fromClusters, err := dynamicClusters(c.from)
+	if err != nil {
+		return nil, err
+	}
+	toClusters, err := dynamicClusters(c.to)
+	if err != nil {
+		return nil, err
+	}
+	for name, f := range fromClusters {
+		t, ok := toClusters[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("cluster %s removed", name))
+			continue
+		}
+		from, to := f.(*cluster.Cluster), t.(*cluster.Cluster)
+		if !proto.Equal(from.GetTransportSocket(), to.GetTransportSocket()) ||
+			!equalTransportSocketMatches(from.GetTransportSocketMatches(), to.GetTransportSocketMatches()) {
+			changes = append(changes, fmt.Sprintf("TLS settings of cluster %s changed", name))
+		}
+	}
+
+	fromRoutes, err := dynamicRoutes(c.from)
+	if err != nil {
+		return nil, err
+	}
+	toRoutes, err := dynamicRoutes(c.to)
+	if err != nil {
+		return nil, err
+	}
+	for name, f := range fromRoutes {
+		t, ok := toRoutes[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("route configuration %s removed", name))
+			continue
+		}
+		changes = append(changes, routeChanges(f.(*route.RouteConfiguration), t.(*route.RouteConfiguration))...)
+	}
+
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// listenerChanges returns the filter chains of the listener removed or with changed TLS settings. The filter chains
+// are matched by name, and the unnamed ones are ignored.
+func listenerChanges(from, to *listener.Listener) []string {
+	var changes []string
+	toChains := map[string]*listener.FilterChain{}
+	for _, fc := range to.GetFilterChains() {
+		toChains[fc.GetName()] = fc
+	}
+	for _, fc := range from.GetFilterChains() {
+		if fc.GetName() == "" {
+			continue
+		}
+		t, ok := toChains[fc.GetName()]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("filter chain %s of listener %s removed", fc.GetName(), from.GetName()))
+		case !proto.Equal(fc.GetTransportSocket(), t.GetTransportSocket()):
+			changes = append(changes, fmt.Sprintf("TLS settings of filter chain %s of listener %s changed", fc.GetName(), from.GetName()))
+		}
+	}
+	return changes
+}
+
+// routeChanges returns the virtual hosts of the route configuration removed, and for the others, their named routes
+// removed or the decrease of their number of routes.
+func routeChanges(from, to *route.RouteConfiguration) []string {
+	var changes []string
+	toHosts := map[string]*route.VirtualHost{}
+	for _, vh := range to.GetVirtualHosts() {
+		toHosts[vh.GetName()] = vh
+	}
+	for _, vh := range from.GetVirtualHosts() {
+		t, ok := toHosts[vh.GetName()]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("virtual host %s of route configuration %s removed", vh.GetName(), from.GetName()))
+			continue
+		}
+		toRoutes := map[string]bool{}
+		for _, r := range t.GetRoutes() {
+			toRoutes[r.GetName()] = true
+		}
+		for _, r := range vh.GetRoutes() {
+			if r.GetName() != "" && !toRoutes[r.GetName()] {
+				changes = append(changes, fmt.Sprintf("route %s of virtual host %s of route configuration %s removed",
+					r.GetName(), vh.GetName(), from.GetName()))
+			}
+		}
+		if len(t.GetRoutes()) < len(vh.GetRoutes()) {
+			changes = append(changes, fmt.Sprintf("virtual host %s of route configuration %s has fewer routes (%d -> %d)",
+				vh.GetName(), from.GetName(), len(vh.GetRoutes()), len(t.GetRoutes())))
+		}
+	}
+	return changes
+}
+
+func equalTransportSocketMatches(a, b []*cluster.Cluster_TransportSocketMatch) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !proto.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
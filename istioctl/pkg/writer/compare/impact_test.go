@@ -0,0 +1,149 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+func configDump(t *testing.T, listeners []*listener.Listener, routes []*route.RouteConfiguration, clusters []*cluster.Cluster) []byte {
+	t.Helper()
+	ld := &admin.ListenersConfigDump{}
+	for _, l := range listeners {
+		ld.DynamicListeners = append(ld.DynamicListeners, &admin.ListenersConfigDump_DynamicListener{
+			ActiveState: &admin.ListenersConfigDump_DynamicListenerState{Listener: protoconv.MessageToAny(l)},
+		})
+	}
+	rd := &admin.RoutesConfigDump{}
+	for _, r := range routes {
+		rd.DynamicRouteConfigs = append(rd.DynamicRouteConfigs, &admin.RoutesConfigDump_DynamicRouteConfig{
+			RouteConfig: protoconv.MessageToAny(r),
+		})
+	}
+	cd := &admin.ClustersConfigDump{}
+	for _, c := range clusters {
+		cd.DynamicActiveClusters = append(cd.DynamicActiveClusters, &admin.ClustersConfigDump_DynamicCluster{
+			Cluster: protoconv.MessageToAny(c),
+		})
+	}
+	out, err := protomarshal.Marshal(&admin.ConfigDump{Configs: []*anypb.Any{
+		protoconv.MessageToAny(ld),
+		protoconv.MessageToAny(rd),
+		protoconv.MessageToAny(cd),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func tlsSocket(sni string) *core.TransportSocket {
+	return &core.TransportSocket{
+		Name:       "envoy.transport_sockets.tls",
+		ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: protoconv.MessageToAny(&tls.UpstreamTlsContext{Sni: sni})},
+	}
+}
+
+func TestBehaviorChanges(t *testing.T) {
+	virtualHost := func(name string, routes ...string) *route.VirtualHost {
+		vh := &route.VirtualHost{Name: name, Domains: []string{"*"}}
+		for _, r := range routes {
+			vh.Routes = append(vh.Routes, &route.Route{Name: r})
+		}
+		return vh
+	}
+	from := configDump(t,
+		[]*listener.Listener{
+			{Name: "0.0.0.0_80"},
+			{Name: "virtualInbound", FilterChains: []*listener.FilterChain{
+				{Name: "inbound|8080||", TransportSocket: tlsSocket("a")},
+				{Name: "inbound|9090||"},
+				{},
+			}},
+		},
+		[]*route.RouteConfiguration{
+			{Name: "80", VirtualHosts: []*route.VirtualHost{
+				virtualHost("a.default.svc.cluster.local:80", "canary", "default", ""),
+				virtualHost("b.default.svc.cluster.local:80", "default"),
+			}},
+			{Name: "9080"},
+		},
+		[]*cluster.Cluster{
+			{Name: "outbound|80||a.default.svc.cluster.local", TransportSocket: tlsSocket("a")},
+			{Name: "outbound|80||b.default.svc.cluster.local"},
+			{Name: "outbound|80||c.default.svc.cluster.local"},
+		})
+	to := configDump(t,
+		[]*listener.Listener{
+			{Name: "virtualInbound", FilterChains: []*listener.FilterChain{
+				{Name: "inbound|8080||", TransportSocket: tlsSocket("b")},
+				{Name: "inbound|8081||"},
+			}},
+		},
+		[]*route.RouteConfiguration{
+			{Name: "80", VirtualHosts: []*route.VirtualHost{
+				virtualHost("a.default.svc.cluster.local:80", "default", ""),
+				virtualHost("c.default.svc.cluster.local:80", "default"),
+			}},
+		},
+		[]*cluster.Cluster{
+			{Name: "outbound|80||a.default.svc.cluster.local", TransportSocket: tlsSocket("b")},
+			{Name: "outbound|80||b.default.svc.cluster.local"},
+		})
+
+	c, err := NewProxyComparator(&bytes.Buffer{}, "old", from, "new", to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.BehaviorChanges()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"TLS settings of cluster outbound|80||a.default.svc.cluster.local changed",
+		"TLS settings of filter chain inbound|8080|| of listener virtualInbound changed",
+		"cluster outbound|80||c.default.svc.cluster.local removed",
+		"filter chain inbound|9090|| of listener virtualInbound removed",
+		"listener 0.0.0.0_80 removed",
+		"route canary of virtual host a.default.svc.cluster.local:80 of route configuration 80 removed",
+		"route configuration 9080 removed",
+		"virtual host a.default.svc.cluster.local:80 of route configuration 80 has fewer routes (3 -> 2)",
+		"virtual host b.default.svc.cluster.local:80 of route configuration 80 removed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the changes\n%q\ngot\n%q", want, got)
+	}
+
+	c, err = NewProxyComparator(&bytes.Buffer{}, "old", from, "new", from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.BehaviorChanges(); err != nil || len(got) != 0 {
+		t.Errorf("expected no changes, got %q (%v)", got, err)
+	}
+}
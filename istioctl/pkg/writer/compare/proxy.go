@@ -0,0 +1,259 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/pmezard/go-difflib/difflib"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// ProxyComparator diffs the config dumps of two proxies, resource by resource
+type ProxyComparator struct {
+	from, to         *configdump.Wrapper
+	fromName, toName string
+	w                io.Writer
+	context          int
+}
+
+// NewProxyComparator is a proxy comparator constructor. The names identify the proxies in the output.
+func NewProxyComparator(w io.Writer, fromName string, fromDump []byte, toName string, toDump []byte) (*ProxyComparator, error) {
+	from := &configdump.Wrapper{}
+	if err := json.Unmarshal(fromDump, from); err != nil {
+		return nil, fmt.Errorf("failed to parse the config dump of %s: %v", fromName, err)
+	}
+	to := &configdump.Wrapper{}
+	if err := json.Unmarshal(toDump, to); err != nil {
+		return nil, fmt.Errorf("failed to parse the config dump of %s: %v", toName, err)
+	}
+	return &ProxyComparator{
+		from:     from,
+		to:       to,
+		fromName: fromName,
+		toName:   toName,
+		w:        w,
+		context:  7,
+	}, nil
+}
+
+// proxyResourceType is a type of xDS resources compared between two proxies.
+type proxyResourceType struct {
+	name string
+	// resources returns the resources of the config dump by name, without their version information.
+	resources func(w *configdump.Wrapper) (map[string]proto.Message, error)
+	// optional is true if the resources may be missing from a config dump, in which case they are not compared.
+	optional bool
+}
+
+var proxyResourceTypes = []proxyResourceType{
+	{name: "Listeners", resources: dynamicListeners},
+	{name: "Routes", resources: dynamicRoutes},
+	{name: "Clusters", resources: dynamicClusters},
+	// The endpoints are only in the config dumps requested with include_eds.
+	{name: "Endpoints", resources: dynamicEndpoints, optional: true},
+}
+
+// Diff prints, for each type of resources, the resources only in one of the proxies and a diff of the resources
+// differing between them. It returns true if the proxies have the same resources.
+func (c *ProxyComparator) Diff() (bool, error) {
+	same := true
+	for _, t := range proxyResourceTypes {
+		from, fromErr := t.resources(c.from)
+		to, toErr := t.resources(c.to)
+		if fromErr != nil || toErr != nil {
+			err := fromErr
+			if err == nil {
+				err = toErr
+			}
+			if !t.optional {
+				return false, fmt.Errorf("failed to compare the %s: %v", t.name, err)
+			}
+			fmt.Fprintf(c.w, "%s Not Compared: %v\n", t.name, err)
+			continue
+		}
+		typeSame, err := c.diffResources(t.name, from, to)
+		if err != nil {
+			return false, err
+		}
+		same = same && typeSame
+	}
+	return same, nil
+}
+
+func (c *ProxyComparator) diffResources(typeName string, from, to map[string]proto.Message) (bool, error) {
+	var onlyFrom, onlyTo, changed []string
+	for name, f := range from {
+		t, ok := to[name]
+		switch {
+		case !ok:
+			onlyFrom = append(onlyFrom, name)
+		case !proto.Equal(f, t):
+			changed = append(changed, name)
+		}
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			onlyTo = append(onlyTo, name)
+		}
+	}
+	if len(onlyFrom) == 0 && len(onlyTo) == 0 && len(changed) == 0 {
+		fmt.Fprintf(c.w, "%s Match (%d)\n", typeName, len(from))
+		return true, nil
+	}
+	sort.Strings(onlyFrom)
+	sort.Strings(onlyTo)
+	sort.Strings(changed)
+
+	fmt.Fprintf(c.w, "%s Don't Match\n", typeName)
+	for _, name := range onlyFrom {
+		fmt.Fprintf(c.w, "  Only in %s: %s\n", c.fromName, name)
+	}
+	for _, name := range onlyTo {
+		fmt.Fprintf(c.w, "  Only in %s: %s\n", c.toName, name)
+	}
+	for _, name := range changed {
+		fmt.Fprintf(c.w, "  Differs: %s\n", name)
+	}
+	for _, name := range changed {
+		fromJSON, err := protomarshal.ToJSONWithIndent(from[name], "    ")
+		if err != nil {
+			return false, err
+		}
+		toJSON, err := protomarshal.ToJSONWithIndent(to[name], "    ")
+		if err != nil {
+			return false, err
+		}
+		diff := difflib.UnifiedDiff{
+			FromFile: c.fromName + " " + name,
+			A:        difflib.SplitLines(fromJSON),
+			ToFile:   c.toName + " " + name,
+			B:        difflib.SplitLines(toJSON),
+			Context:  c.context,
+		}
+		text, err := difflib.GetUnifiedDiffString(diff)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintln(c.w, text)
+	}
+	return false, nil
+}
+
+func dynamicListeners(w *configdump.Wrapper) (map[string]proto.Message, error) {
+	dump, err := w.GetDynamicListenerDump(true)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]proto.Message{}
+	for _, l := range dump.GetDynamicListeners() {
+		resource := &listener.Listener{}
+		if err := l.GetActiveState().GetListener().UnmarshalTo(resource); err != nil {
+			return nil, err
+		}
+		out[resource.GetName()] = resource
+	}
+	return out, nil
+}
+
+func dynamicRoutes(w *configdump.Wrapper) (map[string]proto.Message, error) {
+	dump, err := w.GetDynamicRouteDump(true)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]proto.Message{}
+	for _, r := range dump.GetDynamicRouteConfigs() {
+		resource := &route.RouteConfiguration{}
+		if err := r.GetRouteConfig().UnmarshalTo(resource); err != nil {
+			return nil, err
+		}
+		out[resource.GetName()] = resource
+	}
+	return out, nil
+}
+
+func dynamicClusters(w *configdump.Wrapper) (map[string]proto.Message, error) {
+	dump, err := w.GetDynamicClusterDump(true)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]proto.Message{}
+	for _, c := range dump.GetDynamicActiveClusters() {
+		resource := &cluster.Cluster{}
+		if err := c.GetCluster().UnmarshalTo(resource); err != nil {
+			return nil, err
+		}
+		out[resource.GetName()] = resource
+	}
+	return out, nil
+}
+
+func dynamicEndpoints(w *configdump.Wrapper) (map[string]proto.Message, error) {
+	dump, err := w.GetEndpointsConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]proto.Message{}
+	for _, e := range dump.GetDynamicEndpointConfigs() {
+		if e.GetEndpointConfig() == nil {
+			continue
+		}
+		// Support v2 or v3 in config dump. See ads.go:RequestedTypes for more info.
+		e.EndpointConfig.TypeUrl = v3.EndpointType
+		resource := &endpoint.ClusterLoadAssignment{}
+		if err := e.GetEndpointConfig().UnmarshalTo(resource); err != nil {
+			return nil, err
+		}
+		sortEndpoints(resource)
+		out[resource.GetClusterName()] = resource
+	}
+	return out, nil
+}
+
+// sortEndpoints sorts the localities and their endpoints, as their order is not significant.
+func sortEndpoints(cla *endpoint.ClusterLoadAssignment) {
+	for _, locality := range cla.GetEndpoints() {
+		sort.SliceStable(locality.LbEndpoints, func(i, j int) bool {
+			return endpointAddress(locality.LbEndpoints[i]) < endpointAddress(locality.LbEndpoints[j])
+		})
+	}
+	sort.SliceStable(cla.Endpoints, func(i, j int) bool {
+		a, b := cla.Endpoints[i], cla.Endpoints[j]
+		if a.GetPriority() != b.GetPriority() {
+			return a.GetPriority() < b.GetPriority()
+		}
+		return a.GetLocality().String() < b.GetLocality().String()
+	})
+}
+
+func endpointAddress(e *endpoint.LbEndpoint) string {
+	address := e.GetEndpoint().GetAddress()
+	if pipe := address.GetPipe(); pipe != nil {
+		return pipe.GetPath()
+	}
+	socket := address.GetSocketAddress()
+	return fmt.Sprintf("%s:%d", socket.GetAddress(), socket.GetPortValue())
+}
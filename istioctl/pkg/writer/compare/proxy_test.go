@@ -0,0 +1,191 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+type proxyDump struct {
+	version   string
+	listeners []string
+	clusters  map[string]int64
+	endpoints map[string][]string
+}
+
+func (d proxyDump) marshal(t *testing.T) []byte {
+	t.Helper()
+	listeners := &admin.ListenersConfigDump{}
+	for _, name := range d.listeners {
+		listeners.DynamicListeners = append(listeners.DynamicListeners, &admin.ListenersConfigDump_DynamicListener{
+			Name: name,
+			ActiveState: &admin.ListenersConfigDump_DynamicListenerState{
+				VersionInfo: d.version,
+				LastUpdated: timestamppb.Now(),
+				Listener:    protoconv.MessageToAny(&listener.Listener{Name: name}),
+			},
+		})
+	}
+	routes := &admin.RoutesConfigDump{
+		DynamicRouteConfigs: []*admin.RoutesConfigDump_DynamicRouteConfig{{
+			VersionInfo: d.version,
+			LastUpdated: timestamppb.Now(),
+			RouteConfig: protoconv.MessageToAny(&route.RouteConfiguration{Name: "80"}),
+		}},
+	}
+	clusters := &admin.ClustersConfigDump{}
+	for name, timeout := range d.clusters {
+		clusters.DynamicActiveClusters = append(clusters.DynamicActiveClusters, &admin.ClustersConfigDump_DynamicCluster{
+			VersionInfo: d.version,
+			LastUpdated: timestamppb.Now(),
+			Cluster:     protoconv.MessageToAny(&cluster.Cluster{Name: name, ConnectTimeout: durationpb.New(time.Duration(timeout) * time.Second)}),
+		})
+	}
+	configs := []*anypb.Any{
+		protoconv.MessageToAny(listeners),
+		protoconv.MessageToAny(routes),
+		protoconv.MessageToAny(clusters),
+	}
+	if d.endpoints != nil {
+		eds := &admin.EndpointsConfigDump{}
+		for name, addresses := range d.endpoints {
+			cla := &endpoint.ClusterLoadAssignment{ClusterName: name, Endpoints: []*endpoint.LocalityLbEndpoints{{}}}
+			for _, address := range addresses {
+				cla.Endpoints[0].LbEndpoints = append(cla.Endpoints[0].LbEndpoints, &endpoint.LbEndpoint{
+					HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+						Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+							Address:       address,
+							PortSpecifier: &core.SocketAddress_PortValue{PortValue: 8080},
+						}}},
+					}},
+				})
+			}
+			eds.DynamicEndpointConfigs = append(eds.DynamicEndpointConfigs, &admin.EndpointsConfigDump_DynamicEndpointConfig{
+				VersionInfo:    d.version,
+				EndpointConfig: protoconv.MessageToAny(cla),
+			})
+		}
+		configs = append(configs, protoconv.MessageToAny(eds))
+	}
+	out, err := protomarshal.Marshal(&admin.ConfigDump{Configs: configs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestProxyComparatorDiff(t *testing.T) {
+	from := proxyDump{
+		version:   "2023-01-01T00:00:00Z/1",
+		listeners: []string{"0.0.0.0_80", "virtualInbound"},
+		clusters:  map[string]int64{"outbound|80||a.default.svc.cluster.local": 10, "outbound|80||b.default.svc.cluster.local": 10},
+		endpoints: map[string][]string{"outbound|80||a.default.svc.cluster.local": {"10.0.0.1", "10.0.0.2"}},
+	}
+	cases := []struct {
+		name string
+		to   proxyDump
+		same bool
+		want []string
+	}{
+		{
+			name: "only versions differ",
+			to: proxyDump{
+				version:   "2023-01-01T00:00:00Z/2",
+				listeners: []string{"virtualInbound", "0.0.0.0_80"},
+				clusters:  from.clusters,
+				endpoints: map[string][]string{"outbound|80||a.default.svc.cluster.local": {"10.0.0.2", "10.0.0.1"}},
+			},
+			same: true,
+			want: []string{"Listeners Match (2)", "Routes Match (1)", "Clusters Match (2)", "Endpoints Match (1)"},
+		},
+		{
+			name: "resources differ",
+			to: proxyDump{
+				version:   from.version,
+				listeners: []string{"0.0.0.0_80", "0.0.0.0_8080"},
+				clusters:  map[string]int64{"outbound|80||a.default.svc.cluster.local": 10, "outbound|80||b.default.svc.cluster.local": 5},
+				endpoints: map[string][]string{"outbound|80||a.default.svc.cluster.local": {"10.0.0.1"}},
+			},
+			want: []string{
+				"Listeners Don't Match",
+				"  Only in pod-a.default: virtualInbound",
+				"  Only in pod-b.default: 0.0.0.0_8080",
+				"Routes Match (1)",
+				"Clusters Don't Match",
+				"  Differs: outbound|80||b.default.svc.cluster.local",
+				"--- pod-a.default outbound|80||b.default.svc.cluster.local",
+				"+++ pod-b.default outbound|80||b.default.svc.cluster.local",
+				`-    "connectTimeout": "10s"`,
+				`+    "connectTimeout": "5s"`,
+				"Endpoints Don't Match",
+				"  Differs: outbound|80||a.default.svc.cluster.local",
+				`"address": "10.0.0.2",`,
+			},
+		},
+		{
+			name: "no endpoints",
+			to: proxyDump{
+				version:   from.version,
+				listeners: from.listeners,
+				clusters:  from.clusters,
+			},
+			same: true,
+			want: []string{"Clusters Match (2)", "Endpoints Not Compared: endpoints not found (was include_eds=true used?)"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c, err := NewProxyComparator(out, "pod-a.default", from.marshal(t), "pod-b.default", tc.to.marshal(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			same, err := c.Diff()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same != tc.same {
+				t.Errorf("expected same=%v, got %v", tc.same, same)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected %q in the output:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestNewProxyComparatorInvalidDump(t *testing.T) {
+	if _, err := NewProxyComparator(&bytes.Buffer{}, "a", []byte("{}"), "b", []byte("not json")); err == nil {
+		t.Fatal("expected an error for an invalid config dump")
+	}
+}
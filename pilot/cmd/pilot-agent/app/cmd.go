@@ -78,6 +78,7 @@ func NewRootCommand() *cobra.Command {
 	rootCmd.AddCommand(proxyCmd)
 	rootCmd.AddCommand(requestCmd)
 	rootCmd.AddCommand(waitCmd)
+	rootCmd.AddCommand(newWasmPrefetchCommand())
 	rootCmd.AddCommand(version.CobraCommand())
 	rootCmd.AddCommand(iptables.GetCommand())
 	rootCmd.AddCommand(cleaniptables.GetCommand())
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/options"
+	"istio.io/istio/pkg/cmd"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/wasm"
+	"istio.io/pkg/log"
+)
+
+func newWasmPrefetchCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "wasm-prefetch",
+		Short: "Prefetches the Wasm modules of WasmPlugins into the shared cache of the node",
+		Long: "Watches the WasmPlugins of the cluster, and fetches their modules into WASM_SHARED_CACHE_DIR as soon as " +
+			"they are created or updated. Run on each node, for example as a DaemonSet mounting the hostPath " +
+			"directory shared with the proxies, so that modules are available before proxies request them.",
+		PersistentPreRunE: configureLogging,
+		RunE: func(c *cobra.Command, args []string) error {
+			opts := options.NewWasmOptions()
+			if opts.SharedCacheDir == "" {
+				return errors.New("WASM_SHARED_CACHE_DIR must be set")
+			}
+			client, err := kube.NewDefaultClient()
+			if err != nil {
+				return fmt.Errorf("failed to create kube client: %v", err)
+			}
+			// The modules are only fetched for the agents of the node, the copies of the prefetcher are temporary.
+			dir, err := os.MkdirTemp("", "wasm-prefetch")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(dir)
+			cache := wasm.NewLocalFileCache(dir, opts)
+			defer cache.Cleanup()
+			prefetcher := wasm.NewPrefetcher(client, cache)
+
+			stop := make(chan struct{})
+			client.RunAndWait(stop)
+			go prefetcher.Run(stop)
+			log.Infof("Prefetching Wasm modules to %s", opts.SharedCacheDir)
+			cmd.WaitSignal(stop)
+			return nil
+		},
+	}
+}
@@ -22,6 +22,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/bootstrap/platform"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/pkg/wasm"
@@ -32,21 +33,15 @@ const xdsHeaderPrefix = "XDS_HEADER_"
 
 func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagent.AgentOptions {
 	o := &istioagent.AgentOptions{
-		XDSRootCerts:             xdsRootCA,
-		CARootCerts:              caRootCA,
-		XDSHeaders:               map[string]string{},
-		XdsUdsPath:               filepath.Join(cfg.ConfigPath, "XDS"),
-		IsIPv6:                   proxy.IsIPv6(),
-		ProxyType:                proxy.Type,
-		EnableDynamicProxyConfig: enableProxyConfigXdsEnv,
-		EnableDynamicBootstrap:   enableBootstrapXdsEnv,
-		WASMOptions: wasm.Options{
-			InsecureRegistries:    sets.New(strings.Split(wasmInsecureRegistries, ",")...),
-			ModuleExpiry:          wasmModuleExpiry,
-			PurgeInterval:         wasmPurgeInterval,
-			HTTPRequestTimeout:    wasmHTTPRequestTimeout,
-			HTTPRequestMaxRetries: wasmHTTPRequestMaxRetries,
-		},
+		XDSRootCerts:                xdsRootCA,
+		CARootCerts:                 caRootCA,
+		XDSHeaders:                  map[string]string{},
+		XdsUdsPath:                  filepath.Join(cfg.ConfigPath, "XDS"),
+		IsIPv6:                      proxy.IsIPv6(),
+		ProxyType:                   proxy.Type,
+		EnableDynamicProxyConfig:    enableProxyConfigXdsEnv,
+		EnableDynamicBootstrap:      enableBootstrapXdsEnv,
+		WASMOptions:                 NewWasmOptions(),
 		ProxyIPAddresses:            proxy.IPAddresses,
 		ServiceNode:                 proxy.ServiceNode(),
 		EnvoyStatusPort:             envoyStatusPortEnv,
@@ -60,15 +55,44 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		ProxyXDSDebugViaAgentPort:   proxyXDSDebugViaAgentPort,
 		DNSCapture:                  DNSCaptureByAgent.Get(),
 		DNSForwardParallel:          DNSForwardParallel.Get(),
-		DNSAddr:                     DNSCaptureAddr.Get(),
-		ProxyNamespace:              PodNamespaceVar.Get(),
-		ProxyDomain:                 proxy.DNSDomain,
-		IstiodSAN:                   istiodSAN.Get(),
+		DNSUpstreams:                dnsUpstreams(),
+		DNSUpstreamTimeout:          DNSUpstreamTimeout.Get(),
+		DNSCache: dnsClient.CacheOptions{
+			MaxEntries: DNSCacheSize.Get(),
+			MaxTTL:     DNSCacheMaxTTL.Get(),
+		},
+		DNSAddr:        DNSCaptureAddr.Get(),
+		ProxyNamespace: PodNamespaceVar.Get(),
+		ProxyDomain:    proxy.DNSDomain,
+		IstiodSAN:      istiodSAN.Get(),
 	}
 	extractXDSHeadersFromEnv(o)
 	return o
 }
 
+// NewWasmOptions returns the options of the Wasm module cache, set from the environment.
+func NewWasmOptions() wasm.Options {
+	return wasm.Options{
+		InsecureRegistries:        sets.New(strings.Split(wasmInsecureRegistries, ",")...),
+		ModuleExpiry:              wasmModuleExpiry,
+		PurgeInterval:             wasmPurgeInterval,
+		HTTPRequestTimeout:        wasmHTTPRequestTimeout,
+		HTTPRequestMaxRetries:     wasmHTTPRequestMaxRetries,
+		SignatureVerificationKeys: wasmSignatureVerificationKeys,
+		SharedCacheDir:            wasmSharedCacheDir,
+	}
+}
+
+func dnsUpstreams() []string {
+	var upstreams []string
+	for _, u := range strings.Split(DNSUpstreams.Get(), ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			upstreams = append(upstreams, u)
+		}
+	}
+	return upstreams
+}
+
 // Simplified extraction of gRPC headers from environment.
 // Unlike ISTIO_META, where we need JSON and advanced features - this is just for small string headers.
 func extractXDSHeadersFromEnv(o *istioagent.AgentOptions) {
@@ -61,6 +61,16 @@ var (
 	outputKeyCertToDir = env.Register("OUTPUT_CERTS", "",
 		"The output directory for the key and certificate. If empty, key and certificate will not be saved. "+
 			"Must be set for VMs using provisioning certificates.").Get()
+	outputCertsFileMode = env.Register("OUTPUT_CERTS_FILE_MODE", "",
+		"The octal permission of the key and certificate files written to OUTPUT_CERTS, for example 0640. "+
+			"Defaults to 0600, or 0644 on Kubernetes.").Get()
+	outputCertsRotationSignal = env.Register("OUTPUT_CERTS_ROTATION_SIGNAL", "",
+		"The signal, such as SIGHUP, sent to the process of OUTPUT_CERTS_ROTATION_PID_FILE when the key and "+
+			"certificate files written to OUTPUT_CERTS are rotated.").Get()
+	outputCertsRotationPIDFile = env.Register("OUTPUT_CERTS_ROTATION_PID_FILE", "",
+		"The PID file of the process to signal with OUTPUT_CERTS_ROTATION_SIGNAL.").Get()
+	outputCertsRotationURL = env.Register("OUTPUT_CERTS_ROTATION_URL", "",
+		"The URL to POST to when the key and certificate files written to OUTPUT_CERTS are rotated.").Get()
 
 	caProviderEnv = env.Register("CA_PROVIDER", "Citadel", "name of authentication provider").Get()
 	caEndpointEnv = env.Register("CA_ADDR", "", "Address of the spiffe certificate provider. Defaults to discoveryAddress").Get()
@@ -71,6 +81,12 @@ var (
 	secretTTLEnv = env.Register("SECRET_TTL", 24*time.Hour,
 		"The cert lifetime requested by istio agent").Get()
 
+	jwtSVIDPortEnv = env.Register("JWT_SVID_PORT", 0,
+		"The local port serving the JWT-SVIDs of the workload identity, for the audiences of the audience "+
+			"query parameter of /jwtsvid. Disabled if 0.").Get()
+	jwtSVIDTTLEnv = env.Register("JWT_SVID_TTL", 0*time.Second,
+		"The JWT-SVID lifetime requested by istio agent. The CA default applies if 0.").Get()
+
 	fileDebounceDuration = env.Register("FILE_DEBOUNCE_DURATION", 100*time.Millisecond,
 		"The duration for which the file read operation is delayed once file update is detected").Get()
 
@@ -83,7 +99,13 @@ var (
 	eccSigAlgEnv        = env.Register("ECC_SIGNATURE_ALGORITHM", "", "The type of ECC signature algorithm to use when generating private keys").Get()
 	fileMountedCertsEnv = env.Register("FILE_MOUNTED_CERTS", false, "").Get()
 	credFetcherTypeEnv  = env.Register("CREDENTIAL_FETCHER_TYPE", security.JWT,
-		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
+		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine, JWT, "+
+			"Exec and OIDCTokenFile").Get()
+	credFetcherExecCommand = env.Register("CREDENTIAL_FETCHER_EXEC_COMMAND", "",
+		"The command line of the credential helper of the Exec credential fetcher. It must print the token "+
+			"and its expirationTime as JSON. The arguments are split as by a POSIX shell: they are separated by "+
+			"spaces, and may be quoted with single or double quotes or have their spaces escaped with a backslash. "+
+			"The command is run directly, not by a shell, so variables and globs are not expanded.").Get()
 	credIdentityProvider = env.Register("CREDENTIAL_IDENTITY_PROVIDER", "GoogleComputeEngine",
 		"The identity provider for credential. Currently default supported identity provider is GoogleComputeEngine").Get()
 	proxyXDSDebugViaAgent = env.Register("PROXY_XDS_DEBUG_VIA_AGENT", true,
@@ -101,6 +123,21 @@ var (
 	DNSForwardParallel = env.Register("DNS_FORWARD_PARALLEL", false,
 		"If set to true, agent will send parallel DNS queries to all upstream nameservers")
 
+	DNSUpstreams = env.Register("DNS_UPSTREAMS", "",
+		"Comma separated list of encrypted nameservers the DNS proxy forwards queries for unknown hosts to, instead of "+
+			"the nameservers in resolv.conf. Each is either tls://host[:port] for DNS-over-TLS, or "+
+			"https://host[:port]/path for DNS-over-HTTPS. Can be set through the proxyMetadata of ProxyConfig.")
+
+	DNSUpstreamTimeout = env.Register("DNS_UPSTREAM_TIMEOUT", 5*time.Second,
+		"Timeout for each query forwarded by the DNS proxy to an upstream nameserver")
+
+	DNSCacheSize = env.Register("DNS_PROXY_CACHE_SIZE", 0,
+		"Number of upstream responses cached by the DNS proxy, for hosts not in the name table. "+
+			"Responses are cached for their TTL. If 0, responses are not cached.")
+
+	DNSCacheMaxTTL = env.Register("DNS_PROXY_CACHE_MAX_TTL", 5*time.Minute,
+		"Maximum time upstream responses are cached by the DNS proxy for, regardless of their TTL")
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.Register("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
@@ -120,6 +157,14 @@ var (
 	wasmHTTPRequestMaxRetries = env.Register("WASM_HTTP_REQUEST_MAX_RETRIES", wasm.DefaultHTTPRequestMaxRetries,
 		"maximum number of HTTP/HTTPS request retries for pulling a Wasm module via http/https").Get()
 
+	wasmSignatureVerificationKeys = env.Register("WASM_SIGNATURE_VERIFICATION_KEYS", "",
+		"path to a file with PEM encoded public keys. If set, Wasm modules are only loaded if they carry a cosign "+
+			"signature made by one of the keys").Get()
+
+	wasmSharedCacheDir = env.Register("WASM_SHARED_CACHE_DIR", "",
+		"directory shared by the agents of a node, such as a hostPath volume, where Wasm modules are stored "+
+			"so that they are downloaded once per node").Get()
+
 	// Ability of istio-agent to retrieve bootstrap via XDS
 	enableBootstrapXdsEnv = env.Register("BOOTSTRAP_XDS_AGENT", false,
 		"If set to true, agent retrieves the bootstrap configuration prior to starting Envoy").Get()
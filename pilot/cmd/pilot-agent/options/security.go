@@ -16,8 +16,12 @@ package options
 
 import (
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
+	"github.com/google/shlex"
+
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
 	securityModel "istio.io/istio/pilot/pkg/security/model"
@@ -37,6 +41,9 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 		CAProviderName:                 caProviderEnv,
 		PilotCertProvider:              features.PilotCertProvider,
 		OutputKeyCertToDir:             outputKeyCertToDir,
+		OutputKeyCertRotationSignal:    outputCertsRotationSignal,
+		OutputKeyCertRotationPIDFile:   outputCertsRotationPIDFile,
+		OutputKeyCertRotationURL:       outputCertsRotationURL,
 		ProvCert:                       provCert,
 		ClusterID:                      clusterIDVar.Get(),
 		FileMountedCerts:               fileMountedCertsEnv,
@@ -51,6 +58,8 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 		FileDebounceDuration:           fileDebounceDuration,
 		SecretRotationGracePeriodRatio: secretRotationGracePeriodRatioEnv,
 		STSPort:                        stsPort,
+		JWTSVIDPort:                    jwtSVIDPortEnv,
+		JWTSVIDTTL:                     jwtSVIDTTLEnv,
 		CertSigner:                     certSigner.Get(),
 		CARootPath:                     cafile.CACertFilePath,
 		CertChainFilePath:              security.DefaultCertChainFilePath,
@@ -58,6 +67,14 @@ func NewSecurityOptions(proxyConfig *meshconfig.ProxyConfig, stsPort int, tokenM
 		RootCertFilePath:               security.DefaultRootCertFilePath,
 	}
 
+	if outputCertsFileMode != "" {
+		mode, err := strconv.ParseUint(outputCertsFileMode, 8, 32)
+		if err != nil || mode > 0o777 {
+			return o, fmt.Errorf("invalid OUTPUT_CERTS_FILE_MODE %q", outputCertsFileMode)
+		}
+		o.OutputKeyCertFileMode = os.FileMode(mode)
+	}
+
 	o, err := SetupSecurityOptions(proxyConfig, o, jwtPolicy.Get(),
 		credFetcherTypeEnv, credIdentityProvider)
 	if err != nil {
@@ -99,7 +116,12 @@ func SetupSecurityOptions(proxyConfig *meshconfig.ProxyConfig, secOpt *security.
 	}
 
 	o.CredIdentityProvider = credIdentityProvider
-	credFetcher, err := credentialfetcher.NewCredFetcher(credFetcherTypeEnv, o.TrustDomain, jwtPath, o.CredIdentityProvider)
+	execCommand, err := shlex.Split(credFetcherExecCommand)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CREDENTIAL_FETCHER_EXEC_COMMAND %q: %v", credFetcherExecCommand, err)
+	}
+	credFetcher, err := credentialfetcher.NewCredFetcher(credFetcherTypeEnv, o.TrustDomain, jwtPath, o.CredIdentityProvider,
+		execCommand)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create credential fetcher: %v", err)
 	}
@@ -18,6 +18,7 @@ import (
 	"os"
 	"testing"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/security"
 )
 
@@ -59,3 +60,25 @@ func TestCheckGkeWorkloadCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupSecurityOptionsExecCommand(t *testing.T) {
+	defer func(command string) { credFetcherExecCommand = command }(credFetcherExecCommand)
+
+	credFetcherExecCommand = `echo '{"token": "quoted token", "expirationTime": "2099-01-01T00:00:00Z"}'`
+	o, err := SetupSecurityOptions(&meshconfig.ProxyConfig{}, &security.Options{}, "", security.Exec, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := o.CredFetcher.GetPlatformCredential()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "quoted token" {
+		t.Fatalf("expected the quoted argument to be kept whole, got token %q", token)
+	}
+
+	credFetcherExecCommand = `echo 'unterminated`
+	if _, err := SetupSecurityOptions(&meshconfig.ProxyConfig{}, &security.Options{}, "", security.Exec, ""); err == nil {
+		t.Fatalf("expected an error for an unterminated quote")
+	}
+}
@@ -33,6 +33,8 @@ func NewStatusServerOptions(proxy *model.Proxy, proxyConfig *meshconfig.ProxyCon
 		Probes:         []ready.Prober{agent},
 		NoEnvoy:        agent.EnvoyDisabled(),
 		FetchDNS:       agent.GetDNSTable,
+		FetchDNSCache:  agent.GetDNSCache,
+		FlushDNSCache:  agent.FlushDNSCache,
 		GRPCBootstrap:  agent.GRPCBootstrapPath(),
 	}
 }
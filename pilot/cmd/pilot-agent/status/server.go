@@ -52,6 +52,7 @@ import (
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/pkg/env"
@@ -126,8 +127,12 @@ type Options struct {
 	EnvoyPrometheusPort int
 	Context             context.Context
 	FetchDNS            func() *dnsProto.NameTable
-	NoEnvoy             bool
-	GRPCBootstrap       string
+	// FetchDNSCache returns the responses cached by the DNS proxy, or nil if there is no cache.
+	FetchDNSCache func() []dnsClient.CacheEntry
+	// FlushDNSCache removes the responses cached by the DNS proxy, returning the number removed.
+	FlushDNSCache func() int
+	NoEnvoy       bool
+	GRPCBootstrap string
 }
 
 // Server provides an endpoint for handling status probes.
@@ -354,6 +359,7 @@ func (s *Server) Run(ctx context.Context) {
 	mux.HandleFunc("/debug/pprof/symbol", s.handlePprofSymbol)
 	mux.HandleFunc("/debug/pprof/trace", s.handlePprofTrace)
 	mux.HandleFunc("/debug/ndsz", s.handleNdsz)
+	mux.HandleFunc("/debug/dnscachez", s.handleDNSCachez)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.statusPort))
 	if err != nil {
@@ -842,6 +848,33 @@ func (s *Server) handleNdsz(w http.ResponseWriter, r *http.Request) {
 	writeJSONProto(w, nametable)
 }
 
+// handleDNSCachez dumps the responses cached by the DNS proxy, or flushes them on DELETE.
+func (s *Server) handleDNSCachez(w http.ResponseWriter, r *http.Request) {
+	if !isRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	if s.config.FetchDNSCache == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		entries := s.config.FetchDNSCache()
+		if entries == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		writeJSONProto(w, entries)
+	case http.MethodDelete:
+		writeJSONProto(w, map[string]int{"flushed": s.config.FlushDNSCache()})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // writeJSONProto writes a protobuf to a json payload, handling content type, marshaling, and errors
 func writeJSONProto(w http.ResponseWriter, obj any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -24,6 +24,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/bootstrap"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/serviceregistry/catalog"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/cmd"
 	"istio.io/istio/pkg/config/constants"
@@ -129,12 +130,17 @@ func addFlags(c *cobra.Command) {
 	// Process commandline args.
 	c.PersistentFlags().StringSliceVar(&serverArgs.RegistryOptions.Registries, "registries",
 		[]string{string(provider.Kubernetes)},
-		fmt.Sprintf("Comma separated list of platform service registries to read from (choose one or more from {%s, %s})",
-			provider.Kubernetes, provider.Mock))
+		fmt.Sprintf("Comma separated list of platform service registries to read from (choose one or more from {%s, %s, %s})",
+			provider.Kubernetes, provider.Mock, provider.Catalog))
 	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.ClusterRegistriesNamespace, "clusterRegistriesNamespace",
 		serverArgs.RegistryOptions.ClusterRegistriesNamespace, "Namespace for ConfigMap which stores clusters configs")
 	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.KubeConfig, "kubeconfig", "",
 		"Use a Kubernetes configuration file instead of in-cluster configuration")
+	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.CatalogAddress, "catalogAddress", "",
+		"Address of the service catalog read by the Catalog registry. Either an HTTP(S) URL serving the Consul catalog API, "+
+			"or the path to a local JSON file")
+	c.PersistentFlags().DurationVar(&serverArgs.RegistryOptions.CatalogRefreshInterval, "catalogRefreshInterval",
+		catalog.DefaultRefreshInterval, "How often the Catalog registry polls the service catalog for changes")
 	c.PersistentFlags().StringVar(&serverArgs.MeshConfigFile, "meshConfig", "./etc/istio/config/mesh",
 		"File name for Istio mesh configuration. If not specified, a default mesh will be used.")
 	c.PersistentFlags().StringVar(&serverArgs.NetworksConfigFile, "networksConfig", "./etc/istio/config/meshNetworks",
@@ -143,6 +149,11 @@ func addFlags(c *cobra.Command) {
 		"Select a namespace where the controller resides. If not set, uses ${POD_NAMESPACE} environment variable")
 	c.PersistentFlags().DurationVar(&serverArgs.ShutdownDuration, "shutdownDuration", 10*time.Second,
 		"Duration the discovery server needs to terminate gracefully")
+	c.PersistentFlags().StringVar(&serverArgs.SnapshotOptions.Path, "snapshotPath", "",
+		"File used to persist config and service registry state. If set, the state from the previous run is served "+
+			"on startup while caches sync, and replaced with a full push once they have synced")
+	c.PersistentFlags().DurationVar(&serverArgs.SnapshotOptions.Interval, "snapshotInterval", serverArgs.SnapshotOptions.Interval,
+		"How often the snapshot set by --snapshotPath is written")
 
 	// RegistryOptions Controller options
 	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.FileDir, "configDir", "",
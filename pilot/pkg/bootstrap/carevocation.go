@@ -0,0 +1,284 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	informersv1 "k8s.io/client-go/informers/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/util/sets"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/pkg/log"
+)
+
+const (
+	// crlRefreshInterval is how often the CRL is regenerated, so that proxies always have a valid one.
+	crlRefreshInterval = ca.CRLValidity / 4
+	// crlCheckInterval is how often the signing certificate of the CA is checked for changes, to regenerate the CRL.
+	crlCheckInterval = time.Minute
+)
+
+// caRevocationController keeps the CRL of the Istio CA in sync with the revocation list in the
+// revocation secret, and triggers a push of the CRL to proxies whenever it is regenerated.
+// The certificates issued by the CA are saved in secrets annotated with the istiod instance, so that the certificates
+// of revoked identities are found by all the instances, including the ones issued by former instances.
+type caRevocationController struct {
+	ca        *ca.IstioCA
+	client    kube.Client
+	namespace string
+	// instance is the name of this istiod instance, which names the secrets of the certificates it issued.
+	instance string
+	informer informersv1.SecretInformer
+	queue    controllers.Queue
+	push     func()
+
+	// saved is the last version of the issued certificates saved in the secret of the instance.
+	saved ca.TrackedCertificates
+
+	mu sync.RWMutex
+	// crls holds the CRL signed by the current signing certificate of the CA first, followed by the ones signed by
+	// former signing certificates until they expire, as the certificates they issued may still be in use.
+	crls []generatedCRL
+}
+
+type generatedCRL struct {
+	signer  string
+	pem     []byte
+	created time.Time
+}
+
+func newCARevocationController(istioCA *ca.IstioCA, client kube.Client, namespace, instance string, push func()) *caRevocationController {
+	c := &caRevocationController{
+		ca:        istioCA,
+		client:    client,
+		namespace: namespace,
+		instance:  instance,
+		push:      push,
+	}
+	// Only the revocation secret is watched, rather than all the secrets of the namespace.
+	c.informer = informers.NewSharedInformerFactoryWithOptions(client.Kube(), 12*time.Hour,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fields.OneTermEqualSelector(metav1.ObjectNameField, ca.RevocationSecret).String()
+		})).
+		Core().V1().Secrets()
+	c.queue = controllers.NewQueue("ca revocation",
+		controllers.WithReconciler(c.reconcile),
+		controllers.WithMaxAttempts(5))
+	c.informer.Informer().AddEventHandler(controllers.ObjectHandler(c.queue.AddObject))
+	// Tracking starts before the CA serves any request, so that revoking an identity revokes all its certificates.
+	istioCA.TrackIssuedCertificates()
+	return c
+}
+
+func (c *caRevocationController) Run(stop <-chan struct{}) {
+	go c.informer.Informer().Run(stop)
+	if !kube.WaitForCacheSync(stop, c.informer.Informer().HasSynced) {
+		log.Error("failed to sync CA revocation controller")
+		return
+	}
+	c.restoreIssuedCertificates()
+	key := types.NamespacedName{Namespace: c.namespace, Name: ca.RevocationSecret}
+	// Generate the CRL even if there is no revocation secret, for proxies to get an empty one.
+	c.queue.Add(key)
+	go func() {
+		t := time.NewTicker(crlCheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				c.saveIssuedCertificates()
+				if c.needsRefresh() {
+					c.queue.Add(key)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	c.queue.Run(stop)
+}
+
+// restoreIssuedCertificates adds the certificates saved by this instance before a restart to the tracked ones.
+func (c *caRevocationController) restoreIssuedCertificates() {
+	saved, err := ca.ListTrackedCertificates(context.TODO(), c.client.Kube().CoreV1(), c.namespace)
+	if err != nil {
+		log.Errorf("failed to restore the certificates issued by the CA: %v", err)
+		return
+	}
+	c.ca.AddIssuedCertificates(saved[c.instance])
+}
+
+// saveIssuedCertificates saves the certificates tracked by the CA in the secrets of the instance, if they have changed.
+// Certificates issued since the last save are only known by this instance until the next one.
+func (c *caRevocationController) saveIssuedCertificates() {
+	issued := c.ca.IssuedCertificates()
+	if reflect.DeepEqual(issued, c.saved) {
+		return
+	}
+	if err := ca.SaveTrackedCertificates(context.TODO(), c.client.Kube().CoreV1(), c.namespace, c.instance, issued); err != nil {
+		log.Errorf("failed to save the certificates issued by the CA: %v", err)
+		return
+	}
+	c.saved = issued
+}
+
+// needsRefresh returns true if the CRL is due for regeneration or the signing certificate of the CA has changed.
+func (c *caRevocationController) needsRefresh() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.crls) == 0 {
+		return false
+	}
+	current := c.crls[0]
+	return time.Since(current.created) > crlRefreshInterval || current.signer != c.signer()
+}
+
+func (c *caRevocationController) signer() string {
+	cert, _, _, _ := c.ca.GetCAKeyCertBundle().GetAllPem()
+	return string(cert)
+}
+
+// CRL returns the current PEM encoded CRLs, or nil until they are generated.
+func (c *caRevocationController) CRL() []byte {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out []byte
+	for _, crl := range c.crls {
+		out = append(out, crl.pem...)
+	}
+	return out
+}
+
+func (c *caRevocationController) reconcile(types.NamespacedName) error {
+	l := &ca.RevocationList{}
+	secret, err := c.informer.Lister().Secrets(c.namespace).Get(ca.RevocationSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if l, err = ca.ParseRevocationList(secret.Data[ca.RevocationListFile]); err != nil {
+			return fmt.Errorf("invalid secret %s/%s: %v", c.namespace, ca.RevocationSecret, err)
+		}
+	}
+
+	// Every istiod only knows the certificates it has issued: look up the ones saved by all the instances, including
+	// former ones, and record the ones of the revoked identities in the secret for the other instances. The entries of
+	// expired certificates are removed, so that the secret does not grow forever.
+	issued, err := c.issuedCertificates()
+	if err != nil {
+		return err
+	}
+	update := func(l *ca.RevocationList) bool {
+		revoked := l.RevokeIssuedCertificates(issued)
+		pruned := c.ca.PruneRevocationList(l)
+		return revoked || pruned
+	}
+	if update(l) {
+		err := ca.UpdateRevocationList(context.TODO(), c.client.Kube().CoreV1(), c.namespace, func(latest *ca.RevocationList) (bool, error) {
+			return update(latest), nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update revoked certificates in secret %s/%s: %v", c.namespace, ca.RevocationSecret, err)
+		}
+	}
+
+	signer := c.signer()
+	crl, err := c.ca.GenerateCRL(l)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	c.mu.Lock()
+	crls := []generatedCRL{{signer: signer, pem: crl, created: now}}
+	for _, former := range c.crls {
+		if former.signer != signer && now.Sub(former.created) < ca.CRLValidity {
+			crls = append(crls, former)
+		}
+	}
+	c.crls = crls
+	c.mu.Unlock()
+	log.Infof("generated CRL with %d revoked entries", len(l.Revoked))
+	c.push()
+	return nil
+}
+
+// issuedCertificates returns the certificates issued by this instance, and the ones saved by all the instances.
+// The secrets of former instances are removed once all their certificates have expired.
+func (c *caRevocationController) issuedCertificates() (ca.TrackedCertificates, error) {
+	issued := c.ca.IssuedCertificates()
+	saved, err := ca.ListTrackedCertificates(context.TODO(), c.client.Kube().CoreV1(), c.namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the certificates issued by the CA: %v", err)
+	}
+	for instance, certs := range saved {
+		issued.Merge(certs)
+		if len(certs) == 0 && instance != c.instance {
+			// Saving no certificates removes the secrets of the instance.
+			if err := ca.SaveTrackedCertificates(context.TODO(), c.client.Kube().CoreV1(), c.namespace, instance, certs); err != nil {
+				log.Warnf("failed to remove the secrets of the expired certificates of %s: %v", instance, err)
+			}
+		}
+	}
+	return issued, nil
+}
+
+// initCARevocation distributes the CRL of the Istio CA to proxies, if enabled. It fails if the CA certificate cannot
+// sign CRLs, such as plugged-in CA certificates without the cRLSign key usage.
+func (s *Server) initCARevocation(namespace, podName string) error {
+	if !features.EnableCACRL || s.CA == nil || s.kubeClient == nil {
+		return nil
+	}
+	if err := s.CA.CheckCRLSigning(); err != nil {
+		return fmt.Errorf("ENABLE_CA_CRL is set, but the CA cannot sign CRLs: %v. "+
+			"Reissue the CA certificate with the cRLSign key usage or disable ENABLE_CA_CRL", err)
+	}
+	if podName == "" {
+		podName, _ = os.Hostname()
+	}
+	// Pushes of a new CRL only update the revocation secret, so that the other resources are not regenerated.
+	crlKey := model.ConfigKey{Kind: kind.Secret, Name: ca.RevocationSecret, Namespace: namespace}
+	c := newCARevocationController(s.CA, s.kubeClient, namespace, podName, func() {
+		s.XDSServer.ConfigUpdate(&model.PushRequest{
+			Full:           true,
+			ConfigsUpdated: sets.New(crlKey),
+			Reason:         []model.TriggerReason{model.SecretTrigger},
+		})
+	})
+	s.XDSServer.Generators[v3.CertificateRevocationListType] = &xds.CrlGenerator{CRL: c.CRL, ConfigKey: crlKey}
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go c.Run(stop)
+		return nil
+	})
+	return nil
+}
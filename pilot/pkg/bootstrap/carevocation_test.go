@@ -0,0 +1,188 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestCARevocationController(t *testing.T) {
+	istioCA := newTestCA(t)
+	client := kube.NewFakeClient()
+	pushes := atomic.NewInt32(0)
+	c := newCARevocationController(istioCA, client, "istio-system", "istiod", func() {
+		pushes.Inc()
+	})
+	stop := test.NewStop(t)
+	go c.Run(stop)
+
+	// An empty CRL is distributed without revocation secret.
+	retry.UntilSuccessOrFail(t, func() error {
+		serials, err := revokedSerials(c.CRL())
+		if err != nil {
+			return err
+		}
+		if len(serials) != 0 || pushes.Load() != 1 {
+			return fmt.Errorf("expected empty CRL to be pushed, got %v after %d pushes", serials, pushes.Load())
+		}
+		return nil
+	})
+
+	identity := "spiffe://cluster.local/ns/default/sa/bad"
+	serial := signTestCertificate(t, istioCA, identity)
+
+	err := ca.UpdateRevocationList(context.Background(), client.Kube().CoreV1(), "istio-system", func(l *ca.RevocationList) (bool, error) {
+		return true, l.RevokeIdentity(identity, time.Now(), "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The certificate issued to the identity is recorded in the secret, and revoked in the CRL.
+	retry.UntilSuccessOrFail(t, func() error {
+		l, err := ca.LoadRevocationList(context.Background(), client.Kube().CoreV1(), "istio-system")
+		if err != nil {
+			return err
+		}
+		if len(l.Revoked) != 2 || l.Revoked[1].SerialNumber != serial {
+			return fmt.Errorf("expected certificate %s to be recorded, got %+v", serial, l.Revoked)
+		}
+		serials, err := revokedSerials(c.CRL())
+		if err != nil {
+			return err
+		}
+		if len(serials) != 1 || serials[0] != serial {
+			return fmt.Errorf("expected certificate %s to be revoked, got %v", serial, serials)
+		}
+		return nil
+	})
+}
+
+func TestCARevocationControllerSavedCertificates(t *testing.T) {
+	client := kube.NewFakeClient()
+	identity := "spiffe://cluster.local/ns/default/sa/bad"
+
+	// Controllers which are not run only track and save the certificates of their CA.
+	newInstance := func(name string) *caRevocationController {
+		c := &caRevocationController{ca: newTestCA(t), client: client, namespace: "istio-system", instance: name}
+		c.ca.TrackIssuedCertificates()
+		return c
+	}
+
+	// A former istiod saved the certificate it issued before going away.
+	former := newInstance("istiod-former")
+	serial := signTestCertificate(t, former.ca, identity)
+	former.saveIssuedCertificates()
+
+	// The certificates saved are restored when the instance restarts.
+	restarted := newInstance("istiod-former")
+	restarted.restoreIssuedCertificates()
+	if certs := restarted.ca.IssuedCertificates()[identity]; len(certs) != 1 || certs[0].SerialNumber != serial {
+		t.Fatalf("expected certificate %s to be restored, got %+v", serial, certs)
+	}
+
+	// The secrets of instances whose certificates have all expired are removed.
+	expired := ca.TrackedCertificates{identity: {{SerialNumber: "1", Issued: time.Now().Add(-2 * time.Hour), Expires: time.Now().Add(-time.Hour)}}}
+	if err := ca.SaveTrackedCertificates(context.Background(), client.Kube().CoreV1(), "istio-system", "istiod-gone", expired); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance revokes the certificate issued by the former one.
+	c := newCARevocationController(newTestCA(t), client, "istio-system", "istiod", func() {})
+	go c.Run(test.NewStop(t))
+	err := ca.UpdateRevocationList(context.Background(), client.Kube().CoreV1(), "istio-system", func(l *ca.RevocationList) (bool, error) {
+		return true, l.RevokeIdentity(identity, time.Now(), "")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		serials, err := revokedSerials(c.CRL())
+		if err != nil {
+			return err
+		}
+		if len(serials) != 1 || serials[0] != serial {
+			return fmt.Errorf("expected certificate %s to be revoked, got %v", serial, serials)
+		}
+		return nil
+	})
+	_, err = client.Kube().CoreV1().Secrets("istio-system").Get(context.Background(),
+		ca.TrackedCertificatesSecretName("istiod-gone", 0), metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Fatalf("expected the secret of expired certificates to be removed, got %v", err)
+	}
+}
+
+func newTestCA(t *testing.T) *ca.IstioCA {
+	t.Helper()
+	caOpts, err := ca.NewSelfSignedDebugIstioCAOptions("", time.Hour, time.Hour, time.Hour, "cluster.local", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	istioCA, err := ca.NewIstioCA(caOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return istioCA
+}
+
+// signTestCertificate returns the hex encoded serial number of a certificate signed by the CA for the identity.
+func signTestCertificate(t *testing.T, istioCA *ca.IstioCA, identity string) string {
+	t.Helper()
+	csrPEM, _, err := util.GenCSR(util.CertOptions{Host: identity, RSAKeySize: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := istioCA.Sign(csrPEM, ca.CertOpts{SubjectIDs: []string{identity}, TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := util.ParsePemEncodedCertificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.SerialNumber.Text(16)
+}
+
+// revokedSerials returns the hex encoded serial numbers revoked by the PEM encoded CRL.
+func revokedSerials(b []byte) ([]string, error) {
+	if b == nil {
+		return nil, fmt.Errorf("no CRL generated")
+	}
+	block, _ := pem.Decode(b)
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	var serials []string
+	for _, r := range crl.RevokedCertificates {
+		serials = append(serials, r.SerialNumber.Text(16))
+	}
+	return serials, nil
+}
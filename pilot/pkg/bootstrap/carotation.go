@@ -0,0 +1,434 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"istio.io/istio/pilot/pkg/model"
+	tb "istio.io/istio/pilot/pkg/trustbundle"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+	"istio.io/pkg/log"
+)
+
+// trustBundleCheckInterval is how often the proxies and the other istiods are checked for the distribution of the
+// trust bundle.
+var trustBundleCheckInterval = 5 * time.Second
+
+// caRootRotationConfigMap is the config map in which each istiod records the roots it has distributed to its proxies,
+// by pod name, so that no istiod signs with a new root before the proxies connected to the other istiods trust it.
+const caRootRotationConfigMap = "istio-ca-root-rotation"
+
+// caRootRotation rotates the plugged-in CA to a new root in stages, so that proxies trust the new root before any
+// certificate is signed by it, and keep trusting the former root until the certificates it signed are replaced:
+//  1. the new roots are added to the roots of the CA and to the trust bundle pushed to proxies,
+//  2. once all the proxies have acknowledged the trust bundle, the CA signs with the new certificate,
+//  3. after the overlap, the former roots are removed.
+//
+// The rotation is aborted if the trust bundle is not distributed within the timeout, in which case the CA keeps
+// signing with the former certificate and trusting both roots.
+type caRootRotation struct {
+	bundle      *util.KeyCertBundle
+	trustBundle *tb.TrustBundle
+	// waitForDistribution returns once all the proxies have received the current trust bundle, which includes the
+	// given roots.
+	waitForDistribution func(ctx context.Context, roots []byte) error
+	// updated is called after every change of the roots or the signing certificate of the CA.
+	updated func() error
+	overlap time.Duration
+	// timeout bounds the distribution of the trust bundle, no timeout if zero.
+	timeout time.Duration
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	// target holds the files of the rotation in progress.
+	target *signingCAFiles
+}
+
+// signingCAFiles holds the content of the files of a SigningCAFileBundle.
+type signingCAFiles struct {
+	cert, key, chain, roots []byte
+}
+
+func readSigningCAFiles(fileBundle ca.SigningCAFileBundle) (*signingCAFiles, error) {
+	var err error
+	f := &signingCAFiles{}
+	if f.cert, err = os.ReadFile(fileBundle.SigningCertFile); err != nil {
+		return nil, err
+	}
+	if f.key, err = os.ReadFile(fileBundle.SigningKeyFile); err != nil {
+		return nil, err
+	}
+	for _, file := range fileBundle.CertChainFiles {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		f.chain = append(f.chain, b...)
+	}
+	if f.roots, err = os.ReadFile(fileBundle.RootCertFile); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *signingCAFiles) bytes() []byte {
+	return bytes.Join([][]byte{f.cert, f.key, f.chain, f.roots}, nil)
+}
+
+// Start verifies the new signing files and rotates the CA to them in the background, stopping any rotation in
+// progress. It returns false if the files are the target of the rotation in progress already.
+func (r *caRootRotation) Start(stop <-chan struct{}, files *signingCAFiles) (bool, error) {
+	if err := util.Verify(files.cert, files.key, files.chain, files.roots); err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		if bytes.Equal(r.target.bytes(), files.bytes()) {
+			return false, nil
+		}
+		// The roots of the CA still include the ones of the stopped rotation, they are trusted until the end of
+		// the new one.
+		r.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.target = files
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		err := r.run(ctx, files)
+		r.mu.Lock()
+		if r.target == files {
+			r.cancel, r.target = nil, nil
+		}
+		r.mu.Unlock()
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("failed to rotate the root of the plugged-in CA: %v", err)
+		}
+	}()
+	return true, nil
+}
+
+// InProgress returns true if a rotation is in progress.
+func (r *caRootRotation) InProgress() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancel != nil
+}
+
+func (r *caRootRotation) run(ctx context.Context, files *signingCAFiles) error {
+	cert, key, chain, roots := r.bundle.GetAllPem()
+	combined := mergeRootCerts(roots, files.roots)
+
+	log.Info("Adding the new root of the plugged-in CA to the trust bundle")
+	if err := r.bundle.VerifyAndSetAll(cert, key, chain, combined); err != nil {
+		return err
+	}
+	if err := r.setRoots(combined); err != nil {
+		return err
+	}
+	if err := r.distribute(ctx, files.roots); err != nil {
+		return err
+	}
+
+	log.Info("Trust bundle distributed to all proxies, signing with the new plugged-in CA")
+	if err := r.bundle.VerifyAndSetAll(files.cert, files.key, files.chain, combined); err != nil {
+		return err
+	}
+	if err := r.updated(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(r.overlap):
+	}
+
+	log.Info("Removing the former root of the plugged-in CA from the trust bundle")
+	if err := r.bundle.VerifyAndSetAll(files.cert, files.key, files.chain, files.roots); err != nil {
+		return err
+	}
+	return r.setRoots(files.roots)
+}
+
+// distribute waits for the distribution of the trust bundle, within the timeout of the rotation.
+func (r *caRootRotation) distribute(ctx context.Context, roots []byte) error {
+	if r.timeout <= 0 {
+		return r.waitForDistribution(ctx, roots)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	err := r.waitForDistribution(timeoutCtx, roots)
+	if err != nil && ctx.Err() == nil && timeoutCtx.Err() != nil {
+		return fmt.Errorf("the trust bundle was not distributed within %v, the rotation is aborted and the CA keeps "+
+			"signing with the former root, update the plugged-in CA certificates again to retry: %v", r.timeout, err)
+	}
+	return err
+}
+
+// setRoots sets the roots of the Istio CA in the trust bundle, which triggers a push to proxies.
+func (r *caRootRotation) setRoots(roots []byte) error {
+	var certs []string
+	for _, b := range splitPemCerts(roots) {
+		certs = append(certs, string(b))
+	}
+	if err := r.trustBundle.UpdateTrustAnchor(&tb.TrustAnchorUpdate{
+		TrustAnchorConfig: tb.TrustAnchorConfig{Certs: certs},
+		Source:            tb.SourceIstioCA,
+	}); err != nil {
+		return fmt.Errorf("failed to update the trust bundle: %v", err)
+	}
+	return r.updated()
+}
+
+func splitPemCerts(b []byte) [][]byte {
+	var certs [][]byte
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return certs
+		}
+		if block.Type == "CERTIFICATE" {
+			certs = append(certs, pem.EncodeToMemory(block))
+		}
+	}
+}
+
+// mergeRootCerts returns the PEM encoded certificates of both roots, without duplicates.
+func mergeRootCerts(roots, newRoots []byte) []byte {
+	var merged []byte
+	for _, cert := range append(splitPemCerts(roots), splitPemCerts(newRoots)...) {
+		if !bytes.Contains(merged, cert) {
+			merged = append(merged, cert...)
+		}
+	}
+	return merged
+}
+
+// waitForTrustBundleDistribution pushes the trust bundle, and returns once all the proxies have acknowledged it, and
+// the other istiods have distributed the same roots to their proxies.
+func (s *Server) waitForTrustBundleDistribution(ctx context.Context, roots []byte) error {
+	if err := s.waitForTrustBundleAcks(ctx); err != nil {
+		return err
+	}
+	if s.istiodReplicas == nil {
+		log.Warn("no Kubernetes client, the distribution of the trust bundle by the other istiods is not checked")
+		return nil
+	}
+	return s.istiodReplicas.waitForDistribution(ctx, roots)
+}
+
+// waitForTrustBundleAcks pushes the trust bundle, and returns once all the proxies have acknowledged it.
+// The trust bundle is pushed in the proxy config, which only the proxies with PROXY_CONFIG_XDS_AGENT enabled watch.
+// The other proxies cannot acknowledge it, so they keep the rotation pending until they disconnect, as they would
+// reject the certificates signed by the new root.
+func (s *Server) waitForTrustBundleAcks(ctx context.Context) error {
+	sent := s.XDSServer.SentNonces(v3.ProxyConfigType)
+	s.XDSServer.ConfigUpdate(&model.PushRequest{
+		Full:   true,
+		Reason: []model.TriggerReason{model.GlobalUpdate},
+	})
+	for {
+		pending := s.XDSServer.PendingAcks(v3.ProxyConfigType, sent)
+		unwatched := s.XDSServer.UnwatchedConnections(v3.ProxyConfigType)
+		if len(pending) == 0 && len(unwatched) == 0 {
+			return nil
+		}
+		if len(unwatched) > 0 {
+			log.Warnf("%d proxies do not watch the proxy config and cannot acknowledge the trust bundle, enable "+
+				"PROXY_CONFIG_XDS_AGENT on the proxies for the rotation to proceed", len(unwatched))
+		}
+		if len(pending) > 0 {
+			log.Infof("waiting for %d proxies to acknowledge the trust bundle", len(pending))
+		}
+		select {
+		case <-ctx.Done():
+			pending = append(pending, unwatched...)
+			return fmt.Errorf("%d proxies have not acknowledged the trust bundle: %s", len(pending), strings.Join(pending, ", "))
+		case <-time.After(trustBundleCheckInterval):
+		}
+	}
+}
+
+// istiodReplicas coordinates the distribution of the trust bundle with the other running istiods of the deployment,
+// which share the plugged-in CA certificates. The istiods of other revisions rotate their CA on their own.
+type istiodReplicas struct {
+	client    kubernetes.Interface
+	namespace string
+	podName   string
+
+	mu sync.Mutex
+	// selector selects the pods of the deployment of this istiod, once looked up.
+	selector string
+}
+
+// waitForDistribution records that the proxies of this istiod trust the roots, and returns once all the running
+// istiods have recorded the same.
+func (r *istiodReplicas) waitForDistribution(ctx context.Context, roots []byte) error {
+	sum := sha256.Sum256(roots)
+	hash := hex.EncodeToString(sum[:])
+	if err := r.setDistributed(ctx, hash); err != nil {
+		return fmt.Errorf("failed to record the distribution of the trust bundle in the %s config map: %v",
+			caRootRotationConfigMap, err)
+	}
+	for {
+		pending, err := r.pending(ctx, hash)
+		if err != nil {
+			log.Warnf("failed to check the distribution of the trust bundle by the other istiods: %v", err)
+		} else if len(pending) == 0 {
+			return nil
+		} else {
+			log.Infof("waiting for the istiods %s to distribute the trust bundle", strings.Join(pending, ", "))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("the istiods %s have not distributed the trust bundle", strings.Join(pending, ", "))
+		case <-time.After(trustBundleCheckInterval):
+		}
+	}
+}
+
+// setDistributed records the hash of the roots distributed by this istiod, and removes the entries of the istiods
+// not running anymore.
+func (r *istiodReplicas) setDistributed(ctx context.Context, hash string) error {
+	running, err := r.runningIstiods(ctx)
+	if err != nil {
+		return err
+	}
+	configMaps := r.client.CoreV1().ConfigMaps(r.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, caRootRotationConfigMap, metav1.GetOptions{})
+		create := apierror.IsNotFound(err)
+		if create {
+			cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: caRootRotationConfigMap, Namespace: r.namespace}}
+		} else if err != nil {
+			return err
+		}
+		data := map[string]string{r.podName: hash}
+		for name, value := range cm.Data {
+			if name != r.podName && running[name] {
+				data[name] = value
+			}
+		}
+		cm.Data = data
+		if create {
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+			if apierror.IsAlreadyExists(err) {
+				// Created concurrently, retry as an update.
+				return apierror.NewConflict(v1.Resource("configmaps"), caRootRotationConfigMap, err)
+			}
+			return err
+		}
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// pending returns the names of the running istiods which have not distributed the roots with the given hash.
+func (r *istiodReplicas) pending(ctx context.Context, hash string) ([]string, error) {
+	running, err := r.runningIstiods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	distributed := map[string]string{}
+	cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(ctx, caRootRotationConfigMap, metav1.GetOptions{})
+	if err == nil {
+		distributed = cm.Data
+	} else if !apierror.IsNotFound(err) {
+		return nil, err
+	}
+	var pending []string
+	for name := range running {
+		if distributed[name] != hash {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+// podSelector returns the label selector of the pods of the deployment of this istiod: the labels of its pod, except
+// the ones which differ between the rollouts of the deployment.
+func (r *istiodReplicas) podSelector(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.selector != "" {
+		return r.selector, nil
+	}
+	pod, err := r.client.CoreV1().Pods(r.namespace).Get(ctx, r.podName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the pod %s of this istiod: %v", r.podName, err)
+	}
+	labels := map[string]string{}
+	for k, v := range pod.Labels {
+		if k != appsv1.DefaultDeploymentUniqueLabelKey && k != appsv1.ControllerRevisionHashLabelKey {
+			labels[k] = v
+		}
+	}
+	if len(labels) == 0 {
+		return "", fmt.Errorf("the pod %s of this istiod has no labels to select the other istiods with", r.podName)
+	}
+	r.selector = klabels.SelectorFromSet(labels).String()
+	return r.selector, nil
+}
+
+// runningIstiods returns the names of the running istiod pods of the deployment of this istiod.
+func (r *istiodReplicas) runningIstiods(ctx context.Context) (map[string]bool, error) {
+	selector, err := r.podSelector(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := r.client.CoreV1().Pods(r.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	running := map[string]bool{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodRunning && pod.DeletionTimestamp == nil {
+			running[pod.Name] = true
+		}
+	}
+	return running, nil
+}
@@ -0,0 +1,273 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	tb "istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func genPluggedCA(t *testing.T, org string) *signingCAFiles {
+	t.Helper()
+	rootCert, rootKey, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:          org,
+		TTL:          time.Hour,
+		IsCA:         true,
+		IsSelfSigned: true,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerCert, err := util.ParsePemEncodedCertificate(rootCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerKey, err := util.ParsePemEncodedKey(rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Org:        org,
+		TTL:        time.Hour,
+		IsCA:       true,
+		SignerCert: signerCert,
+		SignerPriv: signerKey,
+		RSAKeySize: 2048,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signingCAFiles{cert: cert, key: key, chain: append(append([]byte{}, cert...), rootCert...), roots: rootCert}
+}
+
+type caRotationState struct {
+	signer      string
+	roots       int
+	trustBundle int
+}
+
+func TestCARootRotation(t *testing.T) {
+	old := genPluggedCA(t, "old")
+	updated := genPluggedCA(t, "new")
+	name := func(cert []byte) string {
+		switch {
+		case bytes.Equal(cert, old.cert):
+			return "old"
+		case bytes.Equal(cert, updated.cert):
+			return "new"
+		}
+		return "unknown"
+	}
+
+	bundle, err := util.NewVerifiedKeyCertBundleFromPem(old.cert, old.key, old.chain, old.roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustBundle := tb.NewTrustBundle(nil)
+	distributed := make(chan struct{})
+	var mu sync.Mutex
+	var states []caRotationState
+	r := &caRootRotation{
+		bundle:      bundle,
+		trustBundle: trustBundle,
+		waitForDistribution: func(ctx context.Context, roots []byte) error {
+			select {
+			case <-distributed:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+		updated: func() error {
+			cert, _, _, roots := bundle.GetAllPem()
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, caRotationState{
+				signer:      name(cert),
+				roots:       len(splitPemCerts(roots)),
+				trustBundle: len(trustBundle.GetTrustBundle()),
+			})
+			return nil
+		},
+		overlap: 100 * time.Millisecond,
+	}
+	assertStates := func(expected ...caRotationState) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(states, expected) {
+				return fmt.Errorf("expected states %+v, got %+v", expected, states)
+			}
+			return nil
+		})
+	}
+
+	stop := test.NewStop(t)
+	if _, err := r.Start(stop, &signingCAFiles{cert: updated.cert, key: old.key, chain: updated.chain, roots: updated.roots}); err == nil {
+		t.Fatal("expected mismatching key to be rejected")
+	}
+	if started, err := r.Start(stop, updated); !started || err != nil {
+		t.Fatalf("expected rotation to start, got %v: %v", started, err)
+	}
+	// The CA keeps signing with the old certificate until the new root is distributed.
+	assertStates(caRotationState{signer: "old", roots: 2, trustBundle: 2})
+	if started, _ := r.Start(stop, updated); started || !r.InProgress() {
+		t.Fatal("expected rotation in progress not to restart")
+	}
+
+	close(distributed)
+	assertStates(
+		caRotationState{signer: "old", roots: 2, trustBundle: 2},
+		caRotationState{signer: "new", roots: 2, trustBundle: 2},
+		caRotationState{signer: "new", roots: 1, trustBundle: 1},
+	)
+	retry.UntilSuccessOrFail(t, func() error {
+		if r.InProgress() {
+			return fmt.Errorf("rotation still in progress")
+		}
+		return nil
+	})
+	if _, _, _, roots := bundle.GetAllPem(); !bytes.Equal(roots, updated.roots) {
+		t.Fatalf("expected only the new root to be trusted, got %s", roots)
+	}
+}
+
+func TestCARootRotationTimeout(t *testing.T) {
+	old := genPluggedCA(t, "old")
+	updated := genPluggedCA(t, "new")
+	bundle, err := util.NewVerifiedKeyCertBundleFromPem(old.cert, old.key, old.chain, old.roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &caRootRotation{
+		bundle:      bundle,
+		trustBundle: tb.NewTrustBundle(nil),
+		waitForDistribution: func(ctx context.Context, roots []byte) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		updated: func() error { return nil },
+		overlap: time.Hour,
+		timeout: 100 * time.Millisecond,
+	}
+	if started, err := r.Start(test.NewStop(t), updated); !started || err != nil {
+		t.Fatalf("expected rotation to start, got %v: %v", started, err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if r.InProgress() {
+			return fmt.Errorf("rotation still in progress")
+		}
+		return nil
+	})
+	// The CA keeps signing with the old certificate, and trusts both roots.
+	cert, _, _, roots := bundle.GetAllPem()
+	if !bytes.Equal(cert, old.cert) {
+		t.Fatalf("expected the old certificate to sign, got %s", cert)
+	}
+	if n := len(splitPemCerts(roots)); n != 2 {
+		t.Fatalf("expected 2 roots, got %d", n)
+	}
+}
+
+func TestIstiodReplicas(t *testing.T) {
+	interval := trustBundleCheckInterval
+	trustBundleCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { trustBundleCheckInterval = interval })
+	istiod := func(name, rev, hash string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "istio-system", Labels: map[string]string{
+				"app":               "custom-istiod",
+				"istio.io/rev":      rev,
+				"pod-template-hash": hash,
+			}},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		istiod("istiod-a", "default", "1", v1.PodRunning),
+		// Pods of another rollout of the deployment are coordinated with, the ones of other revisions are not.
+		istiod("istiod-b", "default", "2", v1.PodRunning),
+		istiod("istiod-c", "default", "1", v1.PodPending),
+		istiod("istiod-canary", "canary", "1", v1.PodRunning),
+	)
+	a := &istiodReplicas{client: client, namespace: "istio-system", podName: "istiod-a"}
+	b := &istiodReplicas{client: client, namespace: "istio-system", podName: "istiod-b"}
+	ctx := context.Background()
+
+	if err := a.setDistributed(ctx, "new"); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := a.pending(ctx, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pending, []string{"istiod-b"}) {
+		t.Fatalf("expected istiod-b to be pending, got %v", pending)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := a.waitForDistribution(timeoutCtx, []byte("roots")); err == nil {
+		t.Fatal("expected the distribution to time out while istiod-b has not distributed the roots")
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- a.waitForDistribution(ctx, []byte("roots"))
+	}()
+	if err := b.waitForDistribution(ctx, []byte("roots")); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForTrustBundleAcks(t *testing.T) {
+	interval := trustBundleCheckInterval
+	trustBundleCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { trustBundleCheckInterval = interval })
+	fakeXds := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	s := &Server{XDSServer: fakeXds.Discovery}
+	if err := s.waitForTrustBundleAcks(context.Background()); err != nil {
+		t.Fatalf("expected no proxy to wait for, got %v", err)
+	}
+
+	// A proxy which does not watch the proxy config cannot acknowledge the trust bundle, the rotation waits for it.
+	fakeXds.ConnectADS().WithType(v3.ClusterType).RequestResponseAck(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.waitForTrustBundleAcks(ctx); err == nil {
+		t.Fatal("expected the proxy not watching the proxy config to keep the rotation pending")
+	}
+}
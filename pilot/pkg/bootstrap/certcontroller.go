@@ -152,8 +152,8 @@ func (s *Server) initDNSCerts() error {
 			return fmt.Errorf("unable to determine signing file format %v", err)
 		}
 
-		// check if signing key file exists the cert dir
-		if _, err := os.Stat(fileBundle.SigningKeyFile); err != nil {
+		// check if signing key file exists the cert dir, or is held by a key provider
+		if _, err := os.Stat(fileBundle.SigningKeyFile); err != nil && caExternalSignerSocket.Get() == "" {
 			log.Infof("No plugged-in cert at %v; self-signed cert is used", fileBundle.SigningKeyFile)
 			caBundle = s.CA.GetCAKeyCertBundle().GetRootCertPem()
 			s.addStartFunc(func(stop <-chan struct{}) error {
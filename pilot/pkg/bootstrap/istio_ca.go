@@ -17,6 +17,10 @@ package bootstrap
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -27,6 +31,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -35,10 +40,12 @@ import (
 	securityModel "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/jwt"
+	"istio.io/istio/pkg/kube/configmapwatcher"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/security/pkg/cmd"
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
+	"istio.io/istio/security/pkg/pki/signer"
 	caserver "istio.io/istio/security/pkg/server/ca"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 	"istio.io/pkg/env"
@@ -126,6 +133,44 @@ var (
 	audience = env.Register("AUDIENCE", "",
 		"Expected audience in the tokens. ")
 
+	caCertsRootOverlap = env.Register("CACERTS_ROOT_ROTATION_OVERLAP", 24*time.Hour,
+		"How long the former root of the plugged-in CA stays trusted once the new root signs certificates, when "+
+			"AUTO_RELOAD_PLUGIN_CERTS detects a new root. It should exceed the TTL of workload certificates.")
+
+	caCertsRootRotationTimeout = env.Register("CACERTS_ROOT_ROTATION_TIMEOUT", 30*time.Minute,
+		"How long istiod waits for the proxies connected to all the istiods of its deployment to trust the new root of the "+
+			"plugged-in CA, before aborting the rotation and signing with the former root. Only the proxies with "+
+			"PROXY_CONFIG_XDS_AGENT enabled can acknowledge the new root. Zero disables the timeout.")
+
+	caAuditLog = env.Register("CA_AUDIT_LOG", false,
+		"If enabled, an audit event is logged to the caaudit scope for every certificate issued by the CA server.")
+
+	caInventorySize = env.Register("CA_INVENTORY_SIZE", 10000,
+		"The maximum number of certificates issued by the CA server kept in memory, for the /debug/certz endpoint. "+
+			"Zero disables the inventory.")
+
+	caCSRRateLimitPerIdentity = env.Register("CA_CSR_RATE_LIMIT_PER_IDENTITY", 0.0,
+		"The number of CSRs per second the CA server signs for every identity. A non-positive value disables the limit.")
+
+	caCSRBurstPerIdentity = env.Register("CA_CSR_BURST_PER_IDENTITY", 10,
+		"The number of CSRs the CA server signs at once for every identity, above CA_CSR_RATE_LIMIT_PER_IDENTITY.")
+
+	caCSRRateLimitPerNode = env.Register("CA_CSR_RATE_LIMIT_PER_NODE", 0.0,
+		"The number of CSRs per second the CA server signs for the workloads of every node, when the node is "+
+			"known from the token of the workload. A non-positive value disables the limit.")
+
+	caCSRBurstPerNode = env.Register("CA_CSR_BURST_PER_NODE", 50,
+		"The number of CSRs the CA server signs at once for the workloads of every node, above CA_CSR_RATE_LIMIT_PER_NODE.")
+
+	caCSRRateLimitRequireNode = env.Register("CA_CSR_RATE_LIMIT_REQUIRE_NODE", false,
+		"If enabled with CA_CSR_RATE_LIMIT_PER_NODE, the CA server rejects the CSRs of the workloads whose node is "+
+			"unknown, instead of only applying CA_CSR_RATE_LIMIT_PER_IDENTITY to them.")
+
+	caExternalSignerSocket = env.Register("CA_EXTERNAL_SIGNER_SOCKET", "",
+		"The Unix domain socket of a key provider, such as a KMS plugin, holding the private key of the plugged-in CA. "+
+			"When set, the CA signs with the key provider and the cacerts need no ca-key.pem. Reloading the cacerts "+
+			"is not supported with a key provider.")
+
 	caRSAKeySize = env.Register("CITADEL_SELF_SIGNED_CA_RSA_KEY_SIZE", 2048,
 		"Specify the RSA key size to use for self-signed Istio CA certificates.")
 
@@ -187,11 +232,51 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 		}
 	}
 
+	caServer.AuditSinks = s.caAuditSinks
+	if caCSRRateLimitPerIdentity.Get() > 0 || caCSRRateLimitPerNode.Get() > 0 {
+		caServer.SetRateLimits(caserver.RateLimitOptions{
+			IdentityQPS:   caCSRRateLimitPerIdentity.Get(),
+			IdentityBurst: caCSRBurstPerIdentity.Get(),
+			NodeQPS:       caCSRRateLimitPerNode.Get(),
+			NodeBurst:     caCSRBurstPerNode.Get(),
+			RequireNode:   caCSRRateLimitRequireNode.Get(),
+		})
+	}
+	if s.kubeClient != nil {
+		s.initCAIdentityMapping(caServer, opts.Namespace)
+	}
 	caServer.Register(grpc)
 
 	log.Info("Istiod CA has started")
 }
 
+// initCAIdentityMapping watches the identity mapping of the CA server in the IdentityMappingConfigMap of the
+// namespace, like the mesh config in the "istio" ConfigMap, as the mesh config API has no field for it. The mapping is
+// disabled when the ConfigMap does not exist, and an invalid update is ignored, keeping the former mapping.
+func (s *Server) initCAIdentityMapping(caServer *caserver.Server, namespace string) {
+	c := configmapwatcher.NewController(s.kubeClient, namespace, caserver.IdentityMappingConfigMap, func(cm *v1.ConfigMap) {
+		if cm == nil {
+			caServer.SetIdentityMapping(nil)
+			return
+		}
+		mapping, err := caserver.ParseIdentityMapping([]byte(cm.Data[caserver.IdentityMappingConfigMapKey]))
+		if err != nil {
+			log.Errorf("failed to load the CA identity mapping of the ConfigMap %s/%s, keeping the former one: %v",
+				namespace, caserver.IdentityMappingConfigMap, err)
+			return
+		}
+		caServer.SetIdentityMapping(mapping)
+		log.Infof("CA identity mapping loaded from the ConfigMap %s/%s with %d rules",
+			namespace, caserver.IdentityMappingConfigMap, len(mapping.Rules))
+	})
+	// RunCA is called by a start func, too late to add one, so the watch stops with the server.
+	go c.Run(s.internalStop)
+	// Load the mapping before the CA server serves the requests.
+	if !s.kubeClient.WaitForCacheSync(s.internalStop, c.HasSynced) {
+		log.Error("failed to wait for the CA identity mapping cache sync")
+	}
+}
+
 // detectAuthEnv will use the JWT token that is mounted in istiod to set the default audience
 // and trust domain for Istiod, if not explicitly defined.
 // K8S will use the same kind of tokens for the pods, and the value in istiod's own token is
@@ -291,6 +376,28 @@ func (s *Server) loadCACerts(caOpts *caOptions, dir string) error {
 	return nil
 }
 
+// initCAAudit records the certificates issued by the CA server in an inventory served by the debug endpoints, and
+// in the audit log if enabled.
+func (s *Server) initCAAudit() {
+	if s.CA == nil && s.RA == nil {
+		return
+	}
+	inventory := caserver.NewInventory(caInventorySize.Get())
+	if caInventorySize.Get() > 0 {
+		s.caAuditSinks = append(s.caAuditSinks, inventory)
+	}
+	if caAuditLog.Get() {
+		s.caAuditSinks = append(s.caAuditSinks, caserver.LogAuditSink{})
+	}
+	s.XDSServer.ListIssuedCertificates = inventory.List
+}
+
+// caUsesCallerNode returns true if the CA server uses the node of the callers, to record it with the issued
+// certificates or to rate limit the CSRs per node.
+func (s *Server) caUsesCallerNode() bool {
+	return len(s.caAuditSinks) > 0 || caCSRRateLimitPerNode.Get() > 0
+}
+
 // handleEvent handles the events on cacerts related files.
 // If create/write(modified) event occurs, then it verifies that
 // newly introduced cacerts are intermediate CA which is generated
@@ -317,9 +424,13 @@ func handleEvent(s *Server) {
 		return
 	}
 
-	// Only updating intermediate CA is supported now
-	if !bytes.Equal(currentCABundle, newCABundle) {
-		log.Info("Updating new ROOT-CA not supported")
+	if !bytes.Equal(currentCABundle, newCABundle) || (s.caRootRotation != nil && s.caRootRotation.InProgress()) {
+		// A new root is only trusted by proxies once it is in the trust bundle pushed to them.
+		if !features.MultiRootMesh {
+			log.Info("Updating new ROOT-CA requires ISTIO_MULTIROOT_MESH")
+			return
+		}
+		rotateCARoot(s, fileBundle)
 		return
 	}
 
@@ -343,6 +454,44 @@ func handleEvent(s *Server) {
 	log.Info("Istiod has detected the newly added intermediate CA and updated its key and certs accordingly")
 }
 
+// rotateCARoot rotates the CA to the new plugged-in root, without interrupting the traffic between proxies.
+func rotateCARoot(s *Server, fileBundle ca.SigningCAFileBundle) {
+	files, err := readSigningCAFiles(fileBundle)
+	if err != nil {
+		log.Error("failed reading plug-in CA certs: ", err)
+		return
+	}
+	if s.caRootRotation == nil {
+		s.caRootRotation = &caRootRotation{
+			bundle:              s.CA.GetCAKeyCertBundle(),
+			trustBundle:         s.workloadTrustBundle,
+			waitForDistribution: s.waitForTrustBundleDistribution,
+			updated:             s.updatePluggedinRootCertAndGenKeyCert,
+			overlap:             caCertsRootOverlap.Get(),
+			timeout:             caCertsRootRotationTimeout.Get(),
+		}
+	}
+	started, err := s.caRootRotation.Start(s.internalStop, files)
+	if err != nil {
+		log.Error("Failed to update new Plug-in CA certs: ", err)
+		return
+	}
+	if started {
+		log.Info("Istiod has detected the new root CA and started rotating to it")
+	}
+}
+
+// initIstiodReplicas sets up the coordination of the rotation of the plugged-in CA with the other istiods.
+func (s *Server) initIstiodReplicas(namespace, podName string) {
+	if s.kubeClient == nil {
+		return
+	}
+	if podName == "" {
+		podName, _ = os.Hostname()
+	}
+	s.istiodReplicas = &istiodReplicas{client: s.kubeClient.Kube(), namespace: namespace, podName: podName}
+}
+
 // handleCACertsFileWatch handles the events on cacerts files
 func (s *Server) handleCACertsFileWatch() {
 	var timerC <-chan time.Time
@@ -426,7 +575,26 @@ func (s *Server) createIstioCA(opts *caOptions) (*ca.IstioCA, error) {
 		// In Istiod, it is possible to provide one via "cacerts" secret in both cases, for consistency.
 		fileBundle.RootCertFile = ""
 	}
-	if _, err := os.Stat(fileBundle.SigningKeyFile); err != nil {
+	if socket := caExternalSignerSocket.Get(); socket != "" {
+		log.Infof("Use local CA certificate, signing with the key provider at %s", socket)
+
+		remoteSigner, err := signer.NewRemoteSigner(socket, signer.DefaultTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
+		}
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			go func() {
+				<-stop
+				_ = remoteSigner.Close()
+			}()
+			return nil
+		})
+		caOpts, err = ca.NewExternalSignerIstioCAOptions(fileBundle, remoteSigner,
+			workloadCertTTL.Get(), maxWorkloadCertTTL.Get(), caRSAKeySize.Get())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
+		}
+	} else if _, err := os.Stat(fileBundle.SigningKeyFile); err != nil {
 		// The user-provided certs are missing - create a self-signed cert.
 		if s.kubeClient != nil {
 			log.Info("Use self-signed certificate as the CA certificate")
@@ -466,6 +634,7 @@ func (s *Server) createIstioCA(opts *caOptions) (*ca.IstioCA, error) {
 			s.initCACertsWatcher()
 		}
 	}
+	caOpts.JWTSigner = s.loadJWTSigningKey(opts)
 	istioCA, err := ca.NewIstioCA(caOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create an istiod CA: %v", err)
@@ -480,6 +649,39 @@ func (s *Server) createIstioCA(opts *caOptions) (*ca.IstioCA, error) {
 	return istioCA, nil
 }
 
+// loadJWTSigningKey returns the key signing the JWT-SVIDs, distinct from the CA key so that the X.509 CA key is not
+// used for another purpose. It is read from the cacerts if plugged in, or else from a secret shared by the istiod
+// replicas, created if needed. Without a key, the CA does not sign JWT-SVIDs.
+func (s *Server) loadJWTSigningKey(opts *caOptions) crypto.Signer {
+	keyFile := path.Join(LocalCertDir.Get(), ca.JWTSigningKeyFile)
+	if _, err := os.Stat(keyFile); err == nil {
+		key, err := ca.LoadJWTSigningKey(keyFile)
+		if err != nil {
+			log.Errorf("failed to load the JWT-SVID signing key %s, JWT-SVIDs are disabled: %v", keyFile, err)
+			return nil
+		}
+		log.Infof("Use the JWT-SVID signing key %s", keyFile)
+		return key
+	}
+	if s.kubeClient == nil {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			log.Errorf("failed to generate a JWT-SVID signing key, JWT-SVIDs are disabled: %v", err)
+			return nil
+		}
+		log.Warnf("Use an in-memory JWT-SVID signing key for testing, no K8S access and no key file %s", keyFile)
+		return key
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	key, err := ca.LoadOrCreateJWTSigningKey(ctx, s.kubeClient.Kube().CoreV1(), opts.Namespace)
+	if err != nil {
+		log.Errorf("failed to load the JWT-SVID signing key secret %s, JWT-SVIDs are disabled: %v", ca.JWTSigningKeySecret, err)
+		return nil
+	}
+	return key
+}
+
 // createIstioRA initializes the Istio RA signing functionality.
 // the caOptions defines the external provider
 // ca cert can come from three sources, order matters:
@@ -42,6 +42,11 @@ type RegistryOptions struct {
 	ClusterRegistriesNamespace string
 	KubeConfig                 string
 
+	// CatalogAddress is the HTTP address, or local file path, of the catalog read by the Catalog registry
+	CatalogAddress string
+	// CatalogRefreshInterval controls how often the Catalog registry polls for changes
+	CatalogRefreshInterval time.Duration
+
 	// DistributionTracking control
 	DistributionCacheRetention time.Duration
 
@@ -63,6 +68,16 @@ type PilotArgs struct {
 	KeepaliveOptions   *keepalive.Options
 	ShutdownDuration   time.Duration
 	JwtRule            string
+	SnapshotOptions    SnapshotOptions
+}
+
+// SnapshotOptions configures the on-disk snapshot of config and service registry state, used to serve
+// proxies on restart while caches sync.
+type SnapshotOptions struct {
+	// Path of the snapshot file. If empty, snapshots are disabled.
+	Path string
+	// Interval between snapshot writes.
+	Interval time.Duration
 }
 
 // DiscoveryServerOptions contains options for create a new discovery server instance.
@@ -146,6 +161,7 @@ func (p *PilotArgs) applyDefaults() {
 	p.RegistryOptions.DistributionTrackingEnabled = features.EnableDistributionTracking
 	p.RegistryOptions.DistributionCacheRetention = features.DistributionHistoryRetention
 	p.RegistryOptions.ClusterRegistriesNamespace = p.Namespace
+	p.SnapshotOptions.Interval = time.Minute
 }
 
 func (p *PilotArgs) Complete() error {
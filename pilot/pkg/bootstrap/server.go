@@ -48,6 +48,7 @@ import (
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	"istio.io/istio/pilot/pkg/snapshot"
 	"istio.io/istio/pilot/pkg/status"
 	"istio.io/istio/pilot/pkg/status/distribution"
 	tb "istio.io/istio/pilot/pkg/trustbundle"
@@ -71,6 +72,7 @@ import (
 	"istio.io/istio/security/pkg/k8s/chiron"
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
+	caserver "istio.io/istio/security/pkg/server/ca"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
 	"istio.io/istio/security/pkg/server/ca/authenticate/kubeauth"
 	"istio.io/pkg/ctrlz"
@@ -158,6 +160,12 @@ type Server struct {
 	istiodCert              *tls.Certificate
 	istiodCertBundleWatcher *keycertbundle.Watcher
 	server                  server.Instance
+	// caAuditSinks record the certificates issued by the CA server.
+	caAuditSinks []caserver.AuditSink
+	// caRootRotation rotates the plugged-in CA to a new root, created on the first root change.
+	caRootRotation *caRootRotation
+	// istiodReplicas coordinates the rotation of the plugged-in CA with the other istiods, nil without Kubernetes.
+	istiodReplicas *istiodReplicas
 
 	readinessProbes map[string]readinessProbe
 
@@ -174,6 +182,9 @@ type Server struct {
 	statusManager  *status.Manager
 	// RWConfigStore is the configstore which allows updates, particularly for status.
 	RWConfigStore model.ConfigStoreController
+
+	// snapshot is the state persisted by a previous run, served on Start while caches sync.
+	snapshot *snapshot.Snapshot
 }
 
 // NewServer creates a new Server instance based on the provided arguments.
@@ -222,6 +233,9 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	// Initialize workload Trust Bundle before XDS Server
 	e.TrustBundle = s.workloadTrustBundle
 	s.XDSServer = xds.NewDiscoveryServer(e, args.PodName, args.RegistryOptions.KubeOptions.ClusterAliases)
+	if err := s.initXDSRecorder(); err != nil {
+		return nil, fmt.Errorf("error initializing xds recorder: %v", err)
+	}
 
 	prometheus.EnableHandlingTimeHistogram()
 
@@ -271,12 +285,17 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	if err := s.maybeCreateCA(caOpts); err != nil {
 		return nil, err
 	}
+	s.initCAAudit()
 
 	if err := s.initControllers(args); err != nil {
 		return nil, err
 	}
 
 	s.XDSServer.InitGenerators(e, args.Namespace, s.internalDebugMux)
+	if err := s.initCARevocation(args.Namespace, args.PodName); err != nil {
+		return nil, err
+	}
+	s.initIstiodReplicas(args.Namespace, args.PodName)
 
 	// Initialize workloadTrustBundle after CA has been initialized
 	if err := s.initWorkloadTrustBundle(args); err != nil {
@@ -311,11 +330,14 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		if err := s.initConfigValidation(args); err != nil {
 			return nil, fmt.Errorf("error initializing config validator: %v", err)
 		}
+		s.initSpiffeBundleEndpoint()
 	}
 
 	// This should be called only after controllers are initialized.
 	s.initRegistryEventHandlers()
 
+	s.initSnapshot(args)
+
 	s.initDiscoveryService()
 
 	s.initSDSServer()
@@ -339,8 +361,13 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	// The k8s JWT authenticator requires the multicluster registry to be initialized,
 	// so we build it later.
 	if s.kubeClient != nil {
-		authenticators = append(authenticators,
-			kubeauth.NewKubeJWTAuthenticator(s.environment.Watcher, s.kubeClient.Kube(), s.clusterID, s.multiclusterController.GetRemoteKubeClient, features.JwtPolicy))
+		kubeAuthn := kubeauth.NewKubeJWTAuthenticator(s.environment.Watcher, s.kubeClient.Kube(), s.clusterID,
+			s.multiclusterController.GetRemoteKubeClient, features.JwtPolicy)
+		if s.caUsesCallerNode() {
+			// The pod informer is shared with the service registry.
+			kubeAuthn.LookupNodes(s.kubeClient.KubeInformer().Core().V1().Pods().Lister())
+		}
+		authenticators = append(authenticators, kubeAuthn)
 	}
 	if len(features.TrustedGatewayCIDR) > 0 {
 		authenticators = append(authenticators, &authenticate.XfccAuthenticator{})
@@ -423,9 +450,20 @@ func (s *Server) Start(stop <-chan struct{}) error {
 	if err := s.server.Start(stop); err != nil {
 		return err
 	}
+	var restored *snapshot.Restored
+	if s.snapshot != nil {
+		var err error
+		if restored, err = s.serveSnapshot(); err != nil {
+			log.Warnf("failed to serve snapshot, waiting for caches to sync: %v", err)
+		}
+		s.snapshot = nil
+	}
 	if !s.waitForCacheSync(stop) {
 		return fmt.Errorf("failed to sync cache")
 	}
+	if restored != nil {
+		restored.Prune(s.environment.EndpointIndex, s.XDSServer)
+	}
 	// Inform Discovery Server so that it can start accepting connections.
 	s.XDSServer.CachesSynced()
 
@@ -1249,6 +1287,10 @@ func (s *Server) initWorkloadTrustBundle(args *PilotArgs) error {
 		return nil
 	})
 
+	if err := s.initFederatedTrustDomains(); err != nil {
+		return err
+	}
+
 	// MeshConfig: Add initial roots
 	err = s.workloadTrustBundle.AddMeshConfigUpdate(s.environment.Mesh())
 	if err != nil {
@@ -16,8 +16,10 @@ package bootstrap
 
 import (
 	"fmt"
+	"time"
 
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	"istio.io/istio/pilot/pkg/serviceregistry/catalog"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
@@ -52,6 +54,10 @@ func (s *Server) initServiceControllers(args *PilotArgs) error {
 			if err := s.initKubeRegistry(args); err != nil {
 				return err
 			}
+		case provider.Catalog:
+			if err := s.initCatalogRegistry(args); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("service registry %s is not supported", r)
 		}
@@ -90,3 +96,20 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 
 	return
 }
+
+// catalogRequestTimeout bounds each request made to the catalog.
+const catalogRequestTimeout = 10 * time.Second
+
+// initCatalogRegistry creates the service controller for the configured service catalog
+func (s *Server) initCatalogRegistry(args *PilotArgs) error {
+	client, err := catalog.NewClient(args.RegistryOptions.CatalogAddress, catalogRequestTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to create catalog client: %v", err)
+	}
+	s.ServiceController().AddRegistry(catalog.NewController(client, catalog.Options{
+		ClusterID:       s.clusterID,
+		RefreshInterval: args.RegistryOptions.CatalogRefreshInterval,
+		XDSUpdater:      s.XDSServer,
+	}))
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/snapshot"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/pkg/log"
+)
+
+// snapshotSchemas are the config kinds persisted in snapshots. Gateway API resources are not included,
+// as they are translated by the Gateway API controller, which is only available once caches sync.
+var snapshotSchemas = collections.Pilot
+
+// initSnapshot loads the snapshot of a previous run, to be served on Start while caches sync, and
+// starts persisting snapshots of the current state.
+func (s *Server) initSnapshot(args *PilotArgs) {
+	opts := args.SnapshotOptions
+	if opts.Path == "" {
+		return
+	}
+	snap, err := snapshot.Read(opts.Path)
+	switch {
+	case os.IsNotExist(err):
+		log.Infof("no snapshot found at %s, starting cold", opts.Path)
+	case err != nil:
+		log.Warnf("ignoring snapshot at %s: %v", opts.Path, err)
+	default:
+		log.Infof("loaded snapshot taken at %v from %s", snap.Time, opts.Path)
+		s.snapshot = snap
+	}
+
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go s.writeSnapshots(opts, stop)
+		return nil
+	})
+}
+
+// serveSnapshot serves the loaded snapshot until caches sync. The returned Restored should be pruned once
+// caches are synced, to remove endpoints that no longer exist.
+func (s *Server) serveSnapshot() (*snapshot.Restored, error) {
+	store, err := s.snapshot.ConfigStore(snapshotSchemas, s.environment.DomainSuffix)
+	if err != nil {
+		return nil, err
+	}
+	// The snapshot is served from its own environment, so that pushes are not computed from partially
+	// synced registries. Endpoints are seeded into the shared index, where registries replace them as they sync.
+	env := model.NewEnvironment()
+	env.ConfigStore = store
+	env.ServiceDiscovery = s.snapshot.ServiceDiscovery()
+	env.Watcher = s.environment.Watcher
+	env.NetworksWatcher = s.environment.NetworksWatcher
+	env.NetworkManager = s.environment.NetworkManager
+	env.DomainSuffix = s.environment.DomainSuffix
+	env.TrustBundle = s.environment.TrustBundle
+	env.EndpointIndex = s.environment.EndpointIndex
+	env.Init()
+
+	restored := s.snapshot.RestoreEndpoints(s.XDSServer)
+	push := model.NewPushContext()
+	push.PushVersion = "snapshot/" + s.snapshot.Time.Format(time.RFC3339)
+	push.JwtKeyResolver = s.XDSServer.JwtKeyResolver
+	if err := push.InitContext(env, nil, nil); err != nil {
+		restored.Prune(s.environment.EndpointIndex, s.XDSServer)
+		return nil, fmt.Errorf("failed to init push context from snapshot: %v", err)
+	}
+	s.XDSServer.ServeSnapshot(push)
+	return restored, nil
+}
+
+// writeSnapshots periodically persists the current state, and once more on shutdown. Nothing is written
+// until caches have synced, to avoid overwriting a good snapshot with partial state.
+func (s *Server) writeSnapshots(opts SnapshotOptions, stop <-chan struct{}) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			s.writeSnapshot(opts.Path)
+			return
+		case <-ticker.C:
+			s.writeSnapshot(opts.Path)
+		}
+	}
+}
+
+func (s *Server) writeSnapshot(path string) {
+	if !s.XDSServer.IsServerReady() || s.XDSServer.IsServingSnapshot() || !s.cachesSynced() {
+		return
+	}
+	t0 := time.Now()
+	snap, err := snapshot.Capture(s.configController, snapshotSchemas, s.environment.ServiceDiscovery, s.environment.EndpointIndex)
+	if err != nil {
+		log.Warnf("failed to capture snapshot: %v", err)
+		return
+	}
+	if err := snapshot.Write(path, snap); err != nil {
+		log.Warnf("failed to write snapshot to %s: %v", path, err)
+		return
+	}
+	log.Debugf("wrote snapshot with %d configs and %d services to %s in %v",
+		len(snap.Configs), len(snap.Services), path, time.Since(t0))
+}
+
+// initXDSRecorder records pushes and responses to PILOT_XDS_RECORDING_PATH, to be replayed against other
+// versions of istiod.
+func (s *Server) initXDSRecorder() error {
+	if features.XDSRecordingPath == "" {
+		return nil
+	}
+	rec, err := xds.NewRecorder(features.XDSRecordingPath)
+	if err != nil {
+		return err
+	}
+	log.Infof("recording xDS pushes to %s", features.XDSRecordingPath)
+	s.XDSServer.Recorder = rec
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go func() {
+			<-stop
+			_ = rec.Close()
+		}()
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"istio.io/istio/pilot/pkg/features"
+	tb "istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/pkg/log"
+)
+
+const (
+	// spiffeBundlePath is the path of the SPIFFE bundle endpoint on the HTTPS webhook server.
+	spiffeBundlePath = "/spiffe/bundle"
+	// spiffeBundleRefreshHint is the refresh hint of the served SPIFFE bundle.
+	spiffeBundleRefreshHint = 5 * time.Minute
+)
+
+// initSpiffeBundleEndpoint serves the SPIFFE bundle of the trust domain of the mesh, holding the roots of the CA and
+// the keys verifying its JWT-SVIDs.
+func (s *Server) initSpiffeBundleEndpoint() {
+	if !features.EnableSpiffeBundleEndpoint || s.CA == nil {
+		return
+	}
+	log.Infof("serving the SPIFFE bundle of the trust domain at %s", spiffeBundlePath)
+	s.httpsMux.HandleFunc(spiffeBundlePath, serveSpiffeBundle(s.CA.GetCAKeyCertBundle().GetRootCertPem, s.CA.JWTAuthorities))
+}
+
+func serveSpiffeBundle(rootCertPem func() []byte, jwtAuthorities func() ([]jose.JSONWebKey, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var roots []*x509.Certificate
+		for rest := rootCertPem(); ; {
+			var block *pem.Block
+			if block, rest = pem.Decode(rest); block == nil {
+				break
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				log.Errorf("failed to parse a root of the CA for the SPIFFE bundle: %v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			roots = append(roots, cert)
+		}
+		jwtKeys, err := jwtAuthorities()
+		if err != nil {
+			log.Errorf("failed to get the JWT authorities of the CA for the SPIFFE bundle: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		b, err := spiffe.MarshalBundle(roots, jwtKeys, spiffeBundleRefreshHint)
+		if err != nil {
+			log.Errorf("failed to marshal the SPIFFE bundle: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	}
+}
+
+// initFederatedTrustDomains adds the roots of the federated trust domains to the workload trust bundle.
+func (s *Server) initFederatedTrustDomains() error {
+	if features.SpiffeFederatedBundleEndpoints == "" {
+		return nil
+	}
+	endpoints, err := spiffe.ParseBundleEndpoints(features.SpiffeFederatedBundleEndpoints)
+	if err != nil {
+		return fmt.Errorf("invalid SPIFFE_FEDERATED_BUNDLE_ENDPOINTS: %v", err)
+	}
+	// The federated roots are trusted for any SPIFFE ID, so the trust domains of the mesh cannot be federated.
+	mesh := s.environment.Mesh()
+	for _, trustDomain := range append([]string{mesh.GetTrustDomain()}, mesh.GetTrustDomainAliases()...) {
+		if _, ok := endpoints[trustDomain]; ok {
+			return fmt.Errorf("invalid SPIFFE_FEDERATED_BUNDLE_ENDPOINTS: %s is a trust domain of the mesh", trustDomain)
+		}
+	}
+	s.workloadTrustBundle.SetFederatedTrustDomains(endpoints)
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go s.workloadTrustBundle.ProcessFederatedTrustDomains(stop, tb.FederatedDefaultPollPeriod)
+		return nil
+	})
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/server"
+	tb "istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test"
+)
+
+func TestServeSpiffeBundle(t *testing.T) {
+	old, updated := genPluggedCA(t, "old"), genPluggedCA(t, "new")
+	roots := mergeRootCerts(old.roots, updated.roots)
+	jwtKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(serveSpiffeBundle(func() []byte {
+		return roots
+	}, func() ([]jose.JSONWebKey, error) {
+		return []jose.JSONWebKey{{Key: &jwtKey.PublicKey, KeyID: "jwt", Algorithm: string(jose.ES256)}}, nil
+	}))
+	defer server.Close()
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(server.Certificate())
+
+	bundle, err := spiffe.FetchBundle("cluster.local", server.Listener.Addr().String()+spiffeBundlePath, caCertPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.X509Authorities) != 2 {
+		t.Fatalf("expected the 2 roots of the CA, got %d", len(bundle.X509Authorities))
+	}
+	if len(bundle.JWTAuthorities) != 1 || bundle.JWTAuthorities[0].KeyID != "jwt" {
+		t.Fatalf("expected the JWT authority of the CA, got %v", bundle.JWTAuthorities)
+	}
+	if bundle.RefreshHint != spiffeBundleRefreshHint {
+		t.Fatalf("unexpected refresh hint %v", bundle.RefreshHint)
+	}
+}
+
+func TestInitFederatedTrustDomains(t *testing.T) {
+	s := &Server{
+		environment: &model.Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{
+			TrustDomain:        "cluster.local",
+			TrustDomainAliases: []string{"old.local"},
+		})},
+		workloadTrustBundle: tb.NewTrustBundle(nil),
+		server:              server.New(),
+	}
+	for endpoints, valid := range map[string]bool{
+		"foo.com|https://foo.com/bundle":                              true,
+		"foo.com|https://foo.com/bundle||cluster.local|https://local": false,
+		"old.local|https://old.local/bundle":                          false,
+	} {
+		test.SetForTest(t, &features.SpiffeFederatedBundleEndpoints, endpoints)
+		if err := s.initFederatedTrustDomains(); (err == nil) != valid {
+			t.Errorf("%s: expected valid %v, got %v", endpoints, valid, err)
+		}
+	}
+}
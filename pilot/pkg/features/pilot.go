@@ -403,6 +403,12 @@ var (
 	EnableCAServer = env.Register("ENABLE_CA_SERVER", true,
 		"If this is set to false, will not create CA server in istiod.").Get()
 
+	EnableCACRL = env.Register("ENABLE_CA_CRL", false,
+		"If enabled, the Istiod CA distributes a CRL of the certificates revoked in the istio-ca-revocations secret "+
+			"to proxies, which reject revoked peer certificates. All the workload certificates of the mesh must be issued "+
+			"by this CA, and the CA certificate must have the cRLSign key usage, or istiod fails to start. Proxies reject all "+
+			"peer certificates if they do not receive a new CRL for 24 hours.").Get()
+
 	EnableDebugOnHTTP = env.Register("ENABLE_DEBUG_ON_HTTP", true,
 		"If this is set to false, the debug interface will not be enabled, recommended for production").Get()
 
@@ -460,6 +466,18 @@ var (
 			"Use || between <trustdomain, endpoint> tuples. Use | as delimiter between trust domain and endpoint in "+
 			"each tuple. For example: foo|https://url/for/foo||bar|https://url/for/bar").Get()
 
+	SpiffeFederatedBundleEndpoints = env.Register("SPIFFE_FEDERATED_BUNDLE_ENDPOINTS", "",
+		"The SPIFFE bundle endpoints of the trust domains federated with the mesh, in the format of "+
+			"SPIFFE_BUNDLE_ENDPOINTS. Istiod periodically fetches the bundle of each trust domain, at its refresh hint, "+
+			"and adds its roots to the trust bundle pushed to proxies, so that they accept mTLS with the workloads of "+
+			"these trust domains. The endpoints are validated against the system roots, and the bundles whose endpoint "+
+			"or roots have the SPIFFE ID of another trust domain are rejected. The trust domain of the mesh and its "+
+			"aliases cannot be federated. Requires ISTIO_MULTIROOT_MESH.").Get()
+
+	EnableSpiffeBundleEndpoint = env.Register("PILOT_ENABLE_SPIFFE_BUNDLE_ENDPOINT", false,
+		"If enabled, istiod serves the SPIFFE bundle of its trust domain, holding the roots of its CA, at the "+
+			"/spiffe/bundle path of its HTTPS webhook server, for the meshes federated with it.").Get()
+
 	EnableXDSCaching = env.Register("PILOT_ENABLE_XDS_CACHE", true,
 		"If true, Pilot will cache XDS responses.").Get()
 
@@ -584,6 +602,15 @@ var (
 			"These checks are extremely expensive, so this should be used only for testing, not production.",
 	).Get()
 
+	// XDSRecordingPath enables recording of pushes and the resulting XDS responses, to be replayed for regression testing.
+	XDSRecordingPath = env.Register(
+		"PILOT_XDS_RECORDING_PATH",
+		"",
+		"If set, the config changes pushed to proxies, and the XDS responses sent as a result, are recorded to this file. "+
+			"The recording can be replayed against another version of istiod to diff the generated config. "+
+			"Recording is expensive, and stores the full mesh config, so this should be used only for testing.",
+	).Get()
+
 	DeltaXds = env.Register("ISTIO_DELTA_XDS", false,
 		"If enabled, pilot will only send the delta configs as opposed to the state of the world on a "+
 			"Resource Request. This feature uses the delta xds api, but does not currently send the actual deltas.").Get()
@@ -627,7 +654,8 @@ var (
 		"AUTO_RELOAD_PLUGIN_CERTS",
 		false,
 		"If enabled, if user introduces new intermediate plug-in CA, user need not to restart istiod to pick up certs."+
-			"Istiod picks newly added intermediate plug-in CA certs and updates it. Plug-in new Root-CA not supported.").Get()
+			"Istiod picks newly added intermediate plug-in CA certs and updates it. A new Root-CA is only picked up "+
+			"with ISTIO_MULTIROOT_MESH, once proxies have received it in their trust bundle.").Get()
 
 	RewriteTCPProbes = env.Register(
 		"REWRITE_TCP_PROBES",
@@ -17,6 +17,7 @@ package model
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
@@ -51,6 +52,16 @@ func (sk ShardKey) MarshalText() (text []byte, err error) {
 	return []byte(sk.String()), nil
 }
 
+// UnmarshalText implements the TextUnmarshaler interface, parsing the format produced by MarshalText
+func (sk *ShardKey) UnmarshalText(text []byte) error {
+	p, c, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("invalid shard key %q", string(text))
+	}
+	sk.Provider, sk.Cluster = provider.ID(p), cluster.ID(c)
+	return nil
+}
+
 // EndpointShards holds the set of endpoint shards of a service. Registries update
 // individual shards incrementally. The shards are aggregated and split into
 // clusters when a push for the specific cluster is needed.
@@ -405,6 +405,8 @@ const (
 	NamespaceUpdate TriggerReason = "namespace"
 	// ClusterUpdate describes a push triggered by a Cluster change
 	ClusterUpdate TriggerReason = "cluster"
+	// SnapshotSynced describes a push triggered by caches syncing, replacing a stale snapshot served on startup
+	SnapshotSynced TriggerReason = "snapshot"
 )
 
 // Merge two update requests together
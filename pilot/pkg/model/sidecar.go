@@ -19,6 +19,8 @@ import (
 	"sort"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/types"
+
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config"
@@ -597,6 +599,42 @@ func (sc *SidecarScope) Services() []*Service {
 	return sc.services
 }
 
+// ServiceForHostname returns the service with the hostname visible to a sidecar, if any.
+func (sc *SidecarScope) ServiceForHostname(hostname host.Name) *Service {
+	if sc == nil {
+		return nil
+	}
+	return sc.servicesByHostname[hostname]
+}
+
+// DestinationRuleHosts returns the hostnames of the services visible to a sidecar that the
+// destination rule applies to, possibly merged with other destination rules.
+func (sc *SidecarScope) DestinationRuleHosts(dr ConfigKey) []host.Name {
+	if sc == nil {
+		return nil
+	}
+	name := types.NamespacedName{Namespace: dr.Namespace, Name: dr.Name}
+	var out []host.Name
+	for hostname, rules := range sc.destinationRules {
+		if consolidatedFrom(rules, name) {
+			out = append(out, hostname)
+		}
+	}
+	return out
+}
+
+// consolidatedFrom returns whether any of the rules was merged from the named destination rule.
+func consolidatedFrom(rules []*ConsolidatedDestRule, name types.NamespacedName) bool {
+	for _, rule := range rules {
+		for _, from := range rule.from {
+			if from == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Return filtered services through the hosts field in the egress portion of the Sidecar config.
 // Note that the returned service could be trimmed.
 func (ilw *IstioEgressListenerWrapper) selectServices(services []*Service, configNamespace string, hosts map[string][]host.Name) []*Service {
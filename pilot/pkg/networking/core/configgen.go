@@ -32,6 +32,11 @@ type ConfigGenerator interface {
 	// once and shared across multiple invocations of this function.
 	BuildListeners(node *model.Proxy, push *model.PushContext) []*listener.Listener
 
+	// BuildDeltaListeners returns both a list of listeners that need to be pushed for a given proxy and a list of listeners
+	// that have been deleted and should be removed from a given proxy. This is Delta LDS output.
+	BuildDeltaListeners(proxy *model.Proxy, updates *model.PushRequest,
+		watched *model.WatchedResource) ([]*listener.Listener, []string, bool)
+
 	// BuildClusters returns the list of clusters for the given proxy. This is the CDS output
 	BuildClusters(node *model.Proxy, req *model.PushRequest) ([]*discovery.Resource, model.XdsLogDetails)
 
@@ -61,7 +61,7 @@ import (
 
 // deltaConfigTypes are used to detect changes and trigger delta calculations. When config updates has ONLY entries
 // in this map, then delta calculation is triggered.
-var deltaConfigTypes = sets.New(kind.ServiceEntry.String())
+var deltaConfigTypes = sets.New(kind.ServiceEntry.String(), kind.DestinationRule.String())
 
 const TransportSocketInternalUpstream = "envoy.transport_sockets.internal_upstream"
 
@@ -96,8 +96,8 @@ func (configgen *ConfigGeneratorImpl) BuildClusters(proxy *model.Proxy, req *mod
 	return configgen.buildClusters(proxy, req, services)
 }
 
-// BuildDeltaClusters generates the deltas (add and delete) for a given proxy. Currently, only service changes are reflected with deltas.
-// Otherwise, we fall back onto generating everything.
+// BuildDeltaClusters generates the deltas (add and delete) for a given proxy. Currently, only service and destination rule
+// changes are reflected with deltas. Otherwise, we fall back onto generating everything.
 func (configgen *ConfigGeneratorImpl) BuildDeltaClusters(proxy *model.Proxy, updates *model.PushRequest,
 	watched *model.WatchedResource,
 ) ([]*discovery.Resource, []string, model.XdsLogDetails, bool) {
@@ -111,51 +111,65 @@ func (configgen *ConfigGeneratorImpl) BuildDeltaClusters(proxy *model.Proxy, upd
 	var services []*model.Service
 	// holds clusters per service, keyed by hostname.
 	serviceClusters := make(map[string]sets.String)
-	// holds service ports, keyed by hostname.
-	// inner map holds port and its cluster name.
-	servicePorts := make(map[string]map[int]string)
 
 	for _, cluster := range watched.ResourceNames {
 		// WatchedResources.ResourceNames will contain the names of the clusters it is subscribed to. We can
-		// check with the name of our service (cluster names are in the format outbound|<port>||<hostname>).
-		_, _, svcHost, port := model.ParseSubsetKey(cluster)
+		// check with the name of our service (cluster names are in the format outbound|<port>|<subset>|<hostname>).
+		_, _, svcHost, _ := model.ParseSubsetKey(cluster)
 		if serviceClusters[string(svcHost)] == nil {
 			serviceClusters[string(svcHost)] = sets.New[string]()
 		}
 		serviceClusters[string(svcHost)].Insert(cluster)
-		if servicePorts[string(svcHost)] == nil {
-			servicePorts[string(svcHost)] = make(map[int]string)
-		}
-		servicePorts[string(svcHost)][port] = cluster
 	}
 
 	// In delta, we only care about the services that have changed.
-	for key := range updates.ConfigsUpdated {
+	for hostname := range deltaClusterHosts(proxy, updates) {
 		// get the service that has changed.
-		service := updates.Push.ServiceForHostname(proxy, host.Name(key.Name))
+		service := updates.Push.ServiceForHostname(proxy, hostname)
 		// if this service removed, we can conclude that it is a removed cluster.
 		if service == nil {
-			for cluster := range serviceClusters[key.Name] {
+			for cluster := range serviceClusters[string(hostname)] {
 				deletedClusters = append(deletedClusters, cluster)
 			}
 		} else {
 			services = append(services, service)
-			// If servicePorts has this service, that means it is old service.
-			if servicePorts[service.Hostname.String()] != nil {
-				oldPorts := servicePorts[service.Hostname.String()]
-				for port, cluster := range oldPorts {
-					// if this service port is removed, we can conclude that it is a removed cluster.
-					if _, exists := service.Ports.GetByPort(port); !exists {
-						deletedClusters = append(deletedClusters, cluster)
-					}
-				}
-			}
 		}
 	}
 	clusters, log := configgen.buildClusters(proxy, updates, services)
+
+	// Any cluster of an updated service that was not generated again, such as for a removed port or
+	// destination rule subset, is a removed cluster.
+	built := sets.New[string]()
+	for _, c := range clusters {
+		built.Insert(c.Name)
+	}
+	for _, service := range services {
+		for cluster := range serviceClusters[service.Hostname.String()] {
+			if !built.Contains(cluster) {
+				deletedClusters = append(deletedClusters, cluster)
+			}
+		}
+	}
 	return clusters, deletedClusters, log, true
 }
 
+// deltaClusterHosts returns the hostnames whose clusters may be affected by the updated configs. Destination rules
+// are mapped to the services they applied to, both before and after the update, so that removed subsets and rules
+// are reflected.
+func deltaClusterHosts(proxy *model.Proxy, updates *model.PushRequest) sets.Set[host.Name] {
+	hosts := sets.New[host.Name]()
+	for key := range updates.ConfigsUpdated {
+		switch key.Kind {
+		case kind.ServiceEntry:
+			hosts.Insert(host.Name(key.Name))
+		case kind.DestinationRule:
+			hosts.InsertAll(proxy.SidecarScope.DestinationRuleHosts(key)...)
+			hosts.InsertAll(proxy.PrevSidecarScope.DestinationRuleHosts(key)...)
+		}
+	}
+	return hosts
+}
+
 // buildClusters builds clusters for the proxy with the services passed.
 func (configgen *ConfigGeneratorImpl) buildClusters(proxy *model.Proxy, req *model.PushRequest,
 	services []*model.Service,
@@ -2660,8 +2660,6 @@ func TestVerifyCertAtClient(t *testing.T) {
 }
 
 func TestBuildDeltaClusters(t *testing.T) {
-	g := NewWithT(t)
-
 	testService1 := &model.Service{
 		Hostname: host.Name("test.com"),
 		Ports: []*model.Port{
@@ -2694,10 +2692,27 @@ func TestBuildDeltaClusters(t *testing.T) {
 		},
 	}
 
-	// TODO: Add more test cases.
+	destinationRule := func(subsets ...string) config.Config {
+		dr := &networking.DestinationRule{Host: "test.com"}
+		for _, subset := range subsets {
+			dr.Subsets = append(dr.Subsets, &networking.Subset{Name: subset, Labels: map[string]string{"version": subset}})
+		}
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.DestinationRule,
+				Name:             "test",
+				Namespace:        TestServiceNamespace,
+			},
+			Spec: dr,
+		}
+	}
+	drKey := model.ConfigKey{Kind: kind.DestinationRule, Name: "test", Namespace: TestServiceNamespace}
+
 	testCases := []struct {
 		name                 string
 		services             []*model.Service
+		configs              []config.Config
+		prevConfigs          []config.Config
 		configUpdated        sets.Set[model.ConfigKey]
 		watchedResourceNames []string
 		usedDelta            bool
@@ -2734,7 +2749,7 @@ func TestBuildDeltaClusters(t *testing.T) {
 		{
 			name:                 "config update that is not delta aware",
 			services:             []*model.Service{testService1, testService2},
-			configUpdated:        sets.New(model.ConfigKey{Kind: kind.DestinationRule, Name: "test.com", Namespace: TestServiceNamespace}),
+			configUpdated:        sets.New(model.ConfigKey{Kind: kind.VirtualService, Name: "test.com", Namespace: TestServiceNamespace}),
 			watchedResourceNames: []string{"outbound|7070||test.com"},
 			usedDelta:            false,
 			removedClusters:      nil,
@@ -2743,14 +2758,71 @@ func TestBuildDeltaClusters(t *testing.T) {
 				"outbound|8080||test.com", "outbound|8080||testnew.com",
 			},
 		},
+		{
+			name:                 "destination rule subset is added",
+			services:             []*model.Service{testService1, testService2},
+			configs:              []config.Config{destinationRule("v1", "v2")},
+			configUpdated:        sets.New(drKey),
+			watchedResourceNames: []string{"outbound|8080||test.com", "outbound|8080|v1|test.com", "outbound|8080||testnew.com"},
+			usedDelta:            true,
+			removedClusters:      nil,
+			expectedClusters: []string{
+				"BlackHoleCluster", "InboundPassthroughClusterIpv4", "PassthroughCluster",
+				"outbound|8080|v1|test.com", "outbound|8080|v2|test.com", "outbound|8080||test.com",
+			},
+		},
+		{
+			name:                 "destination rule subset is removed",
+			services:             []*model.Service{testService1, testService2},
+			configs:              []config.Config{destinationRule("v1")},
+			configUpdated:        sets.New(drKey),
+			watchedResourceNames: []string{"outbound|8080||test.com", "outbound|8080|v1|test.com", "outbound|8080|v2|test.com"},
+			usedDelta:            true,
+			removedClusters:      []string{"outbound|8080|v2|test.com"},
+			expectedClusters: []string{
+				"BlackHoleCluster", "InboundPassthroughClusterIpv4", "PassthroughCluster",
+				"outbound|8080|v1|test.com", "outbound|8080||test.com",
+			},
+		},
+		{
+			name:                 "destination rule is removed",
+			services:             []*model.Service{testService1, testService2},
+			prevConfigs:          []config.Config{destinationRule("v1")},
+			configUpdated:        sets.New(drKey),
+			watchedResourceNames: []string{"outbound|8080||test.com", "outbound|8080|v1|test.com", "outbound|8080||testnew.com"},
+			usedDelta:            true,
+			removedClusters:      []string{"outbound|8080|v1|test.com"},
+			expectedClusters:     []string{"BlackHoleCluster", "InboundPassthroughClusterIpv4", "PassthroughCluster", "outbound|8080||test.com"},
+		},
+		{
+			name:                 "destination rule for another service",
+			services:             []*model.Service{testService2},
+			prevConfigs:          []config.Config{destinationRule("v1")},
+			configs:              []config.Config{destinationRule("v1")},
+			configUpdated:        sets.New(drKey),
+			watchedResourceNames: []string{"outbound|8080||testnew.com"},
+			usedDelta:            true,
+			removedClusters:      nil,
+			expectedClusters:     []string{"BlackHoleCluster", "InboundPassthroughClusterIpv4", "PassthroughCluster"},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
 			cg := NewConfigGenTest(t, TestOptions{
 				Services: tc.services,
+				Configs:  tc.configs,
 			})
-			clusters, removed, delta := cg.DeltaClusters(cg.SetupProxy(nil), tc.configUpdated,
+			proxy := cg.SetupProxy(nil)
+			if tc.prevConfigs != nil {
+				prev := NewConfigGenTest(t, TestOptions{
+					Services: tc.services,
+					Configs:  tc.prevConfigs,
+				})
+				proxy.PrevSidecarScope = prev.SetupProxy(nil).SidecarScope
+			}
+			clusters, removed, delta := cg.DeltaClusters(proxy, tc.configUpdated,
 				&model.WatchedResource{ResourceNames: tc.watchedResourceNames})
 			if delta != tc.usedDelta {
 				t.Errorf("un expected delta, want %v got %v", tc.usedDelta, delta)
@@ -253,6 +253,17 @@ func (f *ConfigGenTest) Listeners(p *model.Proxy) []*listener.Listener {
 	return f.ConfigGen.BuildListeners(p, f.PushContext())
 }
 
+func (f *ConfigGenTest) DeltaListeners(
+	p *model.Proxy,
+	configUpdated sets.Set[model.ConfigKey],
+	watched *model.WatchedResource,
+) ([]*listener.Listener, []string, bool) {
+	return f.ConfigGen.BuildDeltaListeners(p,
+		&model.PushRequest{
+			Push: f.PushContext(), ConfigsUpdated: configUpdated,
+		}, watched)
+}
+
 func (f *ConfigGenTest) Clusters(p *model.Proxy) []*cluster.Cluster {
 	raw, _ := f.ConfigGen.BuildClusters(p, &model.PushRequest{Push: f.PushContext()})
 	res := make([]*cluster.Cluster, 0, len(raw))
@@ -43,9 +43,11 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/kind"
 	"istio.io/istio/pkg/proto"
 	secconst "istio.io/istio/pkg/security"
 	netutil "istio.io/istio/pkg/util/net"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
 	"istio.io/pkg/monitoring"
 )
@@ -111,6 +113,79 @@ func (configgen *ConfigGeneratorImpl) BuildListeners(node *model.Proxy,
 	return builder.getListeners()
 }
 
+// BuildDeltaListeners generates the deltas (add and delete) for a given proxy. Currently, only service changes on
+// sidecars are reflected with deltas, by rebuilding the outbound listeners on the ports of the changed services.
+// Otherwise, we fall back onto generating everything.
+func (configgen *ConfigGeneratorImpl) BuildDeltaListeners(proxy *model.Proxy, updates *model.PushRequest,
+	watched *model.WatchedResource,
+) ([]*listener.Listener, []string, bool) {
+	ports, ok := deltaListenerPorts(proxy, updates)
+	if !ok {
+		return configgen.BuildListeners(proxy, updates.Push), nil, false
+	}
+
+	builder := NewListenerBuilder(proxy, updates.Push)
+	builder.outboundPorts = ports
+	// Inbound listeners depend on the services selecting the proxy, which may have changed as well.
+	builder.appendSidecarInboundListeners()
+	builder.outboundListeners = builder.buildSidecarOutboundListeners(proxy, updates.Push)
+	builder.buildHTTPProxyListener()
+	builder.patchListeners()
+	listeners := builder.getListeners()
+
+	// Any outbound listener on an affected port that was not generated again is a removed listener.
+	built := sets.New[string]()
+	for _, l := range listeners {
+		built.Insert(l.Name)
+	}
+	var deleted []string
+	for _, name := range watched.ResourceNames {
+		if built.Contains(name) {
+			continue
+		}
+		idx := strings.LastIndex(name, "_")
+		if idx < 0 {
+			continue
+		}
+		if port, err := strconv.Atoi(name[idx+1:]); err == nil && ports.Contains(port) {
+			deleted = append(deleted, name)
+		}
+	}
+	return listeners, deleted, true
+}
+
+// deltaListenerPorts returns the outbound ports whose listeners may be affected by the updated configs, covering the
+// ports of the changed services both before and after the update. Deltas are only built for sidecars with a previous
+// scope to compare against, when only services have changed, and when no egress listener binds to a unix domain socket.
+func deltaListenerPorts(proxy *model.Proxy, updates *model.PushRequest) (sets.Set[int], bool) {
+	if proxy.Type != model.SidecarProxy || proxy.PrevSidecarScope == nil || updates == nil || len(updates.ConfigsUpdated) == 0 {
+		return nil, false
+	}
+	if updates.Push.Mesh.ProxyListenPort == 0 {
+		return nil, false
+	}
+	for _, egressListener := range proxy.SidecarScope.EgressListeners {
+		if egressListener.IstioListener != nil && strings.HasPrefix(egressListener.IstioListener.Bind, model.UnixAddressPrefix) {
+			return nil, false
+		}
+	}
+	ports := sets.New[int]()
+	for key := range updates.ConfigsUpdated {
+		if key.Kind != kind.ServiceEntry {
+			return nil, false
+		}
+		hostname := host.Name(key.Name)
+		for _, sc := range []*model.SidecarScope{proxy.PrevSidecarScope, proxy.SidecarScope} {
+			if svc := sc.ServiceForHostname(hostname); svc != nil {
+				for _, port := range svc.Ports {
+					ports.Insert(port.Port)
+				}
+			}
+		}
+	}
+	return ports, true
+}
+
 func BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
 	proxy *model.Proxy, transportProtocol istionetworking.TransportProtocol, gatewayTCPServerWithTerminatingTLS bool,
 ) *auth.DownstreamTlsContext {
@@ -349,6 +424,10 @@ func (lb *ListenerBuilder) buildSidecarOutboundListeners(node *model.Proxy,
 				continue
 			}
 
+			if lb.outboundPorts != nil && !lb.outboundPorts.Contains(int(egressListener.IstioListener.Port.Number)) {
+				continue
+			}
+
 			listenPort := &model.Port{
 				Port:     int(egressListener.IstioListener.Port.Number),
 				Protocol: protocol.Parse(egressListener.IstioListener.Port.Protocol),
@@ -423,6 +502,9 @@ func (lb *ListenerBuilder) buildSidecarOutboundListeners(node *model.Proxy,
 				saddress := service.GetAddressForProxy(node)
 				sExtrAddresses := service.GetExtraAddressesForProxy(node)
 				for _, servicePort := range service.Ports {
+					if lb.outboundPorts != nil && !lb.outboundPorts.Contains(servicePort.Port) {
+						continue
+					}
 					// Skip ports we cannot bind to
 					if !node.CanBindToPort(bindToPort, uint32(servicePort.Port)) {
 						// here, we log at DEBUG level instead of WARN to avoid noise
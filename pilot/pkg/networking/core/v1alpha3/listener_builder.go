@@ -43,6 +43,7 @@ import (
 	"istio.io/istio/pilot/pkg/xds/requestidextension"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/proto"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
 )
 
@@ -61,6 +62,9 @@ type ListenerBuilder struct {
 	virtualOutboundListener *listener.Listener
 	virtualInboundListener  *listener.Listener
 
+	// outboundPorts, if set, limits the sidecar outbound listeners built to those on the given ports.
+	outboundPorts sets.Set[int]
+
 	envoyFilterWrapper *model.EnvoyFilterWrapper
 
 	// authnBuilder provides access to authn (mTLS) configuration for the given proxy.
@@ -17,6 +17,7 @@ package v1alpha3
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -52,8 +53,10 @@ import (
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/schema/kind"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/sets"
 )
 
 const (
@@ -3162,3 +3165,87 @@ func TestOutboundListenerConfig_WithAutoAllocatedAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildDeltaListeners(t *testing.T) {
+	httpService := buildServiceWithPort("httpbin.com", 8080, protocol.HTTP, tnow)
+	tcpService := buildServiceWithPort("tcp.com", 9090, protocol.TCP, tnow)
+	tcpServiceNewPort := buildServiceWithPort("tcp.com", 9091, protocol.TCP, tnow)
+	tcpKey := model.ConfigKey{Kind: kind.ServiceEntry, Name: "tcp.com", Namespace: "default"}
+
+	cases := []struct {
+		name          string
+		prevServices  []*model.Service
+		services      []*model.Service
+		configUpdated sets.Set[model.ConfigKey]
+		watched       []string
+		usedDelta     bool
+		removed       []string
+		expected      []string
+	}{
+		{
+			name:          "service is added",
+			prevServices:  []*model.Service{httpService},
+			services:      []*model.Service{httpService, tcpService},
+			configUpdated: sets.New(tcpKey),
+			watched:       []string{"0.0.0.0_8080", "virtualInbound", "virtualOutbound"},
+			usedDelta:     true,
+			removed:       nil,
+			expected:      []string{"0.0.0.0_9090", "virtualInbound"},
+		},
+		{
+			name:          "service is removed",
+			prevServices:  []*model.Service{httpService, tcpService},
+			services:      []*model.Service{httpService},
+			configUpdated: sets.New(tcpKey),
+			watched:       []string{"0.0.0.0_8080", "0.0.0.0_9090", "virtualInbound", "virtualOutbound"},
+			usedDelta:     true,
+			removed:       []string{"0.0.0.0_9090"},
+			expected:      []string{"virtualInbound"},
+		},
+		{
+			name:          "service port is changed",
+			prevServices:  []*model.Service{httpService, tcpService},
+			services:      []*model.Service{httpService, tcpServiceNewPort},
+			configUpdated: sets.New(tcpKey),
+			watched:       []string{"0.0.0.0_8080", "0.0.0.0_9090", "virtualInbound", "virtualOutbound"},
+			usedDelta:     true,
+			removed:       []string{"0.0.0.0_9090"},
+			expected:      []string{"0.0.0.0_9091", "virtualInbound"},
+		},
+		{
+			name:          "no previous sidecar scope",
+			services:      []*model.Service{httpService, tcpService},
+			configUpdated: sets.New(tcpKey),
+			watched:       []string{"0.0.0.0_8080", "virtualInbound", "virtualOutbound"},
+			usedDelta:     false,
+			removed:       nil,
+			expected:      []string{"0.0.0.0_8080", "0.0.0.0_9090", "virtualInbound", "virtualOutbound"},
+		},
+		{
+			name:          "config update that is not delta aware",
+			prevServices:  []*model.Service{httpService},
+			services:      []*model.Service{httpService, tcpService},
+			configUpdated: sets.New(model.ConfigKey{Kind: kind.VirtualService, Name: "vs", Namespace: "default"}),
+			watched:       []string{"0.0.0.0_8080", "virtualInbound", "virtualOutbound"},
+			usedDelta:     false,
+			removed:       nil,
+			expected:      []string{"0.0.0.0_8080", "0.0.0.0_9090", "virtualInbound", "virtualOutbound"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{Services: tt.services})
+			proxy := cg.SetupProxy(nil)
+			if tt.prevServices != nil {
+				prev := NewConfigGenTest(t, TestOptions{Services: tt.prevServices})
+				proxy.PrevSidecarScope = prev.SetupProxy(nil).SidecarScope
+			}
+			listeners, removed, delta := cg.DeltaListeners(proxy, tt.configUpdated, &model.WatchedResource{ResourceNames: tt.watched})
+			assert.Equal(t, delta, tt.usedDelta)
+			assert.Equal(t, removed, tt.removed)
+			names := xdstest.ExtractListenerNames(listeners)
+			sort.Strings(names)
+			assert.Equal(t, names, tt.expected)
+		})
+	}
+}
@@ -15,6 +15,8 @@
 package matcher
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 
 	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
@@ -71,3 +73,37 @@ func StringMatcherWithPrefix(v, prefix string) *matcher.StringMatcher {
 		}
 	}
 }
+
+// MatchString returns true if the string matcher matches v, as Envoy evaluates it. An error is returned if the
+// matcher has an invalid regex or an unsupported match pattern.
+func MatchString(m *matcher.StringMatcher, v string) (bool, error) {
+	value := v
+	if m.GetIgnoreCase() {
+		value = strings.ToLower(v)
+	}
+	lower := func(s string) string {
+		if m.GetIgnoreCase() {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch p := m.GetMatchPattern().(type) {
+	case *matcher.StringMatcher_Exact:
+		return value == lower(p.Exact), nil
+	case *matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(value, lower(p.Prefix)), nil
+	case *matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(value, lower(p.Suffix)), nil
+	case *matcher.StringMatcher_Contains:
+		return strings.Contains(value, lower(p.Contains)), nil
+	case *matcher.StringMatcher_SafeRegex:
+		// The regex is not affected by ignore_case and must match the whole value.
+		r, err := regexp.Compile("^(?:" + p.SafeRegex.GetRegex() + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid regex %q: %v", p.SafeRegex.GetRegex(), err)
+		}
+		return r.MatchString(v), nil
+	default:
+		return false, fmt.Errorf("unsupported string match pattern %T", p)
+	}
+}
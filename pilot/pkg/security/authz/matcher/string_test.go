@@ -125,3 +125,45 @@ func TestStringMatcherRegex(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchString(t *testing.T) {
+	testCases := []struct {
+		name    string
+		m       *matcher.StringMatcher
+		v       string
+		want    bool
+		wantErr bool
+	}{
+		{name: "exact", m: StringMatcher("foo"), v: "foo", want: true},
+		{name: "exact-mismatch", m: StringMatcher("foo"), v: "foobar"},
+		{name: "prefix", m: StringMatcher("foo*"), v: "foobar", want: true},
+		{name: "suffix", m: StringMatcher("*bar"), v: "foobar", want: true},
+		{
+			name: "contains",
+			m:    &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Contains{Contains: "ob"}},
+			v:    "foobar",
+			want: true,
+		},
+		{
+			name: "ignore-case",
+			m:    &matcher.StringMatcher{MatchPattern: &matcher.StringMatcher_Exact{Exact: "Foo"}, IgnoreCase: true},
+			v:    "fOO",
+			want: true,
+		},
+		{name: "regex-whole-value", m: StringMatcherRegex("fo+"), v: "foo", want: true},
+		{name: "regex-partial-value", m: StringMatcherRegex("fo+"), v: "foobar"},
+		{name: "invalid-regex", m: StringMatcherRegex("("), v: "foo", wantErr: true},
+		{name: "no-pattern", m: &matcher.StringMatcher{}, v: "foo", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MatchString(tc.m, tc.v)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
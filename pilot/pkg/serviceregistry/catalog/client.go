@@ -0,0 +1,174 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a single instance of a service in the catalog. The format follows the Consul health API
+// (/v1/health/service/<name>), which most catalogs can be adapted to.
+type Entry struct {
+	Node    Node
+	Service AgentService
+	Checks  []Check
+}
+
+// Node is the host an instance runs on.
+type Node struct {
+	Node       string
+	Address    string
+	Datacenter string
+}
+
+// AgentService describes the instance as registered in the catalog.
+type AgentService struct {
+	ID      string
+	Service string
+	Tags    []string
+	Address string
+	Port    int
+	Meta    map[string]string
+}
+
+// Check is a health check associated with the instance or its node.
+type Check struct {
+	CheckID string
+	Status  string
+}
+
+// Client reads services from a catalog.
+type Client interface {
+	// Services returns the names of all services in the catalog, with their tags.
+	Services() (map[string][]string, error)
+	// Entries returns all instances of the service, including their health.
+	Entries(service string) ([]*Entry, error)
+}
+
+// NewClient creates a catalog client for the address. HTTP(S) addresses are queried with the Consul
+// catalog API; anything else is treated as the path to a local JSON file, mapping service names to
+// their entries.
+func NewClient(address string, timeout time.Duration) (Client, error) {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		if _, err := url.Parse(address); err != nil {
+			return nil, fmt.Errorf("invalid catalog address %q: %v", address, err)
+		}
+		return &httpClient{
+			address: strings.TrimSuffix(address, "/"),
+			client:  &http.Client{Timeout: timeout},
+		}, nil
+	}
+	if address == "" {
+		return nil, fmt.Errorf("catalog address must be set")
+	}
+	return &fileClient{path: address}, nil
+}
+
+type httpClient struct {
+	address string
+	client  *http.Client
+}
+
+var _ Client = &httpClient{}
+
+func (c *httpClient) Services() (map[string][]string, error) {
+	out := map[string][]string{}
+	if err := c.get("/v1/catalog/services", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *httpClient) Entries(service string) ([]*Entry, error) {
+	var out []*Entry
+	if err := c.get("/v1/health/service/"+url.PathEscape(service), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *httpClient) get(path string, into any) error {
+	resp, err := c.client.Get(c.address + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("catalog request %s failed with status %d: %s", path, resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(into); err != nil {
+		return fmt.Errorf("failed to decode catalog response for %s: %v", path, err)
+	}
+	return nil
+}
+
+// fileClient reads the catalog from a local file. The file is read on every call so that
+// changes are picked up on the next refresh.
+type fileClient struct {
+	path string
+}
+
+var _ Client = &fileClient{}
+
+func (c *fileClient) read() (map[string][]*Entry, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string][]*Entry{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode catalog file %s: %v", c.path, err)
+	}
+	return out, nil
+}
+
+func (c *fileClient) Services() (map[string][]string, error) {
+	catalog, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]string, len(catalog))
+	for name, entries := range catalog {
+		tags := map[string]struct{}{}
+		for _, e := range entries {
+			for _, t := range e.Service.Tags {
+				tags[t] = struct{}{}
+			}
+		}
+		out[name] = make([]string, 0, len(tags))
+		for t := range tags {
+			out[name] = append(out[name], t)
+		}
+		sort.Strings(out[name])
+	}
+	return out, nil
+}
+
+func (c *fileClient) Entries(service string) ([]*Entry, error) {
+	catalog, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	return catalog[service], nil
+}
@@ -0,0 +1,294 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("catalog", "catalog service registry controller", 0)
+
+const (
+	// DefaultDomainSuffix is appended to catalog service names to form their hostnames.
+	DefaultDomainSuffix = "service.consul"
+	// DefaultRefreshInterval is how often the catalog is polled for changes.
+	DefaultRefreshInterval = 5 * time.Second
+)
+
+// Options stores the configurable attributes of a Controller.
+type Options struct {
+	// ClusterID identifies the catalog, for locality and endpoint shards.
+	ClusterID cluster.ID
+	// DomainSuffix is appended to service names to form their hostnames.
+	DomainSuffix string
+	// Namespace that catalog services are placed in, for visibility and policy.
+	Namespace string
+	// RefreshInterval is how often the catalog is polled for changes.
+	RefreshInterval time.Duration
+	// XDSUpdater is notified of endpoint and service changes.
+	XDSUpdater model.XDSUpdater
+}
+
+// Controller is a service registry backed by a service catalog. The catalog is polled periodically, and
+// changes to services and their instances are pushed to the XDSUpdater.
+type Controller struct {
+	client   Client
+	opts     Options
+	handlers model.ControllerHandlers
+	model.NetworkGatewaysHandler
+
+	mu sync.RWMutex
+	// entries is the last seen catalog content, keyed by service name. It is used to detect changes.
+	entries   map[string][]*Entry
+	services  map[host.Name]*model.Service
+	instances map[host.Name][]*model.ServiceInstance
+	// ipToInstances indexes instances by address, for GetProxyServiceInstances.
+	ipToInstances map[string][]*model.ServiceInstance
+
+	synced atomic.Bool
+}
+
+var _ serviceregistry.Instance = &Controller{}
+
+// NewController creates a new catalog service registry.
+func NewController(client Client, opts Options) *Controller {
+	if opts.DomainSuffix == "" {
+		opts.DomainSuffix = DefaultDomainSuffix
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "default"
+	}
+	if opts.RefreshInterval == 0 {
+		opts.RefreshInterval = DefaultRefreshInterval
+	}
+	return &Controller{
+		client:        client,
+		opts:          opts,
+		entries:       map[string][]*Entry{},
+		services:      map[host.Name]*model.Service{},
+		instances:     map[host.Name][]*model.ServiceInstance{},
+		ipToInstances: map[string][]*model.ServiceInstance{},
+	}
+}
+
+func (c *Controller) Provider() provider.ID {
+	return provider.Catalog
+}
+
+func (c *Controller) Cluster() cluster.ID {
+	return c.opts.ClusterID
+}
+
+// Services lists the services in the catalog.
+func (c *Controller) Services() []*model.Service {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]*model.Service, 0, len(c.services))
+	for _, svc := range c.services {
+		out = append(out, svc)
+	}
+	return out
+}
+
+// GetService retrieves a service by host name if it exists.
+func (c *Controller) GetService(hostname host.Name) *model.Service {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.services[hostname]
+}
+
+// InstancesByPort retrieves the instances of the service listening on the port, filtered by labels.
+func (c *Controller) InstancesByPort(svc *model.Service, port int, lbls labels.Instance) []*model.ServiceInstance {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out []*model.ServiceInstance
+	for _, instance := range c.instances[svc.Hostname] {
+		if instance.ServicePort.Port == port && lbls.SubsetOf(instance.Endpoint.Labels) && sendEndpoint(instance.Endpoint) {
+			out = append(out, instance)
+		}
+	}
+	return out
+}
+
+// GetProxyServiceInstances returns the instances co-located with the proxy, matched by address.
+func (c *Controller) GetProxyServiceInstances(node *model.Proxy) []*model.ServiceInstance {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]*model.ServiceInstance, 0)
+	for _, ip := range node.IPAddresses {
+		out = append(out, c.ipToInstances[ip]...)
+	}
+	return out
+}
+
+func (c *Controller) GetProxyWorkloadLabels(proxy *model.Proxy) labels.Instance {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, ip := range proxy.IPAddresses {
+		if instances := c.ipToInstances[ip]; len(instances) > 0 {
+			return instances[0].Endpoint.Labels
+		}
+	}
+	return nil
+}
+
+func (c *Controller) NetworkGateways() []model.NetworkGateway {
+	// TODO: support network gateways registered in the catalog
+	return nil
+}
+
+func (c *Controller) MCSServices() []model.MCSServiceInfo {
+	return nil
+}
+
+func (c *Controller) AppendServiceHandler(f func(*model.Service, model.Event)) {
+	c.handlers.AppendServiceHandler(f)
+}
+
+// AppendWorkloadHandler is a no-op; catalog instances are only exposed as service instances.
+func (c *Controller) AppendWorkloadHandler(func(*model.WorkloadInstance, model.Event)) {}
+
+// Run polls the catalog until the stop channel is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(c.opts.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.refresh(); err != nil {
+			log.Warnf("failed to refresh catalog: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// HasSynced returns true once the catalog has been read successfully at least once.
+func (c *Controller) HasSynced() bool {
+	return c.synced.Load()
+}
+
+// refresh reads the full catalog and applies any changes since the last read.
+func (c *Controller) refresh() error {
+	names, err := c.client.Services()
+	if err != nil {
+		return err
+	}
+	current := make(map[string][]*Entry, len(names))
+	for name := range names {
+		entries, err := c.client.Entries(name)
+		if err != nil {
+			return err
+		}
+		current[name] = entries
+	}
+	c.apply(current)
+	c.synced.Store(true)
+	return nil
+}
+
+// sendEndpoint returns whether the endpoint should be sent to proxies. Like other registries, unhealthy
+// endpoints are dropped unless sending them is enabled, in which case Envoy is told their health status.
+func sendEndpoint(ep *model.IstioEndpoint) bool {
+	return ep.HealthStatus != model.UnHealthy || features.SendUnhealthyEndpoints.Load()
+}
+
+type serviceEvent struct {
+	svc   *model.Service
+	event model.Event
+	// endpointsOnly is set when the service itself is unchanged, and only its instances need to be updated.
+	endpointsOnly bool
+	endpoints     []*model.IstioEndpoint
+}
+
+// apply updates the registry state to match the catalog content, then notifies of the changes.
+func (c *Controller) apply(current map[string][]*Entry) {
+	var events []serviceEvent
+
+	c.mu.Lock()
+	for name, entries := range current {
+		prev, exists := c.entries[name]
+		if exists && reflect.DeepEqual(prev, entries) {
+			continue
+		}
+		svc := convertService(name, entries, c.opts)
+		ev := serviceEvent{svc: svc, event: model.EventAdd}
+		if exists {
+			ev.event = model.EventUpdate
+			if oldSvc := c.services[svc.Hostname]; oldSvc != nil && reflect.DeepEqual(oldSvc.Ports, svc.Ports) {
+				ev.svc = oldSvc
+				ev.endpointsOnly = true
+			}
+		}
+		instances := make([]*model.ServiceInstance, 0, len(entries))
+		for _, e := range entries {
+			instance := convertInstance(ev.svc, e, c.opts.ClusterID)
+			instances = append(instances, instance)
+			if sendEndpoint(instance.Endpoint) {
+				ev.endpoints = append(ev.endpoints, instance.Endpoint)
+			}
+		}
+		c.entries[name] = entries
+		c.services[ev.svc.Hostname] = ev.svc
+		c.instances[ev.svc.Hostname] = instances
+		events = append(events, ev)
+	}
+	for name := range c.entries {
+		if _, f := current[name]; f {
+			continue
+		}
+		hostname := serviceHostname(name, c.opts.DomainSuffix)
+		events = append(events, serviceEvent{svc: c.services[hostname], event: model.EventDelete})
+		delete(c.entries, name)
+		delete(c.services, hostname)
+		delete(c.instances, hostname)
+	}
+	c.ipToInstances = map[string][]*model.ServiceInstance{}
+	for _, instances := range c.instances {
+		for _, instance := range instances {
+			c.ipToInstances[instance.Endpoint.Address] = append(c.ipToInstances[instance.Endpoint.Address], instance)
+		}
+	}
+	c.mu.Unlock()
+
+	shard := model.ShardKeyFromRegistry(c)
+	for _, ev := range events {
+		hostname := string(ev.svc.Hostname)
+		ns := ev.svc.Attributes.Namespace
+		if ev.endpointsOnly {
+			c.opts.XDSUpdater.EDSUpdate(shard, hostname, ns, ev.endpoints)
+			continue
+		}
+		if ev.event != model.EventDelete {
+			c.opts.XDSUpdater.EDSCacheUpdate(shard, hostname, ns, ev.endpoints)
+		}
+		c.opts.XDSUpdater.SvcUpdate(shard, hostname, ns, ev.event)
+		c.handlers.NotifyServiceHandlers(ev.svc, ev.event)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// stubCatalog serves the subset of the Consul API used by the http client.
+type stubCatalog struct {
+	mu      sync.Mutex
+	entries map[string][]*Entry
+}
+
+func (s *stubCatalog) set(entries map[string][]*Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+func (s *stubCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var resp any
+	switch {
+	case r.URL.Path == "/v1/catalog/services":
+		services := map[string][]string{}
+		for name := range s.entries {
+			services[name] = []string{}
+		}
+		resp = services
+	case strings.HasPrefix(r.URL.Path, "/v1/health/service/"):
+		resp = s.entries[strings.TrimPrefix(r.URL.Path, "/v1/health/service/")]
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func entry(id, addr string, port int, status string, tags ...string) *Entry {
+	return &Entry{
+		Node:    Node{Node: "node-" + id, Address: "10.0.0.1", Datacenter: "dc1"},
+		Service: AgentService{ID: id, Service: "reviews", Tags: tags, Address: addr, Port: port, Meta: map[string]string{"protocol": "http"}},
+		Checks:  []Check{{CheckID: "serfHealth", Status: "passing"}, {CheckID: "service:" + id, Status: status}},
+	}
+}
+
+func newTestController(t *testing.T) (*Controller, *stubCatalog, *kubecontroller.FakeXdsUpdater) {
+	stub := &stubCatalog{entries: map[string][]*Entry{}}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(srv.URL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xds := kubecontroller.NewFakeXDS()
+	c := NewController(client, Options{ClusterID: "catalog", XDSUpdater: xds, RefreshInterval: 10 * time.Millisecond})
+	return c, stub, xds
+}
+
+func TestController(t *testing.T) {
+	c, stub, xds := newTestController(t)
+	hostname := host.Name("reviews.service.consul")
+
+	stub.set(map[string][]*Entry{
+		"reviews": {
+			entry("v1", "1.1.1.1", 9080, "passing", "version=v1"),
+			entry("v2", "1.1.1.2", 9080, "critical", "version=v2"),
+		},
+	})
+	go c.Run(test.NewStop(t))
+	retry.UntilOrFail(t, c.HasSynced, retry.Timeout(time.Second))
+
+	ev := xds.WaitOrFail(t, "eds cache")
+	if ev.ID != string(hostname) || len(ev.Endpoints) != 1 || ev.Endpoints[0].Address != "1.1.1.1" {
+		t.Fatalf("expected only the healthy endpoint, got %+v", ev)
+	}
+	xds.WaitOrFail(t, "service")
+
+	svc := c.GetService(hostname)
+	if svc == nil {
+		t.Fatalf("service %v not found", hostname)
+	}
+	if len(svc.Ports) != 1 || svc.Ports[0].Port != 9080 || svc.Ports[0].Protocol != protocol.HTTP {
+		t.Fatalf("unexpected ports %v", svc.Ports)
+	}
+	instances := c.InstancesByPort(svc, 9080, labels.Instance{"version": "v1"})
+	if len(instances) != 1 || instances[0].Endpoint.Address != "1.1.1.1" {
+		t.Fatalf("unexpected instances %v", instances)
+	}
+	if got := c.InstancesByPort(svc, 9080, labels.Instance{"version": "v2"}); len(got) != 0 {
+		t.Fatalf("unhealthy instance should be filtered, got %v", got)
+	}
+	proxyInstances := c.GetProxyServiceInstances(&model.Proxy{IPAddresses: []string{"1.1.1.2"}})
+	if len(proxyInstances) != 1 {
+		t.Fatalf("expected proxy instance for unhealthy workload, got %v", proxyInstances)
+	}
+
+	// The unhealthy instance recovers; only endpoints change.
+	stub.set(map[string][]*Entry{
+		"reviews": {
+			entry("v1", "1.1.1.1", 9080, "passing", "version=v1"),
+			entry("v2", "1.1.1.2", 9080, "warning", "version=v2"),
+		},
+	})
+	ev = xds.WaitOrFail(t, "eds")
+	if len(ev.Endpoints) != 2 {
+		t.Fatalf("expected both endpoints, got %+v", ev.Endpoints)
+	}
+
+	// The service is removed from the catalog.
+	stub.set(map[string][]*Entry{})
+	xds.WaitOrFail(t, "service")
+	retry.UntilOrFail(t, func() bool {
+		return c.GetService(hostname) == nil
+	}, retry.Timeout(time.Second))
+}
+
+func TestFileClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	b, err := json.Marshal(map[string][]*Entry{
+		"reviews": {entry("v1", "1.1.1.1", 9080, "passing", "version=v1", "canary")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services, err := client.Services()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := services["reviews"]; len(tags) != 2 {
+		t.Fatalf("unexpected services %v", services)
+	}
+	entries, err := client.Entries("reviews")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Service.Port != 9080 {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+}
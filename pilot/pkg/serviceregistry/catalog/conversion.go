@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	labelutil "istio.io/istio/pilot/pkg/serviceregistry/util/label"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/network"
+)
+
+const (
+	// protocolMetaKey is the instance metadata key holding the protocol of the instance port.
+	protocolMetaKey = "protocol"
+	// tagSeparator separates the key and value of tags that are converted to labels.
+	tagSeparator = "="
+
+	// Health check statuses, as reported by the catalog.
+	healthCritical    = "critical"
+	healthMaintenance = "maintenance"
+)
+
+// convertLabels converts instance tags of the form key=value, along with the instance metadata, to labels.
+// Tags without a separator are ignored to avoid possible collisions.
+func convertLabels(tags []string, meta map[string]string) labels.Instance {
+	out := make(labels.Instance, len(tags)+len(meta))
+	for k, v := range meta {
+		out[k] = v
+	}
+	for _, tag := range tags {
+		k, v, ok := strings.Cut(tag, tagSeparator)
+		if !ok || k == "" {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func convertProtocol(meta map[string]string) protocol.Instance {
+	p := protocol.Parse(meta[protocolMetaKey])
+	if p.IsUnsupported() {
+		return protocol.TCP
+	}
+	return p
+}
+
+// convertPort builds a port named after its protocol and number, as a service may expose several
+// ports with the same protocol.
+func convertPort(port int, proto protocol.Instance) *model.Port {
+	return &model.Port{
+		Name:     fmt.Sprintf("%s-%d", strings.ToLower(string(proto)), port),
+		Port:     port,
+		Protocol: proto,
+	}
+}
+
+// convertHealth maps the checks of an instance to a health status. Any critical check, or a node or
+// service in maintenance, marks the instance unhealthy; warnings are still considered healthy.
+func convertHealth(checks []Check) model.HealthStatus {
+	for _, c := range checks {
+		switch c.Status {
+		case healthCritical, healthMaintenance:
+			return model.UnHealthy
+		}
+	}
+	return model.Healthy
+}
+
+func convertTLSMode(lbls labels.Instance) string {
+	if mode, f := lbls[label.SecurityTlsMode.Name]; f {
+		return mode
+	}
+	return model.DisabledTLSModeLabel
+}
+
+func serviceHostname(name, domainSuffix string) host.Name {
+	return host.Name(fmt.Sprintf("%s.%s", name, domainSuffix))
+}
+
+// instanceAddress returns the address of the instance, falling back to the node address as the catalog does.
+func instanceAddress(e *Entry) string {
+	if e.Service.Address != "" {
+		return e.Service.Address
+	}
+	return e.Node.Address
+}
+
+// convertService builds the service from all of its instances. The catalog has no notion of a service
+// port, so the ports of the service are the union of the ports of its instances.
+func convertService(name string, entries []*Entry, opts Options) *model.Service {
+	ports := map[int]*model.Port{}
+	for _, e := range entries {
+		if _, f := ports[e.Service.Port]; !f {
+			ports[e.Service.Port] = convertPort(e.Service.Port, convertProtocol(e.Service.Meta))
+		}
+	}
+	svcPorts := make(model.PortList, 0, len(ports))
+	for _, p := range ports {
+		svcPorts = append(svcPorts, p)
+	}
+	sort.Slice(svcPorts, func(i, j int) bool {
+		return svcPorts[i].Port < svcPorts[j].Port
+	})
+
+	hostname := serviceHostname(name, opts.DomainSuffix)
+	return &model.Service{
+		Hostname:       hostname,
+		DefaultAddress: constants.UnspecifiedIP,
+		Ports:          svcPorts,
+		Resolution:     model.ClientSideLB,
+		Attributes: model.ServiceAttributes{
+			ServiceRegistry: provider.Catalog,
+			Name:            string(hostname),
+			Namespace:       opts.Namespace,
+		},
+	}
+}
+
+func convertInstance(svc *model.Service, e *Entry, clusterID cluster.ID) *model.ServiceInstance {
+	port := convertPort(e.Service.Port, convertProtocol(e.Service.Meta))
+	lbls := convertLabels(e.Service.Tags, e.Service.Meta)
+	return &model.ServiceInstance{
+		Endpoint: &model.IstioEndpoint{
+			Address:         instanceAddress(e),
+			EndpointPort:    uint32(e.Service.Port),
+			ServicePortName: port.Name,
+			Locality: model.Locality{
+				Label:     e.Node.Datacenter,
+				ClusterID: clusterID,
+			},
+			Labels:       labelutil.AugmentLabels(lbls, clusterID, e.Node.Datacenter, network.ID("")),
+			TLSMode:      convertTLSMode(lbls),
+			Namespace:    svc.Attributes.Namespace,
+			WorkloadName: e.Service.ID,
+			HealthStatus: convertHealth(e.Checks),
+		},
+		Service:     svc,
+		ServicePort: port,
+	}
+}
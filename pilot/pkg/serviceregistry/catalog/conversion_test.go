@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+func TestConvertLabels(t *testing.T) {
+	got := convertLabels([]string{"version=v1", "canary", "=empty", "env=prod=eu"}, map[string]string{"team": "a", "version": "meta"})
+	want := labels.Instance{"version": "v1", "env": "prod=eu", "team": "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestConvertHealth(t *testing.T) {
+	cases := []struct {
+		checks []Check
+		want   model.HealthStatus
+	}{
+		{nil, model.Healthy},
+		{[]Check{{Status: "passing"}}, model.Healthy},
+		{[]Check{{Status: "passing"}, {Status: "warning"}}, model.Healthy},
+		{[]Check{{Status: "passing"}, {Status: "critical"}}, model.UnHealthy},
+		{[]Check{{Status: "maintenance"}}, model.UnHealthy},
+	}
+	for _, tt := range cases {
+		if got := convertHealth(tt.checks); got != tt.want {
+			t.Errorf("convertHealth(%v) = %v, want %v", tt.checks, got, tt.want)
+		}
+	}
+}
+
+func TestConvertService(t *testing.T) {
+	entries := []*Entry{
+		{Service: AgentService{Port: 9080, Meta: map[string]string{"protocol": "http"}}},
+		{Service: AgentService{Port: 9080, Meta: map[string]string{"protocol": "http"}}},
+		{Service: AgentService{Port: 5000}},
+	}
+	svc := convertService("reviews", entries, Options{DomainSuffix: DefaultDomainSuffix, Namespace: "catalog"})
+	if svc.Hostname != "reviews.service.consul" || svc.Attributes.Namespace != "catalog" {
+		t.Fatalf("unexpected service %v", svc)
+	}
+	want := model.PortList{
+		{Name: "tcp-5000", Port: 5000, Protocol: protocol.TCP},
+		{Name: "http-9080", Port: 9080, Protocol: protocol.HTTP},
+	}
+	if !reflect.DeepEqual(svc.Ports, want) {
+		t.Fatalf("got ports %v, want %v", svc.Ports, want)
+	}
+}
+
+func TestConvertInstance(t *testing.T) {
+	svc := convertService("reviews", nil, Options{DomainSuffix: DefaultDomainSuffix, Namespace: "default"})
+	e := &Entry{
+		Node:    Node{Address: "10.0.0.1", Datacenter: "dc1"},
+		Service: AgentService{ID: "reviews-1", Port: 9080, Tags: []string{"security.istio.io/tlsMode=istio"}},
+		Checks:  []Check{{Status: "critical"}},
+	}
+	instance := convertInstance(svc, e, "catalog")
+	ep := instance.Endpoint
+	if ep.Address != "10.0.0.1" {
+		t.Errorf("expected node address fallback, got %v", ep.Address)
+	}
+	if ep.TLSMode != model.IstioMutualTLSModeLabel {
+		t.Errorf("unexpected tls mode %v", ep.TLSMode)
+	}
+	if ep.HealthStatus != model.UnHealthy {
+		t.Errorf("unexpected health %v", ep.HealthStatus)
+	}
+	if ep.Locality.Label != "dc1" || ep.Locality.ClusterID != "catalog" {
+		t.Errorf("unexpected locality %v", ep.Locality)
+	}
+	if ep.ServicePortName != "tcp-9080" || instance.ServicePort.Port != 9080 {
+		t.Errorf("unexpected port %v/%v", ep.ServicePortName, instance.ServicePort)
+	}
+}
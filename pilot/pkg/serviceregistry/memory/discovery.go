@@ -132,6 +132,13 @@ func (sd *ServiceDiscovery) AddService(svc *model.Service) {
 	sd.mutex.Unlock()
 }
 
+// AddServiceFromRegistry adds an in-memory service, keeping the registry it was discovered by.
+func (sd *ServiceDiscovery) AddServiceFromRegistry(svc *model.Service) {
+	sd.mutex.Lock()
+	sd.services[svc.Hostname] = svc
+	sd.mutex.Unlock()
+}
+
 // AddServiceNotify adds an in-memory service and notifies
 func (sd *ServiceDiscovery) AddServiceNotify(svc *model.Service) {
 	sd.AddService(svc)
@@ -24,6 +24,8 @@ const (
 	Kubernetes ID = "Kubernetes"
 	// External is a service registry for externally provided ServiceEntries
 	External ID = "External"
+	// Catalog is a service registry backed by a Consul-style service catalog
+	Catalog ID = "Catalog"
 )
 
 func (id ID) String() string {
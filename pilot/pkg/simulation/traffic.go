@@ -28,12 +28,14 @@ import (
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/yl2chen/cidranger"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	authzmatcher "istio.io/istio/pilot/pkg/security/authz/matcher"
 	"istio.io/istio/pilot/pkg/xds"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pilot/test/xdstest"
@@ -227,7 +229,7 @@ func (r Result) Matches(t *testing.T, want Result) {
 }
 
 type Simulation struct {
-	t         *testing.T
+	t         test.Failer
 	Listeners []*listener.Listener
 	Clusters  []*cluster.Cluster
 	Routes    []*route.RouteConfiguration
@@ -248,6 +250,20 @@ func NewSimulation(t *testing.T, s *xds.FakeDiscoveryServer, proxy *model.Proxy)
 	return NewSimulationFromConfigGen(t, s.ConfigGenTest, proxy)
 }
 
+// NewSimulationFromResources builds a Simulation from already generated xDS resources, such as those
+// read from an Envoy config dump. Unlike the other constructors, the Failer does not need to be a
+// *testing.T; test.Wrap can be used to run a simulation outside of tests.
+func NewSimulationFromResources(t test.Failer, listeners []*listener.Listener, clusters []*cluster.Cluster,
+	routes []*route.RouteConfiguration,
+) *Simulation {
+	return &Simulation{
+		t:         t,
+		Listeners: listeners,
+		Clusters:  clusters,
+		Routes:    routes,
+	}
+}
+
 // withT swaps out the testing struct. This allows executing sub tests.
 func (sim *Simulation) withT(t *testing.T) *Simulation {
 	cpy := *sim
@@ -256,8 +272,12 @@ func (sim *Simulation) withT(t *testing.T) *Simulation {
 }
 
 func (sim *Simulation) RunExpectations(es []Expect) {
+	st, ok := sim.t.(*testing.T)
+	if !ok {
+		sim.t.Fatalf("RunExpectations requires a *testing.T")
+	}
 	for _, e := range es {
-		sim.t.Run(e.Name, func(t *testing.T) {
+		st.Run(e.Name, func(t *testing.T) {
 			sim.withT(t).Run(e.Call).Matches(t, e.Result)
 		})
 	}
@@ -433,13 +453,55 @@ func (sim *Simulation) matchRoute(vh *route.VirtualHost, input Call) *route.Rout
 			sim.t.Fatalf("unknown route path type")
 		}
 
-		// TODO this only handles path - we need to add headers, query params, etc to be complete.
+		if !sim.matchHeaders(r.Match.GetHeaders(), input.Headers) {
+			continue
+		}
+
+		// TODO this only handles path and headers - we need to add query params, etc to be complete.
 
 		return r
 	}
 	return nil
 }
 
+func (sim *Simulation) matchHeaders(matchers []*route.HeaderMatcher, headers http.Header) bool {
+	for _, hm := range matchers {
+		name := hm.GetName()
+		if name == ":authority" {
+			name = "Host"
+		}
+		values, present := headers[http.CanonicalHeaderKey(name)]
+		var matched bool
+		switch m := hm.GetHeaderMatchSpecifier().(type) {
+		case *route.HeaderMatcher_PresentMatch:
+			matched = present == m.PresentMatch
+		case *route.HeaderMatcher_StringMatch:
+			for _, v := range values {
+				if sim.matchString(m.StringMatch, v) {
+					matched = true
+					break
+				}
+			}
+		case nil:
+			matched = present
+		default:
+			sim.t.Fatalf("unknown header match type %T", m)
+		}
+		if matched == hm.GetInvertMatch() {
+			return false
+		}
+	}
+	return true
+}
+
+func (sim *Simulation) matchString(m *matcher.StringMatcher, v string) bool {
+	match, err := authzmatcher.MatchString(m, v)
+	if err != nil {
+		sim.t.Fatal(err)
+	}
+	return match
+}
+
 func (sim *Simulation) matchVirtualHost(rc *route.RouteConfiguration, host string) *route.VirtualHost {
 	if rc.GetIgnorePortInHostMatching() {
 		if h, _, err := net.SplitHostPort(host); err == nil {
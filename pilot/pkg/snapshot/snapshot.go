@@ -0,0 +1,239 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot persists the state of the config store and service registries to disk, so that
+// istiod can serve the last-known state on restart while its caches sync.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/resource"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("snapshot", "istiod warm restart snapshots", 0)
+
+// version is bumped whenever the format changes in an incompatible way. Snapshots with another
+// version are ignored, rather than risking serving incorrect config.
+const version = 1
+
+// Snapshot is the last-known state of the config store and service registries.
+type Snapshot struct {
+	Version int
+	// Time the snapshot was taken.
+	Time      time.Time
+	Configs   []*crd.IstioKind
+	Services  []*model.Service
+	Endpoints []*ServiceEndpoints
+}
+
+// ServiceEndpoints are the endpoints of a service, as reported by a single registry.
+type ServiceEndpoints struct {
+	Hostname  string
+	Namespace string
+	Shard     model.ShardKey
+	Endpoints []*model.IstioEndpoint
+}
+
+// Capture takes a snapshot of the configs of the given schemas, along with all services and their endpoints.
+func Capture(store model.ConfigStore, schemas collection.Schemas, discovery model.ServiceDiscovery,
+	index *model.EndpointIndex,
+) (*Snapshot, error) {
+	s := &Snapshot{Version: version, Time: time.Now()}
+	for _, schema := range schemas.All() {
+		configs, err := store.List(schema.Resource().GroupVersionKind(), model.NamespaceAll)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %v: %v", schema.Resource().GroupVersionKind(), err)
+		}
+		for _, cfg := range configs {
+			obj, err := crd.ConvertConfig(cfg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert %v: %v", cfg.Key(), err)
+			}
+			s.Configs = append(s.Configs, obj.(*crd.IstioKind))
+		}
+	}
+
+	s.Services = discovery.Services()
+	s.Endpoints = CaptureEndpoints(s.Services, index)
+	return s, nil
+}
+
+// CaptureEndpoints returns the endpoints of the given services, per shard, sorted by namespace and hostname.
+func CaptureEndpoints(services []*model.Service, index *model.EndpointIndex) []*ServiceEndpoints {
+	var out []*ServiceEndpoints
+	for _, svc := range services {
+		shards, f := index.ShardsForService(string(svc.Hostname), svc.Attributes.Namespace)
+		if !f {
+			continue
+		}
+		shards.RLock()
+		for _, shard := range shards.Keys() {
+			eps := make([]*model.IstioEndpoint, 0, len(shards.Shards[shard]))
+			for _, ep := range shards.Shards[shard] {
+				// The cached Envoy endpoint is rebuilt on demand, and does not need to be persisted.
+				cp := *ep
+				cp.EnvoyEndpoint = nil
+				eps = append(eps, &cp)
+			}
+			out = append(out, &ServiceEndpoints{
+				Hostname:  string(svc.Hostname),
+				Namespace: svc.Attributes.Namespace,
+				Shard:     shard,
+				Endpoints: eps,
+			})
+		}
+		shards.RUnlock()
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Hostname < out[j].Hostname
+	})
+	return out
+}
+
+// Write persists the snapshot to path. The snapshot is written to a temporary file first, and then
+// renamed, so that a crash while writing does not leave a truncated snapshot behind.
+func Write(path string, s *Snapshot) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Read loads the snapshot at path.
+func Read(path string) (*Snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %v", path, err)
+	}
+	if s.Version != version {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, version)
+	}
+	return s, nil
+}
+
+// ConfigStore returns an in-memory config store holding the snapshot configs. Configs of kinds
+// not in schemas are skipped.
+func (s *Snapshot) ConfigStore(schemas collection.Schemas, domain string) (model.ConfigStore, error) {
+	store := memory.MakeSkipValidation(schemas)
+	for _, obj := range s.Configs {
+		kgvk := obj.GroupVersionKind()
+		gvk := resource.FromKubernetesGVK(&kgvk)
+		schema, f := schemas.FindByGroupVersionAliasesKind(gvk)
+		if !f {
+			log.Debugf("skipping snapshot config %s/%s of unknown kind %v", obj.Namespace, obj.Name, gvk)
+			continue
+		}
+		cfg, err := crd.ConvertObject(schema, obj, domain)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %v %s/%s: %v", gvk, obj.Namespace, obj.Name, err)
+		}
+		if schema.Resource().IsClusterScoped() {
+			cfg.Namespace = ""
+		}
+		if _, err := store.Create(*cfg); err != nil {
+			return nil, fmt.Errorf("failed to restore %v: %v", cfg.Key(), err)
+		}
+	}
+	return store, nil
+}
+
+// ServiceDiscovery returns a service registry holding the snapshot services.
+func (s *Snapshot) ServiceDiscovery() model.ServiceDiscovery {
+	return memregistry.NewServiceDiscovery(s.Services...)
+}
+
+// Restored tracks the endpoints seeded from a snapshot, so they can be removed once the registries
+// have synced.
+type Restored struct {
+	endpoints []*ServiceEndpoints
+}
+
+// RestoreEndpoints seeds the endpoint index with the snapshot endpoints, under the shards they were
+// captured from. As the registries sync, they replace the endpoints of their shards as usual.
+func (s *Snapshot) RestoreEndpoints(updater model.XDSUpdater) *Restored {
+	for _, se := range s.Endpoints {
+		updater.EDSCacheUpdate(se.Shard, se.Hostname, se.Namespace, se.Endpoints)
+	}
+	return &Restored{endpoints: s.Endpoints}
+}
+
+// Prune removes the restored endpoints that were not replaced by their registry, which means the
+// service or shard no longer exists. It should be called once the registries have synced.
+func (r *Restored) Prune(index *model.EndpointIndex, updater model.XDSUpdater) {
+	pruned := 0
+	for _, se := range r.endpoints {
+		if len(se.Endpoints) == 0 {
+			continue
+		}
+		shards, f := index.ShardsForService(se.Hostname, se.Namespace)
+		if !f {
+			continue
+		}
+		shards.RLock()
+		stale := isRestored(shards.Shards[se.Shard], se.Endpoints)
+		shards.RUnlock()
+		if stale {
+			updater.EDSCacheUpdate(se.Shard, se.Hostname, se.Namespace, nil)
+			pruned++
+		}
+	}
+	log.Infof("pruned %d stale snapshot endpoint shards", pruned)
+}
+
+// isRestored returns whether the current endpoints of a shard are the restored ones. Registries always
+// create new endpoints, so if the shard still holds any restored endpoint it has not been updated since
+// the restore.
+func isRestored(current, restored []*model.IstioEndpoint) bool {
+	if len(current) == 0 {
+		return false
+	}
+	for _, ep := range restored {
+		if current[0] == ep {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/snapshot"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const snapshotConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: ns
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.2.3.4
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs
+  namespace: ns
+spec:
+  hosts:
+  - example.com
+  http:
+  - route:
+    - destination:
+        host: example.com
+`
+
+func TestRoundTrip(t *testing.T) {
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: snapshotConfig})
+	env := f.Discovery.Env
+	snap, err := snapshot.Capture(env.ConfigStore, collections.Pilot, env.ServiceDiscovery, env.EndpointIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Write(path, snap); err != nil {
+		t.Fatal(err)
+	}
+	got, err := snapshot.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := got.ConfigStore(collections.Pilot, "cluster.local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []struct {
+		gvk  config.GroupVersionKind
+		name string
+	}{{gvk.VirtualService, "vs"}, {gvk.ServiceEntry, "se"}} {
+		want := env.ConfigStore.Get(kind.gvk, kind.name, "ns")
+		cfg := store.Get(kind.gvk, kind.name, "ns")
+		if want == nil || cfg == nil {
+			t.Fatalf("%v not restored", kind.name)
+		}
+		assert.Equal(t, cfg.Spec, want.Spec)
+	}
+
+	svc := got.ServiceDiscovery().GetService("example.com")
+	if svc == nil {
+		t.Fatalf("service not restored")
+	}
+	assert.Equal(t, svc.Attributes.Namespace, "ns")
+	if len(got.Endpoints) != 1 || got.Endpoints[0].Endpoints[0].Address != "1.2.3.4" {
+		t.Fatalf("unexpected endpoints %+v", got.Endpoints)
+	}
+}
+
+func TestReadVersionMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(path, []byte(`{"Version": 0}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snapshot.Read(path); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	shard := model.ShardKey{Cluster: "cluster-1", Provider: "Kubernetes"}
+	snap := &snapshot.Snapshot{
+		Endpoints: []*snapshot.ServiceEndpoints{
+			{Hostname: "stale.com", Namespace: "ns", Shard: shard, Endpoints: []*model.IstioEndpoint{{Address: "1.1.1.1"}}},
+			{Hostname: "updated.com", Namespace: "ns", Shard: shard, Endpoints: []*model.IstioEndpoint{{Address: "2.2.2.2"}}},
+		},
+	}
+	index := f.Discovery.Env.EndpointIndex
+	restored := snap.RestoreEndpoints(f.Discovery)
+	endpoints := func(hostname host.Name) []*model.IstioEndpoint {
+		shards, f := index.ShardsForService(string(hostname), "ns")
+		if !f {
+			return nil
+		}
+		shards.RLock()
+		defer shards.RUnlock()
+		return shards.Shards[shard]
+	}
+	if len(endpoints("stale.com")) != 1 || len(endpoints("updated.com")) != 1 {
+		t.Fatalf("endpoints not restored")
+	}
+
+	// The registry reports the current endpoints of one of the services
+	f.Discovery.EDSCacheUpdate(shard, "updated.com", "ns", []*model.IstioEndpoint{{Address: "3.3.3.3"}})
+	restored.Prune(index, f.Discovery)
+
+	if got := endpoints("stale.com"); len(got) != 0 {
+		t.Fatalf("expected stale endpoints to be pruned, got %v", got)
+	}
+	if got := endpoints("updated.com"); len(got) != 1 || got[0].Address != "3.3.3.3" {
+		t.Fatalf("expected updated endpoints to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"encoding/pem"
+	"sort"
+	"time"
+
+	"istio.io/istio/pkg/spiffe"
+)
+
+// SetFederatedTrustDomains sets the SPIFFE bundle endpoints of the federated trust domains, keyed by trust domain.
+// Their roots are added to the trust bundle by ProcessFederatedTrustDomains.
+//
+// The trust bundle is a flat list of roots, which proxies trust for any trust domain. The bundles whose endpoint or
+// X.509 authorities have the SPIFFE ID of another trust domain are therefore rejected by spiffe.FetchBundle, and the
+// trust domains of the mesh must not be federated.
+func (tb *TrustBundle) SetFederatedTrustDomains(endpoints map[string]string) {
+	tb.federationMutex.Lock()
+	tb.federatedEndpoints = endpoints
+	for trustDomain := range tb.federatedCerts {
+		if _, ok := endpoints[trustDomain]; !ok {
+			delete(tb.federatedCerts, trustDomain)
+		}
+	}
+	tb.federationMutex.Unlock()
+	select {
+	case tb.federationUpdateChan <- struct{}{}:
+	default:
+	}
+}
+
+// fetchFederatedTrustAnchors fetches the bundles of the federated trust domains. The roots of a trust domain whose
+// endpoint fails are the last fetched ones, until its endpoint recovers. It returns the shortest refresh hint of the
+// bundles, if any.
+func (tb *TrustBundle) fetchFederatedTrustAnchors() time.Duration {
+	tb.federationMutex.Lock()
+	endpoints := tb.federatedEndpoints
+	tb.federationMutex.Unlock()
+
+	var refreshHint time.Duration
+	fetched := map[string][]string{}
+	for trustDomain, endpoint := range endpoints {
+		bundle, err := spiffe.FetchBundle(trustDomain, endpoint, tb.remoteCaCertPool)
+		if err != nil {
+			trustBundleLog.Errorf("unable to fetch the bundle of federated trust domain %s, keeping its last trust anchors: %v",
+				trustDomain, err)
+			continue
+		}
+		for _, cert := range bundle.X509Authorities {
+			fetched[trustDomain] = append(fetched[trustDomain], string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+		}
+		if bundle.RefreshHint > 0 && (refreshHint == 0 || bundle.RefreshHint < refreshHint) {
+			refreshHint = bundle.RefreshHint
+		}
+	}
+
+	tb.federationMutex.Lock()
+	for trustDomain, certs := range fetched {
+		// The trust domain may no longer be federated since the fetch started.
+		if _, ok := tb.federatedEndpoints[trustDomain]; ok {
+			tb.federatedCerts[trustDomain] = certs
+		}
+	}
+	trustDomains := make([]string, 0, len(tb.federatedCerts))
+	for trustDomain := range tb.federatedCerts {
+		trustDomains = append(trustDomains, trustDomain)
+	}
+	sort.Strings(trustDomains)
+	certs := []string{}
+	for _, trustDomain := range trustDomains {
+		certs = append(certs, tb.federatedCerts[trustDomain]...)
+	}
+	tb.federationMutex.Unlock()
+
+	if err := tb.UpdateTrustAnchor(&TrustAnchorUpdate{
+		TrustAnchorConfig: TrustAnchorConfig{Certs: certs},
+		Source:            sourceFederatedTrustDomains,
+	}); err != nil {
+		trustBundleLog.Errorf("failed to update federated trust domains trustAnchors: %v", err)
+	}
+	return refreshHint
+}
+
+// ProcessFederatedTrustDomains fetches the bundles of the federated trust domains whenever they change, and then
+// periodically at the poll interval, or at the refresh hint of the bundles when it is shorter.
+func (tb *TrustBundle) ProcessFederatedTrustDomains(stop <-chan struct{}, pollInterval time.Duration) {
+	interval := pollInterval
+	for {
+		select {
+		case <-time.After(interval):
+		case <-tb.federationUpdateChan:
+			trustBundleLog.Infof("processing federated trust domains updates")
+		case <-stop:
+			trustBundleLog.Infof("stop processing federated trust domains")
+			return
+		}
+		interval = pollInterval
+		if refreshHint := tb.fetchFederatedTrustAnchors(); refreshHint > 0 && refreshHint < interval {
+			interval = refreshHint
+		}
+	}
+}
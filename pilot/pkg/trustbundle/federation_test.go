@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustbundle
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test"
+)
+
+func TestFederatedTrustDomains(t *testing.T) {
+	block, _ := pem.Decode([]byte(rootCACert))
+	root, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := spiffe.MarshalBundle([]*x509.Certificate{root}, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(bundle)
+	}))
+	defer server.Close()
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(server.Certificate())
+
+	tb := NewTrustBundle(caCertPool)
+	stop := test.NewStop(t)
+	go tb.ProcessFederatedTrustDomains(stop, 100*time.Millisecond)
+
+	tb.SetFederatedTrustDomains(map[string]string{"foo.com": server.Listener.Addr().String()})
+	expectTbCount(t, tb, 1, 3*time.Second, "federated trust domain trustAnchor not added to bundle")
+
+	// The roots of a trust domain whose endpoint fails are kept.
+	server.Close()
+	time.Sleep(300 * time.Millisecond)
+	expectTbCount(t, tb, 1, time.Second, "federated trust domain trustAnchor removed from bundle")
+
+	tb.SetFederatedTrustDomains(map[string]string{})
+	expectTbCount(t, tb, 0, 3*time.Second, "trustAnchor of removed federated trust domain not removed from bundle")
+}
@@ -50,6 +50,11 @@ type TrustBundle struct {
 	endpoints          []string
 	endpointUpdateChan chan struct{}
 	remoteCaCertPool   *x509.CertPool
+
+	federationMutex      sync.Mutex
+	federatedEndpoints   map[string]string
+	federatedCerts       map[string][]string
+	federationUpdateChan chan struct{}
 }
 
 var (
@@ -62,8 +67,12 @@ const (
 	SourceMeshConfig
 	SourceIstioRA
 	sourceSpiffeEndpoints
+	sourceFederatedTrustDomains
 
 	RemoteDefaultPollPeriod = 30 * time.Minute
+	// FederatedDefaultPollPeriod is how often the bundles of federated trust domains are fetched, unless their
+	// refresh hint is shorter.
+	FederatedDefaultPollPeriod = 5 * time.Minute
 )
 
 func isEqSliceStr(certs1 []string, certs2 []string) bool {
@@ -83,15 +92,19 @@ func NewTrustBundle(remoteCaCertPool *x509.CertPool) *TrustBundle {
 	var err error
 	tb := &TrustBundle{
 		sourceConfig: map[Source]TrustAnchorConfig{
-			SourceIstioCA:         {Certs: []string{}},
-			SourceMeshConfig:      {Certs: []string{}},
-			SourceIstioRA:         {Certs: []string{}},
-			sourceSpiffeEndpoints: {Certs: []string{}},
+			SourceIstioCA:               {Certs: []string{}},
+			SourceMeshConfig:            {Certs: []string{}},
+			SourceIstioRA:               {Certs: []string{}},
+			sourceSpiffeEndpoints:       {Certs: []string{}},
+			sourceFederatedTrustDomains: {Certs: []string{}},
 		},
-		mergedCerts:        []string{},
-		updatecb:           nil,
-		endpointUpdateChan: make(chan struct{}, 1),
-		endpoints:          []string{},
+		mergedCerts:          []string{},
+		updatecb:             nil,
+		endpointUpdateChan:   make(chan struct{}, 1),
+		endpoints:            []string{},
+		federatedEndpoints:   map[string]string{},
+		federatedCerts:       map[string][]string{},
+		federationUpdateChan: make(chan struct{}, 1),
 	}
 	if remoteCaCertPool == nil {
 		tb.remoteCaCertPool, err = x509.SystemCertPool()
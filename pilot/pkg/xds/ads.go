@@ -642,6 +642,12 @@ func (s *DiscoveryServer) initializeProxy(con *Connection) error {
 	if err := s.WorkloadEntryController.RegisterWorkload(proxy, con.connectedAt); err != nil {
 		return err
 	}
+	s.initializeProxyState(proxy)
+	return nil
+}
+
+// initializeProxyState computes the state of a proxy needed to generate its configuration.
+func (s *DiscoveryServer) initializeProxyState(proxy *model.Proxy) {
 	s.computeProxyState(proxy, nil)
 	// Discover supported IP Versions of proxy so that appropriate config can be delivered.
 	proxy.DiscoverIPMode()
@@ -651,8 +657,6 @@ func (s *DiscoveryServer) initializeProxy(con *Connection) error {
 	if proxy.Metadata.Generator != "" {
 		proxy.XdsResourceGenerator = s.Generators[proxy.Metadata.Generator]
 	}
-
-	return nil
 }
 
 func (s *DiscoveryServer) computeProxyState(proxy *model.Proxy, request *model.PushRequest) {
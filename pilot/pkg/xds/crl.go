@@ -0,0 +1,78 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+)
+
+// CrlResourceName is the name of the Secret holding the CRL of the Istio CA.
+const CrlResourceName = "crl"
+
+// CrlGenerator generates the certificate revocation list of the Istio CA for agents to add to the
+// validation context of the workload certificates.
+type CrlGenerator struct {
+	// CRL returns the current PEM encoded CRL, or nil if there is none yet.
+	CRL func() []byte
+	// ConfigKey is updated by the pushes of a new CRL.
+	ConfigKey model.ConfigKey
+}
+
+var _ model.XdsResourceGenerator = &CrlGenerator{}
+
+// needsPush returns true for the pushes of a new CRL, as well as the global pushes, which include the initial one.
+func (e *CrlGenerator) needsPush(req *model.PushRequest) bool {
+	if req == nil {
+		return true
+	}
+	if !req.Full {
+		return false
+	}
+	if len(req.ConfigsUpdated) == 0 {
+		return true
+	}
+	_, f := req.ConfigsUpdated[e.ConfigKey]
+	return f
+}
+
+// Generate returns a Secret with a validation context containing the CRL.
+func (e *CrlGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, req *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	if !e.needsPush(req) {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	crl := e.CRL()
+	if crl == nil {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	secret := &tls.Secret{
+		Name: CrlResourceName,
+		Type: &tls.Secret_ValidationContext{
+			ValidationContext: &tls.CertificateValidationContext{
+				Crl: &core.DataSource{
+					Specifier: &core.DataSource_InlineBytes{
+						InlineBytes: crl,
+					},
+				},
+			},
+		},
+	}
+	return model.Resources{&discovery.Resource{Name: CrlResourceName, Resource: protoconv.MessageToAny(secret)}},
+		model.DefaultXdsLogDetails, nil
+}
@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestCrlGenerator(t *testing.T) {
+	crlKey := model.ConfigKey{Kind: kind.Secret, Name: "istio-ca-revocations", Namespace: "istio-system"}
+	gen := &xds.CrlGenerator{
+		CRL:       func() []byte { return []byte("crl") },
+		ConfigKey: crlKey,
+	}
+	cases := []struct {
+		name string
+		req  *model.PushRequest
+		want bool
+	}{
+		{name: "initial request", want: true},
+		{name: "global push", req: &model.PushRequest{Full: true}, want: true},
+		{name: "new CRL", req: &model.PushRequest{Full: true, ConfigsUpdated: sets.New(crlKey)}, want: true},
+		{
+			name: "new CRL with other configs",
+			req: &model.PushRequest{Full: true, ConfigsUpdated: sets.New(crlKey,
+				model.ConfigKey{Kind: kind.VirtualService, Name: "vs", Namespace: "default"})},
+			want: true,
+		},
+		{
+			name: "other secret",
+			req:  &model.PushRequest{Full: true, ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.Secret, Name: "cert", Namespace: "default"})},
+		},
+		{name: "endpoints", req: &model.PushRequest{Full: false}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _, err := gen.Generate(&model.Proxy{}, nil, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(res) == 1; got != tt.want {
+				t.Fatalf("expected CRL push %v, got %d resources", tt.want, len(res))
+			}
+		})
+	}
+}
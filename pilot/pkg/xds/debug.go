@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -28,10 +29,14 @@ import (
 	"time"
 
 	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"google.golang.org/protobuf/proto"
 	anypb "google.golang.org/protobuf/types/known/anypb"
 
@@ -50,8 +55,10 @@ import (
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/network"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/istio/pkg/util/sets"
+	caserver "istio.io/istio/security/pkg/server/ca"
 	istiolog "istio.io/pkg/log"
 )
 
@@ -209,6 +216,9 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/authorizationz", "Internal authorization policies", s.authorizationz)
 	s.addDebugHandler(mux, internalMux, "/debug/telemetryz", "Debug Telemetry configuration", s.telemetryz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
+	// The preview is expensive and reveals the configuration of any proxy, so it is restricted to the istiod namespace.
+	s.addSystemNamespaceDebugHandler(mux, internalMux, "/debug/config_dump_preview",
+		"ConfigDump generated for the passed in xDS node of a proxy, which does not need to be connected to this Pilot", s.configDumpPreview)
 	s.addDebugHandler(mux, internalMux, "/debug/push_status", "Last PushContext Details", s.pushStatusHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/pushcontext", "Debug support for current push context", s.pushContextHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/connections", "Info about the connected XDS clients", s.connectionsHandler)
@@ -216,6 +226,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.injectTemplateHandler(webhook))
 	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.meshHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
+	s.addDebugHandler(mux, internalMux, "/debug/certz", "List certificates recently issued by the CA of istiod", s.certz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
 
@@ -234,6 +245,19 @@ func (s *DiscoveryServer) addDebugHandler(mux *http.ServeMux, internalMux *http.
 	mux.HandleFunc(path, s.allowAuthenticatedOrLocalhost(http.HandlerFunc(handler)))
 }
 
+// addSystemNamespaceDebugHandler adds a debug handler which, on the HTTP server, is only served to localhost and to
+// the workloads of the istiod namespace. The internal mux is served through the debug generator, which already
+// restricts it to the istiod namespace.
+func (s *DiscoveryServer) addSystemNamespaceDebugHandler(mux *http.ServeMux, internalMux *http.ServeMux,
+	path string, help string, handler func(http.ResponseWriter, *http.Request),
+) {
+	s.debugHandlers[path] = help
+	if internalMux != nil {
+		internalMux.HandleFunc(path, handler)
+	}
+	mux.HandleFunc(path, s.allowSystemNamespaceOrLocalhost(http.HandlerFunc(handler)))
+}
+
 func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		// Request is from localhost, no need to authenticate
@@ -241,21 +265,7 @@ func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.
 			next.ServeHTTP(w, req)
 			return
 		}
-		// Authenticate request with the same method as XDS
-		authFailMsgs := make([]string, 0)
-		var ids []string
-		authRequest := security.AuthContext{Request: req}
-		for _, authn := range s.Authenticators {
-			u, err := authn.Authenticate(authRequest)
-			// If one authenticator passes, return
-			if u != nil && u.Identities != nil && err == nil {
-				ids = u.Identities
-				break
-			}
-			authFailMsgs = append(authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
-		}
-		if ids == nil {
-			istiolog.Errorf("Failed to authenticate %s %v", req.URL, authFailMsgs)
+		if s.authenticateHTTP(req) == nil {
 			// Not including detailed info in the response, XDS doesn't either (returns a generic "authentication failure).
 			w.WriteHeader(http.StatusUnauthorized)
 			return
@@ -266,6 +276,45 @@ func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.
 	}
 }
 
+func (s *DiscoveryServer) allowSystemNamespaceOrLocalhost(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if isRequestFromLocalhost(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		ids := s.authenticateHTTP(req)
+		if ids == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		for _, id := range ids {
+			if identity, err := spiffe.ParseIdentity(id); err == nil && identity.Namespace == s.systemNamespace {
+				next.ServeHTTP(w, req)
+				return
+			}
+		}
+		istiolog.Warnf("Identities %v are not allowed to access %s", ids, req.URL.Path)
+		w.WriteHeader(http.StatusForbidden)
+	}
+}
+
+// authenticateHTTP returns the identities of the request, authenticated with the same method as XDS, or nil if it is not
+// authenticated.
+func (s *DiscoveryServer) authenticateHTTP(req *http.Request) []string {
+	authFailMsgs := make([]string, 0)
+	authRequest := security.AuthContext{Request: req}
+	for _, authn := range s.Authenticators {
+		u, err := authn.Authenticate(authRequest)
+		// If one authenticator passes, return
+		if u != nil && u.Identities != nil && err == nil {
+			return u.Identities
+		}
+		authFailMsgs = append(authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
+	}
+	istiolog.Errorf("Failed to authenticate %s %v", req.URL, authFailMsgs)
+	return nil
+}
+
 func isRequestFromLocalhost(r *http.Request) bool {
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
@@ -700,6 +749,126 @@ func (s *DiscoveryServer) ConfigDump(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, dump, req)
 }
 
+// maxConcurrentConfigDumpPreviews is the maximum number of config dumps previewed concurrently, as generating the
+// configuration of a proxy is expensive.
+const maxConcurrentConfigDumpPreviews = 2
+
+// configDumpPreview returns the config dump generated for the proxy of the xDS node passed in, which does not need
+// to be connected to this Pilot instance, for example to preview the configuration a revision would send to a proxy
+// connected to another revision. The node is the base64url encoded JSON of the node of the proxy bootstrap.
+func (s *DiscoveryServer) configDumpPreview(w http.ResponseWriter, req *http.Request) {
+	select {
+	case s.concurrentConfigDumpPreviewLimit <- struct{}{}:
+		defer func() { <-s.concurrentConfigDumpPreviewLimit }()
+	default:
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte("Too many config dump previews in progress, retry later\n"))
+		return
+	}
+	encoded := req.URL.Query().Get("node")
+	if encoded == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide the node of the proxy in the query string\n"))
+		return
+	}
+	node := &core.Node{}
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = protomarshal.UnmarshalAllowUnknown(b, node)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(fmt.Sprintf("Invalid node: %v\n", err)))
+		return
+	}
+	con, err := s.previewConnection(node)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(fmt.Sprintf("Invalid node: %v\n", err)))
+		return
+	}
+	dump, err := s.configDump(con, req.URL.Query().Get("include_eds") == "true")
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	writeJSON(w, dump, req)
+}
+
+// previewConnection returns an unregistered connection of the proxy of the node, watching the clusters and listeners
+// and the routes and endpoints they refer to, as a sidecar or gateway does.
+func (s *DiscoveryServer) previewConnection(node *core.Node) (*Connection, error) {
+	proxy, err := s.initProxyMetadata(node)
+	if err != nil {
+		return nil, err
+	}
+	if alias, exists := s.ClusterAliases[proxy.Metadata.ClusterID]; exists {
+		proxy.Metadata.ClusterID = alias
+	}
+	proxy.LastPushContext = s.globalPushContext()
+	s.initializeProxyState(proxy)
+	con := newConnection("", nil)
+	con.node = node
+	con.proxy = proxy
+
+	req := &model.PushRequest{Push: proxy.LastPushContext, Start: time.Now(), Full: true}
+	generate := func(typeURL string) (model.Resources, error) {
+		w := &model.WatchedResource{TypeUrl: typeURL}
+		proxy.WatchedResources[typeURL] = w
+		gen := s.findGenerator(typeURL, con)
+		if gen == nil {
+			return nil, nil
+		}
+		res, _, err := gen.Generate(proxy, w, req)
+		return res, err
+	}
+	clusters, err := generate(v3.ClusterType)
+	if err != nil {
+		return nil, err
+	}
+	var edsClusters []string
+	for _, r := range clusters {
+		c := &cluster.Cluster{}
+		if err := r.Resource.UnmarshalTo(c); err != nil {
+			return nil, err
+		}
+		if c.GetType() == cluster.Cluster_EDS {
+			edsClusters = append(edsClusters, c.Name)
+		}
+	}
+	listeners, err := generate(v3.ListenerType)
+	if err != nil {
+		return nil, err
+	}
+	var routes []string
+	for _, r := range listeners {
+		l := &listener.Listener{}
+		if err := r.Resource.UnmarshalTo(l); err != nil {
+			return nil, err
+		}
+		for _, fc := range l.GetFilterChains() {
+			for _, f := range fc.GetFilters() {
+				if f.Name != wellknown.HTTPConnectionManager {
+					continue
+				}
+				h := &hcm.HttpConnectionManager{}
+				if err := f.GetTypedConfig().UnmarshalTo(h); err != nil {
+					return nil, err
+				}
+				if rds := h.GetRds(); rds != nil {
+					routes = append(routes, rds.RouteConfigName)
+				}
+			}
+		}
+	}
+	proxy.WatchedResources[v3.RouteType] = &model.WatchedResource{
+		TypeUrl:       v3.RouteType,
+		ResourceNames: sets.SortedList(sets.New(routes...)),
+	}
+	proxy.WatchedResources[v3.EndpointType] = &model.WatchedResource{TypeUrl: v3.EndpointType, ResourceNames: edsClusters}
+	return con, nil
+}
+
 func (s *DiscoveryServer) getResourceTypes(req *http.Request) []string {
 	if shortTypes := req.URL.Query().Get("types"); shortTypes != "" {
 		ts := strings.Split(shortTypes, ",")
@@ -1128,6 +1297,29 @@ func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, s.ListRemoteClusters(), req)
 }
 
+// certz lists the unexpired certificates recently issued by the CA, optionally selected by the identity, node and
+// since (a duration) query parameters.
+func (s *DiscoveryServer) certz(w http.ResponseWriter, req *http.Request) {
+	if s.ListIssuedCertificates == nil {
+		w.WriteHeader(400)
+		return
+	}
+	query := caserver.InventoryQuery{
+		Identity: req.URL.Query().Get("identity"),
+		NodeName: req.URL.Query().Get("node"),
+	}
+	if since := req.URL.Query().Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(fmt.Sprintf("invalid since duration: %v\n", err)))
+			return
+		}
+		query.IssuedAfter = time.Now().Add(-d)
+	}
+	writeJSON(w, s.ListIssuedCertificates(query), req)
+}
+
 // handlePushRequest handles a ?push=true query param and triggers a push.
 // A boolean response is returned to indicate if the caller should continue
 func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Request) bool {
@@ -15,18 +15,26 @@
 package xds_test
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/protomarshal"
 )
 
 func TestSyncz(t *testing.T) {
@@ -232,3 +240,141 @@ func TestDebugHandlers(t *testing.T) {
 		t.Errorf("Error in generatating debug endpoint list")
 	}
 }
+
+func TestConfigDumpPreview(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.2.3.4
+`})
+	ads := s.ConnectADS()
+	var clusters []*cluster.Cluster
+	for _, r := range ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType}).Resources {
+		clusters = append(clusters, xdstest.UnmarshalAny[cluster.Cluster](t, r))
+	}
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResourceNames: xdstest.ExtractEdsClusterNames(clusters)})
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType})
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.RouteType, ResourceNames: []string{"80"}})
+	connected := getConfigDump(t, s.Discovery, "test.default", 200)
+
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(http.NewServeMux(), mux, false, nil)
+	preview := func(node string, wantCode int) *configdump.Wrapper {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/debug/config_dump_preview?node="+node, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != wantCode {
+			t.Fatalf("wanted response code %v, got %v: %s", wantCode, rr.Code, rr.Body.String())
+		}
+		if wantCode > 399 {
+			return nil
+		}
+		got := &configdump.Wrapper{}
+		if err := got.UnmarshalJSON(rr.Body.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	node, err := protomarshal.Marshal(&core.Node{Id: ads.ID, Metadata: (&model.NodeMetadata{}).ToStruct()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previewed := preview(base64.RawURLEncoding.EncodeToString(node), 200)
+	want := dumpResourceNames(t, connected)
+	if len(want["routes"]) == 0 {
+		t.Fatal("the connected proxy has no routes")
+	}
+	assert.Equal(t, dumpResourceNames(t, previewed), want)
+	if len(s.Discovery.AllClients()) != 1 {
+		t.Errorf("the previewed proxy must not be connected, got %d connections", len(s.Discovery.AllClients()))
+	}
+
+	preview("", 400)
+	preview("not-a-node", 400)
+}
+
+// identityAuthenticator authenticates all the HTTP requests with its identity.
+type identityAuthenticator string
+
+func (a identityAuthenticator) Authenticate(security.AuthContext) (*security.Caller, error) {
+	return &security.Caller{Identities: []string{string(a)}}, nil
+}
+
+func (a identityAuthenticator) AuthenticatorType() string {
+	return "identity"
+}
+
+func TestConfigDumpPreviewAccess(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux := http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, nil, false, nil)
+	cases := []struct {
+		name       string
+		remoteAddr string
+		identity   string
+		wantCode   int
+	}{
+		{name: "localhost", remoteAddr: "127.0.0.1:1234", wantCode: http.StatusBadRequest},
+		{name: "unauthenticated", remoteAddr: "10.0.0.1:1234", wantCode: http.StatusUnauthorized},
+		{name: "istiod namespace", remoteAddr: "10.0.0.1:1234", identity: "spiffe://cluster.local/ns/istio-system/sa/istioctl", wantCode: http.StatusBadRequest},
+		{name: "other namespace", remoteAddr: "10.0.0.1:1234", identity: "spiffe://cluster.local/ns/default/sa/sleep", wantCode: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s.Discovery.Authenticators = nil
+			if tc.identity != "" {
+				s.Discovery.Authenticators = []security.Authenticator{identityAuthenticator(tc.identity)}
+			}
+			// The node is missing, so the allowed requests are rejected by the handler itself.
+			req := httptest.NewRequest("GET", "/debug/config_dump_preview", nil)
+			req.RemoteAddr = tc.remoteAddr
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+			if rr.Code != tc.wantCode {
+				t.Fatalf("wanted response code %v, got %v: %s", tc.wantCode, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+// dumpResourceNames returns the names of the listeners, clusters and route configurations of the config dump.
+func dumpResourceNames(t *testing.T, w *configdump.Wrapper) map[string][]string {
+	t.Helper()
+	names := map[string][]string{}
+	listeners, err := w.GetDynamicListenerDump(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range listeners.DynamicListeners {
+		names["listeners"] = append(names["listeners"], l.Name)
+	}
+	clusters, err := w.GetDynamicClusterDump(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range clusters.DynamicActiveClusters {
+		names["clusters"] = append(names["clusters"], xdstest.UnmarshalAny[cluster.Cluster](t, c.Cluster).Name)
+	}
+	routes, err := w.GetDynamicRouteDump(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range routes.DynamicRouteConfigs {
+		names["routes"] = append(names["routes"], xdstest.UnmarshalAny[route.RouteConfiguration](t, r.RouteConfig).Name)
+	}
+	return names
+}
@@ -488,7 +488,15 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection,
 	if req.Delta.Subscribed == nil && isWildcardTypeURL(w.TypeUrl) {
 		// this is probably a bad idea...
 		con.proxy.Lock()
-		w.ResourceNames = currentResources
+		if usedDelta {
+			// Only the changed resources were generated, so the others the proxy already has are still current.
+			names := sets.New(w.ResourceNames...)
+			names.DeleteAll(deletedRes...)
+			names.InsertAll(currentResources...)
+			w.ResourceNames = sets.SortedList(names)
+		} else {
+			w.ResourceNames = currentResources
+		}
 		con.proxy.Unlock()
 	}
 
@@ -63,6 +63,36 @@ func TestDeltaAdsClusterUpdate(t *testing.T) {
 	ads.ExpectNoResponse()
 }
 
+func TestDeltaLDS(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	ads := s.ConnectDeltaADS().WithType(v3.ListenerType)
+	resp := ads.RequestResponseAck(nil)
+	if len(resp.Resources) == 0 {
+		t.Fatalf("expected initial listeners")
+	}
+
+	const svc = "delta.example.com"
+	key := model.ConfigKey{Kind: kind.ServiceEntry, Name: svc, Namespace: ""}
+	// add svc, only send the listeners for its port
+	s.Discovery.MemRegistry.AddHTTPService(svc, "10.10.1.4", 9999)
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, ConfigsUpdated: sets.New(key)})
+	resp = ads.ExpectResponse()
+	if got := xdstest.ExtractResource(resp.Resources); !got.Contains("0.0.0.0_9999") || got.Contains("0.0.0.0_80") {
+		t.Fatalf("received unexpected lds resources %v", sets.SortedList(got))
+	}
+	if len(resp.RemovedResources) != 0 {
+		t.Fatalf("received unexpected removed lds resources %v", resp.RemovedResources)
+	}
+
+	// delete svc, its listener is removed
+	s.Discovery.MemRegistry.RemoveService(svc)
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true, ConfigsUpdated: sets.New(key)})
+	resp = ads.ExpectResponse()
+	if !reflect.DeepEqual(resp.RemovedResources, []string{"0.0.0.0_9999"}) {
+		t.Fatalf("received unexpected removed lds resources %v", resp.RemovedResources)
+	}
+}
+
 func TestDeltaEDS(t *testing.T) {
 	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
 		ConfigString: mustReadFile(t, "tests/testdata/config/destination-rule-locality.yaml"),
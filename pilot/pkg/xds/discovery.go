@@ -42,6 +42,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/security"
+	caserver "istio.io/istio/security/pkg/server/ca"
 )
 
 var (
@@ -97,6 +98,8 @@ type DiscoveryServer struct {
 
 	// concurrentPushLimit is a semaphore that limits the amount of concurrent XDS pushes.
 	concurrentPushLimit chan struct{}
+	// concurrentConfigDumpPreviewLimit is a semaphore that limits the amount of concurrent config dump previews.
+	concurrentConfigDumpPreviewLimit chan struct{}
 	// RequestRateLimit limits the number of new XDS requests allowed. This helps prevent thundering hurd of incoming requests.
 	RequestRateLimit *rate.Limiter
 
@@ -122,6 +125,9 @@ type DiscoveryServer struct {
 	// debugHandlers is the list of all the supported debug handlers.
 	debugHandlers map[string]string
 
+	// systemNamespace is the namespace of istiod, set by InitGenerators.
+	systemNamespace string
+
 	// adsClients reflect active gRPC channels, for both ADS and EDS.
 	adsClients      map[string]*Connection
 	adsClientsMutex sync.RWMutex
@@ -138,6 +144,13 @@ type DiscoveryServer struct {
 	// serverReady indicates caches have been synced up and server is ready to process requests.
 	serverReady atomic.Bool
 
+	// servingSnapshot indicates the server is serving a stale snapshot while caches sync. Pushes are
+	// held until caches are synced, as they would be computed from incomplete state.
+	servingSnapshot atomic.Bool
+
+	// Recorder, if set, records pushes and the responses sent as a result, to be replayed for regression testing.
+	Recorder *Recorder
+
 	debounceOptions debounceOptions
 
 	instanceID string
@@ -151,6 +164,9 @@ type DiscoveryServer struct {
 	// ListRemoteClusters collects debug information about other clusters this istiod reads from.
 	ListRemoteClusters func() []cluster.DebugInfo
 
+	// ListIssuedCertificates lists the unexpired certificates recently issued by the CA of this istiod.
+	ListIssuedCertificates func(query caserver.InventoryQuery) []caserver.IssuedCertificate
+
 	// ClusterAliases are aliase names for cluster. When a proxy connects with a cluster ID
 	// and if it has a different alias we should use that a cluster ID for proxy.
 	ClusterAliases map[cluster.ID]cluster.ID
@@ -159,17 +175,18 @@ type DiscoveryServer struct {
 // NewDiscoveryServer creates DiscoveryServer that sources data from Pilot's internal mesh data structures
 func NewDiscoveryServer(env *model.Environment, instanceID string, clusterAliases map[string]string) *DiscoveryServer {
 	out := &DiscoveryServer{
-		Env:                 env,
-		Generators:          map[string]model.XdsResourceGenerator{},
-		ProxyNeedsPush:      DefaultProxyNeedsPush,
-		concurrentPushLimit: make(chan struct{}, features.PushThrottle),
-		RequestRateLimit:    rate.NewLimiter(rate.Limit(features.RequestLimit), 1),
-		InboundUpdates:      atomic.NewInt64(0),
-		CommittedUpdates:    atomic.NewInt64(0),
-		pushChannel:         make(chan *model.PushRequest, 10),
-		pushQueue:           NewPushQueue(),
-		debugHandlers:       map[string]string{},
-		adsClients:          map[string]*Connection{},
+		Env:                              env,
+		Generators:                       map[string]model.XdsResourceGenerator{},
+		ProxyNeedsPush:                   DefaultProxyNeedsPush,
+		concurrentPushLimit:              make(chan struct{}, features.PushThrottle),
+		concurrentConfigDumpPreviewLimit: make(chan struct{}, maxConcurrentConfigDumpPreviews),
+		RequestRateLimit:                 rate.NewLimiter(rate.Limit(features.RequestLimit), 1),
+		InboundUpdates:                   atomic.NewInt64(0),
+		CommittedUpdates:                 atomic.NewInt64(0),
+		pushChannel:                      make(chan *model.PushRequest, 10),
+		pushQueue:                        NewPushQueue(),
+		debugHandlers:                    map[string]string{},
+		adsClients:                       map[string]*Connection{},
 		debounceOptions: debounceOptions{
 			debounceAfter:     features.DebounceAfter,
 			debounceMax:       features.DebounceMax,
@@ -229,9 +246,33 @@ func (s *DiscoveryServer) Register(rpcs *grpc.Server) {
 var processStartTime = time.Now()
 
 // CachesSynced is called when caches have been synced so that server can accept connections.
+// If a snapshot was being served, a full push replaces it with the synced state.
 func (s *DiscoveryServer) CachesSynced() {
 	log.Infof("All caches have been synced up in %v, marking server ready", time.Since(processStartTime))
 	s.serverReady.Store(true)
+	if s.servingSnapshot.CompareAndSwap(true, false) {
+		log.Infof("Replacing snapshot with synced state")
+		s.ConfigUpdate(&model.PushRequest{
+			Full:   true,
+			Reason: []model.TriggerReason{model.SnapshotSynced},
+		})
+	}
+}
+
+// ServeSnapshot makes the server accept connections before caches have synced, serving the given push
+// context, built from a snapshot of a previous run. Pushes are held until CachesSynced is called.
+func (s *DiscoveryServer) ServeSnapshot(push *model.PushContext) {
+	log.Infof("Serving snapshot (version %s) while caches sync", push.PushVersion)
+	s.updateMutex.Lock()
+	s.Env.PushContext = push
+	s.updateMutex.Unlock()
+	s.servingSnapshot.Store(true)
+	s.serverReady.Store(true)
+}
+
+// IsServingSnapshot returns true if the server is serving a stale snapshot while caches sync.
+func (s *DiscoveryServer) IsServingSnapshot() bool {
+	return s.servingSnapshot.Load()
 }
 
 func (s *DiscoveryServer) IsServerReady() bool {
@@ -304,8 +345,14 @@ func (s *DiscoveryServer) dropCacheForRequest(req *model.PushRequest) {
 
 // Push is called to push changes on config updates using ADS.
 func (s *DiscoveryServer) Push(req *model.PushRequest) {
+	if s.servingSnapshot.Load() {
+		// The pending changes are covered by the full push once caches are synced.
+		log.Debugf("Holding push while serving snapshot: %v", req.Reason)
+		return
+	}
 	if !req.Full {
 		req.Push = s.globalPushContext()
+		s.Recorder.recordPush(s.Env, req, req.Push.PushVersion)
 		s.dropCacheForRequest(req)
 		s.AdsPushAll(versionInfo(), req)
 		return
@@ -322,6 +369,7 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	t0 := time.Now()
 
 	versionLocal := time.Now().Format(time.RFC3339) + "/" + strconv.FormatUint(versionNum.Inc(), 10)
+	s.Recorder.recordPush(s.Env, req, versionLocal)
 	push, err := s.initPushContext(req, oldPushContext, versionLocal)
 	if err != nil {
 		return
@@ -563,6 +611,7 @@ func (s *DiscoveryServer) sendPushes(stopCh <-chan struct{}) {
 
 // InitGenerators initializes generators to be used by XdsServer.
 func (s *DiscoveryServer) InitGenerators(env *model.Environment, systemNameSpace string, internalDebugMux *http.ServeMux) {
+	s.systemNamespace = systemNameSpace
 	edsGen := &EdsGenerator{Server: s}
 	s.StatusGen = NewStatusGen(s)
 	s.Generators[v3.ClusterType] = &CdsGenerator{Server: s}
@@ -658,6 +707,50 @@ func (s *DiscoveryServer) ClientsOf(typeUrl string) []*Connection {
 	return pending
 }
 
+// SentNonces returns the nonce of the last response of the given type sent to each connection, by connection ID.
+func (s *DiscoveryServer) SentNonces(typeURL string) map[string]string {
+	nonces := map[string]string{}
+	for _, con := range s.Clients() {
+		if w := con.Watched(typeURL); w != nil {
+			con.proxy.RLock()
+			nonces[con.conID] = w.NonceSent
+			con.proxy.RUnlock()
+		}
+	}
+	return nonces
+}
+
+// PendingAcks returns the IDs of the connections watching the given type that have not yet acknowledged a response
+// sent after the nonces returned by SentNonces. Connections established since then only need to acknowledge their
+// latest response.
+func (s *DiscoveryServer) PendingAcks(typeURL string, sent map[string]string) []string {
+	var pending []string
+	for _, con := range s.Clients() {
+		w := con.Watched(typeURL)
+		if w == nil {
+			continue
+		}
+		con.proxy.RLock()
+		nonceSent, nonceAcked := w.NonceSent, w.NonceAcked
+		con.proxy.RUnlock()
+		if previous, f := sent[con.conID]; nonceSent == "" || nonceAcked != nonceSent || (f && nonceSent == previous) {
+			pending = append(pending, con.conID)
+		}
+	}
+	return pending
+}
+
+// UnwatchedConnections returns the IDs of the connections not watching the given type, which cannot acknowledge it.
+func (s *DiscoveryServer) UnwatchedConnections(typeURL string) []string {
+	var unwatched []string
+	for _, con := range s.Clients() {
+		if con.Watched(typeURL) == nil {
+			unwatched = append(unwatched, con.conID)
+		}
+	}
+	return unwatched
+}
+
 func (s *DiscoveryServer) WaitForRequestLimit(ctx context.Context) error {
 	if s.RequestRateLimit.Limit() == 0 {
 		// Allow opt out when rate limiting is set to 0qps
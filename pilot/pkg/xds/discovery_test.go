@@ -17,6 +17,8 @@ package xds
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -179,6 +181,30 @@ func (h *fakeStream) Context() context.Context {
 	return context.Background()
 }
 
+func TestServeSnapshot(t *testing.T) {
+	f := NewFakeDiscoveryServer(t, FakeOptions{})
+	snapshot := model.NewPushContext()
+	snapshot.PushVersion = "snapshot"
+	f.Discovery.ServeSnapshot(snapshot)
+	if !f.Discovery.IsServerReady() || !f.Discovery.IsServingSnapshot() {
+		t.Fatal("expected server to be ready and serving snapshot")
+	}
+
+	f.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	f.EnsureSynced(t)
+	if f.PushContext() != snapshot {
+		t.Fatal("push should be held while serving snapshot")
+	}
+
+	f.Discovery.CachesSynced()
+	if f.Discovery.IsServingSnapshot() {
+		t.Fatal("expected snapshot to be replaced once caches synced")
+	}
+	retry.UntilOrFail(t, func() bool {
+		return f.PushContext() != snapshot
+	}, retry.Timeout(time.Second))
+}
+
 func TestDebounce(t *testing.T) {
 	// This test tests the timeout and debouncing of config updates
 	// If it is flaking, DebounceAfter may need to be increased, or the code refactored to mock time.
@@ -492,3 +518,66 @@ func TestShouldRespond(t *testing.T) {
 		})
 	}
 }
+
+func TestPendingAcks(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	req := &discovery.DiscoveryRequest{}
+	ads.RequestResponseAck(t, req)
+	retry.UntilSuccessOrFail(t, func() error {
+		if pending := s.Discovery.PendingAcks(v3.ClusterType, nil); len(pending) != 0 {
+			return fmt.Errorf("expected response to be acknowledged, got pending %v", pending)
+		}
+		return nil
+	})
+
+	// Connections are pending until they acknowledge a new response.
+	sent := s.Discovery.SentNonces(v3.ClusterType)
+	if len(sent) != 1 {
+		t.Fatalf("expected a nonce for a single connection, got %v", sent)
+	}
+	if pending := s.Discovery.PendingAcks(v3.ClusterType, sent); len(pending) != 1 {
+		t.Fatalf("expected connection to be pending, got %v", pending)
+	}
+	if pending := s.Discovery.PendingAcks(v3.ListenerType, nil); len(pending) != 0 {
+		t.Fatalf("expected connections not watching the type to be ignored, got %v", pending)
+	}
+	if unwatched := s.Discovery.UnwatchedConnections(v3.ListenerType); len(unwatched) != 1 {
+		t.Fatalf("expected the connection not to watch listeners, got %v", unwatched)
+	}
+	if unwatched := s.Discovery.UnwatchedConnections(v3.ClusterType); len(unwatched) != 0 {
+		t.Fatalf("expected the connection to watch clusters, got %v", unwatched)
+	}
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	resp := ads.ExpectResponse(t)
+	req.ResponseNonce = resp.Nonce
+	req.VersionInfo = resp.VersionInfo
+	ads.Request(t, req)
+	retry.UntilSuccessOrFail(t, func() error {
+		if pending := s.Discovery.PendingAcks(v3.ClusterType, sent); len(pending) != 0 {
+			return fmt.Errorf("expected new response to be acknowledged, got pending %v", pending)
+		}
+		return nil
+	})
+}
+
+func TestConfigDumpPreviewLimit(t *testing.T) {
+	s := &DiscoveryServer{concurrentConfigDumpPreviewLimit: make(chan struct{}, maxConcurrentConfigDumpPreviews)}
+	for i := 0; i < maxConcurrentConfigDumpPreviews; i++ {
+		s.concurrentConfigDumpPreviewLimit <- struct{}{}
+	}
+	preview := func(wantCode int) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		s.configDumpPreview(rr, httptest.NewRequest("GET", "/debug/config_dump_preview", nil))
+		if rr.Code != wantCode {
+			t.Fatalf("wanted response code %v, got %v: %s", wantCode, rr.Code, rr.Body.String())
+		}
+	}
+	preview(http.StatusTooManyRequests)
+
+	// Once a preview completes, the next one is served, and rejected as the node is missing.
+	<-s.concurrentConfigDumpPreviewLimit
+	preview(http.StatusBadRequest)
+	preview(http.StatusBadRequest)
+}
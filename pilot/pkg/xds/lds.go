@@ -15,6 +15,7 @@
 package xds
 
 import (
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
 	"istio.io/istio/pilot/pkg/model"
@@ -26,7 +27,10 @@ type LdsGenerator struct {
 	Server *DiscoveryServer
 }
 
-var _ model.XdsResourceGenerator = &LdsGenerator{}
+var (
+	_ model.XdsResourceGenerator      = &LdsGenerator{}
+	_ model.XdsDeltaResourceGenerator = &LdsGenerator{}
+)
 
 // Map of all configs that do not impact LDS
 var skippedLdsConfigs = map[model.NodeType]map[kind.Kind]struct{}{
@@ -71,6 +75,21 @@ func (l LdsGenerator) Generate(proxy *model.Proxy, _ *model.WatchedResource, req
 		return nil, model.DefaultXdsLogDetails, nil
 	}
 	listeners := l.Server.ConfigGenerator.BuildListeners(proxy, req.Push)
+	return listenerResources(listeners), model.DefaultXdsLogDetails, nil
+}
+
+// GenerateDeltas for LDS currently only builds deltas for sidecars when services change.
+func (l LdsGenerator) GenerateDeltas(proxy *model.Proxy, req *model.PushRequest,
+	w *model.WatchedResource,
+) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	if !ldsNeedsPush(proxy, req) {
+		return nil, nil, model.DefaultXdsLogDetails, false, nil
+	}
+	listeners, removed, usedDelta := l.Server.ConfigGenerator.BuildDeltaListeners(proxy, req, w)
+	return listenerResources(listeners), removed, model.DefaultXdsLogDetails, usedDelta, nil
+}
+
+func listenerResources(listeners []*listener.Listener) model.Resources {
 	resources := model.Resources{}
 	for _, c := range listeners {
 		resources = append(resources, &discovery.Resource{
@@ -78,5 +97,5 @@ func (l LdsGenerator) Generate(proxy *model.Proxy, _ *model.WatchedResource, req
 			Resource: protoconv.MessageToAny(c),
 		})
 	}
-	return resources, model.DefaultXdsLogDetails, nil
+	return resources
 }
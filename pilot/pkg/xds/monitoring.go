@@ -205,6 +205,7 @@ var triggerMetric = map[model.TriggerReason]monitoring.Metric{
 	model.ProxyRequest:    pushTriggers.With(typeTag.Value(string(model.ProxyRequest))),
 	model.NamespaceUpdate: pushTriggers.With(typeTag.Value(string(model.NamespaceUpdate))),
 	model.ClusterUpdate:   pushTriggers.With(typeTag.Value(string(model.ClusterUpdate))),
+	model.SnapshotSynced:  pushTriggers.With(typeTag.Value(string(model.SnapshotSynced))),
 }
 
 func recordPushTriggers(reasons ...model.TriggerReason) {
@@ -28,6 +28,7 @@ import (
 )
 
 func TestNDS(t *testing.T) {
+	httpPorts := []*dnsProto.NameTable_NameInfo_Port{{Name: "http", Number: 80, Protocol: "tcp"}}
 	cases := []struct {
 		name     string
 		meta     model.NodeMetadata
@@ -44,14 +45,17 @@ func TestNDS(t *testing.T) {
 					"random-1.host.example": {
 						Ips:      []string{"240.240.0.1"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 					"random-2.host.example": {
 						Ips:      []string{"9.9.9.9"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 					"random-3.host.example": {
 						Ips:      []string{"240.240.0.2"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 				},
 			},
@@ -66,6 +70,7 @@ func TestNDS(t *testing.T) {
 					"random-2.host.example": {
 						Ips:      []string{"9.9.9.9"},
 						Registry: "External",
+						Ports:    httpPorts,
 					},
 				},
 			},
@@ -0,0 +1,340 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/snapshot"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/config/schema/resource"
+	"istio.io/istio/pkg/util/protomarshal"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// RecordedEvent is a single entry of a recording, which is stored as a stream of JSON encoded events.
+// Exactly one of the fields is set.
+type RecordedEvent struct {
+	Header   *RecordingHeader  `json:",omitempty"`
+	Push     *RecordedPush     `json:",omitempty"`
+	Response *RecordedResponse `json:",omitempty"`
+}
+
+// RecordingHeader is the first event of a recording, holding the state pushes are recorded relative to.
+type RecordingHeader struct {
+	Time time.Time
+	// Mesh is the mesh config, in JSON. Changes to the mesh config are not recorded.
+	Mesh     string
+	Snapshot *snapshot.Snapshot
+}
+
+// RecordedConfigKey identifies a config. Unlike model.ConfigKey it holds the GroupVersionKind, as kinds are
+// not stable between versions.
+type RecordedConfigKey struct {
+	GroupVersionKind config.GroupVersionKind
+	Name             string
+	Namespace        string
+}
+
+// RecordedServiceKey identifies a service.
+type RecordedServiceKey struct {
+	Hostname  host.Name
+	Namespace string
+}
+
+// RecordedPush is a push, along with the changes to configs, services and endpoints since the previous
+// recorded push. Incremental pushes only record changes to endpoints.
+type RecordedPush struct {
+	Time           time.Time
+	Full           bool
+	PushVersion    string
+	Reason         []model.TriggerReason
+	ConfigsUpdated []RecordedConfigKey
+
+	Configs         []*crd.IstioKind
+	RemovedConfigs  []RecordedConfigKey
+	Services        []*model.Service
+	RemovedServices []RecordedServiceKey
+	// Endpoints holds the endpoints of changed shards. Removed shards have no endpoints.
+	Endpoints []*snapshot.ServiceEndpoints
+}
+
+// RecordedProxy holds the proxy attributes needed to generate its config on replay.
+type RecordedProxy struct {
+	ID              string
+	Type            model.NodeType
+	IPAddresses     []string
+	DNSDomain       string
+	ConfigNamespace string
+	Metadata        *model.NodeMetadata
+}
+
+// RecordedResponse is a response sent to a proxy. Resources are stored as hashes of their contents, which
+// is enough to tell which resources differ on replay.
+type RecordedResponse struct {
+	Time           time.Time
+	Proxy          RecordedProxy
+	TypeURL        string
+	PushVersion    string
+	Full           bool
+	ConfigsUpdated []RecordedConfigKey
+	// Watched holds the names of the resources requested, as passed to the generator.
+	Watched []string
+	// Resources maps resource names to the hash of their contents.
+	Resources map[string]string
+}
+
+type recordedEndpointsKey struct {
+	RecordedServiceKey
+	Shard model.ShardKey
+}
+
+// Recorder records full and incremental pushes, and the SotW responses sent as a result, so that they can be
+// replayed against another version of istiod with Replay. Nothing is recorded until the first push, which
+// records the state at the time in the header.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+
+	started bool
+	// Hashes of the last recorded state, so that only changes are recorded.
+	configs   map[RecordedConfigKey]string
+	services  map[RecordedServiceKey]string
+	endpoints map[recordedEndpointsKey]string
+}
+
+// NewRecorder returns a recorder writing to the file at path.
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Close stops recording.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// recordPush records a push about to be computed, along with the state changes since the last push.
+func (r *Recorder) recordPush(env *model.Environment, req *model.PushRequest, version string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started && !req.Full {
+		return
+	}
+
+	push := &RecordedPush{
+		Time:           time.Now(),
+		Full:           req.Full,
+		PushVersion:    version,
+		Reason:         req.Reason,
+		ConfigsUpdated: recordedConfigKeys(req.ConfigsUpdated),
+	}
+	services := env.ServiceDiscovery.Services()
+	if !req.Full {
+		r.recordEndpoints(push, snapshot.CaptureEndpoints(services, env.EndpointIndex))
+		r.write(&RecordedEvent{Push: push})
+		return
+	}
+
+	snap, err := snapshot.Capture(env.ConfigStore, collections.Pilot, env.ServiceDiscovery, env.EndpointIndex)
+	if err != nil {
+		log.Warnf("failed to record push %s: %v", version, err)
+		return
+	}
+	if !r.started {
+		mesh, err := protomarshal.ToJSON(env.Mesh())
+		if err != nil {
+			log.Warnf("failed to record mesh config: %v", err)
+			return
+		}
+		r.write(&RecordedEvent{Header: &RecordingHeader{Time: snap.Time, Mesh: mesh, Snapshot: snap}})
+		r.started = true
+		// The header holds the current state, so the first push has no changes.
+		initial := &RecordedPush{}
+		r.recordConfigs(initial, snap.Configs)
+		r.recordServices(initial, snap.Services)
+		r.recordEndpoints(initial, snap.Endpoints)
+	}
+	r.recordConfigs(push, snap.Configs)
+	r.recordServices(push, snap.Services)
+	r.recordEndpoints(push, snap.Endpoints)
+	r.write(&RecordedEvent{Push: push})
+}
+
+func (r *Recorder) recordConfigs(push *RecordedPush, configs []*crd.IstioKind) {
+	current := make(map[RecordedConfigKey]string, len(configs))
+	for _, cfg := range configs {
+		kgvk := cfg.GroupVersionKind()
+		key := RecordedConfigKey{GroupVersionKind: resource.FromKubernetesGVK(&kgvk), Name: cfg.Name, Namespace: cfg.Namespace}
+		hash := hashJSON(cfg)
+		current[key] = hash
+		if r.configs[key] != hash {
+			push.Configs = append(push.Configs, cfg)
+		}
+	}
+	for key := range r.configs {
+		if _, f := current[key]; !f {
+			push.RemovedConfigs = append(push.RemovedConfigs, key)
+		}
+	}
+	r.configs = current
+}
+
+func (r *Recorder) recordServices(push *RecordedPush, services []*model.Service) {
+	current := make(map[RecordedServiceKey]string, len(services))
+	for _, svc := range services {
+		key := RecordedServiceKey{Hostname: svc.Hostname, Namespace: svc.Attributes.Namespace}
+		hash := hashJSON(svc)
+		current[key] = hash
+		if r.services[key] != hash {
+			push.Services = append(push.Services, svc)
+		}
+	}
+	for key := range r.services {
+		if _, f := current[key]; !f {
+			push.RemovedServices = append(push.RemovedServices, key)
+		}
+	}
+	r.services = current
+}
+
+func (r *Recorder) recordEndpoints(push *RecordedPush, endpoints []*snapshot.ServiceEndpoints) {
+	current := make(map[recordedEndpointsKey]string, len(endpoints))
+	for _, se := range endpoints {
+		key := recordedEndpointsKey{
+			RecordedServiceKey: RecordedServiceKey{Hostname: host.Name(se.Hostname), Namespace: se.Namespace},
+			Shard:              se.Shard,
+		}
+		hash := hashJSON(se.Endpoints)
+		current[key] = hash
+		if r.endpoints[key] != hash {
+			push.Endpoints = append(push.Endpoints, se)
+		}
+	}
+	for key := range r.endpoints {
+		if _, f := current[key]; !f {
+			push.Endpoints = append(push.Endpoints, &snapshot.ServiceEndpoints{
+				Hostname:  string(key.Hostname),
+				Namespace: key.Namespace,
+				Shard:     key.Shard,
+			})
+		}
+	}
+	r.endpoints = current
+}
+
+// recordResponse records a response sent to a proxy. Secrets are never recorded.
+func (r *Recorder) recordResponse(proxy *model.Proxy, w *model.WatchedResource, req *model.PushRequest, res model.Resources) {
+	if r == nil || w.TypeUrl == v3.SecretType {
+		return
+	}
+	resources := make(map[string]string, len(res))
+	for _, rr := range res {
+		resources[rr.Name] = hashResource(rr.Resource.GetValue())
+	}
+	resp := &RecordedResponse{
+		Time: time.Now(),
+		Proxy: RecordedProxy{
+			ID:              proxy.ID,
+			Type:            proxy.Type,
+			IPAddresses:     proxy.IPAddresses,
+			DNSDomain:       proxy.DNSDomain,
+			ConfigNamespace: proxy.ConfigNamespace,
+			Metadata:        proxy.Metadata,
+		},
+		TypeURL:        w.TypeUrl,
+		PushVersion:    req.Push.PushVersion,
+		Full:           req.Full,
+		ConfigsUpdated: recordedConfigKeys(req.ConfigsUpdated),
+		Watched:        append([]string(nil), w.ResourceNames...),
+		Resources:      resources,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.started {
+		return
+	}
+	r.write(&RecordedEvent{Response: resp})
+}
+
+func (r *Recorder) write(ev *RecordedEvent) {
+	if err := r.enc.Encode(ev); err != nil {
+		log.Warnf("failed to write recording: %v", err)
+	}
+}
+
+// kindGroupVersionKinds maps kinds to the GroupVersionKind recorded for them.
+var kindGroupVersionKinds = func() map[kind.Kind]config.GroupVersionKind {
+	out := map[kind.Kind]config.GroupVersionKind{}
+	for _, s := range collections.PilotGatewayAPI.All() {
+		gvk := s.Resource().GroupVersionKind()
+		out[kind.FromGvk(gvk)] = gvk
+	}
+	return out
+}()
+
+func recordedConfigKeys(keys sets.Set[model.ConfigKey]) []RecordedConfigKey {
+	if len(keys) == 0 {
+		return nil
+	}
+	out := make([]RecordedConfigKey, 0, len(keys))
+	for key := range keys {
+		gvk, f := kindGroupVersionKinds[key.Kind]
+		if !f {
+			// Not a config kind, such as a Kubernetes Secret. These are dropped on replay.
+			gvk = config.GroupVersionKind{Kind: key.Kind.String()}
+		}
+		out = append(out, RecordedConfigKey{GroupVersionKind: gvk, Name: key.Name, Namespace: key.Namespace})
+	}
+	return out
+}
+
+func hashJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return hashResource(b)
+}
+
+// hashResource hashes the serialized resource. Resources are marshaled deterministically, so equal resources
+// have equal hashes.
+func hashResource(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
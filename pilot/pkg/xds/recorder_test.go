@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+const recordedServiceEntry = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.2.3.4
+    labels:
+      version: v1
+`
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.json")
+	rec, err := xds.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+		ConfigString: recordedServiceEntry,
+		DiscoveryServerModifier: func(s *xds.DiscoveryServer) {
+			s.Recorder = rec
+		},
+	})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(t, nil)
+
+	if _, err := s.Store().Create(config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.DestinationRule, Name: "dr", Namespace: "default"},
+		Spec: &networking.DestinationRule{
+			Host:    "example.com",
+			Subsets: []*networking.Subset{{Name: "v1", Labels: map[string]string{"version": "v1"}}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if resp := ads.ExpectResponse(t); len(resp.Resources) == 0 {
+		t.Fatalf("expected clusters after update")
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recording, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := xds.Replay(t, bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pushes < 2 || result.Responses-result.Skipped < 2 {
+		t.Fatalf("expected pushes and responses to be replayed, got %+v", result)
+	}
+	if len(result.Diffs) != 0 {
+		t.Fatalf("unexpected diffs: %+v", result.Diffs)
+	}
+
+	// Changing the recorded subset cluster should be reported
+	result, err = xds.Replay(t, bytes.NewReader(tamperRecording(t, recording, "outbound|80|v1|example.com")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Diffs) != 1 || len(result.Diffs[0].Changed) != 1 || result.Diffs[0].Changed[0] != "outbound|80|v1|example.com" {
+		t.Fatalf("expected changed cluster, got %+v", result.Diffs)
+	}
+}
+
+// tamperRecording changes the recorded hash of the named resource in the last response holding it.
+func tamperRecording(t *testing.T, recording []byte, name string) []byte {
+	lines := bytes.Split(bytes.TrimSpace(recording), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var ev xds.RecordedEvent
+		if err := json.Unmarshal(lines[i], &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Response == nil || ev.Response.Resources[name] == "" {
+			continue
+		}
+		ev.Response.Resources[name] = "tampered"
+		b, err := json.Marshal(ev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines[i] = b
+		return bytes.Join(lines, []byte("\n"))
+	}
+	t.Fatalf("no response holds %v", name)
+	return nil
+}
+
+// TestReplayRecording replays the recording at XDS_REPLAY_RECORDING, such as one taken with PILOT_XDS_RECORDING_PATH
+// on a previous version of istiod, and reports the responses that differ.
+func TestReplayRecording(t *testing.T) {
+	path := os.Getenv("XDS_REPLAY_RECORDING")
+	if path == "" {
+		t.Skip("XDS_REPLAY_RECORDING is not set")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	result, err := xds.Replay(t, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("replayed %d pushes and %d responses, skipped %d", result.Pushes, result.Responses, result.Skipped)
+	for _, d := range result.Diffs {
+		t.Errorf("%s %s at %s: added %v, removed %v, changed %v %s",
+			d.Proxy, v3.GetShortType(d.TypeURL), d.PushVersion, d.Added, d.Removed, d.Changed, d.Error)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/snapshot"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/config/schema/resource"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const replayDomainSuffix = "cluster.local"
+
+// ReplayDiff is a difference between a recorded response and the response generated on replay.
+type ReplayDiff struct {
+	Proxy       string
+	TypeURL     string
+	PushVersion string
+	// Added holds the resources only generated on replay, Removed those only recorded, and Changed
+	// those generated with different contents.
+	Added   []string
+	Removed []string
+	Changed []string
+	// Error is set if the response could not be generated on replay.
+	Error string
+}
+
+// ReplayResult summarizes a replay.
+type ReplayResult struct {
+	Pushes    int
+	Responses int
+	// Skipped counts the responses generated from another push context than the latest recorded push, such
+	// as those sent to proxies connecting while a push is computed, which cannot be reproduced.
+	Skipped int
+	Diffs   []ReplayDiff
+}
+
+// Replay feeds a recording made by a Recorder into a FakeDiscoveryServer, regenerating each recorded response
+// and diffing it against the recorded one. All services and endpoints are replayed from a single in-memory
+// registry, rather than the registries they were recorded from, so ServiceEntry and WorkloadEntry configs are
+// not replayed, and config that depends on the cluster of an endpoint may differ.
+func Replay(t test.Failer, r io.Reader) (*ReplayResult, error) {
+	dec := json.NewDecoder(r)
+	var ev RecordedEvent
+	if err := dec.Decode(&ev); err != nil {
+		return nil, fmt.Errorf("failed to read recording header: %v", err)
+	}
+	if ev.Header == nil || ev.Header.Snapshot == nil {
+		return nil, fmt.Errorf("recording does not start with a header")
+	}
+	m, err := mesh.ApplyMeshConfigDefaults(ev.Header.Mesh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded mesh config: %v", err)
+	}
+	store, err := ev.Header.Snapshot.ConfigStore(collections.Pilot, replayDomainSuffix)
+	if err != nil {
+		return nil, err
+	}
+	var configs []config.Config
+	for _, s := range collections.Pilot.All() {
+		if !replayedConfig(s.Resource().GroupVersionKind()) {
+			continue
+		}
+		cfgs, err := store.List(s.Resource().GroupVersionKind(), model.NamespaceAll)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, cfgs...)
+	}
+
+	rp := &replayer{
+		f:         NewFakeDiscoveryServer(t, FakeOptions{Configs: configs, MeshConfig: m}),
+		endpoints: map[RecordedServiceKey]map[model.ShardKey][]*model.IstioEndpoint{},
+	}
+	rp.applyServices(ev.Header.Snapshot.Services, nil)
+	rp.applyEndpoints(ev.Header.Snapshot.Endpoints)
+
+	result := &ReplayResult{}
+	for {
+		var ev RecordedEvent
+		if err := dec.Decode(&ev); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read recording: %v", err)
+		}
+		switch {
+		case ev.Push != nil:
+			result.Pushes++
+			if err := rp.push(ev.Push); err != nil {
+				return nil, err
+			}
+		case ev.Response != nil:
+			result.Responses++
+			if rp.pushContext == nil || ev.Response.PushVersion != rp.pushContext.PushVersion {
+				result.Skipped++
+				continue
+			}
+			if diff := rp.response(ev.Response); diff != nil {
+				result.Diffs = append(result.Diffs, *diff)
+			}
+		}
+	}
+	return result, nil
+}
+
+type replayer struct {
+	f           *FakeDiscoveryServer
+	pushContext *model.PushContext
+	// endpoints holds the current endpoints of each service, by shard.
+	endpoints map[RecordedServiceKey]map[model.ShardKey][]*model.IstioEndpoint
+}
+
+// replayedConfig returns whether configs of the kind are replayed. ServiceEntry and WorkloadEntry are not, as the
+// services and endpoints derived from them are replayed instead.
+func replayedConfig(k config.GroupVersionKind) bool {
+	return k != gvk.ServiceEntry && k != gvk.WorkloadEntry
+}
+
+func (rp *replayer) push(p *RecordedPush) error {
+	if err := rp.applyConfigs(p.Configs, p.RemovedConfigs); err != nil {
+		return err
+	}
+	rp.applyServices(p.Services, p.RemovedServices)
+	rp.applyEndpoints(p.Endpoints)
+	if !p.Full {
+		return nil
+	}
+
+	req := &model.PushRequest{Full: true, ConfigsUpdated: replayConfigKeys(p.ConfigsUpdated), Reason: p.Reason}
+	push, err := rp.f.Discovery.initPushContext(req, rp.pushContext, p.PushVersion)
+	if err != nil {
+		return fmt.Errorf("failed to init push context %s: %v", p.PushVersion, err)
+	}
+	rp.pushContext = push
+	return nil
+}
+
+func (rp *replayer) applyConfigs(configs []*crd.IstioKind, removed []RecordedConfigKey) error {
+	store := rp.f.Store()
+	for _, obj := range configs {
+		kgvk := obj.GroupVersionKind()
+		gvk := resource.FromKubernetesGVK(&kgvk)
+		schema, f := collections.Pilot.FindByGroupVersionAliasesKind(gvk)
+		if !f || !replayedConfig(schema.Resource().GroupVersionKind()) {
+			continue
+		}
+		cfg, err := crd.ConvertObject(schema, obj, replayDomainSuffix)
+		if err != nil {
+			return fmt.Errorf("failed to convert %v %s/%s: %v", gvk, obj.Namespace, obj.Name, err)
+		}
+		if schema.Resource().IsClusterScoped() {
+			cfg.Namespace = ""
+		}
+		if existing := store.Get(cfg.GroupVersionKind, cfg.Name, cfg.Namespace); existing != nil {
+			cfg.ResourceVersion = existing.ResourceVersion
+			_, err = store.Update(*cfg)
+		} else {
+			_, err = store.Create(*cfg)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply %v: %v", cfg.Key(), err)
+		}
+	}
+	for _, key := range removed {
+		schema, f := collections.Pilot.FindByGroupVersionAliasesKind(key.GroupVersionKind)
+		if !f || !replayedConfig(schema.Resource().GroupVersionKind()) {
+			continue
+		}
+		if err := store.Delete(schema.Resource().GroupVersionKind(), key.Name, key.Namespace, nil); err != nil {
+			return fmt.Errorf("failed to remove %v %s/%s: %v", key.GroupVersionKind, key.Namespace, key.Name, err)
+		}
+	}
+	return nil
+}
+
+func (rp *replayer) applyServices(services []*model.Service, removed []RecordedServiceKey) {
+	for _, svc := range services {
+		rp.f.MemRegistry.AddServiceFromRegistry(svc)
+		// The ports of the service may have changed, so its endpoints are set again.
+		rp.setEndpoints(RecordedServiceKey{Hostname: svc.Hostname, Namespace: svc.Attributes.Namespace})
+	}
+	for _, key := range removed {
+		rp.f.MemRegistry.RemoveService(key.Hostname)
+		delete(rp.endpoints, key)
+	}
+}
+
+func (rp *replayer) applyEndpoints(endpoints []*snapshot.ServiceEndpoints) {
+	updated := sets.New[RecordedServiceKey]()
+	for _, se := range endpoints {
+		key := RecordedServiceKey{Hostname: host.Name(se.Hostname), Namespace: se.Namespace}
+		if rp.endpoints[key] == nil {
+			rp.endpoints[key] = map[model.ShardKey][]*model.IstioEndpoint{}
+		}
+		if len(se.Endpoints) == 0 {
+			delete(rp.endpoints[key], se.Shard)
+		} else {
+			rp.endpoints[key][se.Shard] = se.Endpoints
+		}
+		updated.Insert(key)
+	}
+	for key := range updated {
+		rp.setEndpoints(key)
+	}
+}
+
+// setEndpoints sets the endpoints of all shards of the service in the in-memory registry.
+func (rp *replayer) setEndpoints(key RecordedServiceKey) {
+	svc := rp.f.MemRegistry.GetService(key.Hostname)
+	if svc == nil {
+		return
+	}
+	var eps []*model.IstioEndpoint
+	for _, shard := range rp.endpoints[key] {
+		for _, ep := range shard {
+			// Endpoints of ports that no longer exist are dropped, as the registry would.
+			if _, f := svc.Ports.Get(ep.ServicePortName); f {
+				eps = append(eps, ep)
+			}
+		}
+	}
+	sort.Slice(eps, func(i, j int) bool {
+		return eps[i].Address < eps[j].Address
+	})
+	rp.f.MemRegistry.SetEndpoints(string(key.Hostname), key.Namespace, eps)
+}
+
+// response regenerates the recorded response, returning the difference if any.
+func (rp *replayer) response(resp *RecordedResponse) *ReplayDiff {
+	rec := resp.Proxy
+	proxy := &model.Proxy{
+		ID:              rec.ID,
+		Type:            rec.Type,
+		IPAddresses:     rec.IPAddresses,
+		DNSDomain:       rec.DNSDomain,
+		ConfigNamespace: rec.ConfigNamespace,
+		Metadata:        rec.Metadata,
+	}
+	if rec.Metadata != nil {
+		proxy.Labels = rec.Metadata.Labels
+	}
+	proxy = rp.f.SetupProxy(proxy)
+	proxy.SetSidecarScope(rp.pushContext)
+	proxy.SetGatewaysForProxy(rp.pushContext)
+
+	diff := &ReplayDiff{Proxy: rec.ID, TypeURL: resp.TypeURL, PushVersion: resp.PushVersion}
+	gen := rp.f.Discovery.findGenerator(resp.TypeURL, &Connection{proxy: proxy})
+	if gen == nil {
+		diff.Error = "no generator found"
+		return diff
+	}
+	req := &model.PushRequest{
+		Full:           resp.Full,
+		ConfigsUpdated: replayConfigKeys(resp.ConfigsUpdated),
+		Push:           rp.pushContext,
+		Start:          time.Now(),
+	}
+	w := &model.WatchedResource{TypeUrl: resp.TypeURL, ResourceNames: resp.Watched}
+	res, _, err := gen.Generate(proxy, w, req)
+	if err != nil {
+		diff.Error = err.Error()
+		return diff
+	}
+
+	generated := make(map[string]string, len(res))
+	for _, r := range res {
+		generated[r.Name] = hashResource(r.Resource.GetValue())
+	}
+	for name, hash := range generated {
+		recorded, f := resp.Resources[name]
+		switch {
+		case !f:
+			diff.Added = append(diff.Added, name)
+		case recorded != hash:
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range resp.Resources {
+		if _, f := generated[name]; !f {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+		return nil
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff
+}
+
+// replayConfigKeys converts recorded keys back to config keys. Keys of kinds unknown to this version are dropped.
+func replayConfigKeys(keys []RecordedConfigKey) sets.Set[model.ConfigKey] {
+	if len(keys) == 0 {
+		return nil
+	}
+	out := sets.New[model.ConfigKey]()
+	for _, key := range keys {
+		schema, f := collections.PilotGatewayAPI.FindByGroupVersionAliasesKind(key.GroupVersionKind)
+		if !f {
+			continue
+		}
+		out.Insert(model.ConfigKey{Kind: kind.FromGvk(schema.Resource().GroupVersionKind()), Name: key.Name, Namespace: key.Namespace})
+	}
+	return out
+}
@@ -36,6 +36,8 @@ const (
 	// DebugType requests debug info from istio, a secured implementation for istio debug interface.
 	DebugType     = "istio.io/debug"
 	BootstrapType = resource.APITypePrefix + "envoy.config.bootstrap.v3.Bootstrap"
+	// CertificateRevocationListType delivers the CRL of the Istio CA to agents, as the validation context of a Secret.
+	CertificateRevocationListType = "istio.io/crl"
 
 	// nolint
 	HttpProtocolOptionsType = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
@@ -60,6 +62,8 @@ func GetShortType(typeURL string) string {
 		return "PCDS"
 	case ExtensionConfigurationType:
 		return "ECDS"
+	case CertificateRevocationListType:
+		return "CRL"
 	default:
 		return typeURL
 	}
@@ -86,6 +90,8 @@ func GetMetricType(typeURL string) string {
 		return "ecds"
 	case BootstrapType:
 		return "bds"
+	case CertificateRevocationListType:
+		return "crl"
 	default:
 		return typeURL
 	}
@@ -111,6 +117,8 @@ func GetResourceType(shortType string) string {
 		return ProxyConfigType
 	case "ECDS":
 		return ExtensionConfigurationType
+	case "CRL":
+		return CertificateRevocationListType
 	default:
 		return shortType
 	}
@@ -164,6 +164,7 @@ func (s *DiscoveryServer) pushXds(con *Connection, w *model.WatchedResource, req
 		}
 		return err
 	}
+	s.Recorder.recordResponse(con.proxy, w, req, res)
 
 	switch {
 	case !req.Full:
@@ -15,6 +15,7 @@
 package analysis
 
 import (
+	"istio.io/istio/pkg/config/analysis/diag"
 	"istio.io/istio/pkg/config/analysis/scope"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/util/sets"
@@ -57,11 +58,25 @@ func (c *CombinedAnalyzer) Analyze(ctx Context) {
 			scope.Analysis.Debugf("Analyzer %q has been cancelled...", c.Metadata().Name)
 			return
 		}
-		a.Analyze(ctx)
+		a.Analyze(&analyzerContext{Context: ctx, analyzer: a.Metadata().Name})
 		scope.Analysis.Debugf("Completed analyzer %q...", a.Metadata().Name)
 	}
 }
 
+// analyzerContext records the name of the analyzer on the messages it reports.
+type analyzerContext struct {
+	Context
+	analyzer string
+}
+
+// Report implements Context
+func (c *analyzerContext) Report(col collection.Name, m diag.Message) {
+	if m.Analyzer == "" {
+		m.Analyzer = c.analyzer
+	}
+	c.Context.Report(col, m)
+}
+
 // RemoveSkipped removes analyzers that should be skipped, meaning they meet one of the following criteria:
 // 1. The analyzer requires disabled input collections. The names of removed analyzers are returned.
 // Transformer information is used to determine, based on the disabled input collections, which output collections
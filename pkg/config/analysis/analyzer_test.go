@@ -40,13 +40,16 @@ func (a *analyzer) Metadata() Metadata {
 }
 
 // Analyze implements Analyzer
-func (a *analyzer) Analyze(Context) {
+func (a *analyzer) Analyze(ctx Context) {
 	a.ran = true
+	ctx.Report(a.inputs[0], diag.NewMessage(diag.NewMessageType(diag.Info, "A1", "ran"), nil))
 }
 
-type context struct{}
+type context struct {
+	messages diag.Messages
+}
 
-func (ctx *context) Report(collection.Name, diag.Message)                       {}
+func (ctx *context) Report(_ collection.Name, m diag.Message)                   { ctx.messages.Add(m) }
 func (ctx *context) Find(collection.Name, resource.FullName) *resource.Instance { return nil }
 func (ctx *context) Exists(collection.Name, resource.FullName) bool             { return false }
 func (ctx *context) ForEach(collection.Name, IteratorFn)                        {}
@@ -77,12 +80,19 @@ func TestCombinedAnalyzer(t *testing.T) {
 	g.Expect(removed).To(ConsistOf(a3.Metadata().Name, a4.Metadata().Name))
 	g.Expect(a.Metadata().Inputs).To(ConsistOf(col1.Name(), col2.Name()))
 
-	a.Analyze(&context{})
+	ctx := &context{}
+	a.Analyze(ctx)
 
 	g.Expect(a1.ran).To(BeTrue())
 	g.Expect(a2.ran).To(BeTrue())
 	g.Expect(a3.ran).To(BeFalse())
 	g.Expect(a4.ran).To(BeFalse())
+
+	var reporters []string
+	for _, m := range ctx.messages {
+		reporters = append(reporters, m.Analyzer)
+	}
+	g.Expect(reporters).To(Equal([]string{"a1", "a2"}))
 }
 
 func newSchema(name string) collection.Schema {
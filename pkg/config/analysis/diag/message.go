@@ -63,6 +63,9 @@ type Message struct {
 
 	// Line is the line number of the error place in the message
 	Line int
+
+	// Analyzer is the name of the analyzer that reported the message, if known
+	Analyzer string
 }
 
 // Unstructured returns this message as a JSON-style unstructured map
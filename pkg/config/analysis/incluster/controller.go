@@ -40,8 +40,8 @@ import (
 // Controller manages repeatedly running analyzers in istiod, and reporting results
 // via istio status fields.
 type Controller struct {
-	analyzer  *local.IstiodAnalyzer
-	statusctl *status.Controller
+	analyzer *local.IstiodAnalyzer
+	status   *StatusWriter
 }
 
 func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreController,
@@ -63,22 +63,12 @@ func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreControll
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize analysis controller, releasing lease: %s", err)
 	}
-	ctl := statusManager.CreateIstioStatusController(func(status *v1alpha1.IstioStatus, context any) *v1alpha1.IstioStatus {
-		msgs := context.(diag.Messages)
-		// zero out analysis messages, as this is the sole controller for those
-		status.ValidationMessages = []*v1alpha12.AnalysisMessageBase{}
-		for _, msg := range msgs {
-			status.ValidationMessages = append(status.ValidationMessages, msg.AnalysisMessageBase())
-		}
-		return status
-	})
-	return &Controller{analyzer: ia, statusctl: ctl}, nil
+	return &Controller{analyzer: ia, status: NewStatusWriter(statusManager)}, nil
 }
 
 // Run is blocking
 func (c *Controller) Run(stop <-chan struct{}) {
 	t := time.NewTicker(features.AnalysisInterval)
-	oldmsgs := diag.Messages{}
 	for {
 		select {
 		case <-t.C:
@@ -87,34 +77,62 @@ func (c *Controller) Run(stop <-chan struct{}) {
 				log.Errorf("In-cluster analysis has failed: %s", err)
 				continue
 			}
-			// reorganize messages to map
-			index := map[status.Resource]diag.Messages{}
-			for _, m := range res.Messages {
-				key := status.ResourceFromMetadata(m.Resource.Metadata)
-				index[key] = append(index[key], m)
-			}
-			// if we previously had a message that has been removed, ensure it is removed
-			// TODO: this creates a state destruction problem when istiod crashes
-			// in that old messages may not be removed.  Not sure how to fix this
-			// other than write every object's status every loop.
-			for _, m := range oldmsgs {
-				key := status.ResourceFromMetadata(m.Resource.Metadata)
-				if _, ok := index[key]; !ok {
-					index[key] = diag.Messages{}
-				}
-			}
-			for r, m := range index {
-				// don't try to write status for non-istio types
-				if strings.HasSuffix(r.Group, "istio.io") {
-					log.Debugf("enqueueing update for %s/%s", r.Namespace, r.Name)
-					c.statusctl.EnqueueStatusUpdateResource(m, r)
-				}
-			}
-			oldmsgs = res.Messages
-			log.Debugf("finished enqueueing all statuses")
+			c.status.Write(res.Messages)
 		case <-stop:
 			t.Stop()
 			return
 		}
 	}
 }
+
+// StatusWriter writes the analysis messages to the validation messages of the status of the Istio resources.
+type StatusWriter struct {
+	statusctl *status.Controller
+	// previous are the messages last written, so that the messages resolved since are removed.
+	previous diag.Messages
+}
+
+// NewStatusWriter returns a StatusWriter writing the status with the status manager. It must be the sole writer of
+// the validation messages.
+func NewStatusWriter(statusManager *status.Manager) *StatusWriter {
+	ctl := statusManager.CreateIstioStatusController(func(status *v1alpha1.IstioStatus, context any) *v1alpha1.IstioStatus {
+		msgs := context.(diag.Messages)
+		// zero out analysis messages, as this is the sole controller for those
+		status.ValidationMessages = []*v1alpha12.AnalysisMessageBase{}
+		for _, msg := range msgs {
+			status.ValidationMessages = append(status.ValidationMessages, msg.AnalysisMessageBase())
+		}
+		return status
+	})
+	return &StatusWriter{statusctl: ctl}
+}
+
+// Write enqueues the status updates of the resources with messages, and of the resources whose messages were all
+// resolved since the previous write.
+func (w *StatusWriter) Write(msgs diag.Messages) {
+	// reorganize messages to map
+	index := map[status.Resource]diag.Messages{}
+	for _, m := range msgs {
+		key := status.ResourceFromMetadata(m.Resource.Metadata)
+		index[key] = append(index[key], m)
+	}
+	// if we previously had a message that has been removed, ensure it is removed
+	// TODO: this creates a state destruction problem when istiod crashes
+	// in that old messages may not be removed.  Not sure how to fix this
+	// other than write every object's status every loop.
+	for _, m := range w.previous {
+		key := status.ResourceFromMetadata(m.Resource.Metadata)
+		if _, ok := index[key]; !ok {
+			index[key] = diag.Messages{}
+		}
+	}
+	for r, m := range index {
+		// don't try to write status for non-istio types
+		if strings.HasSuffix(r.Group, "istio.io") {
+			log.Debugf("enqueueing update for %s/%s", r.Namespace, r.Name)
+			w.statusctl.EnqueueStatusUpdateResource(m, r)
+		}
+	}
+	w.previous = msgs
+	log.Debugf("finished enqueueing all statuses")
+}
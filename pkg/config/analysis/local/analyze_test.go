@@ -108,6 +108,7 @@ func TestAnalyzersRun(t *testing.T) {
 
 	result, err := sa.Analyze(cancel)
 	g.Expect(err).To(BeNil())
+	m.Analyzer = a.Metadata().Name
 	g.Expect(result.Messages).To(ConsistOf(m))
 	g.Expect(collectionAccessed).To(Equal(K8SCollection1.Name()))
 	g.Expect(result.ExecutedAnalyzers).To(ConsistOf(a.Metadata().Name))
@@ -135,6 +136,7 @@ func TestFilterOutputByNamespace(t *testing.T) {
 
 	result, err := sa.Analyze(cancel)
 	g.Expect(err).To(BeNil())
+	msg1.Analyzer = a.Metadata().Name
 	g.Expect(result.Messages).To(ConsistOf(msg1))
 }
 
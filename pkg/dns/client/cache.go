@@ -0,0 +1,240 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// CacheOptions configures the cache of upstream responses.
+type CacheOptions struct {
+	// MaxEntries is the number of responses cached. The cache is disabled if unset.
+	MaxEntries int
+	// MaxTTL caps the time responses are cached for, regardless of their TTL. Unlimited if unset.
+	MaxTTL time.Duration
+}
+
+// CacheEntry is a cached upstream response, as shown by the debug endpoint.
+type CacheEntry struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Rcode   string    `json:"rcode"`
+	Answers []string  `json:"answers,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// dnsCache caches upstream responses for the lowest TTL of their records. Negative responses are cached
+// for the TTL of the SOA record in their authority section, as described in RFC 2308, and are not cached
+// if there is none. When full, the least recently used response is evicted.
+type dnsCache struct {
+	opts CacheOptions
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+
+	// now is overridden in tests
+	now func() time.Time
+}
+
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	// dnssec is set for queries with the DNSSEC OK bit, which are answered with additional records.
+	dnssec bool
+}
+
+type cacheItem struct {
+	key     cacheKey
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+func newDNSCache(opts CacheOptions) *dnsCache {
+	if opts.MaxEntries <= 0 {
+		return nil
+	}
+	return &dnsCache{
+		opts:    opts,
+		entries: map[cacheKey]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+func newCacheKey(req *dns.Msg) cacheKey {
+	q := req.Question[0]
+	key := cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}
+	if opt := req.IsEdns0(); opt != nil {
+		key.dnssec = opt.Do()
+	}
+	return key
+}
+
+// get returns the cached response to the request, with TTLs reduced by the time spent in the cache.
+func (c *dnsCache) get(req *dns.Msg) *dns.Msg {
+	key := newCacheKey(req)
+	now := c.now()
+	c.mu.Lock()
+	el, f := c.entries[key]
+	if f && !now.Before(el.Value.(*cacheItem).expires) {
+		c.remove(el)
+		f = false
+	}
+	if !f {
+		c.mu.Unlock()
+		cacheLookups.With(hitTag.Value("false")).Increment()
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	item := el.Value.(*cacheItem)
+	c.mu.Unlock()
+	cacheLookups.With(hitTag.Value("true")).Increment()
+
+	resp := item.msg.Copy()
+	resp.Id = req.Id
+	elapsed := uint32(now.Sub(item.stored) / time.Second)
+	for _, rrs := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if rr.Header().Ttl > elapsed {
+				rr.Header().Ttl -= elapsed
+			} else {
+				rr.Header().Ttl = 0
+			}
+		}
+	}
+	return resp
+}
+
+// put caches the upstream response to the request, if it is cacheable.
+func (c *dnsCache) put(req *dns.Msg, resp *dns.Msg) {
+	if resp.Truncated || (resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError) {
+		return
+	}
+	ttl, ok := responseTTL(resp)
+	if !ok || ttl == 0 {
+		return
+	}
+	expiry := time.Duration(ttl) * time.Second
+	if c.opts.MaxTTL > 0 && expiry > c.opts.MaxTTL {
+		expiry = c.opts.MaxTTL
+	}
+	key := newCacheKey(req)
+	now := c.now()
+	item := &cacheItem{key: key, msg: resp.Copy(), stored: now, expires: now.Add(expiry)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, f := c.entries[key]; f {
+		el.Value = item
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(item)
+	for c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+	cacheEntries.Record(float64(c.lru.Len()))
+}
+
+// remove must be called with the lock held.
+func (c *dnsCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheItem).key)
+	cacheEntries.Record(float64(c.lru.Len()))
+}
+
+// responseTTL returns the time in seconds the response can be cached for. Positive responses are cached
+// for the lowest TTL of their records, negative ones for the TTL of the SOA record of the zone.
+func responseTTL(resp *dns.Msg) (uint32, bool) {
+	if resp.Rcode == dns.RcodeNameError || len(resp.Answer) == 0 {
+		for _, rr := range resp.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				if soa.Minttl < soa.Hdr.Ttl {
+					return soa.Minttl, true
+				}
+				return soa.Hdr.Ttl, true
+			}
+		}
+		return 0, false
+	}
+	var ttl uint32
+	found := false
+	for _, rrs := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range rrs {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			if !found || rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+				found = true
+			}
+		}
+	}
+	return ttl, found
+}
+
+// list returns the cached responses that have not expired, sorted by name.
+func (c *dnsCache) list() []CacheEntry {
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]CacheEntry, 0, c.lru.Len())
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		item := el.Value.(*cacheItem)
+		if !now.Before(item.expires) {
+			continue
+		}
+		entry := CacheEntry{
+			Name:    item.key.name,
+			Type:    dns.TypeToString[item.key.qtype],
+			Rcode:   dns.RcodeToString[item.msg.Rcode],
+			Expires: item.expires,
+		}
+		for _, rr := range item.msg.Answer {
+			entry.Answers = append(entry.Answers, rr.String())
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
+
+// flush removes all cached responses, returning the number removed.
+func (c *dnsCache) flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.lru.Len()
+	c.entries = map[cacheKey]*list.Element{}
+	c.lru.Init()
+	cacheEntries.Record(0)
+	return n
+}
@@ -0,0 +1,237 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// fakeUpstream answers queries with the response built by respond, counting the queries received.
+type fakeUpstream struct {
+	respond func(req *dns.Msg) *dns.Msg
+	queries int
+}
+
+func (u *fakeUpstream) exchange(_ context.Context, req *dns.Msg) (*dns.Msg, error) {
+	u.queries++
+	return u.respond(req), nil
+}
+
+func (u *fakeUpstream) protocol() string {
+	return "fake"
+}
+
+func (u *fakeUpstream) close() {}
+
+func soa(zone string, ttl, minttl uint32) dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      "ns." + zone,
+		Mbox:    "hostmaster." + zone,
+		Minttl:  minttl,
+		Refresh: 3600,
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	ip := []netip.Addr{netip.MustParseAddr("1.1.1.1")}
+	cases := []struct {
+		name     string
+		response func(req *dns.Msg) *dns.Msg
+		// cachedFor is how long the response is expected to be cached for, zero if not cached.
+		cachedFor time.Duration
+	}{
+		{
+			name: "positive response cached for lowest ttl",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetReply(req)
+				resp.Answer = append(cname(req.Question[0].Name, "target.example.com."), a("target.example.com.", ip)...)
+				resp.Answer[1].Header().Ttl = 10
+				return resp
+			},
+			cachedFor: 10 * time.Second,
+		},
+		{
+			name: "ttl capped by max ttl",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetReply(req)
+				resp.Answer = a(req.Question[0].Name, ip)
+				resp.Answer[0].Header().Ttl = 3600
+				return resp
+			},
+			cachedFor: time.Minute,
+		},
+		{
+			name: "nxdomain cached for soa minimum",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetRcode(req, dns.RcodeNameError)
+				resp.Ns = []dns.RR{soa("example.com.", 300, 20)}
+				return resp
+			},
+			cachedFor: 20 * time.Second,
+		},
+		{
+			name: "nodata cached for soa ttl",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetReply(req)
+				resp.Ns = []dns.RR{soa("example.com.", 15, 30)}
+				return resp
+			},
+			cachedFor: 15 * time.Second,
+		},
+		{
+			name: "nxdomain without soa not cached",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetRcode(req, dns.RcodeNameError)
+				return resp
+			},
+		},
+		{
+			name: "server failure not cached",
+			response: func(req *dns.Msg) *dns.Msg {
+				return serverFailure(req)
+			},
+		},
+		{
+			name: "truncated response not cached",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetReply(req)
+				resp.Answer = a(req.Question[0].Name, ip)
+				resp.Truncated = true
+				return resp
+			},
+		},
+		{
+			name: "zero ttl not cached",
+			response: func(req *dns.Msg) *dns.Msg {
+				resp := new(dns.Msg)
+				resp.SetReply(req)
+				resp.Answer = a(req.Question[0].Name, ip)
+				resp.Answer[0].Header().Ttl = 0
+				return resp
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			up := &fakeUpstream{respond: tt.response}
+			h := &LocalDNSServer{
+				upstreams: []upstream{up},
+				cache:     newDNSCache(CacheOptions{MaxEntries: 10, MaxTTL: time.Minute}),
+			}
+			h.cache.now = func() time.Time { return now }
+			query := func() *dns.Msg {
+				req := new(dns.Msg)
+				req.SetQuestion("www.Example.com.", dns.TypeA)
+				resp := h.upstream(nil, req, "www.example.com.")
+				if resp.Id != req.Id {
+					t.Fatalf("expected response id %v, got %v", req.Id, resp.Id)
+				}
+				return resp
+			}
+
+			first := query()
+			now = now.Add(time.Second)
+			second := query()
+			if tt.cachedFor == 0 {
+				if up.queries != 2 {
+					t.Fatalf("expected response not to be cached, got %d upstream queries", up.queries)
+				}
+				return
+			}
+			if up.queries != 1 {
+				t.Fatalf("expected response to be cached, got %d upstream queries", up.queries)
+			}
+			for i, rr := range second.Answer {
+				if got, want := rr.Header().Ttl, first.Answer[i].Header().Ttl-1; got != want {
+					t.Fatalf("expected cached ttl %d, got %d", want, got)
+				}
+			}
+
+			now = now.Add(tt.cachedFor - 2*time.Second)
+			query()
+			if up.queries != 1 {
+				t.Fatalf("expected response to be cached until expiry, got %d upstream queries", up.queries)
+			}
+			now = now.Add(time.Second)
+			query()
+			if up.queries != 2 {
+				t.Fatalf("expected response to expire, got %d upstream queries", up.queries)
+			}
+		})
+	}
+}
+
+func TestDNSCacheEviction(t *testing.T) {
+	c := newDNSCache(CacheOptions{MaxEntries: 2})
+	ip := []netip.Addr{netip.MustParseAddr("1.1.1.1")}
+	put := func(name string) {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = a(name, ip)
+		c.put(req, resp)
+	}
+	cached := func(name string) bool {
+		req := new(dns.Msg)
+		req.SetQuestion(name, dns.TypeA)
+		return c.get(req) != nil
+	}
+
+	put("a.com.")
+	put("b.com.")
+	// Use a.com., so that b.com. is the least recently used
+	if !cached("a.com.") {
+		t.Fatalf("expected a.com. to be cached")
+	}
+	put("c.com.")
+	if cached("b.com.") || !cached("a.com.") || !cached("c.com.") {
+		t.Fatalf("expected b.com. to be evicted, got %v", c.list())
+	}
+
+	entries := c.list()
+	if len(entries) != 2 || entries[0].Name != "a.com." || entries[1].Name != "c.com." || entries[0].Type != "A" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if n := c.flush(); n != 2 {
+		t.Fatalf("expected 2 entries flushed, got %d", n)
+	}
+	if cached("a.com.") || len(c.list()) != 0 {
+		t.Fatalf("expected cache to be empty after flush")
+	}
+}
+
+func TestDNSCacheDisabled(t *testing.T) {
+	if newDNSCache(CacheOptions{}) != nil {
+		t.Fatalf("expected no cache without max entries")
+	}
+	h := &LocalDNSServer{}
+	if h.CacheEntries() != nil || h.FlushCache() != 0 {
+		t.Fatalf("expected no entries without cache")
+	}
+}
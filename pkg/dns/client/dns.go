@@ -20,6 +20,7 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -48,7 +49,12 @@ type LocalDNSServer struct {
 	dnsProxies []*dnsProxy
 
 	resolvConfServers []string
-	searchNamespaces  []string
+	// upstreams are the encrypted nameservers to forward to. If set, resolvConfServers are not used.
+	upstreams        []upstream
+	upstreamTimeout  time.Duration
+	searchNamespaces []string
+	// cache holds upstream responses, if enabled
+	cache *dnsCache
 	// The namespace where the proxy resides
 	// determines the hosts used for shortname resolution
 	proxyNamespace string
@@ -77,6 +83,11 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+	// The key is a SRV query name of a named port (like _http._tcp.productpage.ns1.), for each variant
+	// of the hosts above.
+	srv map[string][]dns.RR
+	// The key is the reverse name of an IP of the hosts above (like 4.3.2.1.in-addr.arpa.).
+	ptr map[string][]dns.RR
 }
 
 const (
@@ -86,10 +97,28 @@ const (
 	defaultTTLInSeconds = 30
 )
 
-func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool) (*LocalDNSServer, error) {
+// NewLocalDNSServer creates a DNS proxy listening on addr. Queries for hosts not in the name table are forwarded
+// to the nameservers in resolv.conf, or to the encrypted upstreams if any are set. Upstreams are either
+// tls://host[:port] for DNS-over-TLS or https://host[:port]/path for DNS-over-HTTPS. Upstream responses are
+// cached according to cacheOptions.
+func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool,
+	upstreams []string, upstreamTimeout time.Duration, cacheOptions CacheOptions,
+) (*LocalDNSServer, error) {
+	if upstreamTimeout <= 0 {
+		upstreamTimeout = defaultUpstreamTimeout
+	}
 	h := &LocalDNSServer{
 		proxyNamespace:            proxyNamespace,
 		forwardToUpstreamParallel: forwardToUpstreamParallel,
+		upstreamTimeout:           upstreamTimeout,
+		cache:                     newDNSCache(cacheOptions),
+	}
+	for _, addr := range upstreams {
+		u, err := newUpstream(addr, upstreamTimeout, nil)
+		if err != nil {
+			return nil, err
+		}
+		h.upstreams = append(h.upstreams, u)
 	}
 
 	registerStats()
@@ -143,7 +172,7 @@ func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardT
 		h.searchNamespaces = dnsConfig.Search
 	}
 
-	log.WithLabels("search", h.searchNamespaces, "servers", h.resolvConfServers).Debugf("initialized DNS")
+	log.WithLabels("search", h.searchNamespaces, "servers", h.resolvConfServers, "upstreams", upstreams).Debugf("initialized DNS")
 
 	if addr == "" {
 		addr = "localhost:15053"
@@ -192,8 +221,12 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		srv:      map[string][]dns.RR{},
+		ptr:      map[string][]dns.RR{},
 	}
 	h.BuildAlternateHosts(nt, lookupTable.buildDNSAnswers)
+	lookupTable.buildSRVAnswers(nt, h.altHosts)
+	lookupTable.buildPTRAnswers(nt)
 	h.lookupTable.Store(lookupTable)
 	h.nameTable.Store(nt)
 	log.Debugf("updated lookup table with %d hosts", len(lookupTable.allHosts))
@@ -205,40 +238,70 @@ func (h *LocalDNSServer) BuildAlternateHosts(nt *dnsProto.NameTable,
 	apply func(map[string]struct{}, []netip.Addr, []netip.Addr, []string),
 ) {
 	for hostname, ni := range nt.Table {
-		// Given a host
-		// if its a non-k8s host, store the host+. as the key with the pre-computed DNS RR records
-		// if its a k8s host, store all variants (i.e. shortname+., shortname+namespace+., fqdn+., etc.)
-		// shortname+. is only for hosts in current namespace
-		var altHosts sets.String
-		if ni.Registry == string(provider.Kubernetes) {
-			altHosts = generateAltHosts(hostname, ni, h.proxyNamespace, h.proxyDomain, h.proxyDomainParts)
-		} else {
-			if !strings.HasSuffix(hostname, ".") {
-				hostname += "."
-			}
-			altHosts = sets.New(hostname)
-		}
 		ipv4, ipv6 := netutil.ParseIPsSplitToV4V6(ni.Ips)
 		if len(ipv6) == 0 && len(ipv4) == 0 {
 			// malformed ips
 			continue
 		}
-		apply(altHosts, ipv4, ipv6, h.searchNamespaces)
+		apply(h.altHosts(hostname, ni), ipv4, ipv6, h.searchNamespaces)
+	}
+}
+
+// altHosts returns the names a host in the name table is resolved for.
+func (h *LocalDNSServer) altHosts(hostname string, ni *dnsProto.NameTable_NameInfo) sets.String {
+	// Given a host
+	// if its a non-k8s host, store the host+. as the key with the pre-computed DNS RR records
+	// if its a k8s host, store all variants (i.e. shortname+., shortname+namespace+., fqdn+., etc.)
+	// shortname+. is only for hosts in current namespace
+	if ni.Registry == string(provider.Kubernetes) {
+		return generateAltHosts(hostname, ni, h.proxyNamespace, h.proxyDomain, h.proxyDomainParts)
 	}
+	return sets.New(dns.Fqdn(hostname))
 }
 
-// upstream sends the request to the upstream server, with associated logs and metrics
+// upstream sends the request to the upstream server, unless the response is cached, with associated logs
 func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string) *dns.Msg {
-	upstreamRequests.Increment()
-	start := time.Now()
+	if h.cache != nil {
+		if response := h.cache.get(req); response != nil {
+			log.Debugf("cached upstream response for hostname %q : %v", hostname, response)
+			return response
+		}
+	}
 	// We did not find the host in our internal cache. Query upstream and return the response as is.
 	log.Debugf("response for hostname %q not found in dns proxy, querying upstream", hostname)
-	response := h.queryUpstream(proxy.upstreamClient, req, log)
-	requestDuration.Record(time.Since(start).Seconds())
+	response := h.queryUpstream(h.upstreamServers(proxy), req, log)
 	log.Debugf("upstream response for hostname %q : %v", hostname, response)
+	if h.cache != nil {
+		h.cache.put(req, response)
+	}
 	return response
 }
 
+// upstreamServers returns the nameservers to forward requests received by the proxy to.
+func (h *LocalDNSServer) upstreamServers(proxy *dnsProxy) []upstream {
+	if len(h.upstreams) > 0 {
+		return h.upstreams
+	}
+	servers := make([]upstream, 0, len(h.resolvConfServers))
+	for _, s := range h.resolvConfServers {
+		servers = append(servers, &plainUpstream{client: proxy.upstreamClient, addr: s})
+	}
+	return servers
+}
+
+// exchange sends the request to a single upstream server, with associated metrics
+func exchange(ctx context.Context, u upstream, req *dns.Msg) (*dns.Msg, error) {
+	protocol := protocolTag.Value(u.protocol())
+	upstreamRequests.With(protocol).Increment()
+	start := time.Now()
+	response, err := u.exchange(ctx, req)
+	requestDuration.With(protocol).Record(time.Since(start).Seconds())
+	if err != nil {
+		failures.With(protocol).Increment()
+	}
+	return response, err
+}
+
 // ServeDNS is the implementation of DNS interface
 func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dns.Msg) {
 	requests.Increment()
@@ -322,6 +385,22 @@ func (h *LocalDNSServer) NameTable() *dnsProto.NameTable {
 	return lt.(*dnsProto.NameTable)
 }
 
+// CacheEntries returns the cached upstream responses, or nil if the cache is disabled.
+func (h *LocalDNSServer) CacheEntries() []CacheEntry {
+	if h.cache == nil {
+		return nil
+	}
+	return h.cache.list()
+}
+
+// FlushCache removes all cached upstream responses, returning the number removed.
+func (h *LocalDNSServer) FlushCache() int {
+	if h.cache == nil {
+		return 0
+	}
+	return h.cache.flush()
+}
+
 // Inspired by https://github.com/coredns/coredns/blob/master/plugin/loadbalance/loadbalance.go
 func roundRobinResponse(res *dns.Msg) {
 	if res.Rcode != dns.RcodeSuccess {
@@ -388,17 +467,20 @@ func (h *LocalDNSServer) Close() {
 	for _, p := range h.dnsProxies {
 		p.close()
 	}
+	for _, u := range h.upstreams {
+		u.close()
+	}
 }
 
-func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+func (h *LocalDNSServer) queryUpstream(servers []upstream, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
 	if h.forwardToUpstreamParallel {
-		return h.queryUpstreamParallel(upstreamClient, req, scope)
+		return h.queryUpstreamParallel(servers, req, scope)
 	}
 
 	var response *dns.Msg
 
-	for _, upstream := range h.resolvConfServers {
-		cResponse, _, err := upstreamClient.Exchange(req, upstream)
+	for _, upstream := range servers {
+		cResponse, err := exchange(context.Background(), upstream, req)
 		if err == nil {
 			response = cResponse
 			break
@@ -422,7 +504,7 @@ func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg,
 //     response—or defer to the operating system, which we have no control over.
 //   - systemd-resolved: which is used as a default resolver in many Linux distributions nowadays also performs parallel
 //     lookups for multiple DNS servers and returns the first successful response.
-func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+func (h *LocalDNSServer) queryUpstreamParallel(servers []upstream, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
 	// Guarantee that the ctx we use below is done when this function returns.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -430,9 +512,8 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 	responseCh := make(chan *dns.Msg)
 	errCh := make(chan error)
 
-	queryOne := func(upstream string) {
-		// Note: After DialContext in ExchangeContext is called, this function cannot be cancelled by context.
-		cResponse, _, err := upstreamClient.ExchangeContext(ctx, req, upstream)
+	queryOne := func(upstream upstream) {
+		cResponse, err := exchange(ctx, upstream, req)
 		if err == nil {
 			// Only reserve first response and ignore others.
 			select {
@@ -448,7 +529,7 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 		}
 	}
 
-	for _, upstream := range h.resolvConfServers {
+	for _, upstream := range servers {
 		go queryOne(upstream)
 	}
 
@@ -461,7 +542,7 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 		case <-errCh:
 			errorsCount++
 			// All servers returned error - return failure.
-			if errorsCount == len(h.resolvConfServers) {
+			if errorsCount == len(servers) {
 				scope.Infof("all upstream failed")
 				return serverFailure(req)
 			}
@@ -510,6 +591,17 @@ func generateAltHosts(hostname string, nameinfo *dnsProto.NameTable_NameInfo, pr
 func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, bool) {
 	var hostFound bool
 
+	switch qtype {
+	case dns.TypeSRV:
+		if answers, f := table.srv[hostname]; f {
+			return answers, true
+		}
+	case dns.TypePTR:
+		// Reverse lookups of IPs that are not in the mesh are sent upstream.
+		answers, f := table.ptr[hostname]
+		return answers, f
+	}
+
 	question := host.Name(hostname)
 	wildcard := false
 	// First check if host exists in all hosts.
@@ -550,7 +642,6 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 	case dns.TypeAAAA:
 		ipAnswers = table.name6[hostname]
 	default:
-		// TODO: handle PTR records for reverse dns lookups
 		return nil, false
 	}
 
@@ -626,6 +717,72 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 	}
 }
 
+// buildSRVAnswers stores SRV records for the named ports of services, as _port._protocol.host. for each name
+// the service is resolved for, following the Kubernetes DNS specification. The records of headless services
+// with named pods, such as StatefulSets, point to each of the pods. Others point to the service itself.
+func (table *LookupTable) buildSRVAnswers(nt *dnsProto.NameTable,
+	altHosts func(string, *dnsProto.NameTable_NameInfo) sets.String,
+) {
+	pods := map[string][]string{}
+	for hostname, ni := range nt.Table {
+		// Pods of headless services are named hostname.subdomain, the subdomain being the service name.
+		if ni.Registry == string(provider.Kubernetes) && strings.Count(ni.Shortname, ".") == 1 {
+			_, svc, _ := strings.Cut(strings.TrimSuffix(hostname, "."), ".")
+			pods[svc] = append(pods[svc], dns.Fqdn(strings.ToLower(hostname)))
+		}
+	}
+	for hostname, ni := range nt.Table {
+		if len(ni.Ports) == 0 || strings.HasPrefix(hostname, "*") {
+			continue
+		}
+		targets := pods[strings.TrimSuffix(hostname, ".")]
+		if len(targets) == 0 {
+			targets = []string{dns.Fqdn(strings.ToLower(hostname))}
+		}
+		sort.Strings(targets)
+		hosts := altHosts(hostname, ni)
+		for _, port := range ni.Ports {
+			prefix := "_" + port.Name + "._" + port.Protocol + "."
+			for h := range hosts {
+				name := strings.ToLower(prefix + h)
+				table.srv[name] = srv(name, targets, port.Number)
+			}
+		}
+	}
+}
+
+// buildPTRAnswers stores PTR records for the IPs of hosts, so that reverse lookups of mesh IPs resolve to
+// them. Where several hosts share an IP, the most specific one, such as the pod of a headless service
+// rather than the service, comes first.
+func (table *LookupTable) buildPTRAnswers(nt *dnsProto.NameTable) {
+	hosts := map[string]sets.String{}
+	for hostname, ni := range nt.Table {
+		if strings.HasPrefix(hostname, "*") {
+			continue
+		}
+		for _, ip := range ni.Ips {
+			reverse, err := dns.ReverseAddr(ip)
+			if err != nil {
+				continue
+			}
+			if hosts[reverse] == nil {
+				hosts[reverse] = sets.New[string]()
+			}
+			hosts[reverse].Insert(dns.Fqdn(strings.ToLower(hostname)))
+		}
+	}
+	for reverse, names := range hosts {
+		targets := names.UnsortedList()
+		sort.Slice(targets, func(i, j int) bool {
+			if li, lj := dns.CountLabel(targets[i]), dns.CountLabel(targets[j]); li != lj {
+				return li > lj
+			}
+			return targets[i] < targets[j]
+		})
+		table.ptr[reverse] = ptr(reverse, targets)
+	}
+}
+
 // Borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hosts.go
 // a takes a slice of ip string and returns a slice of A RRs.
 func a(host string, ips []netip.Addr) []dns.RR {
@@ -663,6 +820,36 @@ func cname(host string, targetHost string) []dns.RR {
 	return []dns.RR{answer}
 }
 
+// srv returns SRV records for the port of each of the targets, weighted equally.
+func srv(host string, targets []string, port uint32) []dns.RR {
+	weight := uint16(100 / len(targets))
+	if weight == 0 {
+		weight = 1
+	}
+	answers := make([]dns.RR, len(targets))
+	for i, target := range targets {
+		r := new(dns.SRV)
+		r.Hdr = dns.RR_Header{Name: host, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: defaultTTLInSeconds}
+		r.Weight = weight
+		r.Port = uint16(port)
+		r.Target = target
+		answers[i] = r
+	}
+	return answers
+}
+
+// ptr returns PTR records pointing to each of the targets.
+func ptr(reverse string, targets []string) []dns.RR {
+	answers := make([]dns.RR, len(targets))
+	for i, target := range targets {
+		r := new(dns.PTR)
+		r.Hdr = dns.RR_Header{Name: reverse, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: defaultTTLInSeconds}
+		r.Ptr = target
+		answers[i] = r
+	}
+	return answers
+}
+
 // Size returns if buffer size *advertised* in the requests OPT record.
 // Or when the request was over TCP, we return the maximum allowed size of 64K.
 func size(proto string, r *dns.Msg) int {
@@ -49,6 +49,8 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 		expectResolutionFailure  int
 		expectExternalResolution bool
 		modifyReq                func(msg *dns.Msg)
+		// plainUpstreamOnly is set for cases relying on the upstream being queried over UDP.
+		plainUpstreamOnly bool
 	}{
 		{
 			name:     "success: non k8s host in local cache",
@@ -219,6 +221,7 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 			host: "giant.",
 			// Upstream UDP server returns big response, we cannot serve it. Compliant server would truncate it.
 			expectResolutionFailure: dns.RcodeServerFailure,
+			plainUpstreamOnly:       true,
 		},
 		{
 			name:     "tcp: large request",
@@ -275,6 +278,9 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 			if (strings.HasPrefix(tt.name, "udp") || strings.HasPrefix(tt.name, "tcp")) && !strings.HasPrefix(tt.name, clients[i].Net) {
 				continue
 			}
+			if tt.plainUpstreamOnly && len(d.upstreams) > 0 {
+				continue
+			}
 			t.Run(clients[i].Net+"-"+tt.name, func(t *testing.T) {
 				m := new(dns.Msg)
 				q := dns.TypeA
@@ -399,7 +405,7 @@ var giantResponse = func() []dns.RR {
 	return a("aaaaaaaaaaaa.aaaaaa.", ips)
 }()
 
-func makeUpstream(t test.Failer, responses map[string]string) string {
+func upstreamMux(t test.Failer, responses map[string]string) *dns.ServeMux {
 	mux := dns.NewServeMux()
 	mux.HandleFunc(".", func(resp dns.ResponseWriter, msg *dns.Msg) {
 		answer := new(dns.Msg)
@@ -442,6 +448,11 @@ func makeUpstream(t test.Failer, responses map[string]string) string {
 			t.Fatalf("err: %s", err)
 		}
 	})
+	return mux
+}
+
+func makeUpstream(t test.Failer, responses map[string]string) string {
+	mux := upstreamMux(t, responses)
 	up := make(chan struct{})
 
 	tcp := &dns.Server{
@@ -495,13 +506,14 @@ func makeUpstream(t test.Failer, responses map[string]string) string {
 	return server.Addr
 }
 
-func initDNS(t test.Failer, forwardToUpstreamParallel bool) *LocalDNSServer {
+func initDNS(t test.Failer, forwardToUpstreamParallel bool, upstreams ...upstream) *LocalDNSServer {
 	srv := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
-	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel)
+	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel, nil, 0, CacheOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
 	testAgentDNS.resolvConfServers = []string{srv}
+	testAgentDNS.upstreams = upstreams
 	testAgentDNS.StartDNS()
 	testAgentDNS.searchNamespaces = []string{"ns1.svc.cluster.local", "svc.cluster.local", "cluster.local"}
 	testAgentDNS.UpdateLookupTable(&dnsProto.NameTable{
@@ -580,3 +592,166 @@ func equalsDNSrecords(got []dns.RR, want []dns.RR) bool {
 	}
 	return reflect.DeepEqual(got, want)
 }
+
+func TestSRVAndPTR(t *testing.T) {
+	d := &LocalDNSServer{
+		proxyNamespace:   "ns1",
+		proxyDomain:      "svc.cluster.local",
+		proxyDomainParts: []string{"svc", "cluster", "local"},
+	}
+	ports := []*dnsProto.NameTable_NameInfo_Port{
+		{Name: "http", Number: 9080, Protocol: "tcp"},
+		{Name: "dns", Number: 53, Protocol: "udp"},
+	}
+	d.UpdateLookupTable(&dnsProto.NameTable{
+		Table: map[string]*dnsProto.NameTable_NameInfo{
+			"productpage.ns1.svc.cluster.local": {
+				Ips:       []string{"9.9.9.9", "2001:db8::1"},
+				Registry:  "Kubernetes",
+				Namespace: "ns1",
+				Shortname: "productpage",
+				Ports:     ports,
+			},
+			"mysql.ns2.svc.cluster.local": {
+				Ips:       []string{"10.0.0.1", "10.0.0.2"},
+				Registry:  "Kubernetes",
+				Namespace: "ns2",
+				Shortname: "mysql",
+				Ports:     []*dnsProto.NameTable_NameInfo_Port{{Name: "tcp-mysql", Number: 3306, Protocol: "tcp"}},
+			},
+			"mysql-0.mysql.ns2.svc.cluster.local": {
+				Ips:       []string{"10.0.0.1"},
+				Registry:  "Kubernetes",
+				Namespace: "ns2",
+				Shortname: "mysql-0.mysql",
+			},
+			"mysql-1.mysql.ns2.svc.cluster.local": {
+				Ips:       []string{"10.0.0.2"},
+				Registry:  "Kubernetes",
+				Namespace: "ns2",
+				Shortname: "mysql-1.mysql",
+			},
+			"example.com": {
+				Ips:      []string{"1.1.1.1"},
+				Registry: "External",
+				Ports:    []*dnsProto.NameTable_NameInfo_Port{{Name: "https", Number: 443, Protocol: "tcp"}},
+			},
+			"*.wildcard.com": {
+				Ips:      []string{"2.2.2.2"},
+				Registry: "External",
+				Ports:    []*dnsProto.NameTable_NameInfo_Port{{Name: "https", Number: 443, Protocol: "tcp"}},
+			},
+		},
+	})
+	table := d.lookupTable.Load().(*LookupTable)
+
+	srvRecord := func(name, target string, port, weight uint16) dns.RR {
+		return &dns.SRV{
+			Hdr:    dns.RR_Header{Name: name, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: defaultTTLInSeconds},
+			Weight: weight,
+			Port:   port,
+			Target: target,
+		}
+	}
+	ptrRecord := func(name string, target string) dns.RR {
+		return &dns.PTR{
+			Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: defaultTTLInSeconds},
+			Ptr: target,
+		}
+	}
+	cases := []struct {
+		name     string
+		qtype    uint16
+		host     string
+		expected []dns.RR
+		found    bool
+	}{
+		{
+			name:     "srv for service",
+			qtype:    dns.TypeSRV,
+			host:     "_http._tcp.productpage.ns1.svc.cluster.local.",
+			expected: []dns.RR{srvRecord("_http._tcp.productpage.ns1.svc.cluster.local.", "productpage.ns1.svc.cluster.local.", 9080, 100)},
+			found:    true,
+		},
+		{
+			name:     "srv for udp port of service short name",
+			qtype:    dns.TypeSRV,
+			host:     "_dns._udp.productpage.",
+			expected: []dns.RR{srvRecord("_dns._udp.productpage.", "productpage.ns1.svc.cluster.local.", 53, 100)},
+			found:    true,
+		},
+		{
+			name:  "srv for wrong protocol",
+			qtype: dns.TypeSRV,
+			host:  "_http._udp.productpage.ns1.svc.cluster.local.",
+		},
+		{
+			name:  "srv for headless service pods",
+			qtype: dns.TypeSRV,
+			host:  "_tcp-mysql._tcp.mysql.ns2.",
+			expected: []dns.RR{
+				srvRecord("_tcp-mysql._tcp.mysql.ns2.", "mysql-0.mysql.ns2.svc.cluster.local.", 3306, 50),
+				srvRecord("_tcp-mysql._tcp.mysql.ns2.", "mysql-1.mysql.ns2.svc.cluster.local.", 3306, 50),
+			},
+			found: true,
+		},
+		{
+			name:     "srv for service entry",
+			qtype:    dns.TypeSRV,
+			host:     "_https._tcp.example.com.",
+			expected: []dns.RR{srvRecord("_https._tcp.example.com.", "example.com.", 443, 100)},
+			found:    true,
+		},
+		{
+			name:  "no srv for wildcard",
+			qtype: dns.TypeSRV,
+			host:  "_https._tcp.*.wildcard.com.",
+		},
+		{
+			name:     "ptr for service",
+			qtype:    dns.TypePTR,
+			host:     "9.9.9.9.in-addr.arpa.",
+			expected: []dns.RR{ptrRecord("9.9.9.9.in-addr.arpa.", "productpage.ns1.svc.cluster.local.")},
+			found:    true,
+		},
+		{
+			name:  "ptr for ipv6",
+			qtype: dns.TypePTR,
+			host:  "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			expected: []dns.RR{ptrRecord("1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+				"productpage.ns1.svc.cluster.local.")},
+			found: true,
+		},
+		{
+			name:  "ptr prefers pod of headless service",
+			qtype: dns.TypePTR,
+			host:  "1.0.0.10.in-addr.arpa.",
+			expected: []dns.RR{
+				ptrRecord("1.0.0.10.in-addr.arpa.", "mysql-0.mysql.ns2.svc.cluster.local."),
+				ptrRecord("1.0.0.10.in-addr.arpa.", "mysql.ns2.svc.cluster.local."),
+			},
+			found: true,
+		},
+		{
+			name:  "ptr for non mesh ip",
+			qtype: dns.TypePTR,
+			host:  "8.8.8.8.in-addr.arpa.",
+		},
+		{
+			name:  "no ptr for wildcard",
+			qtype: dns.TypePTR,
+			host:  "2.2.2.2.in-addr.arpa.",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := table.lookupHost(tt.qtype, tt.host)
+			if found != tt.found {
+				t.Fatalf("expected found=%v, got %v", tt.found, found)
+			}
+			if !equalsDNSrecords(got, tt.expected) {
+				t.Fatalf("dns responses for %s do not match. \n got %v\nwant %v", tt.host, got, tt.expected)
+			}
+		})
+	}
+}
@@ -19,6 +19,10 @@ import (
 )
 
 var (
+	// protocolTag is the protocol used to reach the upstream nameserver: udp, tcp, dot or doh.
+	protocolTag = monitoring.MustCreateLabel("protocol")
+	hitTag      = monitoring.MustCreateLabel("hit")
+
 	requests = monitoring.NewSum(
 		"dns_requests_total",
 		"Total number of DNS requests.",
@@ -27,17 +31,31 @@ var (
 	upstreamRequests = monitoring.NewSum(
 		"dns_upstream_requests_total",
 		"Total number of DNS requests forwarded to upstream.",
+		monitoring.WithLabels(protocolTag),
 	)
 
 	failures = monitoring.NewSum(
 		"dns_upstream_failures_total",
-		"Total number of DNS requests forwarded to upstream.",
+		"Total number of DNS requests forwarded to upstream that failed.",
+		monitoring.WithLabels(protocolTag),
 	)
 
 	requestDuration = monitoring.NewDistribution(
 		"dns_upstream_request_duration_seconds",
 		"Total time in seconds Istio takes to get DNS response from upstream.",
 		[]float64{.005, .001, 0.01, 0.1, 1, 5},
+		monitoring.WithLabels(protocolTag),
+	)
+
+	cacheLookups = monitoring.NewSum(
+		"dns_cache_lookups_total",
+		"Total number of lookups of upstream responses in the DNS cache.",
+		monitoring.WithLabels(hitTag),
+	)
+
+	cacheEntries = monitoring.NewGauge(
+		"dns_cache_entries",
+		"Number of upstream responses in the DNS cache.",
 	)
 )
 
@@ -46,4 +64,6 @@ func registerStats() {
 	monitoring.MustRegister(upstreamRequests)
 	monitoring.MustRegister(failures)
 	monitoring.MustRegister(requestDuration)
+	monitoring.MustRegister(cacheLookups)
+	monitoring.MustRegister(cacheEntries)
 }
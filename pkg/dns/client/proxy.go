@@ -16,7 +16,6 @@ package client
 
 import (
 	"net"
-	"time"
 
 	"github.com/miekg/dns"
 )
@@ -38,9 +37,9 @@ func newDNSProxy(protocol, addr string, resolver *LocalDNSServer) (*dnsProxy, er
 		server:   &dns.Server{},
 		upstreamClient: &dns.Client{
 			Net:          protocol,
-			DialTimeout:  5 * time.Second,
-			ReadTimeout:  5 * time.Second,
-			WriteTimeout: 5 * time.Second,
+			DialTimeout:  resolver.upstreamTimeout,
+			ReadTimeout:  resolver.upstreamTimeout,
+			WriteTimeout: resolver.upstreamTimeout,
 		},
 		protocol: protocol,
 		resolver: resolver,
@@ -0,0 +1,236 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// Protocols of upstream nameservers, used to label metrics.
+	protocolDoT = "dot"
+	protocolDoH = "doh"
+
+	defaultUpstreamTimeout = 5 * time.Second
+	// maxIdleConns is the number of idle connections kept open to each encrypted upstream.
+	maxIdleConns = 8
+
+	dnsMessageContentType = "application/dns-message"
+)
+
+// upstream is a nameserver that queries for hosts not in the name table are forwarded to.
+type upstream interface {
+	exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error)
+	// protocol is the protocol used to reach the upstream, used to label metrics.
+	protocol() string
+	close()
+}
+
+// newUpstream returns an upstream for an encrypted nameserver address: tls://host[:port] for DNS-over-TLS,
+// or https://host[:port]/path for DNS-over-HTTPS. The host of the address is verified against the server
+// certificate. A nil tlsConfig uses the system roots.
+func newUpstream(addr string, timeout time.Duration, tlsConfig *tls.Config) (upstream, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q: %v", addr, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid upstream %q: missing host", addr)
+	}
+	if timeout <= 0 {
+		timeout = defaultUpstreamTimeout
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig = tlsConfig.Clone()
+	tlsConfig.ServerName = u.Hostname()
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	switch u.Scheme {
+	case "tls":
+		port := u.Port()
+		if port == "" {
+			port = "853"
+		}
+		return &dotUpstream{
+			addr: net.JoinHostPort(u.Hostname(), port),
+			client: &dns.Client{
+				Net:          "tcp-tls",
+				TLSConfig:    tlsConfig,
+				DialTimeout:  timeout,
+				ReadTimeout:  timeout,
+				WriteTimeout: timeout,
+			},
+		}, nil
+	case "https":
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		transport.ForceAttemptHTTP2 = true
+		transport.MaxIdleConnsPerHost = maxIdleConns
+		return &dohUpstream{
+			url:    u.String(),
+			client: &http.Client{Transport: transport, Timeout: timeout},
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid upstream %q: scheme must be tls or https", addr)
+	}
+}
+
+// plainUpstream is a resolv.conf nameserver, queried over the protocol of the downstream request.
+type plainUpstream struct {
+	client *dns.Client
+	addr   string
+}
+
+func (u *plainUpstream) exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error) {
+	// Note: After DialContext in ExchangeContext is called, this function cannot be cancelled by context.
+	resp, _, err := u.client.ExchangeContext(ctx, req, u.addr)
+	return resp, err
+}
+
+func (u *plainUpstream) protocol() string {
+	return u.client.Net
+}
+
+func (u *plainUpstream) close() {}
+
+// dotUpstream is a DNS-over-TLS nameserver. Connections are kept open after a query, to be reused by the
+// next ones, as the TLS handshake costs far more than the query itself.
+type dotUpstream struct {
+	addr   string
+	client *dns.Client
+
+	mu   sync.Mutex
+	idle []*dns.Conn
+}
+
+func (u *dotUpstream) exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error) {
+	conn := u.get()
+	if conn != nil {
+		resp, _, err := u.client.ExchangeWithConn(req, conn)
+		if err == nil {
+			u.put(conn)
+			return resp, nil
+		}
+		// The server may have closed the idle connection, retry on a new one.
+		_ = conn.Close()
+	}
+	conn, err := u.client.DialContext(ctx, u.addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, _, err := u.client.ExchangeWithConn(req, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	u.put(conn)
+	return resp, nil
+}
+
+func (u *dotUpstream) get() *dns.Conn {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.idle) == 0 {
+		return nil
+	}
+	conn := u.idle[len(u.idle)-1]
+	u.idle = u.idle[:len(u.idle)-1]
+	return conn
+}
+
+func (u *dotUpstream) put(conn *dns.Conn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.idle) >= maxIdleConns {
+		_ = conn.Close()
+		return
+	}
+	u.idle = append(u.idle, conn)
+}
+
+func (u *dotUpstream) protocol() string {
+	return protocolDoT
+}
+
+func (u *dotUpstream) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, conn := range u.idle {
+		_ = conn.Close()
+	}
+	u.idle = nil
+}
+
+// dohUpstream is a DNS-over-HTTPS nameserver, queried with POST requests as described in RFC 8484.
+// Connections are reused by the HTTP transport.
+type dohUpstream struct {
+	url    string
+	client *http.Client
+}
+
+func (u *dohUpstream) exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error) {
+	// RFC 8484 recommends a zero ID, so that equal queries are cacheable by HTTP caches.
+	msg := req.Copy()
+	msg.Id = 0
+	body, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	hreq.Header.Set("Content-Type", dnsMessageContentType)
+	hreq.Header.Set("Accept", dnsMessageContentType)
+	hresp, err := u.client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer hresp.Body.Close()
+	if hresp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream %s returned status %d", u.url, hresp.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(hresp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	resp := new(dns.Msg)
+	if err := resp.Unpack(b); err != nil {
+		return nil, err
+	}
+	resp.Id = req.Id
+	return resp, nil
+}
+
+func (u *dohUpstream) protocol() string {
+	return protocolDoH
+}
+
+func (u *dohUpstream) close() {
+	u.client.CloseIdleConnections()
+}
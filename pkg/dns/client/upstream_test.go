@@ -0,0 +1,202 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestDNSOverTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	srv.Close()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	up := make(chan struct{})
+	server := &dns.Server{
+		Listener:          l,
+		Net:               "tcp-tls",
+		Handler:           upstreamMux(t, map[string]string{"www.bing.com.": "1.1.1.1"}),
+		NotifyStartedFunc: func() { close(up) },
+	}
+	go func() {
+		if err := server.ActivateAndServe(); err != nil {
+			log.Warnf("listen error: %v", err)
+		}
+	}()
+	select {
+	case <-time.After(time.Second * 10):
+		t.Fatalf("setup timeout")
+	case <-up:
+	}
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	d := initDNS(t, false, newTestUpstream(t, "tls://127.0.0.1:"+port, srv.Certificate()))
+	testDNS(t, d)
+}
+
+func TestDNSOverHTTPS(t *testing.T) {
+	mux := upstreamMux(t, map[string]string{"www.bing.com.": "1.1.1.1"})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req := new(dns.Msg)
+		if err := req.Unpack(b); err != nil || req.Id != 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw := &recordingResponseWriter{}
+		mux.ServeDNS(rw, req)
+		out, err := rw.msg.Pack()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", dnsMessageContentType)
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(srv.Close)
+
+	d := initDNS(t, true, newTestUpstream(t, srv.URL+"/dns-query", srv.Certificate()))
+	testDNS(t, d)
+}
+
+func TestNewUpstream(t *testing.T) {
+	cases := []struct {
+		addr     string
+		protocol string
+		target   string
+	}{
+		{addr: "tls://1.1.1.1", protocol: protocolDoT, target: "1.1.1.1:853"},
+		{addr: "tls://dns.example.com:8853", protocol: protocolDoT, target: "dns.example.com:8853"},
+		{addr: "https://dns.example.com/dns-query", protocol: protocolDoH, target: "https://dns.example.com/dns-query"},
+		{addr: "udp://1.1.1.1"},
+		{addr: "tls://"},
+		{addr: "1.1.1.1"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.addr, func(t *testing.T) {
+			u, err := newUpstream(tt.addr, 0, nil)
+			if tt.protocol == "" {
+				if err == nil {
+					t.Fatalf("expected error for %v", tt.addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.protocol() != tt.protocol {
+				t.Fatalf("expected protocol %v, got %v", tt.protocol, u.protocol())
+			}
+			var target string
+			switch u := u.(type) {
+			case *dotUpstream:
+				target = u.addr
+			case *dohUpstream:
+				target = u.url
+			}
+			if target != tt.target {
+				t.Fatalf("expected target %v, got %v", tt.target, target)
+			}
+		})
+	}
+}
+
+func TestDNSOverTLSReconnects(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	srv.Close()
+	l, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	mux := upstreamMux(t, map[string]string{"www.bing.com.": "1.1.1.1"})
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// Answer a single query on each connection, then close it.
+			conn := &dns.Conn{Conn: c}
+			req, err := conn.ReadMsg()
+			if err == nil {
+				rw := &recordingResponseWriter{}
+				mux.ServeDNS(rw, req)
+				_ = conn.WriteMsg(rw.msg)
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	u := newTestUpstream(t, "tls://127.0.0.1:"+port, srv.Certificate())
+	for i := 0; i < 3; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("www.bing.com.", dns.TypeA)
+		resp, err := u.exchange(context.Background(), req)
+		if err != nil {
+			t.Fatalf("query %d failed: %v", i, err)
+		}
+		if len(resp.Answer) != 1 {
+			t.Fatalf("unexpected response %v", resp)
+		}
+	}
+}
+
+func newTestUpstream(t *testing.T, addr string, cert *x509.Certificate) upstream {
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	u, err := newUpstream(addr, time.Second, &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(u.close)
+	return u
+}
+
+// recordingResponseWriter captures the response written by a dns.Handler.
+type recordingResponseWriter struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (w *recordingResponseWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/protocol"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	netutil "istio.io/istio/pkg/util/net"
 )
@@ -117,6 +118,7 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 		nameInfo := &dnsProto.NameTable_NameInfo{
 			Ips:      addressList,
 			Registry: string(svc.Attributes.ServiceRegistry),
+			Ports:    nameTablePorts(svc.Ports),
 		}
 		if svc.Attributes.ServiceRegistry == provider.Kubernetes &&
 			!strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
@@ -132,3 +134,23 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 	}
 	return out
 }
+
+// nameTablePorts returns the named ports of a service, which SRV records are built for.
+func nameTablePorts(ports model.PortList) []*dnsProto.NameTable_NameInfo_Port {
+	var out []*dnsProto.NameTable_NameInfo_Port
+	for _, port := range ports {
+		if port.Name == "" {
+			continue
+		}
+		transport := "tcp"
+		if port.Protocol == protocol.UDP {
+			transport = "udp"
+		}
+		out = append(out, &dnsProto.NameTable_NameInfo_Port{
+			Name:     port.Name,
+			Number:   uint32(port.Port),
+			Protocol: transport,
+		})
+	}
+	return out
+}
@@ -136,6 +136,16 @@ func TestNameTable(t *testing.T) {
 				Port:     8000,
 				Protocol: protocol.HTTP,
 			},
+			&model.Port{
+				Name:     "udp-port",
+				Port:     5353,
+				Protocol: protocol.UDP,
+			},
+			// Unnamed ports have no SRV records
+			&model.Port{
+				Port:     53,
+				Protocol: protocol.UDP,
+			},
 		},
 		Resolution: model.ClientSideLB,
 		Attributes: model.ServiceAttributes{
@@ -200,6 +210,8 @@ func TestNameTable(t *testing.T) {
 	sepush.AddServiceInstances(headlessServiceForServiceEntry,
 		makeServiceInstances(pod4, headlessServiceForServiceEntry, "", ""))
 
+	headlessPorts := []*dnsProto.NameTable_NameInfo_Port{{Name: "tcp-port", Number: 9000, Protocol: "tcp"}}
+
 	cases := []struct {
 		name                       string
 		proxy                      *model.Proxy
@@ -242,6 +254,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -275,6 +288,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -314,6 +328,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -354,6 +369,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -369,6 +385,11 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "wildcard-svc",
 						Namespace: "testns",
+						Ports: []*dnsProto.NameTable_NameInfo_Port{
+							{Name: "tcp-port", Number: 9000, Protocol: "tcp"},
+							{Name: "http-port", Number: 8000, Protocol: "tcp"},
+							{Name: "udp-port", Number: 5353, Protocol: "udp"},
+						},
 					},
 				},
 			},
@@ -390,6 +411,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "9.6.7.8", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -403,6 +425,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -416,6 +439,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
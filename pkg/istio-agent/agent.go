@@ -51,6 +51,7 @@ import (
 	gca "istio.io/istio/security/pkg/nodeagent/caclient/providers/google"
 	cas "istio.io/istio/security/pkg/nodeagent/caclient/providers/google-cas"
 	"istio.io/istio/security/pkg/nodeagent/sds"
+	"istio.io/istio/security/pkg/stsservice/jwtsvid"
 	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
 )
@@ -96,6 +97,9 @@ type Agent struct {
 	sdsServer   *sds.Server
 	secretCache *cache.SecretManagerClient
 
+	// jwtSVIDServer serves the JWT-SVIDs of the workload identity to the applications.
+	jwtSVIDServer *jwtsvid.Server
+
 	// Used when proxying envoy xds via istio-agent is enabled.
 	xdsProxy    *XdsProxy
 	fileWatcher filewatcher.FileWatcher
@@ -124,6 +128,12 @@ type AgentOptions struct {
 	DNSAddr string
 	// DNSForwardParallel indicates whether the agent should send parallel DNS queries to all upstream nameservers.
 	DNSForwardParallel bool
+	// DNSUpstreams are the encrypted nameservers the DNS proxy forwards to, instead of the ones in resolv.conf.
+	DNSUpstreams []string
+	// DNSUpstreamTimeout is the timeout of queries forwarded to upstream nameservers.
+	DNSUpstreamTimeout time.Duration
+	// DNSCache configures the cache of upstream responses of the DNS proxy.
+	DNSCache dnsClient.CacheOptions
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -343,6 +353,9 @@ func (a *Agent) Run(ctx context.Context) (func(), error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to start SDS server: %v", err)
 		}
+		if err = a.initJWTSVIDServer(); err != nil {
+			return nil, fmt.Errorf("failed to start JWT-SVID server: %v", err)
+		}
 	}
 	a.xdsProxy, err = initXdsProxy(a)
 	if err != nil {
@@ -435,6 +448,24 @@ func (a *Agent) initSdsServer() error {
 	return nil
 }
 
+// initJWTSVIDServer serves the JWT-SVIDs of the workload identity on localhost, if enabled.
+func (a *Agent) initJWTSVIDServer() error {
+	if a.secOpts.JWTSVIDPort <= 0 {
+		return nil
+	}
+	localHostAddr := localHostIPv4
+	if a.cfg.IsIPv6 {
+		localHostAddr = localHostIPv6
+	}
+	var err error
+	a.jwtSVIDServer, err = jwtsvid.NewServer(jwtsvid.Config{
+		LocalHostAddr: localHostAddr,
+		LocalPort:     a.secOpts.JWTSVIDPort,
+		TTL:           a.secOpts.JWTSVIDTTL,
+	}, a.secretCache)
+	return err
+}
+
 // getWorkloadCerts will attempt to get a cert, with infinite exponential backoff
 // It will not return until both workload cert and root cert are generated.
 //
@@ -490,7 +521,8 @@ func (a *Agent) initLocalDNSServer() (err error) {
 	// we don't need dns server on gateways
 	if a.cfg.DNSCapture && a.cfg.ProxyType == model.SidecarProxy {
 		if a.localDNSServer, err = dnsClient.NewLocalDNSServer(a.cfg.ProxyNamespace, a.cfg.ProxyDomain, a.cfg.DNSAddr,
-			a.cfg.DNSForwardParallel); err != nil {
+			a.cfg.DNSForwardParallel, a.cfg.DNSUpstreams, a.cfg.DNSUpstreamTimeout,
+			a.cfg.DNSCache); err != nil {
 			return err
 		}
 		a.localDNSServer.StartDNS()
@@ -536,6 +568,22 @@ func (a *Agent) Check() (err error) {
 	return nil
 }
 
+// GetDNSCache returns the upstream responses cached by the DNS proxy, or nil if there is no cache.
+func (a *Agent) GetDNSCache() []dnsClient.CacheEntry {
+	if a.localDNSServer == nil {
+		return nil
+	}
+	return a.localDNSServer.CacheEntries()
+}
+
+// FlushDNSCache removes the upstream responses cached by the DNS proxy, returning the number removed.
+func (a *Agent) FlushDNSCache() int {
+	if a.localDNSServer == nil {
+		return 0
+	}
+	return a.localDNSServer.FlushCache()
+}
+
 // GetDNSTable builds DNS table used in debugging interface.
 func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 	if a.localDNSServer != nil && a.localDNSServer.NameTable() != nil {
@@ -573,6 +621,9 @@ func (a *Agent) close() {
 	if a.sdsServer != nil {
 		a.sdsServer.Stop()
 	}
+	if a.jwtSVIDServer != nil {
+		a.jwtSVIDServer.Stop()
+	}
 	if a.secretCache != nil {
 		a.secretCache.Close()
 	}
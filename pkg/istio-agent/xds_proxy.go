@@ -29,6 +29,7 @@ import (
 	"sync"
 	"time"
 
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"go.uber.org/atomic"
 	"golang.org/x/net/http2"
@@ -187,6 +188,17 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 			return ia.secretCache.UpdateConfigTrustBundle(trustBundle)
 		}
 	}
+	if ia.secretCache != nil {
+		proxy.handlers[v3.CertificateRevocationListType] = func(resp *anypb.Any) error {
+			secret := &tlsv3.Secret{}
+			if err := resp.UnmarshalTo(secret); err != nil {
+				log.Errorf("failed to unmarshal certificate revocation list: %v", err)
+				return err
+			}
+			ia.secretCache.UpdateCRL(secret.GetValidationContext().GetCrl().GetInlineBytes())
+			return nil
+		}
+	}
 
 	proxyLog.Infof("Initializing with upstream address %q and cluster %q", proxy.istiodAddress, proxy.clusterID)
 
@@ -463,6 +475,12 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 						TypeUrl: v3.ProxyConfigType,
 					})
 				}
+				// fire off an initial CRL request
+				if _, f := p.handlers[v3.CertificateRevocationListType]; f {
+					con.sendRequest(&discovery.DiscoveryRequest{
+						TypeUrl: v3.CertificateRevocationListType,
+					})
+				}
 				// set flag before sending the initial request to prevent race.
 				initialRequestsSent.Store(true)
 				// Fire of a configured initial request, if there is one
@@ -567,16 +585,14 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 }
 
 func (p *XdsProxy) rewriteAndForward(con *ProxyConnection, resp *discovery.DiscoveryResponse, forward func(resp *discovery.DiscoveryResponse)) {
-	sendNack := wasm.MaybeConvertWasmExtensionConfig(resp.Resources, p.wasmCache)
-	if sendNack {
+	if err := wasm.MaybeConvertWasmExtensionConfig(resp.Resources, p.wasmCache); err != nil {
 		proxyLog.Debugf("sending NACK for ECDS resources %+v", resp.Resources)
 		con.sendRequest(&discovery.DiscoveryRequest{
 			VersionInfo:   p.ecdsLastAckVersion.Load(),
 			TypeUrl:       v3.ExtensionConfigurationType,
 			ResponseNonce: resp.Nonce,
 			ErrorDetail: &google_rpc.Status{
-				// TODO(bianpengyuan): make error message more informative.
-				Message: "failed to fetch wasm module",
+				Message: fmt.Sprintf("failed to fetch wasm module: %v", err),
 			},
 		})
 		return
@@ -94,6 +94,12 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream xds.DeltaDiscoveryStream)
 						TypeUrl: v3.ProxyConfigType,
 					})
 				}
+				// fire off an initial CRL request
+				if _, f := p.handlers[v3.CertificateRevocationListType]; f {
+					con.sendDeltaRequest(&discovery.DeltaDiscoveryRequest{
+						TypeUrl: v3.CertificateRevocationListType,
+					})
+				}
 				// Fire of a configured initial request, if there is one
 				if initialRequest != nil {
 					con.sendDeltaRequest(initialRequest)
@@ -284,15 +290,13 @@ func (p *XdsProxy) deltaRewriteAndForward(con *ProxyConnection, resp *discovery.
 	for i := range resp.Resources {
 		resources = append(resources, resp.Resources[i].Resource)
 	}
-	sendNack := wasm.MaybeConvertWasmExtensionConfig(resources, p.wasmCache)
-	if sendNack {
+	if err := wasm.MaybeConvertWasmExtensionConfig(resources, p.wasmCache); err != nil {
 		proxyLog.Debugf("sending NACK for ECDS resources %+v", resp.Resources)
 		con.sendDeltaRequest(&discovery.DeltaDiscoveryRequest{
 			TypeUrl:       v3.ExtensionConfigurationType,
 			ResponseNonce: resp.Nonce,
 			ErrorDetail: &google_rpc.Status{
-				// TODO(bianpengyuan): make error message more informative.
-				Message: "failed to fetch wasm module",
+				Message: fmt.Sprintf("failed to fetch wasm module: %v", err),
 			},
 		})
 		return
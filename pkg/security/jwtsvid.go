@@ -0,0 +1,31 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"time"
+)
+
+// JWTSVIDResponse holds a JWT-SVID signed by the CA, served as JSON to the workloads by the agent.
+type JWTSVIDResponse struct {
+	SpiffeID  string    `json:"spiffeId"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// JWTSVIDClient is implemented by the CA clients able to fetch JWT-SVIDs.
+type JWTSVIDClient interface {
+	FetchJWTSVID(audience []string, ttl time.Duration) (*JWTSVIDResponse, error)
+}
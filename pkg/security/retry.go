@@ -27,12 +27,15 @@ import (
 
 var caLog = log.RegisterScope("ca", "ca client", 0)
 
+// CARetryCodes are the codes of the failed CA calls retried by CARetryOptions.
+var CARetryCodes = []codes.Code{codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unavailable}
+
 // CARetryOptions returns the default retry options recommended for CA calls
 // This includes 5 retries, with backoff from 100ms -> 1.6s with jitter.
 var CARetryOptions = []retry.CallOption{
 	retry.WithMax(5),
 	retry.WithBackoff(wrapBackoffWithMetrics(retry.BackoffExponentialWithJitter(100*time.Millisecond, 0.1))),
-	retry.WithCodes(codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unavailable),
+	retry.WithCodes(CARetryCodes...),
 }
 
 // CARetryInterceptor is a grpc UnaryInterceptor that adds retry options, as a convenience wrapper
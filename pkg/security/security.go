@@ -100,6 +100,12 @@ const (
 	// JWT is a Credential fetcher type that reads from a JWT token file
 	JWT = "JWT"
 
+	// Exec is a Credential fetcher type that runs a credential helper
+	Exec = "Exec"
+
+	// OIDCTokenFile is a Credential fetcher type that caches the OIDC token of a file, refreshed by another process
+	OIDCTokenFile = "OIDCTokenFile"
+
 	// Mock is Credential fetcher type of mock plugin
 	Mock = "Mock" // testing only
 
@@ -169,6 +175,18 @@ type Options struct {
 	// OutputKeyCertToDir is the directory for output the key and certificate
 	OutputKeyCertToDir string
 
+	// OutputKeyCertFileMode is the permission of the key and certificate files output to OutputKeyCertToDir.
+	// Defaults to 0600, or 0644 on Kubernetes.
+	OutputKeyCertFileMode os.FileMode
+
+	// OutputKeyCertRotationSignal is sent to the process of OutputKeyCertRotationPIDFile when the key and
+	// certificate output to OutputKeyCertToDir are rotated, so that it reloads them.
+	OutputKeyCertRotationSignal  string
+	OutputKeyCertRotationPIDFile string
+
+	// OutputKeyCertRotationURL is POSTed to when the key and certificate output to OutputKeyCertToDir are rotated.
+	OutputKeyCertRotationURL string
+
 	// ProvCert is the directory for client to provide the key and certificate to CA server when authenticating
 	// with mTLS. This is not used for workload mTLS communication, and is
 	ProvCert string
@@ -209,6 +227,12 @@ type Options struct {
 	// STS port
 	STSPort int
 
+	// JWTSVIDPort is the local port serving the JWT-SVIDs of the workload identity. Disabled if 0.
+	JWTSVIDPort int
+
+	// JWTSVIDTTL is the lifetime of the JWT-SVIDs requested to the CA. The CA default applies if 0.
+	JWTSVIDTTL time.Duration
+
 	// authentication provider specific plugins, will exchange the token
 	// For example exchange long lived refresh with access tokens.
 	// Used by the secret fetcher when signing CSRs.
@@ -328,6 +352,9 @@ type SecretItem struct {
 
 	RootCert []byte
 
+	// CRL is the PEM encoded certificate revocation list of the mesh CA, set for the root cert request.
+	CRL []byte
+
 	// ResourceName passed from envoy SDS discovery request.
 	// "ROOTCA" for root cert request, "default" for key/cert request.
 	ResourceName string
@@ -389,10 +416,33 @@ func (ac *AuthContext) Header(header string) []string {
 	return nil
 }
 
+// KubernetesInfo carries the Kubernetes information of a caller, as known to the authenticator.
+type KubernetesInfo struct {
+	PodName           string
+	PodNamespace      string
+	PodUID            string
+	PodServiceAccount string
+	// NodeName is only known for tokens bound to a pod by Kubernetes 1.30+.
+	NodeName string
+}
+
+// CallerAttributes are the attributes of a caller verified by its authenticator, such as the SANs of its client
+// certificate or the claims of its token. They are used to map the caller to the identities it may request.
+type CallerAttributes struct {
+	// Authenticator is the type of the authenticator of the caller.
+	Authenticator string
+	DNSNames      []string
+	URIs          []string
+	Claims        map[string]any
+}
+
 // Caller carries the identity and authentication source of a caller.
 type Caller struct {
 	AuthSource AuthSource
 	Identities []string
+
+	KubernetesInfo KubernetesInfo
+	Attributes     CallerAttributes
 }
 
 // Authenticator determines the caller identity based on request context.
@@ -415,6 +465,7 @@ func (am *AuthenticationManager) Authenticate(ctx context.Context) *Caller {
 		u, err := authn.Authenticate(req)
 		if u != nil && len(u.Identities) > 0 && err == nil {
 			securityLog.Debugf("Authentication successful through auth source %v", u.AuthSource)
+			u.Attributes.Authenticator = authn.AuthenticatorType()
 			return u
 		}
 		am.authFailMsgs = append(am.authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -170,6 +171,24 @@ func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrust
 	map[string][]*x509.Certificate, error,
 ) {
 	spiffeLog.Infof("Processing SPIFFE bundle configuration: %v", inputString)
+	config, err := ParseBundleEndpoints(inputString)
+	if err != nil {
+		return nil, err
+	}
+
+	caCertPool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SystemCertPool: %v", err)
+	}
+	for _, cert := range extraTrustedCerts {
+		caCertPool.AddCert(cert)
+	}
+	return RetrieveSpiffeBundleRootCerts(config, caCertPool, totalRetryTimeout)
+}
+
+// ParseBundleEndpoints parses SPIFFE bundle endpoints of trust domains in the format of:
+// "foo|URL1||bar|URL2||baz|URL3..."
+func ParseBundleEndpoints(inputString string) (map[string]string, error) {
 	config := make(map[string]string)
 	tuples := strings.Split(inputString, "||")
 	for _, tuple := range tuples {
@@ -181,15 +200,7 @@ func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrust
 		endpoint := items[1]
 		config[trustDomain] = endpoint
 	}
-
-	caCertPool, err := x509.SystemCertPool()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get SystemCertPool: %v", err)
-	}
-	for _, cert := range extraTrustedCerts {
-		caCertPool.AddCert(cert)
-	}
-	return RetrieveSpiffeBundleRootCerts(config, caCertPool, totalRetryTimeout)
+	return config, nil
 }
 
 // RetrieveSpiffeBundleRootCerts retrieves the trusted CA certificates from a list of SPIFFE bundle endpoints.
@@ -197,34 +208,11 @@ func RetrieveSpiffeBundleRootCertsFromStringInput(inputString string, extraTrust
 func RetrieveSpiffeBundleRootCerts(config map[string]string, caCertPool *x509.CertPool, retryTimeout time.Duration) (
 	map[string][]*x509.Certificate, error,
 ) {
-	httpClient := &http.Client{
-		Timeout: time.Second * 10,
-	}
-
 	ret := map[string][]*x509.Certificate{}
 	for trustDomain, endpoint := range config {
-		if !strings.HasPrefix(endpoint, "https://") {
-			endpoint = "https://" + endpoint
-		}
-		u, err := url.Parse(endpoint)
+		endpoint, httpClient, err := newBundleClient(endpoint, caCertPool)
 		if err != nil {
-			return nil, fmt.Errorf("failed to split the SPIFFE bundle URL: %v", err)
-		}
-
-		config := &tls.Config{
-			ServerName: u.Hostname(),
-			RootCAs:    caCertPool,
-		}
-
-		httpClient.Transport = &http.Transport{
-			Proxy:           http.ProxyFromEnvironment,
-			TLSClientConfig: config,
-			DialContext: (&net.Dialer{
-				Timeout: time.Second * 10,
-			}).DialContext,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
+			return nil, err
 		}
 
 		retryBackoffTime := firstRetryBackOffTime
@@ -257,34 +245,146 @@ func RetrieveSpiffeBundleRootCerts(config map[string]string, caCertPool *x509.Ce
 		}
 		defer resp.Body.Close()
 
-		doc := new(bundleDoc)
-		if err := json.NewDecoder(resp.Body).Decode(doc); err != nil {
-			return nil, fmt.Errorf("trust domain [%s] at URL [%s] failed to decode bundle: %v", trustDomain, endpoint, err)
+		bundle, err := decodeBundle(trustDomain, endpoint, resp.Body)
+		if err != nil {
+			return nil, err
 		}
+		// Only the last X.509 authority of the bundle is trusted. FetchBundle, used for the federated trust domains,
+		// returns all of them.
+		ret[trustDomain] = append(ret[trustDomain], bundle.X509Authorities[len(bundle.X509Authorities)-1])
+	}
+	for trustDomain, certs := range ret {
+		spiffeLog.Infof("Loaded SPIFFE trust bundle for: %v, containing %d certs", trustDomain, len(certs))
+	}
+	return ret, nil
+}
 
-		var cert *x509.Certificate
-		for i, key := range doc.Keys {
-			if key.Use == "x509-svid" {
-				if len(key.Certificates) != 1 {
-					return nil, fmt.Errorf("trust domain [%s] at URL [%s] expected 1 certificate in x509-svid entry %d; got %d",
-						trustDomain, endpoint, i, len(key.Certificates))
-				}
-				cert = key.Certificates[0]
-			}
+// Bundle is the SPIFFE bundle of a trust domain.
+type Bundle struct {
+	TrustDomain     string
+	X509Authorities []*x509.Certificate
+	// JWTAuthorities are the keys verifying the JWT-SVIDs of the trust domain.
+	JWTAuthorities []jose.JSONWebKey
+	// RefreshHint is how often the bundle should be fetched again, if set by the bundle endpoint.
+	RefreshHint time.Duration
+}
+
+// FetchBundle retrieves the SPIFFE bundle of the trust domain from its SPIFFE bundle endpoint, in a single attempt.
+// It uses the supplied cert pool to validate the endpoint. The bundle is rejected if the certificate of the endpoint
+// or one of its X.509 authorities has the SPIFFE ID of another trust domain.
+func FetchBundle(trustDomain, endpoint string, caCertPool *x509.CertPool) (*Bundle, error) {
+	endpoint, httpClient, err := newBundleClient(endpoint, caCertPool)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("calling %s failed with error: %v", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calling %s failed with unexpected status: %v", endpoint, resp.StatusCode)
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		if td := otherTrustDomain(trustDomain, resp.TLS.PeerCertificates[0]); td != "" {
+			return nil, fmt.Errorf("trust domain [%s] at URL [%s] is served with a certificate of trust domain %s",
+				trustDomain, endpoint, td)
 		}
-		if cert == nil {
-			return nil, fmt.Errorf("trust domain [%s] at URL [%s] does not provide a X509 SVID", trustDomain, endpoint)
+	}
+	bundle, err := decodeBundle(trustDomain, endpoint, resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, cert := range bundle.X509Authorities {
+		if td := otherTrustDomain(trustDomain, cert); td != "" {
+			return nil, fmt.Errorf("trust domain [%s] at URL [%s] provides an X.509 authority of trust domain %s",
+				trustDomain, endpoint, td)
 		}
-		if certs, ok := ret[trustDomain]; ok {
-			ret[trustDomain] = append(certs, cert)
-		} else {
-			ret[trustDomain] = []*x509.Certificate{cert}
+	}
+	return bundle, nil
+}
+
+// otherTrustDomain returns the trust domain of the first SPIFFE ID of the certificate which is not in the trust
+// domain, if any.
+func otherTrustDomain(trustDomain string, cert *x509.Certificate) string {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == Scheme && uri.Host != trustDomain {
+			return uri.Host
 		}
 	}
-	for trustDomain, certs := range ret {
-		spiffeLog.Infof("Loaded SPIFFE trust bundle for: %v, containing %d certs", trustDomain, len(certs))
+	return ""
+}
+
+// MarshalBundle returns the SPIFFE bundle document of the X.509 and JWT authorities, as served by a SPIFFE bundle
+// endpoint.
+func MarshalBundle(roots []*x509.Certificate, jwtAuthorities []jose.JSONWebKey, refreshHint time.Duration) ([]byte, error) {
+	doc := bundleDoc{RefreshHint: int(refreshHint.Seconds())}
+	doc.Keys = []jose.JSONWebKey{}
+	for _, root := range roots {
+		doc.Keys = append(doc.Keys, jose.JSONWebKey{
+			Key:          root.PublicKey,
+			Certificates: []*x509.Certificate{root},
+			Use:          "x509-svid",
+		})
 	}
-	return ret, nil
+	for _, key := range jwtAuthorities {
+		key.Use = "jwt-svid"
+		doc.Keys = append(doc.Keys, key)
+	}
+	return json.Marshal(doc)
+}
+
+func newBundleClient(endpoint string, caCertPool *x509.CertPool) (string, *http.Client, error) {
+	if !strings.HasPrefix(endpoint, "https://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to split the SPIFFE bundle URL: %v", err)
+	}
+
+	config := &tls.Config{
+		ServerName: u.Hostname(),
+		RootCAs:    caCertPool,
+	}
+
+	return endpoint, &http.Client{
+		Timeout: time.Second * 10,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: config,
+			DialContext: (&net.Dialer{
+				Timeout: time.Second * 10,
+			}).DialContext,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}, nil
+}
+
+func decodeBundle(trustDomain, endpoint string, body io.Reader) (*Bundle, error) {
+	doc := new(bundleDoc)
+	if err := json.NewDecoder(body).Decode(doc); err != nil {
+		return nil, fmt.Errorf("trust domain [%s] at URL [%s] failed to decode bundle: %v", trustDomain, endpoint, err)
+	}
+
+	bundle := &Bundle{TrustDomain: trustDomain, RefreshHint: time.Duration(doc.RefreshHint) * time.Second}
+	for i, key := range doc.Keys {
+		if key.Use == "x509-svid" {
+			if len(key.Certificates) != 1 {
+				return nil, fmt.Errorf("trust domain [%s] at URL [%s] expected 1 certificate in x509-svid entry %d; got %d",
+					trustDomain, endpoint, i, len(key.Certificates))
+			}
+			bundle.X509Authorities = append(bundle.X509Authorities, key.Certificates[0])
+		} else if key.Use == "jwt-svid" {
+			bundle.JWTAuthorities = append(bundle.JWTAuthorities, key)
+		}
+	}
+	if len(bundle.X509Authorities) == 0 {
+		return nil, fmt.Errorf("trust domain [%s] at URL [%s] does not provide a X509 SVID", trustDomain, endpoint)
+	}
+	return bundle, nil
 }
 
 // PeerCertVerifier is an instance to verify the peer certificate in the SPIFFE way using the retrieved root certificates.
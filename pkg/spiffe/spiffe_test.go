@@ -16,11 +16,18 @@ package spiffe
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"path/filepath"
 	"reflect"
 	"strings"
@@ -346,6 +353,147 @@ func TestRetrieveSpiffeBundleRootCertsFromStringInput(t *testing.T) {
 	}
 }
 
+func TestFetchBundle(t *testing.T) {
+	var roots []*x509.Certificate
+	for _, file := range []string{"root-cert.pem", "root-cert-alt.pem"} {
+		block, _ := pem.Decode(util.ReadFile(t, filepath.Join(env.IstioSrc, "samples/certs", file)))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, cert)
+	}
+	bundle, err := MarshalBundle(roots, nil, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write(bundle)
+	}))
+	defer server.Close()
+	caCertPool := x509.NewCertPool()
+	caCertPool.AddCert(server.Certificate())
+
+	got, err := FetchBundle("foo.com", server.Listener.Addr().String(), caCertPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TrustDomain != "foo.com" || got.RefreshHint != 5*time.Minute {
+		t.Fatalf("unexpected bundle %+v", got)
+	}
+	if len(got.X509Authorities) != len(roots) {
+		t.Fatalf("expected %d roots, got %d", len(roots), len(got.X509Authorities))
+	}
+	for i, root := range roots {
+		if !root.Equal(got.X509Authorities[i]) {
+			t.Fatalf("unexpected root %d: %v", i, got.X509Authorities[i].Subject)
+		}
+	}
+
+	if _, err := FetchBundle("foo.com", server.Listener.Addr().String(), x509.NewCertPool()); err == nil {
+		t.Fatal("expected an untrusted endpoint to fail")
+	}
+
+	// The legacy bundle endpoints only provide the last X.509 authority.
+	legacy, err := RetrieveSpiffeBundleRootCerts(map[string]string{"foo.com": server.Listener.Addr().String()}, caCertPool, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy["foo.com"]) != 1 || !legacy["foo.com"][0].Equal(roots[len(roots)-1]) {
+		t.Fatalf("expected the last root only, got %d roots", len(legacy["foo.com"]))
+	}
+}
+
+func TestFetchBundleOtherTrustDomain(t *testing.T) {
+	localRoot, localKey := newSpiffeCert(t, "spiffe://cluster.local")
+	fooRoot, _ := newSpiffeCert(t, "spiffe://foo.com")
+	testCases := []struct {
+		name        string
+		roots       []*x509.Certificate
+		serverCert  *x509.Certificate
+		serverKey   crypto.Signer
+		errContains string
+	}{
+		{
+			name:  "roots of the trust domain",
+			roots: []*x509.Certificate{fooRoot},
+		},
+		{
+			name:        "root of another trust domain",
+			roots:       []*x509.Certificate{fooRoot, localRoot},
+			errContains: "provides an X.509 authority of trust domain cluster.local",
+		},
+		{
+			name:        "endpoint of another trust domain",
+			roots:       []*x509.Certificate{fooRoot},
+			serverCert:  localRoot,
+			serverKey:   localKey,
+			errContains: "is served with a certificate of trust domain cluster.local",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle, err := MarshalBundle(tc.roots, nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				_, _ = w.Write(bundle)
+			}))
+			if tc.serverCert != nil {
+				server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{tc.serverCert.Raw}, PrivateKey: tc.serverKey}}}
+			}
+			server.StartTLS()
+			defer server.Close()
+			caCertPool := x509.NewCertPool()
+			caCertPool.AddCert(server.Certificate())
+
+			_, err = FetchBundle("foo.com", server.Listener.Addr().String(), caCertPool)
+			if tc.errContains == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Fatalf("expected error containing %q, got %v", tc.errContains, err)
+			}
+		})
+	}
+}
+
+// newSpiffeCert returns a self-signed certificate of 127.0.0.1 with the SPIFFE ID.
+func newSpiffeCert(t *testing.T, id string) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		URIs:                  []*url.URL{uri},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
 // TestVerifyPeerCert tests VerifyPeerCert is effective at the client side, using a TLS server.
 func TestGetGeneralCertPoolAndVerifyPeerCert(t *testing.T) {
 	validRootCert := string(util.ReadFile(t, validRootCertFile1))
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	extensions "istio.io/api/extensions/v1alpha1"
 	"istio.io/istio/pkg/util/sets"
@@ -62,6 +63,11 @@ type LocalFileCache struct {
 	// http fetcher fetches Wasm module with HTTP get.
 	httpFetcher *HTTPFetcher
 
+	// verifier checks the signature of fetched modules, if signatures are required.
+	verifier *signatureVerifier
+	// verifierErr is set if the signature verification keys cannot be loaded, failing all fetches.
+	verifierErr error
+
 	// directory path used to store Wasm module.
 	dir string
 
@@ -108,6 +114,9 @@ type cacheEntry struct {
 	last time.Time
 	// set of URLs referencing this entry
 	referencingURLs sets.String
+	// verifiedBy is the fingerprint of the keys the signature of the module was verified with, if any.
+	// Entries are only used if they were verified with the keys of the cache.
+	verifiedBy string
 }
 
 type cacheOptions struct {
@@ -136,6 +145,8 @@ func (o cacheOptions) sanitize() cacheOptions {
 	if o.HTTPRequestMaxRetries != 0 {
 		ret.HTTPRequestMaxRetries = o.HTTPRequestMaxRetries
 	}
+	ret.SignatureVerificationKeys = o.SignatureVerificationKeys
+	ret.SharedCacheDir = o.SharedCacheDir
 
 	return ret
 }
@@ -145,6 +156,7 @@ func (o cacheOptions) allowInsecure(host string) bool {
 }
 
 // NewLocalFileCache create a new Wasm module cache which downloads and stores Wasm module files locally.
+// Modules are also stored in options.SharedCacheDir if it is set, for the other caches of the node.
 func NewLocalFileCache(dir string, options Options) *LocalFileCache {
 	wasmLog.Debugf("LocalFileCache is created with the option\n%#v", options)
 
@@ -157,6 +169,12 @@ func NewLocalFileCache(dir string, options Options) *LocalFileCache {
 		cacheOptions: cacheOptions.sanitize(),
 		stopChan:     make(chan struct{}),
 	}
+	if options.SignatureVerificationKeys != "" {
+		cache.verifier, cache.verifierErr = newSignatureVerifier(options.SignatureVerificationKeys)
+		if cache.verifierErr != nil {
+			wasmLog.Errorf("Wasm modules will not be loaded: %v", cache.verifierErr)
+		}
+	}
 
 	go func() {
 		cache.purge()
@@ -188,16 +206,24 @@ func shouldIgnoreResourceVersion(pullPolicy extensions.PullPolicy, u *url.URL) b
 	}
 }
 
-func getModulePath(baseDir string, mkey moduleKey) (string, error) {
+func getModuleDir(baseDir string, mkey moduleKey) (string, error) {
 	sha := sha256.Sum256([]byte(mkey.name))
 	hashedName := hex.EncodeToString(sha[:])
 	moduleDir := filepath.Join(baseDir, hashedName)
 	if _, err := os.Stat(moduleDir); errors.Is(err, os.ErrNotExist) {
 		err := os.Mkdir(moduleDir, 0o755)
-		if err != nil {
+		if err != nil && !errors.Is(err, os.ErrExist) {
 			return "", err
 		}
 	}
+	return moduleDir, nil
+}
+
+func getModulePath(baseDir string, mkey moduleKey) (string, error) {
+	moduleDir, err := getModuleDir(baseDir, mkey)
+	if err != nil {
+		return "", err
+	}
 	return filepath.Join(moduleDir, fmt.Sprintf("%s.wasm", mkey.checksum)), nil
 }
 
@@ -229,14 +255,41 @@ func (c *LocalFileCache) Get(
 		c.touchEntry(key)
 		return modulePath, nil
 	}
+	if c.verifierErr != nil {
+		wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+		return "", fmt.Errorf("cannot verify signature of Wasm module %s: %v", downloadURL, c.verifierErr)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	insecure := c.allowInsecure(u.Host)
+	var fetcher *ImageFetcher
+	if u.Scheme == "oci" {
+		imgFetcherOps := ImageFetcherOption{
+			Insecure: insecure,
+		}
+		if pullSecret != nil {
+			imgFetcherOps.PullSecret = pullSecret
+		}
+		wasmLog.Debugf("fetching oci image from %s with options: %v", downloadURL, imgFetcherOps)
+		fetcher = NewImageFetcher(ctx, imgFetcherOps)
+	}
+	if c.SharedCacheDir != "" {
+		// Other agents of the node may be fetching the same module. Wait for them, as they may store it for us.
+		unlock, err := c.lockModule(ctx, key.moduleKey)
+		if err != nil {
+			return "", fmt.Errorf("could not lock Wasm module %s in the shared cache: %v", downloadURL, err)
+		}
+		defer unlock()
+		if key.checksum != "" {
+			if modulePath = c.getSharedEntry(ctx, key, u, fetcher, insecure); modulePath != "" {
+				return modulePath, nil
+			}
+		}
+	}
 	// Fetch the image now as it is not available in cache.
 	var b []byte         // Byte array of Wasm binary.
 	var dChecksum string // Hex-Encoded sha256 checksum of binary.
-	var binaryFetcher func() ([]byte, error)
-	insecure := c.allowInsecure(u.Host)
-
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	var img v1.Image
 	switch u.Scheme {
 	case "http", "https":
 		// Download the Wasm module with http fetcher.
@@ -247,18 +300,9 @@ func (c *LocalFileCache) Get(
 		}
 
 		// Get sha256 checksum and check if it is the same as provided one.
-		sha := sha256.Sum256(b)
-		dChecksum = hex.EncodeToString(sha[:])
+		dChecksum = sha256Hex(b)
 	case "oci":
-		imgFetcherOps := ImageFetcherOption{
-			Insecure: insecure,
-		}
-		if pullSecret != nil {
-			imgFetcherOps.PullSecret = pullSecret
-		}
-		wasmLog.Debugf("fetching oci image from %s with options: %v", downloadURL, imgFetcherOps)
-		fetcher := NewImageFetcher(ctx, imgFetcherOps)
-		binaryFetcher, dChecksum, err = fetcher.PrepareFetch(u.Host + u.Path)
+		img, dChecksum, err = fetcher.fetchImage(u.Host + u.Path)
 		if err != nil {
 			wasmRemoteFetchCount.With(resultTag.Value(manifestFailure)).Increment()
 			return "", fmt.Errorf("could not fetch Wasm OCI image: %v", err)
@@ -274,13 +318,38 @@ func (c *LocalFileCache) Get(
 			c.touchEntry(key)
 			return modulePath, nil
 		}
+		if c.SharedCacheDir != "" {
+			if modulePath = c.getSharedEntry(ctx, key, u, fetcher, insecure); modulePath != "" {
+				return modulePath, nil
+			}
+		}
 	} else if dChecksum != key.checksum {
 		wasmRemoteFetchCount.With(resultTag.Value(checksumMismatch)).Increment()
 		return "", fmt.Errorf("module downloaded from %v has checksum %v, which does not match: %v", downloadURL, dChecksum, key.checksum)
 	}
 
-	if binaryFetcher != nil {
-		b, err = binaryFetcher()
+	if c.verifier != nil {
+		// Images are verified before their binary is downloaded, as the signature is bound to the manifest digest.
+		if err := c.verifySignature(ctx, key, u, fetcher, b, insecure); err != nil {
+			wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+			return "", fmt.Errorf("signature verification of Wasm module %s failed: %v", downloadURL, err)
+		}
+	}
+
+	if img != nil {
+		if c.SharedCacheDir != "" {
+			// Download the whole image, to store it in the shared cache along with its manifest.
+			var shared *sharedImage
+			if shared, err = downloadImage(img, dChecksum); err == nil {
+				img = shared.image()
+				if err := c.addSharedImage(key.moduleKey, shared); err != nil {
+					wasmLog.Errorf("failed to store Wasm module %s in the shared cache: %v", downloadURL, err)
+				}
+			}
+		}
+		if err == nil {
+			b, err = extractWasmBinary(img)
+		}
 		if err != nil {
 			wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
 			return "", fmt.Errorf("could not fetch Wasm binary: %v", err)
@@ -296,6 +365,12 @@ func (c *LocalFileCache) Get(
 
 	key.checksum = dChecksum
 
+	if c.SharedCacheDir != "" && img == nil {
+		if err := c.addSharedModule(key.moduleKey, b); err != nil {
+			wasmLog.Errorf("failed to store Wasm module %s in the shared cache: %v", downloadURL, err)
+		}
+	}
+
 	modulePath, err = getModulePath(c.dir, key.moduleKey)
 	if err != nil {
 		return "", err
@@ -307,6 +382,19 @@ func (c *LocalFileCache) Get(
 	return modulePath, nil
 }
 
+// verifySignature verifies the signature of the module with the checksum of the key, which is the image fetched
+// by fetcher if set, or else the module b fetched over http/https.
+func (c *LocalFileCache) verifySignature(ctx context.Context, key cacheKey, u *url.URL, fetcher *ImageFetcher, b []byte, insecure bool) error {
+	if fetcher == nil {
+		return c.verifier.verifyHTTPModule(ctx, c.httpFetcher, key.downloadURL, b, insecure)
+	}
+	signatures, err := fetcher.FetchSignatures(u.Host+u.Path, key.checksum)
+	if err != nil {
+		return err
+	}
+	return c.verifier.verifyImage(signatures, key.checksum)
+}
+
 // Cleanup closes background Wasm module purge routine.
 func (c *LocalFileCache) Cleanup() {
 	close(c.stopChan)
@@ -340,7 +428,8 @@ func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte, f string) err
 	needChecksumUpdate := c.updateChecksum(key)
 
 	// Check if the module has already been added. If so, avoid writing the file again.
-	if ce, ok := c.modules[key.moduleKey]; ok {
+	// Modules which were not verified are written again, as the verified module may differ.
+	if ce, ok := c.modules[key.moduleKey]; ok && ce.verifiedBy == c.verificationPolicy() {
 		// Update last touched time.
 		ce.last = time.Now()
 		if needChecksumUpdate {
@@ -350,7 +439,8 @@ func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte, f string) err
 	}
 
 	// Materialize the Wasm module into a local file. Use checksum as name of the module.
-	if err := os.WriteFile(f, wasmModule, 0o644); err != nil {
+	// The file is written atomically, so that a module replaced while in use is never read partially.
+	if err := writeFileAtomically(f, wasmModule); err != nil {
 		return err
 	}
 
@@ -358,6 +448,10 @@ func (c *LocalFileCache) addEntry(key cacheKey, wasmModule []byte, f string) err
 		modulePath:      f,
 		last:            time.Now(),
 		referencingURLs: sets.New[string](),
+		verifiedBy:      c.verificationPolicy(),
+	}
+	if unverified, ok := c.modules[key.moduleKey]; ok {
+		ce.referencingURLs = unverified.referencingURLs
 	}
 	if needChecksumUpdate {
 		ce.referencingURLs.Insert(key.downloadURL)
@@ -399,7 +493,8 @@ func (c *LocalFileCache) getEntry(key cacheKey, ignoreResourceVersion bool) (str
 		}
 	}
 
-	if ce, ok := c.modules[key.moduleKey]; ok {
+	// Modules whose signature was not verified with the keys of the cache are fetched and verified again.
+	if ce, ok := c.modules[key.moduleKey]; ok && ce.verifiedBy == c.verificationPolicy() {
 		// Update last touched time.
 		ce.last = time.Now()
 		modulePath = ce.modulePath
@@ -409,6 +504,15 @@ func (c *LocalFileCache) getEntry(key cacheKey, ignoreResourceVersion bool) (str
 	return modulePath, key.checksum
 }
 
+// verificationPolicy returns the fingerprint of the keys modules must be signed with, or an empty string if
+// signatures are not verified.
+func (c *LocalFileCache) verificationPolicy() string {
+	if c.verifier == nil {
+		return ""
+	}
+	return c.verifier.fingerprint
+}
+
 // Purge periodically clean up the stale Wasm modules local file and the cache map.
 func (c *LocalFileCache) purge() {
 	ticker := time.NewTicker(c.PurgeInterval)
@@ -424,16 +528,19 @@ func (c *LocalFileCache) purge() {
 				// The module has not be touched for expiry duration, delete it from the map as well as the local dir.
 				if err := os.Remove(m.modulePath); err != nil {
 					wasmLog.Errorf("failed to purge Wasm module %v: %v", m.modulePath, err)
-				} else {
-					for downloadURL := range m.referencingURLs {
-						delete(c.checksums, downloadURL)
-					}
-					delete(c.modules, k)
-					wasmLog.Debugf("successfully removed stale Wasm module %v", m.modulePath)
+					continue
 				}
+				wasmLog.Debugf("successfully removed stale Wasm module %v", m.modulePath)
+				for downloadURL := range m.referencingURLs {
+					delete(c.checksums, downloadURL)
+				}
+				delete(c.modules, k)
 			}
 			wasmCacheEntries.Record(float64(len(c.modules)))
 			c.mux.Unlock()
+			if c.SharedCacheDir != "" {
+				c.purgeSharedDir()
+			}
 		case <-c.stopChan:
 			// Currently this will only happen in test.
 			return
@@ -447,6 +554,26 @@ func (ce *cacheEntry) expired(expiry time.Duration) bool {
 	return now.Sub(ce.last) > expiry
 }
 
+// writeFileAtomically writes data to a temporary file renamed to path, so that readers never see a partial file.
+func writeFileAtomically(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
 var wasmMagicNumber = []byte{0x00, 0x61, 0x73, 0x6d}
 
 func isValidWasmBinary(in []byte) bool {
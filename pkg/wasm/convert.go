@@ -15,6 +15,8 @@
 package wasm
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -23,7 +25,7 @@ import (
 	rbac "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
-	"go.uber.org/atomic"
+	"github.com/hashicorp/go-multierror"
 	anypb "google.golang.org/protobuf/types/known/anypb"
 
 	extensions "istio.io/api/extensions/v1alpha1"
@@ -44,11 +46,13 @@ func createAllowAllFilter(name string) (*anypb.Any, error) {
 
 // MaybeConvertWasmExtensionConfig converts any presence of module remote download to local file.
 // It downloads the Wasm module and stores the module locally in the file system.
-func MaybeConvertWasmExtensionConfig(resources []*anypb.Any, cache Cache) bool {
+// A non-nil error is returned if the resources should be rejected, describing why.
+func MaybeConvertWasmExtensionConfig(resources []*anypb.Any, cache Cache) error {
 	var wg sync.WaitGroup
 	numResources := len(resources)
 	wg.Add(numResources)
-	sendNack := atomic.NewBool(false)
+	var mu sync.Mutex
+	var nackErr *multierror.Error
 	startTime := time.Now()
 	defer func() {
 		wasmConfigConversionDuration.Record(float64(time.Since(startTime).Milliseconds()))
@@ -58,9 +62,11 @@ func MaybeConvertWasmExtensionConfig(resources []*anypb.Any, cache Cache) bool {
 		go func(i int) {
 			defer wg.Done()
 
-			newExtensionConfig, nack := convert(resources[i], cache)
-			if nack {
-				sendNack.Store(true)
+			newExtensionConfig, err := convert(resources[i], cache)
+			if err != nil {
+				mu.Lock()
+				nackErr = multierror.Append(nackErr, err)
+				mu.Unlock()
 				return
 			}
 			resources[i] = newExtensionConfig
@@ -68,13 +74,17 @@ func MaybeConvertWasmExtensionConfig(resources []*anypb.Any, cache Cache) bool {
 	}
 
 	wg.Wait()
-	return sendNack.Load()
+	return nackErr.ErrorOrNil()
 }
 
-func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, sendNack bool) {
+// convert rewrites the remote load of a Wasm extension config to a local file. A non-nil nackErr is returned
+// if the resource should be rejected.
+func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, nackErr error) {
 	ec := &core.TypedExtensionConfig{}
 	newExtensionConfig = resource
-	sendNack = false
+	sendNack := false
+	// cause is the reason the remote load could not be converted.
+	var cause error
 	status := noRemoteLoad
 	defer func() {
 		wasmConfigConversionCount.
@@ -88,8 +98,12 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 				// If the fallback is failing, send the Nack regardless of fail_open.
 				wasmLog.Infof("failed to create allow-all filter as a fallback of %s Wasm Module.", ec.GetName())
 				sendNack = true
+				cause = err
 			}
 		}
+		if sendNack {
+			nackErr = fmt.Errorf("%s: %v", ec.GetName(), cause)
+		}
 	}()
 	if err := resource.UnmarshalTo(ec); err != nil {
 		wasmLog.Debugf("failed to unmarshal extension config resource: %v", err)
@@ -146,6 +160,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 		if sec, found := envs.KeyValues[model.WasmSecretEnv]; found {
 			if sec == "" {
 				status = fetchFailure
+				cause = errors.New("missing image pulling secret")
 				wasmLog.Errorf("cannot fetch Wasm module %v: missing image pulling secret", wasmHTTPFilterConfig.Config.Name)
 				return
 			}
@@ -175,6 +190,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	httpURI := remote.GetHttpUri()
 	if httpURI == nil {
 		status = missRemoteFetchHint
+		cause = errors.New("remote load does not have httpUri specified")
 		wasmLog.Errorf("wasm remote fetch %+v does not have httpUri specified", remote)
 		return
 	}
@@ -191,6 +207,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	f, err := cache.Get(httpURI.GetUri(), remote.Sha256, wasmHTTPFilterConfig.Config.Name, resourceVersion, timeout, pullSecret, pullPolicy)
 	if err != nil {
 		status = fetchFailure
+		cause = err
 		wasmLog.Errorf("cannot fetch Wasm module %v: %v", remote.GetHttpUri().GetUri(), err)
 		return
 	}
@@ -209,6 +226,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	wasmTypedConfig, err := anypb.New(wasmHTTPFilterConfig)
 	if err != nil {
 		status = marshalFailure
+		cause = err
 		wasmLog.Errorf("failed to marshal new wasm HTTP filter %+v to protobuf Any: %v", wasmHTTPFilterConfig, err)
 		return
 	}
@@ -218,6 +236,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	nec, err := anypb.New(ec)
 	if err != nil {
 		status = marshalFailure
+		cause = err
 		wasmLog.Errorf("failed to marshal new extension config resource: %v", err)
 		return
 	}
@@ -20,6 +20,7 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -34,6 +35,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/hashicorp/go-multierror"
 )
@@ -42,7 +44,6 @@ import (
 // The spec is here https://github.com/solo-io/wasm/blob/master/spec/README.md.
 // Basically, this supports fetching and unpackaging three types of container images containing a Wasm binary.
 type ImageFetcherOption struct {
-	// TODO(mathetake) Add signature verification stuff.
 	PullSecret []byte
 	Insecure   bool
 }
@@ -90,11 +91,80 @@ func NewImageFetcher(ctx context.Context, opt ImageFetcherOption) *ImageFetcher
 // Wasm binary is not fetched immediately, but returned by `binaryFetcher` function, which is returned by PrepareFetch.
 // By this way, we can have another chance to check cache with `actualDigest` without downloading the OCI image.
 func (o *ImageFetcher) PrepareFetch(url string) (binaryFetcher func() ([]byte, error), actualDigest string, err error) {
-	ref, err := name.ParseReference(url)
+	img, actualDigest, err := o.fetchImage(url)
 	if err != nil {
-		err = fmt.Errorf("could not parse url in image reference: %v", err)
 		return
 	}
+	binaryFetcher = func() ([]byte, error) {
+		return extractWasmBinary(img)
+	}
+	return
+}
+
+// fetchImage fetches the manifest of the image at url, and returns the image with its manifest digest.
+// The layers of the image are only downloaded when they are read.
+func (o *ImageFetcher) fetchImage(url string) (v1.Image, string, error) {
+	desc, err := o.get(url)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch image.
+	img, err := desc.Image()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not fetch image: %v", err)
+	}
+
+	// Check Manifest's digest if expManifestDigest is not empty.
+	d, _ := img.Digest()
+	return img, d.Hex, nil
+}
+
+// extractWasmBinary extracts the Wasm binary from an image in any of the formats of the Wasm Image Specification.
+func extractWasmBinary(img v1.Image) ([]byte, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve manifest: %v", err)
+	}
+
+	if manifest.MediaType == types.DockerManifestSchema2 {
+		// This case, assume we have docker images with "application/vnd.docker.distribution.manifest.v2+json"
+		// as the manifest media type. Note that the media type of manifest is Docker specific and
+		// all OCI images would have an empty string in .MediaType field.
+		ret, err := extractDockerImage(img)
+		if err != nil {
+			return nil, fmt.Errorf("could not extract Wasm file from the image as Docker container %v", err)
+		}
+		return ret, nil
+	}
+
+	// We try to parse it as the "compat" variant image with a single "application/vnd.oci.image.layer.v1.tar+gzip" layer.
+	ret, errCompat := extractOCIStandardImage(img)
+	if errCompat == nil {
+		return ret, nil
+	}
+
+	// Otherwise, we try to parse it as the *oci* variant image with custom artifact media types.
+	ret, errOCI := extractOCIArtifactImage(img)
+	if errOCI == nil {
+		return ret, nil
+	}
+
+	// We failed to parse the image in any format, so wrap the errors and return.
+	return nil, fmt.Errorf("the given image is in invalid format as an OCI image: %v",
+		multierror.Append(err,
+			fmt.Errorf("could not parse as compat variant: %v", errCompat),
+			fmt.Errorf("could not parse as oci variant: %v", errOCI),
+		),
+	)
+}
+
+// get fetches the descriptor of the image at url.
+func (o *ImageFetcher) get(url string) (*remote.Descriptor, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse url in image reference: %v", err)
+	}
 	wasmLog.Infof("fetching image %s from registry %s with tag %s", ref.Context().RepositoryStr(),
 		ref.Context().RegistryStr(), ref.Identifier())
 
@@ -110,58 +180,67 @@ func (o *ImageFetcher) PrepareFetch(url string) (binaryFetcher func() ([]byte, e
 	}
 
 	if err != nil {
-		err = fmt.Errorf("could not fetch manifest: %v", err)
-		return
+		return nil, fmt.Errorf("could not fetch manifest: %w", err)
 	}
+	return desc, nil
+}
 
-	// Fetch image.
+// imageSignature is a cosign signature of an image.
+type imageSignature struct {
+	payload   []byte
+	signature []byte
+}
+
+// FetchSignatures fetches the cosign signatures of the image at url with the given manifest digest.
+// Cosign stores them in the same repository as the image, tagged with the digest of the signed manifest.
+// No signature is returned if the signature image does not exist.
+func (o *ImageFetcher) FetchSignatures(url, digest string) ([]imageSignature, error) {
+	ref, err := name.ParseReference(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse url in image reference: %v", err)
+	}
+	desc, err := o.get(ref.Context().Name() + ":sha256-" + digest + signatureSuffix)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
 	img, err := desc.Image()
 	if err != nil {
-		err = fmt.Errorf("could not fetch image: %v", err)
-		return
+		return nil, fmt.Errorf("could not fetch signature image: %v", err)
 	}
-
-	// Check Manifest's digest if expManifestDigest is not empty.
-	d, _ := img.Digest()
-	actualDigest = d.Hex
-	binaryFetcher = func() ([]byte, error) {
-		manifest, err := img.Manifest()
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve signature manifest: %v", err)
+	}
+	signatures := make([]imageSignature, 0, len(manifest.Layers))
+	for _, l := range manifest.Layers {
+		encoded, found := l.Annotations[cosignSignatureAnnotation]
+		if !found {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(encoded)
 		if err != nil {
-			return nil, fmt.Errorf("could not retrieve manifest: %v", err)
+			return nil, fmt.Errorf("signature of layer %s is not base64 encoded: %v", l.Digest, err)
 		}
-
-		if manifest.MediaType == types.DockerManifestSchema2 {
-			// This case, assume we have docker images with "application/vnd.docker.distribution.manifest.v2+json"
-			// as the manifest media type. Note that the media type of manifest is Docker specific and
-			// all OCI images would have an empty string in .MediaType field.
-			ret, err := extractDockerImage(img)
-			if err != nil {
-				return nil, fmt.Errorf("could not extract Wasm file from the image as Docker container %v", err)
-			}
-			return ret, nil
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch signature layer %s: %v", l.Digest, err)
 		}
-
-		// We try to parse it as the "compat" variant image with a single "application/vnd.oci.image.layer.v1.tar+gzip" layer.
-		ret, errCompat := extractOCIStandardImage(img)
-		if errCompat == nil {
-			return ret, nil
+		r, err := layer.Compressed()
+		if err != nil {
+			return nil, fmt.Errorf("could not get signature layer content: %v", err)
 		}
-
-		// Otherwise, we try to parse it as the *oci* variant image with custom artifact media types.
-		ret, errOCI := extractOCIArtifactImage(img)
-		if errOCI == nil {
-			return ret, nil
+		payload, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read signature layer content: %v", err)
 		}
-
-		// We failed to parse the image in any format, so wrap the errors and return.
-		return nil, fmt.Errorf("the given image is in invalid format as an OCI image: %v",
-			multierror.Append(err,
-				fmt.Errorf("could not parse as compat variant: %v", errCompat),
-				fmt.Errorf("could not parse as oci variant: %v", errOCI),
-			),
-		)
+		signatures = append(signatures, imageSignature{payload: payload, signature: sig})
 	}
-	return
+	return signatures, nil
 }
 
 // extractDockerImage extracts the Wasm binary from the
@@ -33,6 +33,13 @@ type Options struct {
 	InsecureRegistries    sets.String
 	HTTPRequestTimeout    time.Duration
 	HTTPRequestMaxRetries int
+	// SignatureVerificationKeys is the path to a file with PEM encoded public keys. When set, modules are only
+	// loaded if they are signed by one of the keys.
+	SignatureVerificationKeys string
+	// SharedCacheDir is a directory shared by the agents of a node, such as a hostPath volume. When set, modules
+	// are also stored there, so that each module is downloaded once per node. Modules read from there are checked
+	// against their checksum and signature before being copied to the agent directory.
+	SharedCacheDir string
 }
 
 func defaultOptions() Options {
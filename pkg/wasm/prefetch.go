@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+
+	clientextensions "istio.io/client-go/pkg/apis/extensions/v1alpha1"
+	"istio.io/istio/pilot/pkg/model/credentials"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+)
+
+// prefetchTimeout is the timeout of a module fetch by the Prefetcher, matching the one set by istiod in ECDS.
+const prefetchTimeout = 30 * time.Second
+
+// Prefetcher fetches the modules of WasmPlugins into a cache as soon as the plugins are created or updated,
+// rather than on the first ECDS request of a proxy using them. Used with a cache in the shared directory of
+// a node, the modules are already there when the agents of the node need them.
+type Prefetcher struct {
+	client   kube.Client
+	cache    Cache
+	queue    controllers.Queue
+	informer cache.SharedIndexInformer
+}
+
+// NewPrefetcher creates a Prefetcher of the modules of all the WasmPlugins of the cluster.
+func NewPrefetcher(client kube.Client, c Cache) *Prefetcher {
+	p := &Prefetcher{
+		client: client,
+		cache:  c,
+	}
+	p.queue = controllers.NewQueue("wasm prefetcher",
+		controllers.WithReconciler(p.prefetch),
+		controllers.WithMaxAttempts(5))
+	p.informer = client.IstioInformer().Extensions().V1alpha1().WasmPlugins().Informer()
+	p.informer.AddEventHandler(controllers.FilteredObjectSpecHandler(p.queue.AddObject, func(controllers.Object) bool {
+		return true
+	}))
+	return p
+}
+
+// Run prefetches modules until stop is closed.
+func (p *Prefetcher) Run(stop <-chan struct{}) {
+	if !kube.WaitForCacheSync(stop, p.informer.HasSynced) {
+		wasmLog.Errorf("failed to sync wasm prefetcher")
+		return
+	}
+	p.queue.Run(stop)
+}
+
+// HasSynced returns true once the modules of the existing WasmPlugins have been fetched.
+func (p *Prefetcher) HasSynced() bool {
+	return p.queue.HasSynced()
+}
+
+func (p *Prefetcher) prefetch(key types.NamespacedName) error {
+	obj, exists, err := p.informer.GetIndexer().GetByKey(key.String())
+	if err != nil || !exists {
+		// Modules of removed plugins are left to expire from the cache.
+		return err
+	}
+	plugin := obj.(*clientextensions.WasmPlugin)
+	spec := &plugin.Spec
+
+	u, err := url.Parse(spec.Url)
+	if err != nil {
+		wasmLog.Warnf("cannot prefetch Wasm module of %v: invalid url %q", key, spec.Url)
+		return nil
+	}
+	// Same defaulting as istiod: no scheme means an OCI image, and local files are not fetched.
+	if u.Scheme == "" {
+		u.Scheme = "oci"
+	}
+	if u.Scheme == "file" {
+		return nil
+	}
+
+	var pullSecret []byte
+	if spec.ImagePullSecret != "" {
+		if pullSecret, err = p.pullSecret(spec.ImagePullSecret, plugin.Namespace); err != nil {
+			return fmt.Errorf("cannot prefetch Wasm module of %v: %v", key, err)
+		}
+	}
+	path, err := p.cache.Get(u.String(), spec.Sha256, plugin.Namespace+"."+plugin.Name, plugin.ResourceVersion,
+		prefetchTimeout, pullSecret, spec.ImagePullPolicy)
+	if err != nil {
+		return fmt.Errorf("cannot prefetch Wasm module of %v: %v", key, err)
+	}
+	wasmLog.Infof("prefetched Wasm module of %v to %v", key, path)
+	return nil
+}
+
+// pullSecret returns the docker config of the image pull secret, which is in the namespace of the plugin.
+func (p *Prefetcher) pullSecret(name, namespace string) ([]byte, error) {
+	sr, err := credentials.ParseResourceName(credentials.ToResourceName(name), namespace, "", "")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := p.client.Kube().CoreV1().Secrets(namespace).Get(context.TODO(), sr.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get image pull secret %s/%s: %v", namespace, sr.Name, err)
+	}
+	if secret.Type != corev1.SecretTypeDockerConfigJson {
+		return nil, fmt.Errorf("type of image pull secret %s/%s is not %s", namespace, sr.Name, corev1.SecretTypeDockerConfigJson)
+	}
+	return secret.Data[corev1.DockerConfigJsonKey], nil
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	extensions "istio.io/api/extensions/v1alpha1"
+	clientextensions "istio.io/client-go/pkg/apis/extensions/v1alpha1"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// recordingCache records the modules fetched through it.
+type recordingCache struct {
+	mu      sync.Mutex
+	fetches map[string]string
+}
+
+func (c *recordingCache) Get(
+	downloadURL, checksum, resourceName, resourceVersion string,
+	timeout time.Duration, pullSecret []byte, pullPolicy extensions.PullPolicy,
+) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fetches[resourceName] = fmt.Sprintf("%s %s %s", downloadURL, pullSecret, pullPolicy)
+	return "/cache/" + resourceName, nil
+}
+
+func (c *recordingCache) Cleanup() {}
+
+func (c *recordingCache) fetched() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]string, len(c.fetches))
+	for k, v := range c.fetches {
+		out[k] = v
+	}
+	return out
+}
+
+func TestPrefetcher(t *testing.T) {
+	client := kube.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("docker-config")},
+	})
+	createPlugin := func(plugin *clientextensions.WasmPlugin) {
+		t.Helper()
+		if _, err := client.Istio().ExtensionsV1alpha1().WasmPlugins(plugin.Namespace).Create(
+			context.Background(), plugin, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createPlugin(&clientextensions.WasmPlugin{
+		ObjectMeta: metav1.ObjectMeta{Name: "oci", Namespace: "default"},
+		Spec: extensions.WasmPlugin{
+			Url:             "registry.example.com/plugin:v1",
+			ImagePullSecret: "pull-secret",
+			ImagePullPolicy: extensions.PullPolicy_Always,
+		},
+	})
+	createPlugin(&clientextensions.WasmPlugin{
+		ObjectMeta: metav1.ObjectMeta{Name: "local", Namespace: "default"},
+		Spec:       extensions.WasmPlugin{Url: "file:///plugin.wasm"},
+	})
+	cache := &recordingCache{fetches: map[string]string{}}
+	p := NewPrefetcher(client, cache)
+	stop := make(chan struct{})
+	defer close(stop)
+	client.RunAndWait(stop)
+	go p.Run(stop)
+
+	retry.UntilOrFail(t, p.HasSynced, retry.Timeout(time.Second*10))
+	want := map[string]string{"default.oci": "oci://registry.example.com/plugin:v1 docker-config Always"}
+	if got := cache.fetched(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected fetches %v, got %v", want, got)
+	}
+
+	// New plugins are fetched as soon as they are created.
+	createPlugin(&clientextensions.WasmPlugin{
+		ObjectMeta: metav1.ObjectMeta{Name: "http", Namespace: "ns"},
+		Spec:       extensions.WasmPlugin{Url: "https://example.com/plugin.wasm"},
+	})
+	retry.UntilSuccessOrFail(t, func() error {
+		if got := cache.fetched()["ns.http"]; got != "https://example.com/plugin.wasm  UNSPECIFIED_POLICY" {
+			return fmt.Errorf("unexpected fetch %q", got)
+		}
+		return nil
+	}, retry.Timeout(time.Second*10))
+}
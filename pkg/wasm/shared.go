@@ -0,0 +1,325 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// This file implements the shared cache mode of LocalFileCache, where the agents of a node store the modules they
+// fetch in the same directory, so that each module is downloaded once per node:
+//   - fetches of a module are serialized with an advisory lock on a file in the module directory, and agents check
+//     the directory for the module once they hold the lock.
+//   - the directory can be written by any agent of the node, so nothing read from it is trusted. Modules fetched
+//     over http/https must match their checksum, and images must match their manifest digest, as well as their
+//     blobs the digests of the manifest. The signatures are then verified as for downloaded modules, and the module
+//     is copied to the directory of the agent, where it cannot be replaced once verified.
+//   - modules are touched whenever they are read, and removed by any agent once they have not been read by any
+//     of them for the module expiry.
+// Modules fetched over http/https are stored as <checksum>.wasm in the module directory, and images as their
+// manifest, <manifest digest>.manifest, and the blobs it references, <blob digest>.blob.
+
+const (
+	// moduleLockFile is the name of the lock file in module directories.
+	moduleLockFile = ".lock"
+
+	sharedModuleSuffix   = ".wasm"
+	sharedManifestSuffix = ".manifest"
+	sharedBlobSuffix     = ".blob"
+)
+
+// lockModule serializes the fetches of a module by the agents sharing the cache directory.
+func (c *LocalFileCache) lockModule(ctx context.Context, mkey moduleKey) (func(), error) {
+	moduleDir, err := getModuleDir(c.SharedCacheDir, mkey)
+	if err != nil {
+		return nil, err
+	}
+	return lockFile(ctx, filepath.Join(moduleDir, moduleLockFile))
+}
+
+// getSharedEntry looks the module up in the shared cache directory, and adds it to the cache once verified.
+// It returns the path of the module in the cache, or an empty string if the module is not in the shared cache
+// directory or cannot be verified. fetcher is the fetcher of the image, nil for modules fetched over http/https.
+// It must be called with the module locked.
+func (c *LocalFileCache) getSharedEntry(ctx context.Context, key cacheKey, u *url.URL, fetcher *ImageFetcher, insecure bool) string {
+	moduleDir, err := getModuleDir(c.SharedCacheDir, key.moduleKey)
+	if err != nil {
+		return ""
+	}
+	b, files, err := c.readSharedModule(ctx, moduleDir, key, u, fetcher, insecure)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			wasmLog.Debugf("Wasm module %s is not in the shared cache: %v", key.downloadURL, err)
+		} else {
+			wasmLog.Warnf("ignoring Wasm module %s of the shared cache: %v", key.downloadURL, err)
+		}
+		return ""
+	}
+
+	// Touch the files of the module, so that they are not purged by other agents while it is in use.
+	now := time.Now()
+	for _, f := range files {
+		_ = os.Chtimes(f, now, now)
+	}
+	modulePath, err := getModulePath(c.dir, key.moduleKey)
+	if err == nil {
+		err = c.addEntry(key, b, modulePath)
+	}
+	if err != nil {
+		wasmLog.Errorf("failed to add Wasm module %s of the shared cache: %v", key.downloadURL, err)
+		return ""
+	}
+	return modulePath
+}
+
+// readSharedModule reads and verifies the module in the shared cache directory, and returns its binary with the
+// files it was read from.
+func (c *LocalFileCache) readSharedModule(ctx context.Context, moduleDir string, key cacheKey, u *url.URL,
+	fetcher *ImageFetcher, insecure bool,
+) ([]byte, []string, error) {
+	var b []byte
+	var img *sharedImage
+	var files []string
+	var err error
+	if fetcher == nil {
+		f := filepath.Join(moduleDir, key.checksum+sharedModuleSuffix)
+		if b, err = os.ReadFile(f); err != nil {
+			return nil, nil, err
+		}
+		if checksum := sha256Hex(b); checksum != key.checksum {
+			return nil, nil, fmt.Errorf("module has checksum %v, which does not match: %v", checksum, key.checksum)
+		}
+		files = []string{f}
+	} else {
+		if img, err = readSharedImage(moduleDir, key.checksum); err != nil {
+			return nil, nil, err
+		}
+		files = img.files(moduleDir)
+	}
+
+	// Images are verified before their binary is extracted, as the signature is bound to the manifest digest.
+	if c.verifier != nil {
+		if err := c.verifySignature(ctx, key, u, fetcher, b, insecure); err != nil {
+			return nil, nil, fmt.Errorf("signature verification failed: %v", err)
+		}
+	}
+	if img != nil {
+		if b, err = extractWasmBinary(img.image()); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !isValidWasmBinary(b) {
+		return nil, nil, errors.New("module is not a valid Wasm binary")
+	}
+	return b, files, nil
+}
+
+// addSharedModule stores a module fetched over http/https in the shared cache directory.
+func (c *LocalFileCache) addSharedModule(mkey moduleKey, b []byte) error {
+	moduleDir, err := getModuleDir(c.SharedCacheDir, mkey)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(filepath.Join(moduleDir, mkey.checksum+sharedModuleSuffix), b)
+}
+
+// addSharedImage stores an image in the shared cache directory. Its blobs are written before its manifest,
+// so that agents do not find the manifest of an image before it is complete.
+func (c *LocalFileCache) addSharedImage(mkey moduleKey, img *sharedImage) error {
+	moduleDir, err := getModuleDir(c.SharedCacheDir, mkey)
+	if err != nil {
+		return err
+	}
+	for h, b := range img.blobs {
+		if err := writeFileAtomically(filepath.Join(moduleDir, h.Hex+sharedBlobSuffix), b); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomically(filepath.Join(moduleDir, mkey.checksum+sharedManifestSuffix), img.rawManifest)
+}
+
+// sharedImage is an image whose manifest and blobs are held in memory, once they have been checked against the
+// expected manifest digest. It is not affected by changes to the files it was read from.
+type sharedImage struct {
+	rawManifest []byte
+	manifest    *v1.Manifest
+	blobs       map[v1.Hash][]byte
+}
+
+var _ partial.CompressedImageCore = &sharedImage{}
+
+// newSharedImage checks that the manifest has the given digest and the blobs returned by blob have the digest of
+// their descriptor in the manifest, and returns the image they make up.
+func newSharedImage(digest string, rawManifest []byte, blob func(v1.Descriptor) ([]byte, error)) (*sharedImage, error) {
+	if d := sha256Hex(rawManifest); d != digest {
+		return nil, fmt.Errorf("manifest has digest %v, which does not match: %v", d, digest)
+	}
+	manifest, err := v1.ParseManifest(bytes.NewReader(rawManifest))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse manifest: %v", err)
+	}
+	img := &sharedImage{
+		rawManifest: rawManifest,
+		manifest:    manifest,
+		blobs:       map[v1.Hash][]byte{},
+	}
+	for _, desc := range append([]v1.Descriptor{manifest.Config}, manifest.Layers...) {
+		if desc.Digest.Algorithm != "sha256" {
+			return nil, fmt.Errorf("unsupported digest algorithm of blob %v", desc.Digest)
+		}
+		b, err := blob(desc)
+		if err != nil {
+			return nil, err
+		}
+		if d := sha256Hex(b); d != desc.Digest.Hex {
+			return nil, fmt.Errorf("blob %v has digest sha256:%v", desc.Digest, d)
+		}
+		img.blobs[desc.Digest] = b
+	}
+	return img, nil
+}
+
+// readSharedImage reads the image with the given manifest digest from the module directory.
+func readSharedImage(moduleDir, digest string) (*sharedImage, error) {
+	rawManifest, err := os.ReadFile(filepath.Join(moduleDir, digest+sharedManifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+	return newSharedImage(digest, rawManifest, func(desc v1.Descriptor) ([]byte, error) {
+		return os.ReadFile(filepath.Join(moduleDir, desc.Digest.Hex+sharedBlobSuffix))
+	})
+}
+
+// downloadImage downloads the manifest and blobs of a fetched image with the given manifest digest.
+func downloadImage(img v1.Image, digest string) (*sharedImage, error) {
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve manifest: %v", err)
+	}
+	configDigest, err := img.ConfigName()
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve config digest: %v", err)
+	}
+	return newSharedImage(digest, rawManifest, func(desc v1.Descriptor) ([]byte, error) {
+		if desc.Digest == configDigest {
+			return img.RawConfigFile()
+		}
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch layer %v: %v", desc.Digest, err)
+		}
+		r, err := layer.Compressed()
+		if err != nil {
+			return nil, fmt.Errorf("could not get layer content: %v", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	})
+}
+
+// files returns the paths of the files of the image in the module directory.
+func (i *sharedImage) files(moduleDir string) []string {
+	files := make([]string, 0, len(i.blobs)+1)
+	files = append(files, filepath.Join(moduleDir, sha256Hex(i.rawManifest)+sharedManifestSuffix))
+	for h := range i.blobs {
+		files = append(files, filepath.Join(moduleDir, h.Hex+sharedBlobSuffix))
+	}
+	return files
+}
+
+// image returns the image as a v1.Image, to extract its binary.
+func (i *sharedImage) image() v1.Image {
+	img, _ := partial.CompressedToImage(i)
+	return img
+}
+
+// RawManifest implements partial.CompressedImageCore.
+func (i *sharedImage) RawManifest() ([]byte, error) {
+	return i.rawManifest, nil
+}
+
+// RawConfigFile implements partial.CompressedImageCore.
+func (i *sharedImage) RawConfigFile() ([]byte, error) {
+	return i.blobs[i.manifest.Config.Digest], nil
+}
+
+// MediaType implements partial.CompressedImageCore.
+func (i *sharedImage) MediaType() (types.MediaType, error) {
+	if i.manifest.MediaType == "" {
+		return types.OCIManifestSchema1, nil
+	}
+	return i.manifest.MediaType, nil
+}
+
+// LayerByDigest implements partial.CompressedImageCore.
+func (i *sharedImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	for _, desc := range i.manifest.Layers {
+		if desc.Digest == h {
+			return static.NewLayer(i.blobs[h], desc.MediaType), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown layer %v", h)
+}
+
+// purgeSharedDir removes the files of the shared cache directory that have not been read by any agent
+// for the module expiry, as well as temporary files left by interrupted writes.
+func (c *LocalFileCache) purgeSharedDir() {
+	_ = filepath.WalkDir(c.SharedCacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isSharedCacheFile(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || time.Since(info.ModTime()) <= c.ModuleExpiry {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			wasmLog.Errorf("failed to purge Wasm module file %v: %v", path, err)
+		} else {
+			wasmLog.Debugf("successfully removed stale Wasm module file %v", path)
+		}
+		return nil
+	})
+}
+
+func isSharedCacheFile(path string) bool {
+	for _, suffix := range []string{sharedModuleSuffix, sharedManifestSuffix, sharedBlobSuffix, ".tmp"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sha256Hex(b []byte) string {
+	sha := sha256.Sum256(b)
+	return hex.EncodeToString(sha[:])
+}
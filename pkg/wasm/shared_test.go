@@ -0,0 +1,238 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+
+	extensions "istio.io/api/extensions/v1alpha1"
+)
+
+func TestWasmSharedCache(t *testing.T) {
+	binary := append(wasmHeader, []byte("shared")...)
+	sha := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(sha[:])
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// Slow down the download, so that the agents fetch concurrently.
+		time.Sleep(50 * time.Millisecond)
+		_, _ = w.Write(binary)
+	}))
+	defer ts.Close()
+
+	options := defaultOptions()
+	options.SharedCacheDir = t.TempDir()
+	newCache := func() *LocalFileCache {
+		c := NewLocalFileCache(t.TempDir(), options)
+		t.Cleanup(func() { close(c.stopChan) })
+		return c
+	}
+	get := func(c *LocalFileCache) string {
+		path, err := c.Get(ts.URL, checksum, "namespace.resource", "1", time.Second*10, nil, extensions.PullPolicy_UNSPECIFIED_POLICY)
+		if err != nil {
+			t.Errorf("failed to download Wasm module: %v", err)
+		}
+		return path
+	}
+	moduleDir, err := getModuleDir(options.SharedCacheDir, moduleKey{name: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedPath := filepath.Join(moduleDir, checksum+sharedModuleSuffix)
+
+	// Agents of the node fetch the module concurrently, it is only downloaded once.
+	agents := []*LocalFileCache{newCache(), newCache(), newCache()}
+	paths := make([]string, len(agents))
+	var wg sync.WaitGroup
+	for i, c := range agents {
+		wg.Add(1)
+		go func(i int, c *LocalFileCache) {
+			defer wg.Done()
+			paths[i] = get(c)
+		}(i, c)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expected module to be downloaded once, got %d downloads", n)
+	}
+	// Each agent loads its own copy of the module, which cannot be replaced by the other agents.
+	for i, p := range paths {
+		if !strings.HasPrefix(p, agents[i].dir) {
+			t.Fatalf("expected module to be copied to the agent directory %s, got %s", agents[i].dir, p)
+		}
+		if b, err := os.ReadFile(p); err != nil || !bytes.Equal(b, binary) {
+			t.Fatalf("expected module %s to be the downloaded one, got %q (%v)", p, b, err)
+		}
+	}
+
+	// An agent starting later finds the module in the shared directory.
+	get(newCache())
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expected module to be found in the shared cache, got %d downloads", n)
+	}
+
+	// Modules unread by all agents are purged, and fetched again by agents which do not have them.
+	old := time.Now().Add(-2 * options.ModuleExpiry)
+	if err := os.Chtimes(sharedPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	agents[1].purgeSharedDir()
+	if _, err := os.Stat(sharedPath); !os.IsNotExist(err) {
+		t.Fatalf("expected stale module to be purged, got %v", err)
+	}
+	get(newCache())
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected purged module to be downloaded again, got %d downloads", n)
+	}
+
+	// Used modules are not purged.
+	agents[1].purgeSharedDir()
+	if _, err := os.Stat(sharedPath); err != nil {
+		t.Fatalf("expected used module not to be purged: %v", err)
+	}
+
+	// Modules of the shared directory which do not match their checksum are downloaded again.
+	tampered := append(wasmHeader, []byte("tampered")...)
+	if err := os.WriteFile(sharedPath, tampered, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := get(newCache())
+	if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, binary) {
+		t.Fatalf("expected tampered module not to be used, got %q (%v)", b, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("expected tampered module to be downloaded again, got %d downloads", n)
+	}
+	if b, err := os.ReadFile(sharedPath); err != nil || !bytes.Equal(b, binary) {
+		t.Fatalf("expected tampered module to be replaced in the shared cache, got %q (%v)", b, err)
+	}
+}
+
+func TestWasmSharedCacheImage(t *testing.T) {
+	var blobRequests int32
+	reg := registry.New()
+	rs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			atomic.AddInt32(&blobRequests, 1)
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer rs.Close()
+	ru, err := url.Parse(rs.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := setupOCIRegistry(t, ru.Host)
+	downloadURL := fmt.Sprintf("oci://%s/test/valid/docker:v0.1.0", ru.Host)
+	want := append(wasmHeader, []byte("this is wasm plugin")...)
+
+	options := defaultOptions()
+	options.SharedCacheDir = t.TempDir()
+	get := func() {
+		t.Helper()
+		c := NewLocalFileCache(t.TempDir(), options)
+		defer close(c.stopChan)
+		path, err := c.Get(downloadURL, "", "namespace.resource", "1", time.Second*10, nil, extensions.PullPolicy_UNSPECIFIED_POLICY)
+		if err != nil {
+			t.Fatalf("failed to download Wasm module: %v", err)
+		}
+		if b, err := os.ReadFile(path); err != nil || !bytes.Equal(b, want) {
+			t.Fatalf("expected module %s to be the one of the image, got %q (%v)", path, b, err)
+		}
+	}
+
+	get()
+	downloads := atomic.LoadInt32(&blobRequests)
+	if downloads == 0 {
+		t.Fatalf("expected the blobs of the image to be downloaded")
+	}
+	moduleDir, err := getModuleDir(options.SharedCacheDir, moduleKey{name: urlAsResourceName(downloadURL)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := readSharedImage(moduleDir, digest)
+	if err != nil {
+		t.Fatalf("expected the image to be stored in the shared cache: %v", err)
+	}
+
+	// Other agents only fetch the manifest from the registry, to resolve the tag.
+	get()
+	if n := atomic.LoadInt32(&blobRequests); n != downloads {
+		t.Fatalf("expected the image to be found in the shared cache, got %d blob downloads", n-downloads)
+	}
+
+	// Images whose blobs do not match the manifest are downloaded again.
+	for h := range img.blobs {
+		if err := os.WriteFile(filepath.Join(moduleDir, h.Hex+sharedBlobSuffix), []byte("tampered"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get()
+	if n := atomic.LoadInt32(&blobRequests); n != 2*downloads {
+		t.Fatalf("expected tampered image to be downloaded again, got %d blob downloads", n-downloads)
+	}
+}
+
+func TestWasmSharedCacheSignatureVerification(t *testing.T) {
+	_, trustedKeys := newSigningKey(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.pem")
+	if err := os.WriteFile(keysFile, trustedKeys, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unsigned := append(wasmHeader, []byte("unsigned")...)
+	checksum := sha256Hex(unsigned)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/unsigned.wasm" {
+			_, _ = w.Write(unsigned)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+	downloadURL := ts.URL + "/unsigned.wasm"
+
+	// The module is stored by an agent which does not verify signatures.
+	options := defaultOptions()
+	options.SharedCacheDir = t.TempDir()
+	c := NewLocalFileCache(t.TempDir(), options)
+	defer close(c.stopChan)
+	if _, err := c.Get(downloadURL, checksum, "namespace.resource", "1", time.Second*10, nil, extensions.PullPolicy_UNSPECIFIED_POLICY); err != nil {
+		t.Fatalf("failed to download Wasm module: %v", err)
+	}
+
+	options.SignatureVerificationKeys = keysFile
+	c = NewLocalFileCache(t.TempDir(), options)
+	defer close(c.stopChan)
+	_, err := c.Get(downloadURL, checksum, "namespace.resource", "1", time.Second*10, nil, extensions.PullPolicy_UNSPECIFIED_POLICY)
+	if err == nil || !strings.Contains(err.Error(), "no signature found") {
+		t.Fatalf("expected unsigned module of the shared cache to be rejected, got %v", err)
+	}
+}
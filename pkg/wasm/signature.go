@@ -0,0 +1,186 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// This file implements offline, key-based verification of Wasm module signatures, following the conventions of cosign:
+//   - the Wasm binary of modules fetched over http/https is signed with `cosign sign-blob`, and the base64 encoded
+//     signature is served next to the module, at the module URL suffixed with `.sig`.
+//   - OCI images are signed with `cosign sign`, which pushes the signature as an image tagged
+//     `sha256-<manifest digest>.sig` to the same repository. Each layer of that image is a simple signing payload
+//     naming the signed manifest digest, with the signature of the payload in the layer annotations.
+// Signatures are ECDSA (ASN.1 encoded), RSA PKCS #1 v1.5 over the SHA-256 digest of the signed bytes,
+// or Ed25519 over the signed bytes themselves.
+
+const (
+	// signatureSuffix is appended to the URL of http/https modules to find their signature.
+	signatureSuffix = ".sig"
+	// cosignSignatureAnnotation holds the signature of the simple signing payload in a layer of a cosign signature image.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+)
+
+var errNoSignature = errors.New("no signature found")
+
+// signatureVerifier verifies module signatures against a set of trusted public keys.
+type signatureVerifier struct {
+	keys []crypto.PublicKey
+	// fingerprint identifies the set of trusted keys, to record which keys a cached module was verified with.
+	fingerprint string
+}
+
+// newSignatureVerifier loads the PEM encoded public keys in the given file.
+func newSignatureVerifier(path string) (*signatureVerifier, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read Wasm signature verification keys: %v", err)
+	}
+	keys, err := parsePublicKeys(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse Wasm signature verification keys in %s: %v", path, err)
+	}
+	fingerprint, err := keysFingerprint(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &signatureVerifier{keys: keys, fingerprint: fingerprint}, nil
+}
+
+// keysFingerprint returns the hex encoded SHA-256 digest of the DER encoded keys.
+func keysFingerprint(keys []crypto.PublicKey) (string, error) {
+	h := sha256.New()
+	for _, key := range keys {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		h.Write(der)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func parsePublicKeys(b []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+	return keys, nil
+}
+
+// verify checks that sig is a signature of payload made by one of the trusted keys.
+func (v *signatureVerifier) verify(payload, sig []byte) bool {
+	digest := sha256.Sum256(payload)
+	for _, key := range v.keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], sig) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyHTTPModule verifies the signature served next to a module fetched over http/https.
+func (v *signatureVerifier) verifyHTTPModule(ctx context.Context, fetcher *HTTPFetcher, url string, module []byte, insecure bool) error {
+	encoded, err := fetcher.Fetch(ctx, url+signatureSuffix, insecure)
+	if err != nil {
+		return fmt.Errorf("%v: %v", errNoSignature, err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+	if err != nil {
+		return fmt.Errorf("signature at %s%s is not base64 encoded: %v", url, signatureSuffix, err)
+	}
+	if !v.verify(module, sig) {
+		return errors.New("signature does not match any trusted key")
+	}
+	return nil
+}
+
+// simpleSigningPayload is the payload signed by cosign for container images.
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// verifyImage verifies that one of the cosign signatures of the image with the given manifest digest
+// was made by a trusted key.
+func (v *signatureVerifier) verifyImage(signatures []imageSignature, digest string) error {
+	if len(signatures) == 0 {
+		return errNoSignature
+	}
+	for _, s := range signatures {
+		if !v.verify(s.payload, s.signature) {
+			continue
+		}
+		var p simpleSigningPayload
+		if err := json.Unmarshal(s.payload, &p); err != nil {
+			continue
+		}
+		// The payload must be about this image, otherwise a signature of another image could be replayed.
+		if p.Critical.Image.DockerManifestDigest == sha256SchemePrefix+digest {
+			return nil
+		}
+	}
+	return errors.New("no signature of the image matches any trusted key")
+}
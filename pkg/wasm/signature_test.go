@@ -0,0 +1,264 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	extensions "istio.io/api/extensions/v1alpha1"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestWasmCacheSignatureVerification(t *testing.T) {
+	trusted, trustedKeys := newSigningKey(t)
+	untrusted, _ := newSigningKey(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.pem")
+	if err := os.WriteFile(keysFile, trustedKeys, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	signed := append(wasmHeader, []byte("signed")...)
+	unsigned := append(wasmHeader, []byte("unsigned")...)
+	tampered := append(wasmHeader, []byte("tampered")...)
+	foreign := append(wasmHeader, []byte("foreign")...)
+	files := map[string][]byte{
+		"/signed.wasm":       signed,
+		"/signed.wasm.sig":   signBlob(t, trusted, signed),
+		"/unsigned.wasm":     unsigned,
+		"/tampered.wasm":     tampered,
+		"/tampered.wasm.sig": signBlob(t, trusted, signed),
+		"/foreign.wasm":      foreign,
+		"/foreign.wasm.sig":  signBlob(t, untrusted, foreign),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b, f := files[r.URL.Path]; f {
+			_, _ = w.Write(b)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	rs := httptest.NewServer(registry.New())
+	defer rs.Close()
+	ru, err := url.Parse(rs.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, invalidDigest := setupOCIRegistry(t, ru.Host)
+	pushImageSignature(t, ru.Host+"/test/valid/docker", digest, digest, trusted)
+	// The signature of another image must not be accepted for this one.
+	pushImageSignature(t, ru.Host+"/test/invalid", invalidDigest, digest, trusted)
+
+	cases := []struct {
+		name    string
+		url     string
+		keys    string
+		wantErr string
+	}{
+		{name: "signed module", url: ts.URL + "/signed.wasm", keys: keysFile},
+		{name: "unsigned module", url: ts.URL + "/unsigned.wasm", keys: keysFile, wantErr: "no signature found"},
+		{name: "tampered module", url: ts.URL + "/tampered.wasm", keys: keysFile, wantErr: "does not match any trusted key"},
+		{name: "untrusted key", url: ts.URL + "/foreign.wasm", keys: keysFile, wantErr: "does not match any trusted key"},
+		{name: "verification disabled", url: ts.URL + "/unsigned.wasm"},
+		{name: "missing keys", url: ts.URL + "/signed.wasm", keys: filepath.Join(t.TempDir(), "missing.pem"), wantErr: "cannot verify signature"},
+		{name: "signed image", url: fmt.Sprintf("oci://%s/test/valid/docker:v0.1.0", ru.Host), keys: keysFile},
+		{name: "image signed for another digest", url: fmt.Sprintf("oci://%s/test/invalid", ru.Host), keys: keysFile, wantErr: "no signature of the image"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := defaultOptions()
+			options.SignatureVerificationKeys = c.keys
+			cache := NewLocalFileCache(t.TempDir(), options)
+			defer close(cache.stopChan)
+
+			_, err := cache.Get(c.url, "", "namespace.resource", "1", time.Second*10, nil, extensions.PullPolicy_UNSPECIFIED_POLICY)
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatalf("failed to download Wasm module: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", c.wantErr, err)
+			}
+			if len(cache.modules) != 0 {
+				t.Fatalf("expected module not to be cached")
+			}
+		})
+	}
+}
+
+func TestWasmCacheUnverifiedEntries(t *testing.T) {
+	trusted, trustedKeys := newSigningKey(t)
+	keysFile := filepath.Join(t.TempDir(), "keys.pem")
+	if err := os.WriteFile(keysFile, trustedKeys, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	signed := append(wasmHeader, []byte("signed")...)
+	unsigned := append(wasmHeader, []byte("unsigned")...)
+	files := map[string][]byte{
+		"/signed.wasm":     signed,
+		"/signed.wasm.sig": signBlob(t, trusted, signed),
+		"/unsigned.wasm":   unsigned,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b, f := files[r.URL.Path]; f {
+			_, _ = w.Write(b)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		name       string
+		url        string
+		binary     []byte
+		verifiedBy string
+		wantErr    string
+	}{
+		{name: "unverified unsigned module", url: ts.URL + "/unsigned.wasm", binary: unsigned, wantErr: "no signature found"},
+		{name: "unverified signed module", url: ts.URL + "/signed.wasm", binary: signed},
+		{name: "module verified with other keys", url: ts.URL + "/unsigned.wasm", binary: unsigned, verifiedBy: "other", wantErr: "no signature found"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := defaultOptions()
+			options.SignatureVerificationKeys = keysFile
+			cache := NewLocalFileCache(t.TempDir(), options)
+			defer close(cache.stopChan)
+
+			sha := sha256.Sum256(c.binary)
+			checksum := fmt.Sprintf("%x", sha)
+			stale := filepath.Join(t.TempDir(), "stale.wasm")
+			cache.modules[moduleKey{name: c.url, checksum: checksum}] = &cacheEntry{
+				modulePath:      stale,
+				last:            time.Now(),
+				referencingURLs: sets.New(c.url),
+				verifiedBy:      c.verifiedBy,
+			}
+
+			path, err := cache.Get(c.url, checksum, "namespace.resource", "1", time.Second*10, nil, extensions.PullPolicy_UNSPECIFIED_POLICY)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("expected error containing %q, got path %q and error %v", c.wantErr, path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to download Wasm module: %v", err)
+			}
+			if path == stale {
+				t.Fatalf("expected the unverified module not to be used")
+			}
+			if got := cache.modules[moduleKey{name: c.url, checksum: checksum}].verifiedBy; got != cache.verifier.fingerprint {
+				t.Fatalf("expected the module to be recorded as verified, got %q", got)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	_, ecKey := newSigningKey(t)
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	keys, err := parsePublicKeys(append(ecKey, edKey...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(keys))
+	}
+	if _, err := parsePublicKeys([]byte("not a key")); err == nil {
+		t.Fatalf("expected error without keys")
+	}
+}
+
+func newSigningKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// signBlob returns the signature of b, as written by `cosign sign-blob`.
+func signBlob(t *testing.T, key crypto.Signer, b []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(b)
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+// pushImageSignature pushes a signature of the image with signedDigest to repo, as `cosign sign` does,
+// tagged for the image with digest.
+func pushImageSignature(t *testing.T, repo, digest, signedDigest string, key crypto.Signer) {
+	t.Helper()
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":"sha256:%s"},`+
+		`"type":"cosign container image signature"},"optional":null}`, repo, signedDigest))
+	sig := signBlob(t, key, payload)
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, "application/vnd.dev.cosign.simplesigning.v1+json"),
+		Annotations: map[string]string{cosignSignatureAnnotation: strings.TrimSpace(string(sig))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	if err := crane.Push(img, repo+":sha256-"+digest+signatureSuffix); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -22,7 +22,9 @@ import (
 	"istio.io/istio/security/pkg/credentialfetcher/plugin"
 )
 
-func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string) (security.CredFetcher, error) {
+// NewCredFetcher returns the credential fetcher of the type. The exec command is the command line of the credential
+// helper of the Exec type, and the OIDC token file of the OIDCTokenFile type is the JWT path.
+func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string, execCommand []string) (security.CredFetcher, error) {
 	switch credtype {
 	case security.GCE:
 		return plugin.CreateGCEPlugin(trustdomain, jwtPath, identityProvider), nil
@@ -32,6 +34,17 @@ func NewCredFetcher(credtype, trustdomain, jwtPath, identityProvider string) (se
 			return nil, nil // no cred fetcher - using certificates only
 		}
 		return plugin.CreateTokenPlugin(jwtPath), nil
+	case security.Exec:
+		if len(execCommand) == 0 {
+			return nil, fmt.Errorf("the command of the %s credential fetcher is unset", credtype)
+		}
+		return plugin.CreateExecPlugin(execCommand, identityProvider), nil
+	case security.OIDCTokenFile:
+		p, err := plugin.CreateOIDCTokenFilePlugin(jwtPath, identityProvider)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
 	case security.Mock: // for test only
 		return plugin.CreateMockPlugin("test_token"), nil
 	default:
@@ -15,9 +15,17 @@
 package credentialfetcher
 
 import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"istio.io/istio/pkg/file"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/retry"
 	"istio.io/istio/security/pkg/credentialfetcher/plugin"
 )
 
@@ -49,6 +57,24 @@ func TestNewCredFetcher(t *testing.T) {
 			expectedToken:    "test_token",
 			expectedIdp:      "fakeIDP",
 		},
+		"exec without command test": {
+			fetcherType:      security.Exec,
+			trustdomain:      "",
+			jwtPath:          "",
+			identityProvider: "",
+			expectedErr:      "the command of the Exec credential fetcher is unset",
+			expectedToken:    "",
+			expectedIdp:      "",
+		},
+		"oidc token file without path test": {
+			fetcherType:      security.OIDCTokenFile,
+			trustdomain:      "",
+			jwtPath:          "",
+			identityProvider: "",
+			expectedErr:      "the path of the OIDC token file is unset",
+			expectedToken:    "",
+			expectedIdp:      "",
+		},
 		"invalid test": {
 			fetcherType:      "foo",
 			trustdomain:      "",
@@ -63,10 +89,11 @@ func TestNewCredFetcher(t *testing.T) {
 	// Disable token refresh for GCE VM credential fetcher.
 	plugin.SetTokenRotation(false)
 	for id, tc := range testCases {
+		id, tc := id, tc
 		t.Run(id, func(t *testing.T) {
 			t.Parallel()
 			cf, err := NewCredFetcher(
-				tc.fetcherType, tc.trustdomain, tc.jwtPath, tc.identityProvider)
+				tc.fetcherType, tc.trustdomain, tc.jwtPath, tc.identityProvider, nil)
 			if cf != nil {
 				defer cf.Stop()
 			}
@@ -100,3 +127,130 @@ func TestNewCredFetcher(t *testing.T) {
 	// Restore token refresh for other tests.
 	plugin.SetTokenRotation(true)
 }
+
+// fakeJWT returns an unsigned JWT expiring at exp.
+func fakeJWT(exp time.Time) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"vm","exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + claims + ".c2ln"
+}
+
+func TestExecCredFetcher(t *testing.T) {
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "count")
+	helper := filepath.Join(dir, "helper.sh")
+	// The helper prints a new token on each run, and fails once the fail file exists.
+	script := fmt.Sprintf(`#!/bin/sh
+[ -f %[1]s/fail ] && echo "helper unavailable" >&2 && exit 1
+echo x >> %[2]s
+echo "{\"token\": \"token-$(wc -l < %[2]s | tr -d ' ')\", \"expirationTime\": \"$1\"}"
+`, dir, counter)
+	if err := os.WriteFile(helper, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(t *testing.T, cf security.CredFetcher) string {
+		t.Helper()
+		token, err := cf.GetPlatformCredential()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	t.Run("cached", func(t *testing.T) {
+		_ = os.Remove(counter)
+		expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		cf, err := NewCredFetcher(security.Exec, "", "", "idp", []string{helper, expiry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cf.Stop()
+		if cf.GetIdentityProvider() != "idp" {
+			t.Fatalf("unexpected identity provider %s", cf.GetIdentityProvider())
+		}
+		if token := fetch(t, cf); token != "token-1" {
+			t.Fatalf("unexpected token %s", token)
+		}
+		if token := fetch(t, cf); token != "token-1" {
+			t.Fatalf("expected the cached token, got %s", token)
+		}
+	})
+	t.Run("refreshed near expiry", func(t *testing.T) {
+		_ = os.Remove(counter)
+		expiry := time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+		cf, err := NewCredFetcher(security.Exec, "", "", "", []string{helper, expiry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cf.Stop()
+		fetch(t, cf)
+		time.Sleep(900 * time.Millisecond)
+		if token := fetch(t, cf); token != "token-2" {
+			t.Fatalf("expected a new token, got %s", token)
+		}
+	})
+	t.Run("helper failure", func(t *testing.T) {
+		_ = os.Remove(counter)
+		expiry := time.Now().Add(2 * time.Second).UTC().Format(time.RFC3339Nano)
+		cf, err := NewCredFetcher(security.Exec, "", "", "", []string{helper, expiry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cf.Stop()
+		fetch(t, cf)
+		if err := os.WriteFile(filepath.Join(dir, "fail"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(filepath.Join(dir, "fail"))
+		time.Sleep(1700 * time.Millisecond)
+		if token := fetch(t, cf); token != "token-1" {
+			t.Fatalf("expected the cached token while valid, got %s", token)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if _, err := cf.GetPlatformCredential(); err == nil || !strings.Contains(err.Error(), "helper unavailable") {
+			t.Fatalf("expected the error of the helper, got %v", err)
+		}
+	})
+}
+
+func TestOIDCTokenFileCredFetcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "istio-token")
+	first := fakeJWT(time.Now().Add(time.Hour))
+	if err := os.WriteFile(path, []byte(first+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewCredFetcher(security.OIDCTokenFile, "", path, "idp", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cf.Stop()
+	if token, err := cf.GetPlatformCredential(); err != nil || token != first {
+		t.Fatalf("expected the token of the file, got %q: %v", token, err)
+	}
+
+	// The token is reloaded when the file is replaced.
+	second := fakeJWT(time.Now().Add(2 * time.Hour))
+	if err := file.AtomicWrite(path, []byte(second), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		token, err := cf.GetPlatformCredential()
+		if err != nil {
+			return err
+		}
+		if token != second {
+			return fmt.Errorf("expected the updated token")
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+
+	// An expired token is rejected.
+	if err := file.AtomicWrite(path, []byte(fakeJWT(time.Now().Add(-time.Minute))), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, err := cf.GetPlatformCredential(); err == nil || !strings.Contains(err.Error(), "expired") {
+			return fmt.Errorf("expected the expired token to be rejected, got %v", err)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+}
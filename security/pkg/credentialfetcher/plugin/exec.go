@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is the exec plugin of credentialfetcher, getting the credential from an on-host helper.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/security/pkg/util"
+	"istio.io/pkg/log"
+)
+
+var execcredLog = log.RegisterScope("execcred", "Exec credential fetcher for istio agent", 0)
+
+const (
+	// execTimeout is the timeout of the credential helper.
+	execTimeout = 30 * time.Second
+	// execRefreshRatio is the ratio of the lifetime of the credential after which it is refreshed.
+	execRefreshRatio = 0.8
+)
+
+// ExecCredential is the output of the credential helper, in JSON.
+type ExecCredential struct {
+	Token string `json:"token"`
+	// ExpirationTime is the expiry of the token. If not set, the expiry of the token is read from its "exp" claim,
+	// if it is a JWT.
+	ExpirationTime *time.Time `json:"expirationTime,omitempty"`
+}
+
+// ExecPlugin is the plugin object.
+type ExecPlugin struct {
+	command          []string
+	identityProvider string
+
+	// mutex serializes the executions of the credential helper, and protects the cached token.
+	mutex     sync.Mutex
+	token     string
+	expiry    time.Time
+	refreshAt time.Time
+}
+
+// CreateExecPlugin creates a credential fetcher plugin running the command, whose output is an ExecCredential.
+// The token is cached until most of its lifetime has elapsed.
+func CreateExecPlugin(command []string, identityProvider string) *ExecPlugin {
+	return &ExecPlugin{
+		command:          command,
+		identityProvider: identityProvider,
+	}
+}
+
+// GetPlatformCredential returns the cached token, or runs the credential helper to get a new one. If the credential
+// helper fails, the cached token is returned as long as it has not expired.
+func (p *ExecPlugin) GetPlatformCredential() (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	if p.token != "" && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+	cred, err := p.exec()
+	if err != nil {
+		if p.token != "" && (p.expiry.IsZero() || now.Before(p.expiry)) {
+			execcredLog.Warnf("failed to refresh the credential, using the cached one: %v", err)
+			return p.token, nil
+		}
+		return "", err
+	}
+
+	p.token = cred.Token
+	p.expiry = time.Time{}
+	if cred.ExpirationTime != nil {
+		p.expiry = *cred.ExpirationTime
+	} else if exp, err := util.GetExp(cred.Token); err == nil {
+		p.expiry = exp
+	}
+	// Tokens without expiry are fetched on each call.
+	p.refreshAt = now
+	if !p.expiry.IsZero() {
+		p.refreshAt = now.Add(time.Duration(float64(p.expiry.Sub(now)) * execRefreshRatio))
+	}
+	execcredLog.Debugf("got credential from %s, expiring at %v", p.command[0], p.expiry)
+	return p.token, nil
+}
+
+func (p *ExecPlugin) exec() (*ExecCredential, error) {
+	if len(p.command) == 0 {
+		return nil, fmt.Errorf("the command of the credential helper is unset")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("credential helper %s failed: %v: %s", p.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	cred := &ExecCredential{}
+	if err := json.Unmarshal(stdout.Bytes(), cred); err != nil {
+		return nil, fmt.Errorf("invalid output of the credential helper %s: %v", p.command[0], err)
+	}
+	if cred.Token == "" {
+		return nil, fmt.Errorf("credential helper %s returned no token", p.command[0])
+	}
+	return cred, nil
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *ExecPlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
+
+func (p *ExecPlugin) Stop() {}
@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This is the OIDC token file plugin of credentialfetcher, for tokens refreshed on disk by another process.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"istio.io/istio/security/pkg/util"
+	"istio.io/pkg/log"
+)
+
+var oidcfilecredLog = log.RegisterScope("oidcfilecred", "OIDC token file credential fetcher for istio agent", 0)
+
+// OIDCTokenFilePlugin is the plugin object.
+type OIDCTokenFilePlugin struct {
+	path             string
+	identityProvider string
+
+	watcher *fsnotify.Watcher
+	closing chan struct{}
+
+	// mutex protects the cached token.
+	mutex  sync.RWMutex
+	token  string
+	expiry time.Time
+}
+
+// CreateOIDCTokenFilePlugin creates a credential fetcher plugin caching the OIDC token of the file, and reloading it
+// when the file changes.
+func CreateOIDCTokenFilePlugin(path, identityProvider string) (*OIDCTokenFilePlugin, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the path of the OIDC token file is unset")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// The directory is watched, as the file is usually replaced by a rename or a symlink swap.
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("failed to watch the OIDC token file %s: %v", path, err)
+	}
+	p := &OIDCTokenFilePlugin{
+		path:             path,
+		identityProvider: identityProvider,
+		watcher:          watcher,
+		closing:          make(chan struct{}),
+	}
+	if err := p.reload(); err != nil {
+		oidcfilecredLog.Warnf("failed to load the OIDC token file: %v", err)
+	}
+	go p.watch()
+	return p, nil
+}
+
+func (p *OIDCTokenFilePlugin) watch() {
+	for {
+		select {
+		case event, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+			oidcfilecredLog.Debugf("event for the OIDC token file: %v", event)
+			if err := p.reload(); err != nil {
+				oidcfilecredLog.Warnf("failed to reload the OIDC token file: %v", err)
+			}
+		case err, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+			oidcfilecredLog.Errorf("failed to watch the OIDC token file %s: %v", p.path, err)
+		case <-p.closing:
+			return
+		}
+	}
+}
+
+// reload reads the token of the file. The cached token is kept if the file is missing, empty, or holds the same token.
+func (p *OIDCTokenFilePlugin) reload() error {
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return fmt.Errorf("the OIDC token file %s is empty", p.path)
+	}
+	exp, err := util.GetExp(token)
+	if err != nil {
+		return fmt.Errorf("invalid OIDC token in %s: %v", p.path, err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if token != p.token {
+		p.token = token
+		p.expiry = exp
+		oidcfilecredLog.Infof("loaded the OIDC token of %s, expiring at %v", p.path, exp)
+	}
+	return nil
+}
+
+// GetPlatformCredential returns the cached OIDC token. The file is read again if the cached token has expired, in
+// case an update was missed.
+func (p *OIDCTokenFilePlugin) GetPlatformCredential() (string, error) {
+	p.mutex.RLock()
+	token, expiry := p.token, p.expiry
+	p.mutex.RUnlock()
+	if token != "" && (expiry.IsZero() || time.Now().Before(expiry)) {
+		return token, nil
+	}
+
+	if err := p.reload(); err != nil {
+		return "", err
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if !p.expiry.IsZero() && !time.Now().Before(p.expiry) {
+		return "", fmt.Errorf("the OIDC token of %s expired at %v", p.path, p.expiry)
+	}
+	return p.token, nil
+}
+
+// GetIdentityProvider returns the name of the identity provider that can authenticate the workload credential.
+func (p *OIDCTokenFilePlugin) GetIdentityProvider() string {
+	return p.identityProvider
+}
+
+func (p *OIDCTokenFilePlugin) Stop() {
+	close(p.closing)
+	_ = p.watcher.Close()
+}
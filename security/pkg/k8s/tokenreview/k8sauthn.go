@@ -22,14 +22,24 @@ import (
 	k8sauth "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pkg/security"
+)
+
+// Keys of the extra user info of the token review of a token bound to a pod.
+const (
+	podNameKey  = "authentication.kubernetes.io/pod-name"
+	podUIDKey   = "authentication.kubernetes.io/pod-uid"
+	nodeNameKey = "authentication.kubernetes.io/node-name"
 )
 
 // ValidateK8sJwt validates a k8s JWT at API server.
-// Return {<namespace>, <serviceaccountname>} in the targetToken when the validation passes.
-// Otherwise, return the error.
+// Return the namespace and service account of the targetToken, and the pod and node it is bound to if any,
+// when the validation passes. Otherwise, return the error.
+// The token review only includes the node of the pod since Kubernetes 1.30.
 // targetToken: the JWT of the K8s service account to be reviewed
 // aud: list of audiences to check. If empty 1st party tokens will be checked.
-func ValidateK8sJwt(kubeClient kubernetes.Interface, targetToken string, aud []string) ([]string, error) {
+func ValidateK8sJwt(kubeClient kubernetes.Interface, targetToken string, aud []string) (security.KubernetesInfo, error) {
 	tokenReview := &k8sauth.TokenReview{
 		Spec: k8sauth.TokenReviewSpec{
 			Token: targetToken,
@@ -40,15 +50,15 @@ func ValidateK8sJwt(kubeClient kubernetes.Interface, targetToken string, aud []s
 	}
 	reviewRes, err := kubeClient.AuthenticationV1().TokenReviews().Create(context.TODO(), tokenReview, metav1.CreateOptions{})
 	if err != nil {
-		return nil, err
+		return security.KubernetesInfo{}, err
 	}
 
 	return getTokenReviewResult(reviewRes)
 }
 
-func getTokenReviewResult(tokenReview *k8sauth.TokenReview) ([]string, error) {
+func getTokenReviewResult(tokenReview *k8sauth.TokenReview) (security.KubernetesInfo, error) {
 	if tokenReview.Status.Error != "" {
-		return nil, fmt.Errorf("the service account authentication returns an error: %v",
+		return security.KubernetesInfo{}, fmt.Errorf("the service account authentication returns an error: %v",
 			tokenReview.Status.Error)
 	}
 	// An example SA token:
@@ -67,12 +77,16 @@ func getTokenReviewResult(tokenReview *k8sauth.TokenReview) ([]string, error) {
 	//   "user":{
 	//     "username":"system:serviceaccount:default:example-pod-sa",
 	//     "uid":"ff578a9e-65d3-11e8-aad2-42010a8a001d",
-	//     "groups":["system:serviceaccounts","system:serviceaccounts:default","system:authenticated"]
+	//     "groups":["system:serviceaccounts","system:serviceaccounts:default","system:authenticated"],
+	//     "extra":{
+	//       "authentication.kubernetes.io/pod-name":["example-pod"],
+	//       "authentication.kubernetes.io/pod-uid":["2bdb8e5a-4d26-4bf5-a1a1-ffbd76d6f2e0"]
+	//     }
 	//    }
 	// }
 
 	if !tokenReview.Status.Authenticated {
-		return nil, fmt.Errorf("the token is not authenticated")
+		return security.KubernetesInfo{}, fmt.Errorf("the token is not authenticated")
 	}
 	inServiceAccountGroup := false
 	for _, group := range tokenReview.Status.User.Groups {
@@ -82,15 +96,26 @@ func getTokenReviewResult(tokenReview *k8sauth.TokenReview) ([]string, error) {
 		}
 	}
 	if !inServiceAccountGroup {
-		return nil, fmt.Errorf("the token is not a service account")
+		return security.KubernetesInfo{}, fmt.Errorf("the token is not a service account")
 	}
 	// "username" is in the form of system:serviceaccount:{namespace}:{service account name}",
 	// e.g., "username":"system:serviceaccount:default:example-pod-sa"
 	subStrings := strings.Split(tokenReview.Status.User.Username, ":")
 	if len(subStrings) != 4 {
-		return nil, fmt.Errorf("invalid username field in the token review result")
+		return security.KubernetesInfo{}, fmt.Errorf("invalid username field in the token review result")
+	}
+	return security.KubernetesInfo{
+		PodName:           extra(tokenReview, podNameKey),
+		PodNamespace:      subStrings[2],
+		PodUID:            extra(tokenReview, podUIDKey),
+		PodServiceAccount: subStrings[3],
+		NodeName:          extra(tokenReview, nodeNameKey),
+	}, nil
+}
+
+func extra(tokenReview *k8sauth.TokenReview, key string) string {
+	if values := tokenReview.Status.User.Extra[key]; len(values) == 1 {
+		return values[0]
 	}
-	namespace := subStrings[2]
-	saName := subStrings[3]
-	return []string{namespace, saName}, nil
+	return ""
 }
@@ -20,15 +20,17 @@ import (
 	"testing"
 
 	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"istio.io/istio/pkg/security"
 )
 
-// TestGetTokenReviewResult verifies that getTokenReviewResult returns expected {<namespace>, <serviceaccountname>}.
+// TestGetTokenReviewResult verifies that getTokenReviewResult returns the expected Kubernetes info.
 func TestGetTokenReviewResult(t *testing.T) {
 	testCases := []struct {
 		name           string
 		tokenReview    authenticationv1.TokenReview
 		expectedError  error
-		expectedResult []string
+		expectedResult security.KubernetesInfo
 	}{
 		{
 			name: "the service account authentication error",
@@ -38,7 +40,7 @@ func TestGetTokenReviewResult(t *testing.T) {
 				},
 			},
 			expectedError:  fmt.Errorf("the service account authentication returns an error: authentication error"),
-			expectedResult: nil,
+			expectedResult: security.KubernetesInfo{},
 		},
 		{
 			name: "not authenticated",
@@ -48,7 +50,7 @@ func TestGetTokenReviewResult(t *testing.T) {
 				},
 			},
 			expectedError:  fmt.Errorf("the token is not authenticated"),
-			expectedResult: nil,
+			expectedResult: security.KubernetesInfo{},
 		},
 		{
 			name: "token is not a service account",
@@ -63,7 +65,7 @@ func TestGetTokenReviewResult(t *testing.T) {
 				},
 			},
 			expectedError:  fmt.Errorf("the token is not a service account"),
-			expectedResult: nil,
+			expectedResult: security.KubernetesInfo{},
 		},
 		{
 			name: "invalid username",
@@ -81,7 +83,7 @@ func TestGetTokenReviewResult(t *testing.T) {
 				},
 			},
 			expectedError:  fmt.Errorf("invalid username field in the token review result"),
-			expectedResult: nil,
+			expectedResult: security.KubernetesInfo{},
 		},
 		{
 			name: "success",
@@ -100,7 +102,37 @@ func TestGetTokenReviewResult(t *testing.T) {
 				},
 			},
 			expectedError:  nil,
-			expectedResult: []string{"default", "example-pod-sa"},
+			expectedResult: security.KubernetesInfo{PodNamespace: "default", PodServiceAccount: "example-pod-sa"},
+		},
+		{
+			name: "bound token",
+			tokenReview: authenticationv1.TokenReview{
+				Status: authenticationv1.TokenReviewStatus{
+					Authenticated: true,
+					User: authenticationv1.UserInfo{
+						Username: "system:serviceaccount:default:example-pod-sa",
+						UID:      "ff578a9e-65d3-11e8-aad2-42010a8a001d",
+						Groups: []string{
+							"system:serviceaccounts",
+							"system:serviceaccounts:default",
+							"system:authenticated",
+						},
+						Extra: map[string]authenticationv1.ExtraValue{
+							podNameKey:  {"example-pod"},
+							podUIDKey:   {"2bdb8e5a-4d26-4bf5-a1a1-ffbd76d6f2e0"},
+							nodeNameKey: {"example-node"},
+						},
+					},
+				},
+			},
+			expectedError: nil,
+			expectedResult: security.KubernetesInfo{
+				PodName:           "example-pod",
+				PodNamespace:      "default",
+				PodUID:            "2bdb8e5a-4d26-4bf5-a1a1-ffbd76d6f2e0",
+				PodServiceAccount: "example-pod-sa",
+				NodeName:          "example-node",
+			},
 		},
 	}
 	for _, tc := range testCases {
@@ -107,12 +107,18 @@ type SecretManagerClient struct {
 
 	// outputMutex protects writes of certificates to disk
 	outputMutex sync.Mutex
+	// rotationHook notifies the consumers of the certificates written to disk of their rotation, if configured.
+	rotationHook *nodeagentutil.RotationHook
 
 	// Dynamically configured Trust Bundle Mutex
 	configTrustBundleMutex sync.RWMutex
 	// Dynamically configured Trust Bundle
 	configTrustBundle []byte
 
+	// crl is the certificate revocation list of the mesh CA, distributed by istiod.
+	crl      []byte
+	crlMutex sync.RWMutex
+
 	// queue maintains all certificate rotation events that need to be triggered when they are about to expire
 	queue queue.Delayed
 	stop  chan struct{}
@@ -165,6 +171,11 @@ type FileCert struct {
 
 // NewSecretManagerClient creates a new SecretManagerClient.
 func NewSecretManagerClient(caClient security.Client, options *security.Options) (*SecretManagerClient, error) {
+	rotationHook, err := nodeagentutil.NewRotationHook(options.OutputKeyCertRotationSignal,
+		options.OutputKeyCertRotationPIDFile, options.OutputKeyCertRotationURL)
+	if err != nil {
+		return nil, err
+	}
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
@@ -179,10 +190,11 @@ func NewSecretManagerClient(caClient security.Client, options *security.Options)
 			PrivateKeyPath:    options.KeyFilePath,
 			CaCertificatePath: options.RootCertFilePath,
 		},
-		certWatcher: watcher,
-		fileCerts:   make(map[FileCert]struct{}),
-		stop:        make(chan struct{}),
-		caRootPath:  options.CARootPath,
+		certWatcher:  watcher,
+		fileCerts:    make(map[FileCert]struct{}),
+		stop:         make(chan struct{}),
+		caRootPath:   options.CARootPath,
+		rotationHook: rotationHook,
 	}
 
 	go ret.queue.Run(ret.stop)
@@ -198,6 +210,15 @@ func (sc *SecretManagerClient) Close() {
 	close(sc.stop)
 }
 
+// FetchJWTSVID fetches a JWT-SVID of the workload identity for the audience, if the CA client supports it.
+func (sc *SecretManagerClient) FetchJWTSVID(audience []string, ttl time.Duration) (*security.JWTSVIDResponse, error) {
+	client, ok := sc.caClient.(security.JWTSVIDClient)
+	if !ok {
+		return nil, fmt.Errorf("the CA client does not support JWT-SVIDs")
+	}
+	return client.FetchJWTSVID(audience, ttl)
+}
+
 func (sc *SecretManagerClient) RegisterSecretHandler(h func(resourceName string)) {
 	sc.certMutex.Lock()
 	defer sc.certMutex.Unlock()
@@ -223,6 +244,7 @@ func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *sec
 			ns = &security.SecretItem{
 				ResourceName: resourceName,
 				RootCert:     rootCertBundle,
+				CRL:          sc.getCRL(),
 			}
 			cacheLog.WithLabels("ttl", time.Until(c.ExpireTime)).Info("returned workload trust anchor from cache")
 
@@ -242,6 +264,15 @@ func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *sec
 	return nil
 }
 
+// notifyRotation notifies the consumers of the certificates written to disk that the resource was rotated.
+func (sc *SecretManagerClient) notifyRotation(resourceName string) {
+	if err := sc.rotationHook.Notify(sc.configOptions.OutputKeyCertToDir); err != nil {
+		resourceLog(resourceName).Warnf("failed to notify the rotation of %v: %v", sc.configOptions.OutputKeyCertToDir, err)
+		return
+	}
+	resourceLog(resourceName).Infof("notified the rotation of %v", sc.configOptions.OutputKeyCertToDir)
+}
+
 // GenerateSecret passes the cached secret to SDS.StreamSecrets and SDS.FetchSecret.
 func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *security.SecretItem, err error) {
 	cacheLog.Debugf("generate secret %q", resourceName)
@@ -251,18 +282,22 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 			return
 		}
 		// We need to hold a mutex here, otherwise if two threads are writing the same certificate,
-		// we may permanently end up with a mismatch key/cert pair. We still make end up temporarily
-		// with mismatched key/cert pair since we cannot atomically write multiple files. It may be
-		// possible by keeping the output in a directory with clever use of symlinks in the future,
-		// if needed.
+		// we may permanently end up with a mismatch key/cert pair. The files are swapped together
+		// through a symlink to their directory, so the consumers never read a mismatched pair.
 		sc.outputMutex.Lock()
 		if resourceName == security.RootCertReqResourceName || resourceName == security.WorkloadKeyCertResourceName {
-			if err := nodeagentutil.OutputKeyCertToDir(sc.configOptions.OutputKeyCertToDir, secret.PrivateKey,
-				secret.CertificateChain, secret.RootCert); err != nil {
+			written, err := nodeagentutil.OutputKeyCertToDir(sc.configOptions.OutputKeyCertToDir,
+				sc.configOptions.OutputKeyCertFileMode, secret.PrivateKey, secret.CertificateChain, secret.RootCert)
+			if err != nil {
 				cacheLog.Errorf("error when output the resource: %v", err)
 			} else {
 				resourceLog(resourceName).Debugf("output the resource to %v", sc.configOptions.OutputKeyCertToDir)
 			}
+			// The hook is only called once all the files of the resource are written, so that the consumers
+			// reload a matching key and certificate.
+			if written && err == nil && sc.rotationHook != nil {
+				go sc.notifyRotation(resourceName)
+			}
 		}
 		sc.outputMutex.Unlock()
 	}()
@@ -305,6 +340,7 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 
 	if resourceName == security.RootCertReqResourceName {
 		ns.RootCert = sc.mergeTrustAnchorBytes(ns.RootCert)
+		ns.CRL = sc.getCRL()
 	} else {
 		// If periodic cert refresh resulted in discovery of a new root, trigger a ROOTCA request to refresh trust anchor
 		oldRoot := sc.cache.GetRoot()
@@ -754,6 +790,25 @@ func (sc *SecretManagerClient) UpdateConfigTrustBundle(trustBundle []byte) error
 	return nil
 }
 
+// UpdateCRL updates the certificate revocation list of the mesh CA, which is added to the validation context of the
+// workload trust anchors.
+func (sc *SecretManagerClient) UpdateCRL(crl []byte) {
+	sc.crlMutex.Lock()
+	if bytes.Equal(sc.crl, crl) {
+		sc.crlMutex.Unlock()
+		return
+	}
+	sc.crl = crl
+	sc.crlMutex.Unlock()
+	sc.OnSecretUpdate(security.RootCertReqResourceName)
+}
+
+func (sc *SecretManagerClient) getCRL() []byte {
+	sc.crlMutex.RLock()
+	defer sc.crlMutex.RUnlock()
+	return sc.crl
+}
+
 // mergeTrustAnchorBytes: Merge cert bytes with the cached TrustAnchors.
 func (sc *SecretManagerClient) mergeTrustAnchorBytes(caCerts []byte) []byte {
 	return sc.mergeConfigTrustBundle(pkiutil.PemCertBytestoString(caCerts))
@@ -16,13 +16,18 @@ package cache
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -32,6 +37,7 @@ import (
 	"istio.io/istio/pkg/testcerts"
 	"istio.io/istio/security/pkg/nodeagent/caclient/providers/mock"
 	"istio.io/istio/security/pkg/nodeagent/cafile"
+	nodeagentutil "istio.io/istio/security/pkg/nodeagent/util"
 	pkiutil "istio.io/istio/security/pkg/pki/util"
 	"istio.io/pkg/log"
 )
@@ -503,6 +509,9 @@ func verifySecret(t *testing.T, gotSecret *security.SecretItem, expectedSecret *
 			t.Fatalf("root cert: expected %v but got %v", expectedSecret.RootCert,
 				gotSecret.RootCert)
 		}
+		if !bytes.Equal(expectedSecret.CRL, gotSecret.CRL) {
+			t.Fatalf("crl: expected %s but got %s", expectedSecret.CRL, gotSecret.CRL)
+		}
 	} else {
 		if !bytes.Equal(expectedSecret.CertificateChain, gotSecret.CertificateChain) {
 			t.Fatalf("cert chain: expected %s but got %s", string(expectedSecret.CertificateChain),
@@ -641,6 +650,40 @@ func TestProxyConfigAnchors(t *testing.T) {
 	})
 }
 
+func TestCertificateRevocationList(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour, false)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	u := NewUpdateTracker(t)
+	sc := createCache(t, fakeCACli, u.Callback, security.Options{WorkloadRSAKeySize: 2048})
+	if _, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName); err != nil {
+		t.Fatal(err)
+	}
+	u.Reset()
+	caClientRootCert := []byte(strings.TrimRight(fakeCACli.GeneratedCerts[0][2], "\n"))
+
+	crl := []byte("fake crl")
+	sc.UpdateCRL(crl)
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+	checkSecret(t, sc, security.RootCertReqResourceName, security.SecretItem{
+		ResourceName: security.RootCertReqResourceName,
+		RootCert:     caClientRootCert,
+		CRL:          crl,
+	})
+
+	// The same CRL does not trigger an update, and the CRL is not added to the workload certificate.
+	sc.UpdateCRL(crl)
+	u.Expect(map[string]int{security.RootCertReqResourceName: 1})
+	secret, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.CRL != nil {
+		t.Fatalf("expected no CRL in workload certificate")
+	}
+}
+
 func TestOSCACertGenerateSecret(t *testing.T) {
 	fakeCACli, err := mock.NewMockCAClient(time.Hour, false)
 	if err != nil {
@@ -743,3 +786,141 @@ func TestTryAddFileWatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputKeyCertRotationHook(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour, false)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	posts := make(chan nodeagentutil.RotationEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		event := nodeagentutil.RotationEvent{}
+		if err := json.NewDecoder(req.Body).Decode(&event); err != nil {
+			t.Errorf("invalid rotation event: %v", err)
+		}
+		posts <- event
+	}))
+	defer server.Close()
+	signals := make(chan os.Signal, 10)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "app.pid")
+	if err := os.WriteFile(pidFile, []byte(fmt.Sprint(os.Getpid())), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(dir, "certs")
+	if err := os.Mkdir(outputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sc := createCache(t, fakeCACli, func(resourceName string) {}, security.Options{
+		WorkloadRSAKeySize:           2048,
+		OutputKeyCertToDir:           outputDir,
+		OutputKeyCertFileMode:        0o640,
+		OutputKeyCertRotationSignal:  "SIGHUP",
+		OutputKeyCertRotationPIDFile: pidFile,
+		OutputKeyCertRotationURL:     server.URL,
+	})
+	if _, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName); err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	for _, f := range []string{"key.pem", "cert-chain.pem"} {
+		info, err := os.Stat(filepath.Join(outputDir, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o640 {
+			t.Errorf("expected %s to have mode 0640, got %v", f, info.Mode().Perm())
+		}
+	}
+	select {
+	case <-signals:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the process to be signaled on rotation")
+	}
+	select {
+	case event := <-posts:
+		if event.Directory != outputDir {
+			t.Fatalf("unexpected rotation event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the rotation URL to be called")
+	}
+
+	// The files are unchanged, so the consumers are not notified again.
+	if _, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName); err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	select {
+	case event := <-posts:
+		t.Fatalf("unexpected rotation event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestNewRotationHookInvalid(t *testing.T) {
+	for _, opts := range []security.Options{
+		{OutputKeyCertRotationSignal: "SIGHUP"},
+		{OutputKeyCertRotationSignal: "SIGFOO", OutputKeyCertRotationPIDFile: "/app.pid"},
+		{OutputKeyCertRotationURL: "localhost:8080/reload"},
+	} {
+		if _, err := NewSecretManagerClient(nil, &opts); err == nil {
+			t.Errorf("expected options %+v to be rejected", opts)
+		}
+	}
+}
+
+func TestOutputKeyCertToDirSwap(t *testing.T) {
+	dir := t.TempDir()
+	// Regular files written by previous versions are replaced by symlinks.
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), []byte("old-key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	expectFiles := func(expected map[string]string) {
+		t.Helper()
+		for name, content := range expected {
+			if target, err := os.Readlink(filepath.Join(dir, name)); err != nil || target != filepath.Join("..data", name) {
+				t.Fatalf("expected %s to be a symlink to ..data, got %q: %v", name, target, err)
+			}
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != content {
+				t.Fatalf("expected %s to be %q, got %q", name, content, got)
+			}
+		}
+		versions, err := filepath.Glob(filepath.Join(dir, "..20*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 {
+			t.Fatalf("expected a single version of the files, got %v", versions)
+		}
+	}
+
+	written, err := nodeagentutil.OutputKeyCertToDir(dir, 0, []byte("key"), []byte("chain"), nil)
+	if err != nil || !written {
+		t.Fatalf("expected the files to be written, got %v: %v", written, err)
+	}
+	expectFiles(map[string]string{"key.pem": "key", "cert-chain.pem": "chain"})
+
+	// The files not output are kept in the new version.
+	written, err = nodeagentutil.OutputKeyCertToDir(dir, 0, nil, nil, []byte("root"))
+	if err != nil || !written {
+		t.Fatalf("expected the files to be written, got %v: %v", written, err)
+	}
+	expectFiles(map[string]string{"key.pem": "key", "cert-chain.pem": "chain", "root-cert.pem": "root"})
+
+	written, err = nodeagentutil.OutputKeyCertToDir(dir, 0, []byte("key"), []byte("chain"), nil)
+	if err != nil || written {
+		t.Fatalf("expected the unchanged files not to be written, got %v: %v", written, err)
+	}
+
+	written, err = nodeagentutil.OutputKeyCertToDir(dir, 0, []byte("new-key"), []byte("new-chain"), nil)
+	if err != nil || !written {
+		t.Fatalf("expected the files to be written, got %v: %v", written, err)
+	}
+	expectFiles(map[string]string{"key.pem": "new-key", "cert-chain.pem": "new-chain", "root-cert.pem": "root"})
+}
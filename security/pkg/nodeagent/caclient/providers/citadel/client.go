@@ -20,28 +20,43 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net"
 	"os"
 	"strings"
+	"time"
 
+	retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	"go.uber.org/atomic"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 
 	pb "istio.io/api/security/v1alpha1"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/security/pkg/nodeagent/caclient"
 	"istio.io/istio/security/pkg/pki/util"
+	jwtsvidpb "istio.io/istio/security/proto/jwtsvid/v1alpha1"
 	"istio.io/pkg/log"
 )
 
 const (
 	bearerTokenPrefix = "Bearer "
+
+	// maxRateLimitedAttempts is the number of times a CSR is sent while the CA rate limits it.
+	maxRateLimitedAttempts = 5
 )
 
+// rateLimitedBackoff is the delay before sending again a CSR rate limited by the CA for the first time, doubled
+// on every attempt. The CA may request a longer delay.
+var rateLimitedBackoff = time.Second
+
 var citadelClientLog = log.RegisterScope("citadelclient", "citadel client debugging", 0)
 
 type CitadelClient struct {
@@ -105,7 +120,17 @@ func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) ([]strin
 	}
 
 	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("ClusterID", c.opts.ClusterID))
-	resp, err := c.client.CreateCertificate(ctx, req)
+	var resp *pb.IstioCertificateResponse
+	var err error
+	for attempt := 1; ; attempt++ {
+		resp, err = c.client.CreateCertificate(ctx, req)
+		delay, limited := rateLimitedDelay(err, attempt)
+		if !limited || attempt == maxRateLimitedAttempts {
+			break
+		}
+		citadelClientLog.Warnf("CSR rate limited by the CA, attempt %d in %v", attempt+1, delay)
+		time.Sleep(delay)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("create certificate: %v", err)
 	}
@@ -117,6 +142,66 @@ func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) ([]strin
 	return resp.CertChain, nil
 }
 
+// rateLimitedDelay returns the delay before sending again a CSR rate limited by the CA: the exponential backoff of the
+// attempt, or the delay requested by the CA if longer, with jitter to spread the CSRs of the rate limited agents.
+func rateLimitedDelay(err error, attempt int) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if err == nil || !ok || st.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+	delay := rateLimitedBackoff << (attempt - 1)
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok && info.RetryDelay.AsDuration() > delay {
+			delay = info.RetryDelay.AsDuration()
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1)), true
+}
+
+// caRetryInterceptor retries the CA calls like security.CARetryInterceptor, except the rate limited ones, which
+// CSRSign retries after a longer delay.
+func caRetryInterceptor() grpc.DialOption {
+	var retryCodes []codes.Code
+	for _, c := range security.CARetryCodes {
+		if c != codes.ResourceExhausted {
+			retryCodes = append(retryCodes, c)
+		}
+	}
+	opts := append(append([]retry.CallOption{}, security.CARetryOptions...), retry.WithCodes(retryCodes...))
+	return grpc.WithUnaryInterceptor(retry.UnaryClientInterceptor(opts...))
+}
+
+var _ security.JWTSVIDClient = &CitadelClient{}
+
+// FetchJWTSVID requests a JWT-SVID of the workload identity for the audience from Citadel (Istiod).
+func (c *CitadelClient) FetchJWTSVID(audience []string, ttl time.Duration) (*security.JWTSVIDResponse, error) {
+	if err := c.reconnectIfNeeded(); err != nil {
+		return nil, err
+	}
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("ClusterID", c.opts.ClusterID))
+	// The identity is only used by the CA if its identity mapping has rules for the workload, as in the CSR.
+	spiffeID := spiffe.Identity{
+		TrustDomain:    c.opts.TrustDomain,
+		Namespace:      c.opts.WorkloadNamespace,
+		ServiceAccount: c.opts.ServiceAccount,
+	}
+	req := &jwtsvidpb.JWTSVIDRequest{
+		Audience:         audience,
+		ValidityDuration: int64(ttl.Seconds()),
+		SpiffeId:         spiffeID.String(),
+	}
+	resp, err := jwtsvidpb.NewJWTSVIDServiceClient(c.conn).CreateJWTSVID(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("create JWT-SVID: %v", err)
+	}
+	return &security.JWTSVIDResponse{
+		SpiffeID:  resp.SpiffeId,
+		Token:     resp.Token,
+		ExpiresAt: resp.ExpiresAt.AsTime(),
+	}, nil
+}
+
 func (c *CitadelClient) getTLSDialOption() (grpc.DialOption, error) {
 	certPool, err := getRootCertificate(c.tlsOpts.RootCert)
 	if err != nil {
@@ -207,7 +292,7 @@ func (c *CitadelClient) buildConnection() (*grpc.ClientConn, error) {
 	conn, err := grpc.Dial(c.opts.CAEndpoint,
 		opts,
 		grpc.WithPerRPCCredentials(c.provider),
-		security.CARetryInterceptor())
+		caRetryInterceptor())
 	if err != nil {
 		citadelClientLog.Errorf("Failed to connect to endpoint %s: %v", c.opts.CAEndpoint, err)
 		return nil, fmt.Errorf("failed to connect to endpoint %s", c.opts.CAEndpoint)
@@ -26,11 +26,14 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/atomic"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	pb "istio.io/api/security/v1alpha1"
 	testutil "istio.io/istio/pilot/test/util"
@@ -289,6 +292,72 @@ func TestCitadelClient(t *testing.T) {
 	}
 }
 
+type rateLimitingCAServer struct {
+	pb.UnimplementedIstioCertificateServiceServer
+	limited  int32
+	attempts *atomic.Int32
+}
+
+func (ca *rateLimitingCAServer) CreateCertificate(context.Context, *pb.IstioCertificateRequest) (*pb.IstioCertificateResponse, error) {
+	if ca.attempts.Inc() <= ca.limited {
+		st, _ := status.New(codes.ResourceExhausted, "CSR rate limit exceeded").
+			WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(50 * time.Millisecond)})
+		return nil, st.Err()
+	}
+	return &pb.IstioCertificateResponse{CertChain: fakeCert}, nil
+}
+
+func TestCitadelClientRateLimited(t *testing.T) {
+	rateLimitedBackoff = 10 * time.Millisecond
+	t.Cleanup(func() {
+		rateLimitedBackoff = time.Second
+	})
+	for _, tc := range []struct {
+		name        string
+		limited     int32
+		expectedErr string
+	}{
+		{name: "retried after delay", limited: 2},
+		{name: "too many attempts", limited: maxRateLimitedAttempts, expectedErr: "code = ResourceExhausted"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := &rateLimitingCAServer{limited: tc.limited, attempts: atomic.NewInt32(0)}
+			s := grpc.NewServer()
+			t.Cleanup(s.Stop)
+			lis, err := net.Listen("tcp", mockServerAddress)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pb.RegisterIstioCertificateServiceServer(s, server)
+			go func() {
+				_ = s.Serve(lis)
+			}()
+			cli, err := NewCitadelClient(&security.Options{CAEndpoint: lis.Addr().String()}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(cli.Close)
+
+			start := time.Now()
+			resp, err := cli.CSRSign([]byte{0o1}, 1)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("expected error %q, got %v", tc.expectedErr, err)
+				}
+			} else if err != nil || !reflect.DeepEqual(resp, fakeCert) {
+				t.Fatalf("expected certs after rate limited attempts, got %v: %v", resp, err)
+			}
+			// Rate limited CSRs are only retried by the client, after the delay requested by the CA.
+			if got := server.attempts.Load(); got != tc.limited+1 && got != maxRateLimitedAttempts {
+				t.Fatalf("unexpected number of attempts %d", got)
+			}
+			if elapsed := time.Since(start); elapsed < time.Duration(tc.limited-1)*50*time.Millisecond {
+				t.Fatalf("expected retry delay to be honoured, retried within %v", elapsed)
+			}
+		})
+	}
+}
+
 type mockTokenCAServer struct {
 	pb.UnimplementedIstioCertificateServiceServer
 	Certs []string
@@ -234,6 +234,16 @@ func toEnvoySecret(s *security.SecretItem, caRootPath string, pkpConf *mesh.Priv
 				},
 			},
 		}
+		if len(s.CRL) > 0 {
+			vc := secret.GetValidationContext()
+			vc.Crl = &core.DataSource{
+				Specifier: &core.DataSource_InlineBytes{
+					InlineBytes: s.CRL,
+				},
+			}
+			// The CRL is only issued by the CA signing workload certificates, not by the roots or intermediates.
+			vc.OnlyVerifyLeafCertCrl = true
+		}
 	} else {
 		switch pkpConf.GetProvider().(type) {
 		case *mesh.PrivateKeyProvider_Cryptomb:
@@ -40,6 +40,7 @@ var (
 	fakeRootCert         = []byte{0o0}
 	fakeCertificateChain = []byte{0o1}
 	fakePrivateKey       = []byte{0o2}
+	fakeCRL              = []byte{0o5}
 
 	fakePushCertificateChain = []byte{0o3}
 	fakePushPrivateKey       = []byte{0o4}
@@ -89,6 +90,7 @@ type Expectation struct {
 	CertChain    []byte
 	Key          []byte
 	RootCert     []byte
+	CRL          []byte
 }
 
 func (s *TestServer) extractPrivateKeyProvider(provider *tlsv3.PrivateKeyProvider) []byte {
@@ -116,6 +118,10 @@ func (s *TestServer) Verify(resp *discovery.DiscoveryResponse, expectations ...E
 			Key:          expectationKey,
 			CertChain:    scrt.GetTlsCertificate().GetCertificateChain().GetInlineBytes(),
 			RootCert:     scrt.GetValidationContext().GetTrustedCa().GetInlineBytes(),
+			CRL:          scrt.GetValidationContext().GetCrl().GetInlineBytes(),
+		}
+		if len(r.CRL) > 0 && !scrt.GetValidationContext().GetOnlyVerifyLeafCertCrl() {
+			s.t.Fatalf("expected CRL to only apply to leaf certificates")
 		}
 		if diff := cmp.Diff(e, r); diff != "" {
 			s.t.Fatalf("got diff: %v", diff)
@@ -268,6 +274,22 @@ func TestSDS(t *testing.T) {
 		// No need to push a new root if just the cert changes
 		root.ExpectNoResponse(t)
 	})
+	t.Run("push root with crl", func(t *testing.T) {
+		s := setupSDS(t)
+		root := s.Connect()
+		s.Verify(root.RequestResponseAck(t, &discovery.DiscoveryRequest{ResourceNames: []string{rootResourceName}}), expectRoot)
+
+		s.UpdateSecret(ca2.RootCertReqResourceName, &ca2.SecretItem{
+			RootCert:     fakeRootCert,
+			CRL:          fakeCRL,
+			ResourceName: ca2.RootCertReqResourceName,
+		})
+		s.Verify(root.ExpectResponse(t), Expectation{
+			ResourceName: rootResourceName,
+			RootCert:     fakeRootCert,
+			CRL:          fakeCRL,
+		})
+	})
 	t.Run("reconnect", func(t *testing.T) {
 		s := setupSDS(t)
 		c := s.Connect()
@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// RotationHook notifies the consumers of the key and certificate output to a directory that they were rotated, by
+// sending a signal to a process, and/or a POST request to a URL.
+type RotationHook struct {
+	signal  syscall.Signal
+	pidFile string
+	url     string
+	client  *http.Client
+}
+
+// RotationEvent is the body of the POST request sent to the URL of the rotation hook.
+type RotationEvent struct {
+	// Directory is the directory of the rotated key and certificate files.
+	Directory string `json:"directory"`
+}
+
+// NewRotationHook returns the rotation hook sending the signal to the process whose PID is in the PID file, and/or
+// POSTing a RotationEvent to the URL. The signal is a name, such as SIGHUP, or a number. Returns nil if neither the
+// signal nor the URL is set.
+func NewRotationHook(signal, pidFile, url string) (*RotationHook, error) {
+	if signal == "" && url == "" {
+		return nil, nil
+	}
+	h := &RotationHook{pidFile: pidFile, url: url}
+	if signal != "" {
+		if pidFile == "" {
+			return nil, fmt.Errorf("the PID file of the process to signal on rotation is not set")
+		}
+		sig, f := signals[strings.ToUpper(signal)]
+		if !f {
+			n, err := strconv.Atoi(signal)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid rotation signal %q", signal)
+			}
+			sig = syscall.Signal(n)
+		}
+		h.signal = sig
+	}
+	if url != "" {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("invalid rotation URL %q", url)
+		}
+		h.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return h, nil
+}
+
+// Notify notifies the consumers that the files of the directory were rotated.
+func (h *RotationHook) Notify(dir string) error {
+	if h.signal != 0 {
+		if err := h.sendSignal(); err != nil {
+			return err
+		}
+	}
+	if h.url != "" {
+		return h.post(dir)
+	}
+	return nil
+}
+
+func (h *RotationHook) sendSignal() error {
+	b, err := os.ReadFile(h.pidFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the PID file %s: %v", h.pidFile, err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("invalid PID in %s: %v", h.pidFile, err)
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := p.Signal(h.signal); err != nil {
+		return fmt.Errorf("failed to send %v to process %d: %v", h.signal, pid, err)
+	}
+	return nil
+}
+
+func (h *RotationHook) post(dir string) error {
+	body, err := json.Marshal(RotationEvent{Directory: dir})
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to call the rotation URL %s: %v", h.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("rotation URL %s returned unexpected status %v", h.url, resp.StatusCode)
+	}
+	return nil
+}
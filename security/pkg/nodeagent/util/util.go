@@ -69,16 +69,29 @@ func GetMetricsCounterValueWithTags(metricName string, tags map[string]string) (
 	return float64(0), fmt.Errorf("no metrics matched tags %s: %d", metricName, len(rows))
 }
 
-// Output the key and certificate to the given directory.
-// If directory string is empty, return nil.
-func OutputKeyCertToDir(dir string, privateKey, certChain, rootCert []byte) error {
-	var err error
+const (
+	// keyCertDataDir is the symlink to the current version of the key and certificates output to a directory, which
+	// is a sibling directory holding them, like in the Kubernetes projected volumes.
+	keyCertDataDir = "..data"
+	// keyCertVersionDirFormat is the prefix of the directories holding a version of the key and certificates.
+	keyCertVersionDirFormat = "..2006_01_02_15_04_05."
+)
+
+// Output the key and certificate to the given directory, with the file mode if set.
+// If directory string is empty, return nil. Returns whether any file was written.
+//
+// The files are written to a new directory, and the ..data symlink is then atomically swapped to it, like in the
+// Kubernetes projected volumes: key.pem, cert-chain.pem and root-cert.pem are symlinks through ..data, so that they
+// are always read from the same version. The nil inputs keep their current file.
+func OutputKeyCertToDir(dir string, fileMode os.FileMode, privateKey, certChain, rootCert []byte) (bool, error) {
 	if len(dir) == 0 {
-		return nil
+		return false, nil
 	}
 
 	certFileMode := os.FileMode(0o600)
-	if k8sInCluster.Get() != "" {
+	if fileMode != 0 {
+		certFileMode = fileMode
+	} else if k8sInCluster.Get() != "" {
 		// If this is running on k8s, give more permission to the file certs.
 		// This is typically used to share the certs with non-proxy containers in the pod which does not run as root or 1337.
 		// For example, prometheus server could use proxy provisioned certs to scrape application metrics through mTLS.
@@ -86,30 +99,73 @@ func OutputKeyCertToDir(dir string, privateKey, certChain, rootCert []byte) erro
 	}
 	// Depending on the SDS resource to output, some fields may be nil
 	if privateKey == nil && certChain == nil && rootCert == nil {
-		return fmt.Errorf("the input private key, cert chain, and root cert are nil")
+		return false, fmt.Errorf("the input private key, cert chain, and root cert are nil")
 	}
 
-	writeIfNotEqual := func(fileName string, newData []byte) error {
+	changed := false
+	files := map[string][]byte{}
+	for fileName, newData := range map[string][]byte{"key.pem": privateKey, "cert-chain.pem": certChain, "root-cert.pem": rootCert} {
+		oldData, _ := os.ReadFile(path.Join(dir, fileName))
 		if newData == nil {
-			return nil
+			newData = oldData
+		} else if !bytes.Equal(oldData, newData) {
+			changed = true
 		}
-		oldData, _ := os.ReadFile(path.Join(dir, fileName))
-		if !bytes.Equal(oldData, newData) {
-			if err := file.AtomicWrite(path.Join(dir, fileName), newData, certFileMode); err != nil {
-				return fmt.Errorf("failed to write data to file %v: %v", fileName, err)
-			}
+		if newData != nil {
+			files[fileName] = newData
 		}
-		return nil
+	}
+	if !changed {
+		return false, nil
 	}
 
-	if err = writeIfNotEqual("key.pem", privateKey); err != nil {
-		return err
+	versionDir, err := os.MkdirTemp(dir, time.Now().UTC().Format(keyCertVersionDirFormat))
+	if err != nil {
+		return false, fmt.Errorf("failed to create the directory of the key and certificates: %v", err)
 	}
-	if err = writeIfNotEqual("cert-chain.pem", certChain); err != nil {
-		return err
+	if err := os.Chmod(versionDir, 0o755); err != nil {
+		_ = os.RemoveAll(versionDir)
+		return false, fmt.Errorf("failed to change the mode of %v: %v", versionDir, err)
+	}
+	for fileName, data := range files {
+		if err := file.AtomicWrite(path.Join(versionDir, fileName), data, certFileMode); err != nil {
+			_ = os.RemoveAll(versionDir)
+			return false, fmt.Errorf("failed to write data to file %v: %v", fileName, err)
+		}
+	}
+
+	dataDir := path.Join(dir, keyCertDataDir)
+	oldVersionDir, _ := os.Readlink(dataDir)
+	if err := atomicSymlink(path.Base(versionDir), dataDir); err != nil {
+		_ = os.RemoveAll(versionDir)
+		return false, err
+	}
+	if oldVersionDir != "" {
+		_ = os.RemoveAll(path.Join(dir, oldVersionDir))
+	}
+	// The files are only replaced by symlinks on the first output, or when the directory held regular files.
+	for fileName := range files {
+		target := path.Join(keyCertDataDir, fileName)
+		if current, err := os.Readlink(path.Join(dir, fileName)); err == nil && current == target {
+			continue
+		}
+		if err := atomicSymlink(target, path.Join(dir, fileName)); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// atomicSymlink creates or replaces the symlink with one to the target, atomically.
+func atomicSymlink(target, link string) error {
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("failed to create the symlink %v: %v", tmp, err)
 	}
-	if err = writeIfNotEqual("root-cert.pem", rootCert); err != nil {
-		return err
+	if err := os.Rename(tmp, link); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace %v: %v", link, err)
 	}
 	return nil
 }
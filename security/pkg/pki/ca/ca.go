@@ -16,10 +16,13 @@ package ca
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	apierror "k8s.io/apimachinery/pkg/api/errors"
@@ -109,6 +112,12 @@ type IstioCAOptions struct {
 
 	KeyCertBundle *util.KeyCertBundle
 
+	// Signer signs the certificates in place of the private key of the KeyCertBundle, if set.
+	Signer crypto.Signer
+
+	// JWTSigner signs the JWT-SVIDs. The CA does not sign JWT-SVIDs if not set.
+	JWTSigner crypto.Signer
+
 	// Config for creating self-signed root cert rotator.
 	RotatorConfig *SelfSignedCARootCertRotatorConfig
 }
@@ -270,6 +279,51 @@ func NewPluggedCertIstioCAOptions(fileBundle SigningCAFileBundle,
 	return caOpts, nil
 }
 
+// NewExternalSignerIstioCAOptions returns a new IstioCAOptions instance using given certificate, whose private key
+// is held by the signer. The signing key file of the bundle is not used.
+func NewExternalSignerIstioCAOptions(fileBundle SigningCAFileBundle, signer crypto.Signer,
+	defaultCertTTL, maxCertTTL time.Duration, caRSAKeySize int,
+) (caOpts *IstioCAOptions, err error) {
+	caOpts = &IstioCAOptions{
+		CAType:         pluggedCertCA,
+		DefaultCertTTL: defaultCertTTL,
+		MaxCertTTL:     maxCertTTL,
+		CARSAKeySize:   caRSAKeySize,
+		Signer:         signer,
+	}
+
+	certBytes, err := os.ReadFile(fileBundle.SigningCertFile)
+	if err != nil {
+		return nil, err
+	}
+	var certChainBytes []byte
+	for _, f := range fileBundle.CertChainFiles {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		certChainBytes = append(certChainBytes, b...)
+	}
+	rootCertBytes, err := os.ReadFile(fileBundle.RootCertFile)
+	if err != nil {
+		return nil, err
+	}
+	if caOpts.KeyCertBundle, err = util.NewVerifiedKeyCertBundleWithPublicKey(
+		certBytes, signer.Public(), certChainBytes, rootCertBytes); err != nil {
+		return nil, fmt.Errorf("failed to create CA KeyCertBundle (%v)", err)
+	}
+
+	cert, err := util.ParsePemEncodedCertificate(certBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("certificate is not authorized to sign other certificates")
+	}
+
+	return caOpts, nil
+}
+
 // IstioCA generates keys and certificates for Istio identities.
 type IstioCA struct {
 	defaultCertTTL time.Duration
@@ -277,10 +331,18 @@ type IstioCA struct {
 	caRSAKeySize   int
 
 	keyCertBundle *util.KeyCertBundle
+	// signer signs the certificates in place of the private key of keyCertBundle, if set.
+	signer crypto.Signer
+	// jwtSigner signs the JWT-SVIDs, if set.
+	jwtSigner *jwtSigner
 
 	// rootCertRotator periodically rotates self-signed root cert for CA. It is nil
 	// if CA is not self-signed CA.
 	rootCertRotator *SelfSignedCARootCertRotator
+
+	// issued holds the workload certificates issued to each identity, if TrackIssuedCertificates was called.
+	issued      TrackedCertificates
+	issuedMutex sync.Mutex
 }
 
 // NewIstioCA returns a new IstioCA instance.
@@ -288,9 +350,18 @@ func NewIstioCA(opts *IstioCAOptions) (*IstioCA, error) {
 	ca := &IstioCA{
 		maxCertTTL:    opts.MaxCertTTL,
 		keyCertBundle: opts.KeyCertBundle,
+		signer:        opts.Signer,
 		caRSAKeySize:  opts.CARSAKeySize,
 	}
 
+	if opts.JWTSigner != nil {
+		s, err := newJWTSigner(opts.JWTSigner)
+		if err != nil {
+			return ca, err
+		}
+		ca.jwtSigner = s
+	}
+
 	if opts.CAType == selfSignedCA && opts.RotatorConfig != nil && opts.RotatorConfig.CheckInterval > time.Duration(0) {
 		ca.rootCertRotator = NewSelfSignedCARootCertRotator(opts.RotatorConfig, ca)
 	}
@@ -346,8 +417,11 @@ func (ca *IstioCA) GenKeyCert(hostnames []string, certTTL time.Duration, checkLi
 
 	// use the type of private key the CA uses to generate an intermediate CA of that type (e.g. CA cert using RSA will
 	// cause intermediate CAs using RSA to be generated)
-	_, signingKey, _, _ := ca.keyCertBundle.GetAll()
-	if util.IsSupportedECPrivateKey(signingKey) {
+	if ca.signer != nil {
+		if _, ok := ca.signer.Public().(*ecdsa.PublicKey); ok {
+			opts.ECSigAlg = util.EcdsaSigAlg
+		}
+	} else if _, signingKey, _, _ := ca.keyCertBundle.GetAll(); util.IsSupportedECPrivateKey(signingKey) {
 		opts.ECSigAlg = util.EcdsaSigAlg
 	}
 
@@ -364,6 +438,18 @@ func (ca *IstioCA) GenKeyCert(hostnames []string, certTTL time.Duration, checkLi
 	return certPEM, privPEM, nil
 }
 
+// signingCertKey returns the signing certificate of the CA, and the signer if any or else the private key.
+func (ca *IstioCA) signingCertKey() (*x509.Certificate, crypto.PrivateKey) {
+	signingCert, signingKey, _, _ := ca.keyCertBundle.GetAll()
+	if ca.signer != nil {
+		return signingCert, ca.signer
+	}
+	if signingKey == nil {
+		return signingCert, nil
+	}
+	return signingCert, *signingKey
+}
+
 func (ca *IstioCA) minTTL(defaultCertTTL time.Duration) (time.Duration, error) {
 	certChainPem := ca.keyCertBundle.GetCertChainPem()
 	if len(certChainPem) == 0 {
@@ -387,7 +473,7 @@ func (ca *IstioCA) minTTL(defaultCertTTL time.Duration) (time.Duration, error) {
 }
 
 func (ca *IstioCA) sign(csrPEM []byte, subjectIDs []string, requestedLifetime time.Duration, checkLifetime, forCA bool) ([]byte, error) {
-	signingCert, signingKey, _, _ := ca.keyCertBundle.GetAll()
+	signingCert, signingKey := ca.signingCertKey()
 	if signingCert == nil {
 		return nil, caerror.NewError(caerror.CANotReady, fmt.Errorf("Istio CA is not ready")) // nolint
 	}
@@ -412,10 +498,13 @@ func (ca *IstioCA) sign(csrPEM []byte, subjectIDs []string, requestedLifetime ti
 			"requested TTL %s is greater than the max allowed TTL %s", requestedLifetime, ca.maxCertTTL))
 	}
 
-	certBytes, err := util.GenCertFromCSR(csr, signingCert, csr.PublicKey, *signingKey, subjectIDs, lifetime, forCA)
+	certBytes, err := util.GenCertFromCSR(csr, signingCert, csr.PublicKey, signingKey, subjectIDs, lifetime, forCA)
 	if err != nil {
 		return nil, caerror.NewError(caerror.CertGenError, err)
 	}
+	if !forCA {
+		ca.trackIssuedCertificate(certBytes, subjectIDs)
+	}
 
 	block := &pem.Block{
 		Type:  "CERTIFICATE",
@@ -30,6 +30,7 @@ import (
 
 	k8ssecret "istio.io/istio/security/pkg/k8s/secret"
 	caerror "istio.io/istio/security/pkg/pki/error"
+	"istio.io/istio/security/pkg/pki/signer"
 	"istio.io/istio/security/pkg/pki/util"
 )
 
@@ -445,7 +446,7 @@ func TestSignCSR(t *testing.T) {
 			maxTTL:       365 * 24 * time.Hour,
 			requestedTTL: 30 * 24 * time.Hour,
 			verifyFields: util.VerifyFields{
-				KeyUsage: x509.KeyUsageCertSign,
+				KeyUsage: x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 				IsCA:     true,
 				Host:     subjectID,
 			},
@@ -460,7 +461,7 @@ func TestSignCSR(t *testing.T) {
 			maxTTL:       365 * 24 * time.Hour,
 			requestedTTL: 30 * 24 * time.Hour,
 			verifyFields: util.VerifyFields{
-				KeyUsage: x509.KeyUsageCertSign,
+				KeyUsage: x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 				IsCA:     true,
 				Host:     subjectID,
 			},
@@ -630,6 +631,57 @@ func TestSignWithCertChain(t *testing.T) {
 	}
 }
 
+func TestExternalSignerCA(t *testing.T) {
+	fileBundle := SigningCAFileBundle{
+		RootCertFile:    "../testdata/multilevelpki/root-cert.pem",
+		CertChainFiles:  []string{"../testdata/multilevelpki/int-cert-chain.pem"},
+		SigningCertFile: "../testdata/multilevelpki/int-cert.pem",
+	}
+
+	mismatched, err := signer.NewFileSigner("../testdata/key.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewExternalSignerIstioCAOptions(fileBundle, mismatched, 30*time.Minute, time.Hour, 2048); err == nil {
+		t.Fatal("expected a signer not matching the signing cert to be rejected")
+	}
+
+	keySigner, err := signer.NewFileSigner("../testdata/multilevelpki/int-key.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caopts, err := NewExternalSignerIstioCAOptions(fileBundle, keySigner, 30*time.Minute, time.Hour, 2048)
+	if err != nil {
+		t.Fatalf("Failed to create an external signer CA Options: %v", err)
+	}
+	ca, err := NewIstioCA(caopts)
+	if err != nil {
+		t.Fatalf("Got error while creating external signer CA: %v", err)
+	}
+	if _, key, _, _ := ca.GetCAKeyCertBundle().GetAllPem(); len(key) != 0 {
+		t.Fatalf("expected no private key in the CA bundle, got %s", key)
+	}
+
+	certPEM, privPEM, err := ca.GenKeyCert([]string{"spiffe://cluster.local/ns/foo/sa/bar"}, time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, privPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cert.Certificate) != 3 {
+		t.Fatalf("Unexpected number of certificates returned: %d (expected 3)", len(cert.Certificate))
+	}
+	if err := util.VerifyCertificate(privPEM, certPEM, ca.GetCAKeyCertBundle().GetRootCertPem(), &util.VerifyFields{
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		Host:        "spiffe://cluster.local/ns/foo/sa/bar",
+	}); err != nil {
+		t.Fatalf("Failed to verify the certificate signed by the external signer: %v", err)
+	}
+}
+
 func TestGenKeyCert(t *testing.T) {
 	cases := map[string]struct {
 		rootCertFile      string
@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	v1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// JWTSigningKeyFile is the file of the plugged-in CA holding the PEM encoded private key signing the JWT-SVIDs.
+	JWTSigningKeyFile = "jwt-signing-key.pem"
+	// JWTSigningKeySecret stores the private key signing the JWT-SVIDs generated by istiod, shared by its replicas.
+	JWTSigningKeySecret = "istio-ca-jwt-signing-key"
+)
+
+// LoadJWTSigningKey reads the PEM encoded private key signing the JWT-SVIDs from the file.
+func LoadJWTSigningKey(file string) (crypto.Signer, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseJWTSigningKey(b)
+}
+
+// LoadOrCreateJWTSigningKey reads the private key signing the JWT-SVIDs from JWTSigningKeySecret, creating the secret
+// with a new ECDSA P-256 key if it does not exist.
+func LoadOrCreateJWTSigningKey(ctx context.Context, client corev1.CoreV1Interface, namespace string) (crypto.Signer, error) {
+	secret, err := client.Secrets(namespace).Get(ctx, JWTSigningKeySecret, metav1.GetOptions{})
+	if err == nil {
+		return parseJWTSigningKey(secret.Data[PrivateKeyFile])
+	}
+	if !apierror.IsNotFound(err) {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	secret = &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: JWTSigningKeySecret, Namespace: namespace},
+		Type:       v1.SecretTypeOpaque,
+		Data:       map[string][]byte{PrivateKeyFile: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})},
+	}
+	if _, err := client.Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		if apierror.IsAlreadyExists(err) {
+			// Created concurrently by another istiod.
+			return LoadOrCreateJWTSigningKey(ctx, client, namespace)
+		}
+		return nil, err
+	}
+	pkiCaLog.Infof("Created the JWT-SVID signing key secret %s/%s", namespace, JWTSigningKeySecret)
+	return key, nil
+}
+
+func parseJWTSigningKey(b []byte) (crypto.Signer, error) {
+	key, err := util.ParsePemEncodedKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the JWT-SVID signing key: %v", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported JWT-SVID signing key type %T", key)
+	}
+	return signer, nil
+}
+
+// SignJWTSVID returns a JWT-SVID of the SPIFFE ID for the audiences, valid for the TTL, and signed by the JWT signing
+// key of the CA. The JWT-SVID can be verified with the JWTAuthorities of the CA.
+func (ca *IstioCA) SignJWTSVID(spiffeID string, audiences []string, ttl time.Duration) (string, time.Time, error) {
+	if ca.jwtSigner == nil {
+		return "", time.Time{}, fmt.Errorf("the CA has no JWT-SVID signing key")
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: ca.jwtSigner.alg, Key: ca.jwtSigner},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", ca.jwtSigner.jwk.KeyID))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiry := now.Add(ttl)
+	token, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Subject:  spiffeID,
+		Audience: audiences,
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(expiry),
+	}).CompactSerialize()
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign JWT-SVID: %v", err)
+	}
+	return token, expiry, nil
+}
+
+// JWTAuthorities returns the public keys verifying the JWT-SVIDs signed by the CA, with their key ID.
+func (ca *IstioCA) JWTAuthorities() ([]jose.JSONWebKey, error) {
+	if ca.jwtSigner == nil {
+		return nil, nil
+	}
+	return []jose.JSONWebKey{*ca.jwtSigner.jwk}, nil
+}
+
+// jwtSigner signs JWTs with a crypto.Signer, which may hold no private key, such as an external signer.
+type jwtSigner struct {
+	signer crypto.Signer
+	alg    jose.SignatureAlgorithm
+	jwk    *jose.JSONWebKey
+}
+
+var _ jose.OpaqueSigner = &jwtSigner{}
+
+func newJWTSigner(signer crypto.Signer) (*jwtSigner, error) {
+	s := &jwtSigner{signer: signer}
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		s.alg = jose.RS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			s.alg = jose.ES256
+		case elliptic.P384():
+			s.alg = jose.ES384
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve %s for JWT-SVIDs", pub.Curve.Params().Name)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T for JWT-SVIDs", pub)
+	}
+	s.jwk = &jose.JSONWebKey{Key: signer.Public(), Algorithm: string(s.alg)}
+	thumbprint, err := s.jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	s.jwk.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return s, nil
+}
+
+func (s *jwtSigner) Public() *jose.JSONWebKey {
+	return s.jwk
+}
+
+func (s *jwtSigner) Algs() []jose.SignatureAlgorithm {
+	return []jose.SignatureAlgorithm{s.alg}
+}
+
+func (s *jwtSigner) SignPayload(payload []byte, alg jose.SignatureAlgorithm) ([]byte, error) {
+	hash := crypto.SHA256
+	if alg == jose.ES384 {
+		hash = crypto.SHA384
+	}
+	h := hash.New()
+	h.Write(payload)
+	signature, err := s.signer.Sign(rand.Reader, h.Sum(nil), hash)
+	if err != nil || alg == jose.RS256 {
+		return signature, err
+	}
+	// JWS ECDSA signatures are the concatenation of R and S, not ASN.1 encoded.
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(signature, &sig); err != nil {
+		return nil, fmt.Errorf("invalid ECDSA signature: %v", err)
+	}
+	size := (s.signer.Public().(*ecdsa.PublicKey).Curve.Params().BitSize + 7) / 8
+	out := make([]byte, 2*size)
+	sig.R.FillBytes(out[:size])
+	sig.S.FillBytes(out[size:])
+	return out, nil
+}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSignJWTSVID(t *testing.T) {
+	const spiffeID = "spiffe://cluster.local/ns/foo/sa/bar"
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, key := range map[string]crypto.Signer{"RSA": rsaKey, "ECDSA": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			ca, err := createCA(time.Hour, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := ca.SignJWTSVID(spiffeID, []string{"foo.com"}, 5*time.Minute); err == nil {
+				t.Fatal("expected the CA without JWT signing key not to sign JWT-SVIDs")
+			}
+			if ca.jwtSigner, err = newJWTSigner(key); err != nil {
+				t.Fatal(err)
+			}
+			token, expiry, err := ca.SignJWTSVID(spiffeID, []string{"foo.com", "bar.com"}, 5*time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := time.Until(expiry); d <= 4*time.Minute || d > 5*time.Minute {
+				t.Fatalf("unexpected expiry %v", expiry)
+			}
+
+			authorities, err := ca.JWTAuthorities()
+			if err != nil || len(authorities) != 1 {
+				t.Fatalf("expected one JWT authority, got %v: %v", authorities, err)
+			}
+			parsed, err := jwt.ParseSigned(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid := parsed.Headers[0].KeyID; kid != authorities[0].KeyID {
+				t.Fatalf("expected key ID %s, got %s", authorities[0].KeyID, kid)
+			}
+			claims := jwt.Claims{}
+			if err := parsed.Claims(authorities[0].Key, &claims); err != nil {
+				t.Fatalf("failed to verify the JWT-SVID: %v", err)
+			}
+			if err := claims.Validate(jwt.Expected{Subject: spiffeID, Audience: jwt.Audience{"foo.com"}, Time: time.Now()}); err != nil {
+				t.Fatalf("unexpected claims %+v: %v", claims, err)
+			}
+		})
+	}
+}
+
+func TestLoadOrCreateJWTSigningKey(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	created, err := LoadOrCreateJWTSigningKey(context.Background(), client.CoreV1(), "istio-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadOrCreateJWTSigningKey(context.Background(), client.CoreV1(), "istio-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created.Public().(*ecdsa.PublicKey).Equal(loaded.Public()) {
+		t.Fatal("expected the key of the secret to be loaded")
+	}
+}
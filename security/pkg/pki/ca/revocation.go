@@ -0,0 +1,541 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	// RevocationSecret stores the list of the certificates revoked by the CA.
+	RevocationSecret = "istio-ca-revocations"
+	// RevocationListFile is the key of the JSON encoded RevocationList in RevocationSecret.
+	RevocationListFile = "revocations.json"
+	// CRLValidity is how long the CRLs generated by the CA are valid for. Proxies reject all the peer certificates
+	// once their CRL expires, so CRLs must be regenerated and distributed well before.
+	CRLValidity = 24 * time.Hour
+
+	// TrackedCertificatesSecretPrefix prefixes the names of the secrets where each CA instance saves the certificates
+	// it has issued, so that the certificates of revoked identities are found even if the instance has restarted.
+	TrackedCertificatesSecretPrefix = "istio-ca-issued-"
+	// TrackedCertificatesLabel is set to "true" on the secrets of the tracked certificates.
+	TrackedCertificatesLabel = "istio.io/ca-issued-certificates"
+	// TrackedCertificatesInstanceAnnotation is the name of the CA instance which saved the tracked certificates of a
+	// secret.
+	TrackedCertificatesInstanceAnnotation = "istio.io/ca-instance"
+	// TrackedCertificatesFile is the key of the JSON encoded TrackedCertificates in their secrets.
+	TrackedCertificatesFile = "issued.json"
+
+	// maxTrackedCertificatesSize bounds the size of the tracked certificates saved in each secret, well below the
+	// 1 MiB limit of the Kubernetes secrets. The certificates of an instance are split across as many secrets as needed.
+	maxTrackedCertificatesSize = 512 * 1024
+)
+
+// RevokedCertificate is an entry of a RevocationList. Exactly one of SerialNumber and Identity is set.
+type RevokedCertificate struct {
+	// SerialNumber is the lowercase hex encoded serial number of the revoked certificate.
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// Identity is the SPIFFE identity, all the certificates of which issued before RevocationTime are revoked.
+	Identity string `json:"identity,omitempty"`
+	// RevocationTime is when the certificate or identity was revoked.
+	RevocationTime time.Time `json:"revocationTime"`
+	// Reason is a free form description of why the certificate or identity was revoked.
+	Reason string `json:"reason,omitempty"`
+	// NotAfter is when the revoked certificate expires, if known.
+	NotAfter *time.Time `json:"notAfter,omitempty"`
+}
+
+// expired returns true if the certificates of the entry have all expired. The certificates of an entry are issued
+// before its revocation, so they expire at most maxCertTTL after it if their expiration is not known.
+func (r RevokedCertificate) expired(now time.Time, maxCertTTL time.Duration) bool {
+	if r.NotAfter != nil {
+		return now.After(*r.NotAfter)
+	}
+	return now.Sub(r.RevocationTime) > maxCertTTL
+}
+
+// RevocationList is the list of the certificates revoked by the CA, as stored in RevocationSecret.
+type RevocationList struct {
+	Revoked []RevokedCertificate `json:"revoked"`
+}
+
+// ParseRevocationList parses a JSON encoded RevocationList. An empty input is an empty list.
+func ParseRevocationList(b []byte) (*RevocationList, error) {
+	l := &RevocationList{}
+	if len(b) == 0 {
+		return l, nil
+	}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("failed to parse revocation list: %v", err)
+	}
+	for i, r := range l.Revoked {
+		if (r.SerialNumber == "") == (r.Identity == "") {
+			return nil, fmt.Errorf("entry %d of revocation list must have exactly one of serialNumber and identity", i)
+		}
+		if r.SerialNumber != "" {
+			if _, err := ParseSerialNumber(r.SerialNumber); err != nil {
+				return nil, fmt.Errorf("entry %d of revocation list: %v", i, err)
+			}
+		}
+	}
+	return l, nil
+}
+
+// Marshal returns the JSON encoding of the list.
+func (l *RevocationList) Marshal() ([]byte, error) {
+	return json.MarshalIndent(l, "", "  ")
+}
+
+// ParseSerialNumber parses a hex encoded certificate serial number, optionally separated by colons as printed by openssl.
+func ParseSerialNumber(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.ReplaceAll(strings.TrimPrefix(s, "0x"), ":", ""), 16)
+	if !ok || n.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid certificate serial number %q", s)
+	}
+	return n, nil
+}
+
+// RevokeSerialNumber adds the certificate with the given hex encoded serial number to the list.
+// It returns false if the certificate was already revoked.
+func (l *RevocationList) RevokeSerialNumber(serial string, at time.Time, reason string) (bool, error) {
+	return l.revokeSerialNumber(serial, at, nil, reason)
+}
+
+func (l *RevocationList) revokeSerialNumber(serial string, at time.Time, notAfter *time.Time, reason string) (bool, error) {
+	n, err := ParseSerialNumber(serial)
+	if err != nil {
+		return false, err
+	}
+	serial = n.Text(16)
+	for _, r := range l.Revoked {
+		if r.SerialNumber == serial {
+			return false, nil
+		}
+	}
+	l.Revoked = append(l.Revoked, RevokedCertificate{SerialNumber: serial, RevocationTime: at, Reason: reason, NotAfter: notAfter})
+	return true, nil
+}
+
+// Prune removes the entries whose certificates have all expired, as of now, returning true if the list has changed.
+// Certificates are valid for at most maxCertTTL, which bounds the expiration of the ones revoked by identity or by a
+// serial number with no known expiration.
+func (l *RevocationList) Prune(now time.Time, maxCertTTL time.Duration) bool {
+	revoked := l.Revoked[:0]
+	for _, r := range l.Revoked {
+		if !r.expired(now, maxCertTTL) {
+			revoked = append(revoked, r)
+		}
+	}
+	changed := len(revoked) != len(l.Revoked)
+	l.Revoked = revoked
+	return changed
+}
+
+// RevokeIdentity adds the certificates of the given SPIFFE identity issued until now to the list.
+// Certificates issued after the revocation are valid, so that workloads can get new ones with new keys.
+func (l *RevocationList) RevokeIdentity(identity string, at time.Time, reason string) error {
+	if _, err := spiffe.ParseIdentity(identity); err != nil {
+		return fmt.Errorf("invalid identity %q: %v", identity, err)
+	}
+	l.Revoked = append(l.Revoked, RevokedCertificate{Identity: identity, RevocationTime: at, Reason: reason})
+	return nil
+}
+
+// LoadRevocationList reads the revocation list from RevocationSecret, returning an empty list if there is no secret.
+func LoadRevocationList(ctx context.Context, client corev1.CoreV1Interface, namespace string) (*RevocationList, error) {
+	secret, err := client.Secrets(namespace).Get(ctx, RevocationSecret, metav1.GetOptions{})
+	if apierror.IsNotFound(err) {
+		return &RevocationList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseRevocationList(secret.Data[RevocationListFile])
+}
+
+// UpdateRevocationList applies update to the revocation list in RevocationSecret, creating the secret if needed.
+// The secret is not written if update returns false.
+func UpdateRevocationList(ctx context.Context, client corev1.CoreV1Interface, namespace string,
+	update func(l *RevocationList) (bool, error),
+) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := client.Secrets(namespace).Get(ctx, RevocationSecret, metav1.GetOptions{})
+		create := apierror.IsNotFound(err)
+		if create {
+			secret = &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: RevocationSecret, Namespace: namespace},
+				Type:       v1.SecretTypeOpaque,
+			}
+		} else if err != nil {
+			return err
+		}
+		l, err := ParseRevocationList(secret.Data[RevocationListFile])
+		if err != nil {
+			return err
+		}
+		changed, err := update(l)
+		if err != nil || !changed {
+			return err
+		}
+		b, err := l.Marshal()
+		if err != nil {
+			return err
+		}
+		secret.Data = map[string][]byte{RevocationListFile: b}
+		if create {
+			_, err = client.Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+			if apierror.IsAlreadyExists(err) {
+				// Created concurrently, retry as an update.
+				return apierror.NewConflict(v1.Resource("secrets"), RevocationSecret, err)
+			}
+			return err
+		}
+		_, err = client.Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// TrackedCertificate is a workload certificate issued by the CA, remembered to revoke the certificates of an identity.
+type TrackedCertificate struct {
+	// SerialNumber is the lowercase hex encoded serial number of the certificate.
+	SerialNumber string    `json:"serialNumber"`
+	Issued       time.Time `json:"issued"`
+	Expires      time.Time `json:"expires"`
+}
+
+// TrackedCertificates are the unexpired certificates issued to each identity.
+type TrackedCertificates map[string][]TrackedCertificate
+
+// Merge adds the unexpired certificates of other which are not already in t.
+func (t TrackedCertificates) Merge(other TrackedCertificates) {
+	now := time.Now()
+	for id, certs := range other {
+		for _, c := range certs {
+			if !c.Expires.After(now) || t.contains(id, c.SerialNumber) {
+				continue
+			}
+			t[id] = append(t[id], c)
+		}
+	}
+}
+
+func (t TrackedCertificates) contains(identity, serial string) bool {
+	for _, c := range t[identity] {
+		if c.SerialNumber == serial {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackIssuedCertificates makes the CA remember the workload certificates it issues from now on, until they expire,
+// so that RevokeIssuedCertificates can find the certificates of revoked identities.
+func (ca *IstioCA) TrackIssuedCertificates() {
+	ca.issuedMutex.Lock()
+	defer ca.issuedMutex.Unlock()
+	if ca.issued == nil {
+		ca.issued = TrackedCertificates{}
+	}
+}
+
+// trackIssuedCertificate remembers a certificate issued to the given identities, if tracking is enabled.
+func (ca *IstioCA) trackIssuedCertificate(certBytes []byte, subjectIDs []string) {
+	ca.issuedMutex.Lock()
+	defer ca.issuedMutex.Unlock()
+	if ca.issued == nil {
+		return
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		pkiCaLog.Warnf("failed to parse issued certificate: %v", err)
+		return
+	}
+	now := time.Now()
+	for _, id := range subjectIDs {
+		certs := ca.issued[id][:0]
+		for _, c := range ca.issued[id] {
+			if c.Expires.After(now) {
+				certs = append(certs, c)
+			}
+		}
+		ca.issued[id] = append(certs, TrackedCertificate{SerialNumber: cert.SerialNumber.Text(16), Issued: now, Expires: cert.NotAfter})
+	}
+}
+
+// IssuedCertificates returns the unexpired certificates tracked by the CA.
+func (ca *IstioCA) IssuedCertificates() TrackedCertificates {
+	ca.issuedMutex.Lock()
+	defer ca.issuedMutex.Unlock()
+	out := TrackedCertificates{}
+	out.Merge(ca.issued)
+	return out
+}
+
+// AddIssuedCertificates adds certificates issued by this CA before it started tracking them, such as before a
+// restart, to the tracked ones. It has no effect if tracking is not enabled.
+func (ca *IstioCA) AddIssuedCertificates(certs TrackedCertificates) {
+	ca.issuedMutex.Lock()
+	defer ca.issuedMutex.Unlock()
+	if ca.issued != nil {
+		ca.issued.Merge(certs)
+	}
+}
+
+// RevokeIssuedCertificates adds to the list the serial numbers of the certificates the CA has tracked for the identities
+// revoked in it, returning true if the list has changed. See RevocationList.RevokeIssuedCertificates.
+func (ca *IstioCA) RevokeIssuedCertificates(l *RevocationList) bool {
+	return l.RevokeIssuedCertificates(ca.IssuedCertificates())
+}
+
+// PruneRevocationList removes the entries of the list whose certificates have expired, given the max certificate TTL
+// of the CA, returning true if the list has changed.
+func (ca *IstioCA) PruneRevocationList(l *RevocationList) bool {
+	return l.Prune(time.Now(), ca.maxCertTTL)
+}
+
+// RevokeIssuedCertificates adds to the list the serial numbers of the certificates issued to the identities revoked in
+// it before their revocation, returning true if the list has changed. Only the certificates in issued are known, so
+// the certificates tracked by every instance of the CA must be given, see ListTrackedCertificates.
+func (l *RevocationList) RevokeIssuedCertificates(issued TrackedCertificates) bool {
+	changed := false
+	for _, r := range l.Revoked {
+		if r.Identity == "" {
+			continue
+		}
+		for _, c := range issued[r.Identity] {
+			if c.Issued.After(r.RevocationTime) {
+				continue
+			}
+			reason := fmt.Sprintf("identity %s revoked", r.Identity)
+			if r.Reason != "" {
+				reason += ": " + r.Reason
+			}
+			expires := c.Expires
+			if added, _ := l.revokeSerialNumber(c.SerialNumber, r.RevocationTime, &expires, reason); added {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// TrackedCertificatesSecretName returns the name of the secret of the given shard of the certificates tracked by the
+// CA instance with the given name, such as the istiod pod name.
+func TrackedCertificatesSecretName(instance string, shard int) string {
+	return fmt.Sprintf("%s%s-%d", TrackedCertificatesSecretPrefix, instance, shard)
+}
+
+// shards returns the JSON encodings of parts of the certificates, each at most maxSize bytes long. The certificates
+// of an identity may be split across parts.
+func (t TrackedCertificates) shards(maxSize int) ([][]byte, error) {
+	ids := make([]string, 0, len(t))
+	for id := range t {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var out [][]byte
+	shard, size := TrackedCertificates{}, 0
+	flush := func() error {
+		b, err := json.Marshal(shard)
+		if err != nil {
+			return err
+		}
+		out = append(out, b)
+		shard, size = TrackedCertificates{}, 0
+		return nil
+	}
+	for _, id := range ids {
+		for _, c := range t[id] {
+			b, err := json.Marshal(c)
+			if err != nil {
+				return nil, err
+			}
+			// Over-estimate the size of the entry by counting the identity key for each certificate.
+			entrySize := len(b) + len(id) + 8
+			if size+entrySize > maxSize && size > 0 {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			shard[id] = append(shard[id], c)
+			size += entrySize
+		}
+	}
+	if size > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// SaveTrackedCertificates saves the certificates tracked by a CA instance in its secrets, splitting them across as
+// many secrets as needed to stay within the size limit of a secret. The secrets no longer needed are removed, so saving
+// no certificates removes all the secrets of the instance.
+func SaveTrackedCertificates(ctx context.Context, client corev1.CoreV1Interface, namespace, instance string,
+	certs TrackedCertificates,
+) error {
+	shards, err := certs.shards(maxTrackedCertificatesSize)
+	if err != nil {
+		return err
+	}
+	secrets, err := client.Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: TrackedCertificatesLabel + "=true"})
+	if err != nil {
+		return err
+	}
+	existing := map[string]*v1.Secret{}
+	for i, secret := range secrets.Items {
+		if secret.Annotations[TrackedCertificatesInstanceAnnotation] == instance {
+			existing[secret.Name] = &secrets.Items[i]
+		}
+	}
+
+	for i, b := range shards {
+		name := TrackedCertificatesSecretName(instance, i)
+		if current, f := existing[name]; f {
+			delete(existing, name)
+			if bytes.Equal(current.Data[TrackedCertificatesFile], b) {
+				continue
+			}
+		}
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Labels:      map[string]string{TrackedCertificatesLabel: "true"},
+				Annotations: map[string]string{TrackedCertificatesInstanceAnnotation: instance},
+			},
+			Type: v1.SecretTypeOpaque,
+			Data: map[string][]byte{TrackedCertificatesFile: b},
+		}
+		_, err = client.Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+		if apierror.IsNotFound(err) {
+			_, err = client.Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// The secrets are removed after the others are written, so that their certificates are always saved in one of them.
+	for name := range existing {
+		if err := client.Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierror.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTrackedCertificates returns the unexpired certificates saved by each CA instance, by instance name.
+// Instances which no longer exist, such as former istiod pods, are included until their secrets are removed.
+func ListTrackedCertificates(ctx context.Context, client corev1.CoreV1Interface, namespace string) (map[string]TrackedCertificates, error) {
+	secrets, err := client.Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: TrackedCertificatesLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]TrackedCertificates{}
+	for _, secret := range secrets.Items {
+		saved := TrackedCertificates{}
+		if err := json.Unmarshal(secret.Data[TrackedCertificatesFile], &saved); err != nil {
+			return nil, fmt.Errorf("invalid secret %s/%s: %v", namespace, secret.Name, err)
+		}
+		instance := secret.Annotations[TrackedCertificatesInstanceAnnotation]
+		if out[instance] == nil {
+			out[instance] = TrackedCertificates{}
+		}
+		out[instance].Merge(saved)
+	}
+	return out, nil
+}
+
+// CheckCRLSigning returns an error if the signing certificate of the CA cannot sign CRLs. CA certificates generated
+// without the cRLSign key usage, such as by former versions of tools/certs, must be reissued to enable revocation.
+func (ca *IstioCA) CheckCRLSigning() error {
+	_, _, err := ca.crlSigner()
+	return err
+}
+
+// crlSigner returns the signing certificate and key of the CA, if they can sign CRLs.
+func (ca *IstioCA) crlSigner() (*x509.Certificate, crypto.Signer, error) {
+	signingCert, signingKey := ca.signingCertKey()
+	if signingCert == nil {
+		return nil, nil, fmt.Errorf("istio CA is not ready")
+	}
+	signer, ok := signingKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("the CA private key cannot sign CRLs")
+	}
+	if signingCert.KeyUsage != 0 && signingCert.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return nil, nil, fmt.Errorf("the CA certificate does not allow signing CRLs, as it lacks the cRLSign key usage")
+	}
+	return signingCert, signer, nil
+}
+
+// GenerateCRL returns a PEM encoded CRL of the certificates revoked in the list, signed by the CA. Entries of expired
+// certificates are left out.
+func (ca *IstioCA) GenerateCRL(l *RevocationList) ([]byte, error) {
+	signingCert, signer, err := ca.crlSigner()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var revoked []pkix.RevokedCertificate
+	for _, r := range l.Revoked {
+		if r.SerialNumber == "" || r.expired(now, ca.maxCertTTL) {
+			continue
+		}
+		n, err := ParseSerialNumber(r.SerialNumber)
+		if err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: n, RevocationTime: r.RevocationTime})
+	}
+	sort.Slice(revoked, func(i, j int) bool {
+		return revoked[i].SerialNumber.Cmp(revoked[j].SerialNumber) < 0
+	})
+	template := &x509.RevocationList{
+		RevokedCertificates: revoked,
+		// CRLs generated later, by any instance of the CA, must have a greater number.
+		Number:     big.NewInt(now.UnixNano()),
+		ThisUpdate: now.Add(-util.ClockSkewGracePeriod),
+		NextUpdate: now.Add(CRLValidity),
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, signingCert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRL: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), nil
+}
@@ -0,0 +1,280 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestRevocationList(t *testing.T) {
+	l := &RevocationList{}
+	now := time.Now()
+	if added, err := l.RevokeSerialNumber("0A:1B:2c", now, "leaked"); err != nil || !added {
+		t.Fatalf("expected serial number to be revoked, got %v %v", added, err)
+	}
+	if added, err := l.RevokeSerialNumber("a1b2c", now, ""); err != nil || added {
+		t.Fatalf("expected serial number to be already revoked, got %v %v", added, err)
+	}
+	if _, err := l.RevokeSerialNumber("not-hex", now, ""); err == nil {
+		t.Fatalf("expected invalid serial number to be rejected")
+	}
+	if err := l.RevokeIdentity("spiffe://cluster.local/ns/default/sa/bad", now, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.RevokeIdentity("cluster.local/ns/default/sa/bad", now, ""); err == nil {
+		t.Fatalf("expected invalid identity to be rejected")
+	}
+
+	b, err := l.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseRevocationList(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Revoked) != 2 || parsed.Revoked[0].SerialNumber != "a1b2c" || parsed.Revoked[0].Reason != "leaked" {
+		t.Fatalf("unexpected revocation list %+v", parsed.Revoked)
+	}
+
+	for _, invalid := range []string{
+		`{"revoked":[{"revocationTime":"2022-01-01T00:00:00Z"}]}`,
+		`{"revoked":[{"serialNumber":"zz","revocationTime":"2022-01-01T00:00:00Z"}]}`,
+		`{"revoked":[{"serialNumber":"1a","identity":"spiffe://cluster.local/ns/a/sa/b"}]}`,
+	} {
+		if _, err := ParseRevocationList([]byte(invalid)); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestGenerateCRL(t *testing.T) {
+	ca, err := createCA(24*time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca.TrackIssuedCertificates()
+	identity := "spiffe://cluster.local/ns/default/sa/bad"
+	sign := func(id string) *big.Int {
+		t.Helper()
+		csrPEM, _, err := util.GenCSR(util.CertOptions{Host: id, RSAKeySize: 2048})
+		if err != nil {
+			t.Fatal(err)
+		}
+		certPEM, err := ca.Sign(csrPEM, CertOpts{SubjectIDs: []string{id}, TTL: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := util.ParsePemEncodedCertificate(certPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert.SerialNumber
+	}
+	revoked := sign(identity)
+	other := sign("spiffe://cluster.local/ns/default/sa/good")
+
+	l := &RevocationList{}
+	if err := l.RevokeIdentity(identity, time.Now(), "compromised"); err != nil {
+		t.Fatal(err)
+	}
+	renewed := sign(identity)
+	if !ca.RevokeIssuedCertificates(l) {
+		t.Fatalf("expected certificates of the revoked identity to be added")
+	}
+	if ca.RevokeIssuedCertificates(l) {
+		t.Fatalf("expected certificates to be added once")
+	}
+	// Revocations older than the max TTL are left out of the CRL.
+	l.Revoked = append(l.Revoked, RevokedCertificate{SerialNumber: "abc", RevocationTime: time.Now().Add(-48 * time.Hour)})
+
+	crlPEM, err := ca.GenerateCRL(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(crlPEM)
+	if block == nil || block.Type != "X509 CRL" {
+		t.Fatalf("expected a PEM encoded CRL, got %s", crlPEM)
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingCert, _, _, _ := ca.GetCAKeyCertBundle().GetAll()
+	if err := crl.CheckSignatureFrom(signingCert); err != nil {
+		t.Fatalf("CRL is not signed by the CA: %v", err)
+	}
+	if len(crl.RevokedCertificates) != 1 || crl.RevokedCertificates[0].SerialNumber.Cmp(revoked) != 0 {
+		t.Fatalf("expected only %v to be revoked, got %+v (other %v, renewed %v)", revoked, crl.RevokedCertificates, other, renewed)
+	}
+	if !crl.NextUpdate.After(time.Now().Add(CRLValidity - time.Minute)) {
+		t.Fatalf("unexpected CRL next update %v", crl.NextUpdate)
+	}
+}
+
+func TestGenerateCRLWithoutCRLSignKeyUsage(t *testing.T) {
+	caopts, err := NewPluggedCertIstioCAOptions(SigningCAFileBundle{
+		RootCertFile:    "../testdata/multilevelpki/root-cert.pem",
+		CertChainFiles:  []string{"../testdata/multilevelpki/int-cert-chain.pem"},
+		SigningCertFile: "../testdata/multilevelpki/int-cert.pem",
+		SigningKeyFile:  "../testdata/multilevelpki/int-key.pem",
+	}, time.Hour, time.Hour, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := NewIstioCA(caopts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.CheckCRLSigning(); err == nil || !strings.Contains(err.Error(), "cRLSign") {
+		t.Fatalf("expected the CA certificate to be rejected without cRLSign key usage, got %v", err)
+	}
+	if _, err := ca.GenerateCRL(&RevocationList{}); err == nil || !strings.Contains(err.Error(), "cRLSign") {
+		t.Fatalf("expected CRL generation to fail without cRLSign key usage, got %v", err)
+	}
+}
+
+func TestUpdateRevocationList(t *testing.T) {
+	client := fake.NewSimpleClientset().CoreV1()
+	ctx := context.Background()
+	revoke := func(serial string) {
+		t.Helper()
+		err := UpdateRevocationList(ctx, client, "istio-system", func(l *RevocationList) (bool, error) {
+			return l.RevokeSerialNumber(serial, time.Now(), "")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	revoke("1a")
+	revoke("2b")
+	revoke("1a")
+
+	l, err := LoadRevocationList(ctx, client, "istio-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.Revoked) != 2 || l.Revoked[0].SerialNumber != "1a" || l.Revoked[1].SerialNumber != "2b" {
+		t.Fatalf("unexpected revocation list %+v", l.Revoked)
+	}
+	if l, err := LoadRevocationList(ctx, client, "other"); err != nil || len(l.Revoked) != 0 {
+		t.Fatalf("expected empty list without secret, got %v %v", l, err)
+	}
+}
+
+func TestPruneRevocationList(t *testing.T) {
+	now := time.Now()
+	expired, valid := now.Add(-time.Minute), now.Add(time.Minute)
+	l := &RevocationList{Revoked: []RevokedCertificate{
+		{SerialNumber: "1a", RevocationTime: now.Add(-time.Hour), NotAfter: &expired},
+		{SerialNumber: "2b", RevocationTime: now.Add(-time.Hour), NotAfter: &valid},
+		{SerialNumber: "3c", RevocationTime: now.Add(-3 * time.Hour)},
+		{SerialNumber: "4d", RevocationTime: now.Add(-time.Hour)},
+		{Identity: "spiffe://cluster.local/ns/default/sa/old", RevocationTime: now.Add(-3 * time.Hour)},
+		{Identity: "spiffe://cluster.local/ns/default/sa/new", RevocationTime: now.Add(-time.Hour)},
+	}}
+	if !l.Prune(now, 2*time.Hour) {
+		t.Fatalf("expected the expired entries to be removed")
+	}
+	var kept []string
+	for _, r := range l.Revoked {
+		kept = append(kept, r.SerialNumber+r.Identity)
+	}
+	if want := []string{"2b", "4d", "spiffe://cluster.local/ns/default/sa/new"}; strings.Join(kept, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v to be kept, got %v", want, kept)
+	}
+	if l.Prune(now, 2*time.Hour) {
+		t.Fatalf("expected no change once pruned")
+	}
+}
+
+func TestSaveTrackedCertificates(t *testing.T) {
+	client := fake.NewSimpleClientset().CoreV1()
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+	certs := TrackedCertificates{}
+	for i := 0; i < 20000; i++ {
+		id := fmt.Sprintf("spiffe://cluster.local/ns/default/sa/sa-%d", i%100)
+		certs[id] = append(certs[id], TrackedCertificate{SerialNumber: fmt.Sprintf("%x", i+1), Issued: time.Now(), Expires: expires})
+	}
+	countSecrets := func() int {
+		t.Helper()
+		secrets, err := client.Secrets("istio-system").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range secrets.Items {
+			if len(s.Data[TrackedCertificatesFile]) > maxTrackedCertificatesSize {
+				t.Fatalf("secret %s is larger than %d bytes", s.Name, maxTrackedCertificatesSize)
+			}
+		}
+		return len(secrets.Items)
+	}
+
+	if err := SaveTrackedCertificates(ctx, client, "istio-system", "istiod", certs); err != nil {
+		t.Fatal(err)
+	}
+	if n := countSecrets(); n < 2 {
+		t.Fatalf("expected the certificates to be split across secrets, got %d secrets", n)
+	}
+	saved, err := ListTrackedCertificates(ctx, client, "istio-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(trackedSerials(saved["istiod"]), trackedSerials(certs)) {
+		t.Fatalf("expected all the certificates to be saved")
+	}
+
+	// Fewer certificates need fewer secrets, and no certificates none.
+	fewer := TrackedCertificates{"spiffe://cluster.local/ns/default/sa/sa-0": certs["spiffe://cluster.local/ns/default/sa/sa-0"]}
+	if err := SaveTrackedCertificates(ctx, client, "istio-system", "istiod", fewer); err != nil {
+		t.Fatal(err)
+	}
+	if n := countSecrets(); n != 1 {
+		t.Fatalf("expected a single secret, got %d", n)
+	}
+	if err := SaveTrackedCertificates(ctx, client, "istio-system", "istiod", TrackedCertificates{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countSecrets(); n != 0 {
+		t.Fatalf("expected the secrets to be removed, got %d", n)
+	}
+}
+
+// trackedSerials returns the sorted serial numbers of the certificates of each identity.
+func trackedSerials(certs TrackedCertificates) map[string][]string {
+	out := map[string][]string{}
+	for id, cs := range certs {
+		for _, c := range cs {
+			out[id] = append(out[id], c.SerialNumber)
+		}
+		sort.Strings(out[id])
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/security/pkg/pki/util"
+	pb "istio.io/istio/security/proto/signer/v1alpha1"
+)
+
+type signerServer struct {
+	pb.UnimplementedSignerServer
+	signer crypto.Signer
+}
+
+// NewServer returns a gRPC server serving the Signer service with the signer.
+func NewServer(signer crypto.Signer) *grpc.Server {
+	s := grpc.NewServer()
+	pb.RegisterSignerServer(s, &signerServer{signer: signer})
+	return s
+}
+
+func (s *signerServer) PublicKey(context.Context, *pb.PublicKeyRequest) (*pb.PublicKeyResponse, error) {
+	b, err := x509.MarshalPKIXPublicKey(s.signer.Public())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal the public key: %v", err)
+	}
+	return &pb.PublicKeyResponse{PublicKey: b}, nil
+}
+
+func (s *signerServer) Sign(_ context.Context, req *pb.SignRequest) (*pb.SignResponse, error) {
+	hash := crypto.Hash(req.Hash)
+	if hash != 0 && (!hash.Available() || len(req.Digest) != hash.Size()) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid digest for hash %v", hash)
+	}
+	var opts crypto.SignerOpts = hash
+	if req.Pss != nil {
+		opts = &rsa.PSSOptions{SaltLength: int(req.Pss.SaltLength), Hash: hash}
+	}
+	signature, err := s.signer.Sign(rand.Reader, req.Digest, opts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to sign: %v", err)
+	}
+	return &pb.SignResponse{Signature: signature}, nil
+}
+
+// NewFileSigner returns the signer of a PEM encoded private key file. It is the signer of the reference key provider,
+// security/tools/file_signer, for tests and for the development of key providers.
+func NewFileSigner(keyFile string) (crypto.Signer, error) {
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := util.ParsePemEncodedKey(b)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
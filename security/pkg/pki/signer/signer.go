@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signer delegates the signing operations of the Istio CA to an external key provider, such as a KMS, so
+// that the private key of the CA is never held by istiod.
+//
+// The key provider serves the istio.security.signer.v1alpha1.Signer gRPC service, defined in
+// security/proto/signer/v1alpha1/signer.proto, on a Unix domain socket, with the semantics of crypto.Signer:
+// PublicKey returns the PKIX, ASN.1 DER encoded public key, and Sign signs a digest with the given hash function,
+// and RSA-PSS salt length if any. The security/tools/file_signer command is a reference key provider.
+package signer
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	pb "istio.io/istio/security/proto/signer/v1alpha1"
+)
+
+// DefaultTimeout is the default timeout of the calls to the key provider.
+const DefaultTimeout = 10 * time.Second
+
+// RemoteSigner is a crypto.Signer holding no private key, whose signing operations are done by a key provider.
+type RemoteSigner struct {
+	conn      *grpc.ClientConn
+	client    pb.SignerClient
+	publicKey crypto.PublicKey
+	timeout   time.Duration
+}
+
+var _ crypto.Signer = &RemoteSigner{}
+
+// NewRemoteSigner connects to the key provider listening on the Unix domain socket, and fetches its public key.
+func NewRemoteSigner(socket string, timeout time.Duration) (*RemoteSigner, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	conn, err := grpc.Dial("unix://"+strings.TrimPrefix(socket, "unix://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the key provider at %s: %v", socket, err)
+	}
+	s := &RemoteSigner{conn: conn, client: pb.NewSignerClient(conn), timeout: timeout}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := s.client.PublicKey(ctx, &pb.PublicKeyRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to get the public key of the key provider at %s: %v", socket, err)
+	}
+	if s.publicKey, err = x509.ParsePKIXPublicKey(resp.PublicKey); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to parse the public key of the key provider at %s: %v", socket, err)
+	}
+	return s, nil
+}
+
+// Public returns the public key of the key provider.
+func (s *RemoteSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs the digest with the key of the key provider. The rand argument is ignored, randomness being up to the
+// key provider.
+func (s *RemoteSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	req := &pb.SignRequest{Digest: digest, Hash: uint32(opts.HashFunc())}
+	if pss, ok := opts.(*rsa.PSSOptions); ok {
+		req.Pss = &pb.PSSOptions{SaltLength: int32(pss.SaltLength)}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	resp, err := s.client.Sign(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("key provider failed to sign: %v", err)
+	}
+	return resp.Signature, nil
+}
+
+// Close closes the connection to the key provider.
+func (s *RemoteSigner) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+// serveFileSigner serves the key file on a Unix domain socket for the duration of the test, and returns the socket.
+func serveFileSigner(t *testing.T, keyFile string) string {
+	t.Helper()
+	fileSigner, err := NewFileSigner(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "signer.sock")
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(fileSigner)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+	return socket
+}
+
+func TestRemoteSigner(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyFile string
+		opts    crypto.SignerOpts
+	}{
+		{name: "RSA PKCS1v15", keyFile: "../testdata/key.pem", opts: crypto.SHA256},
+		{name: "RSA PSS", keyFile: "../testdata/key.pem", opts: &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}},
+		{name: "ECDSA", keyFile: "../testdata/ec-root-key.pem", opts: crypto.SHA256},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			remote, err := NewRemoteSigner(serveFileSigner(t, tc.keyFile), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer remote.Close()
+
+			local, err := NewFileSigner(tc.keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !local.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(remote.Public()) {
+				t.Fatalf("expected the public key of the key provider")
+			}
+			digest := sha256.Sum256([]byte("hello"))
+			signature, err := remote.Sign(rand.Reader, digest[:], tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verify(remote.Public(), digest[:], signature, tc.opts); err != nil {
+				t.Fatalf("invalid signature: %v", err)
+			}
+
+			if _, err := remote.Sign(rand.Reader, digest[:8], tc.opts); err == nil {
+				t.Fatal("expected digest of the wrong size to be rejected")
+			}
+		})
+	}
+}
+
+func verify(pub crypto.PublicKey, digest, signature []byte, opts crypto.SignerOpts) error {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			return rsa.VerifyPSS(pub, opts.HashFunc(), digest, signature, pss)
+		}
+		return rsa.VerifyPKCS1v15(pub, opts.HashFunc(), digest, signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, signature) {
+			return fmt.Errorf("ECDSA verification failure")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key %T", pub)
+}
@@ -282,7 +282,50 @@ func TimeBeforeCertExpires(certBytes []byte, now time.Time) (time.Duration, erro
 
 // Verify that the cert chain, root cert and key/cert match.
 func Verify(certBytes, privKeyBytes, certChainBytes, rootCertBytes []byte) error {
-	// Verify the cert can be verified from the root cert through the cert chain.
+	if _, err := verifyCertChain(certBytes, certChainBytes, rootCertBytes); err != nil {
+		return err
+	}
+
+	// Verify that the key can be correctly parsed.
+	if _, err := ParsePemEncodedKey(privKeyBytes); err != nil {
+		return fmt.Errorf("failed to parse private key PEM: %v", err)
+	}
+
+	// Verify the cert and key match.
+	if _, err := tls.X509KeyPair(certBytes, privKeyBytes); err != nil {
+		return fmt.Errorf("the cert does not match the key")
+	}
+
+	return nil
+}
+
+// VerifyWithPublicKey verifies that the cert chain and root cert match, and that the cert matches the public key of
+// a private key held outside of the bundle.
+func VerifyWithPublicKey(certBytes []byte, publicKey crypto.PublicKey, certChainBytes, rootCertBytes []byte) error {
+	cert, err := verifyCertChain(certBytes, certChainBytes, rootCertBytes)
+	if err != nil {
+		return err
+	}
+	pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(publicKey) {
+		return fmt.Errorf("the cert does not match the public key")
+	}
+	return nil
+}
+
+// NewVerifiedKeyCertBundleWithPublicKey returns a new KeyCertBundle without private key, verified against the public
+// key of a private key held outside of the bundle.
+func NewVerifiedKeyCertBundleWithPublicKey(certBytes []byte, publicKey crypto.PublicKey, certChainBytes,
+	rootCertBytes []byte,
+) (*KeyCertBundle, error) {
+	if err := VerifyWithPublicKey(certBytes, publicKey, certChainBytes, rootCertBytes); err != nil {
+		return nil, err
+	}
+	return NewKeyCertBundleFromPem(certBytes, nil, certChainBytes, rootCertBytes), nil
+}
+
+// verifyCertChain verifies the cert can be verified from the root cert through the cert chain.
+func verifyCertChain(certBytes, certChainBytes, rootCertBytes []byte) (*x509.Certificate, error) {
 	rcp := x509.NewCertPool()
 	rcp.AppendCertsFromPEM(rootCertBytes)
 
@@ -295,27 +338,16 @@ func Verify(certBytes, privKeyBytes, certChainBytes, rootCertBytes []byte) error
 	}
 	cert, err := ParsePemEncodedCertificate(certBytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse cert PEM: %v", err)
+		return nil, fmt.Errorf("failed to parse cert PEM: %v", err)
 	}
 	chains, err := cert.Verify(opts)
 
 	if len(chains) == 0 || err != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"cannot verify the cert with the provided root chain and cert "+
 				"pool with error: %v", err)
 	}
-
-	// Verify that the key can be correctly parsed.
-	if _, err = ParsePemEncodedKey(privKeyBytes); err != nil {
-		return fmt.Errorf("failed to parse private key PEM: %v", err)
-	}
-
-	// Verify the cert and key match.
-	if _, err := tls.X509KeyPair(certBytes, privKeyBytes); err != nil {
-		return fmt.Errorf("the cert does not match the key")
-	}
-
-	return nil
+	return cert, nil
 }
 
 func extractCertExpiryTimestamp(certType string, certPem []byte) (float64, error) {
@@ -0,0 +1,137 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+var auditLog = log.RegisterScope("caaudit", "Audit log of the certificates issued by the CA server", 0)
+
+// IssuedCertificate is the audit event of a certificate issued by the CA server.
+type IssuedCertificate struct {
+	Identities   []string  `json:"identities"`
+	SerialNumber string    `json:"serialNumber"`
+	TTL          string    `json:"ttl"`
+	IssuedAt     time.Time `json:"issuedAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	CertSigner   string    `json:"certSigner,omitempty"`
+
+	// The requester, as known to the authenticator.
+	PodName       string `json:"podName,omitempty"`
+	PodNamespace  string `json:"podNamespace,omitempty"`
+	NodeName      string `json:"nodeName,omitempty"`
+	RemoteAddress string `json:"remoteAddress,omitempty"`
+}
+
+// AuditSink receives the audit event of every certificate issued by the CA server. It is called synchronously with
+// the issuance, and must not block.
+type AuditSink interface {
+	Record(cert IssuedCertificate)
+}
+
+// LogAuditSink writes the audit events to the caaudit log scope, as JSON.
+type LogAuditSink struct{}
+
+var _ AuditSink = LogAuditSink{}
+
+func (LogAuditSink) Record(cert IssuedCertificate) {
+	b, err := json.Marshal(cert)
+	if err != nil {
+		auditLog.Errorf("failed to marshal audit event: %v", err)
+		return
+	}
+	auditLog.Info(string(b))
+}
+
+// InventoryQuery selects certificates of an Inventory. Empty fields select all the certificates.
+type InventoryQuery struct {
+	Identity    string
+	NodeName    string
+	IssuedAfter time.Time
+}
+
+func (q InventoryQuery) matches(cert IssuedCertificate) bool {
+	if q.NodeName != "" && cert.NodeName != q.NodeName {
+		return false
+	}
+	if cert.IssuedAt.Before(q.IssuedAfter) {
+		return false
+	}
+	if q.Identity == "" {
+		return true
+	}
+	for _, id := range cert.Identities {
+		if id == q.Identity {
+			return true
+		}
+	}
+	return false
+}
+
+// Inventory keeps the latest certificates issued by the CA server in memory until they expire, up to a maximum
+// number of certificates beyond which the oldest ones are dropped.
+type Inventory struct {
+	mu    sync.RWMutex
+	size  int
+	certs []IssuedCertificate
+}
+
+var _ AuditSink = &Inventory{}
+
+// NewInventory returns an Inventory keeping up to size certificates.
+func NewInventory(size int) *Inventory {
+	return &Inventory{size: size}
+}
+
+func (i *Inventory) Record(cert IssuedCertificate) {
+	if i.size <= 0 {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.certs) >= i.size {
+		now := time.Now()
+		active := i.certs[:0]
+		for _, c := range i.certs {
+			if c.ExpiresAt.After(now) {
+				active = append(active, c)
+			}
+		}
+		i.certs = active
+		if len(i.certs) >= i.size {
+			i.certs = append(i.certs[:0], i.certs[len(i.certs)-i.size+1:]...)
+		}
+	}
+	i.certs = append(i.certs, cert)
+}
+
+// List returns the unexpired certificates selected by the query, the most recently issued first.
+func (i *Inventory) List(query InventoryQuery) []IssuedCertificate {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	now := time.Now()
+	certs := []IssuedCertificate{}
+	for j := len(i.certs) - 1; j >= 0; j-- {
+		if c := i.certs[j]; c.ExpiresAt.After(now) && query.matches(c) {
+			certs = append(certs, c)
+		}
+	}
+	return certs
+}
@@ -0,0 +1,71 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInventory(t *testing.T) {
+	now := time.Now()
+	issued := func(serial string, identity string, issuedAt time.Time, ttl time.Duration) IssuedCertificate {
+		return IssuedCertificate{
+			Identities:   []string{identity},
+			SerialNumber: serial,
+			IssuedAt:     issuedAt,
+			ExpiresAt:    issuedAt.Add(ttl),
+			NodeName:     "node-1",
+		}
+	}
+	serials := func(certs []IssuedCertificate) []string {
+		out := []string{}
+		for _, c := range certs {
+			out = append(out, c.SerialNumber)
+		}
+		return out
+	}
+
+	i := NewInventory(3)
+	i.Record(issued("1", "a", now.Add(-2*time.Hour), time.Hour))
+	i.Record(issued("2", "a", now.Add(-time.Hour), 2*time.Hour))
+	i.Record(issued("3", "b", now.Add(-time.Minute), time.Hour))
+
+	cases := []struct {
+		name     string
+		query    InventoryQuery
+		expected []string
+	}{
+		{"all active", InventoryQuery{}, []string{"3", "2"}},
+		{"identity", InventoryQuery{Identity: "a"}, []string{"2"}},
+		{"node", InventoryQuery{NodeName: "node-2"}, []string{}},
+		{"issued after", InventoryQuery{IssuedAfter: now.Add(-30 * time.Minute)}, []string{"3"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := serials(i.List(c.query)); !reflect.DeepEqual(got, c.expected) {
+				t.Fatalf("expected %v, got %v", c.expected, got)
+			}
+		})
+	}
+
+	// Expired certificates are dropped first, then the oldest ones.
+	i.Record(issued("4", "c", now, time.Hour))
+	i.Record(issued("5", "c", now, time.Hour))
+	if got := serials(i.certs); !reflect.DeepEqual(got, []string{"3", "4", "5"}) {
+		t.Fatalf("expected oldest certificates to be dropped, got %v", got)
+	}
+}
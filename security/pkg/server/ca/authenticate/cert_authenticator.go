@@ -16,6 +16,7 @@ package authenticate
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 
@@ -69,15 +70,7 @@ func (cca *ClientCertAuthenticator) authenticateGrpc(ctx context.Context) (*secu
 		return nil, fmt.Errorf("no verified chain is found")
 	}
 
-	ids, err := util.ExtractIDs(chains[0][0].Extensions)
-	if err != nil {
-		return nil, err
-	}
-
-	return &security.Caller{
-		AuthSource: security.AuthSourceClientCertificate,
-		Identities: ids,
-	}, nil
+	return callerFromCertificate(chains[0][0])
 }
 
 // authenticateHTTP performs mTLS authentication for http requests. Requires having the endpoints on a listener
@@ -92,13 +85,22 @@ func (cca *ClientCertAuthenticator) authenticateHTTP(req *http.Request) (*securi
 		return nil, fmt.Errorf("no verified chain is found")
 	}
 
-	ids, err := util.ExtractIDs(chains[0][0].Extensions)
+	return callerFromCertificate(chains[0][0])
+}
+
+func callerFromCertificate(cert *x509.Certificate) (*security.Caller, error) {
+	ids, err := util.ExtractIDs(cert.Extensions)
 	if err != nil {
 		return nil, err
 	}
 
-	return &security.Caller{
+	caller := &security.Caller{
 		AuthSource: security.AuthSourceClientCertificate,
 		Identities: ids,
-	}, nil
+	}
+	caller.Attributes.DNSNames = cert.DNSNames
+	for _, uri := range cert.URIs {
+		caller.Attributes.URIs = append(caller.Attributes.URIs, uri.String())
+	}
+	return caller, nil
 }
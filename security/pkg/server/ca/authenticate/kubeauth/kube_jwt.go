@@ -22,6 +22,7 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/metadata"
 	"k8s.io/client-go/kubernetes"
+	listerv1 "k8s.io/client-go/listers/core/v1"
 
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/mesh"
@@ -55,6 +56,10 @@ type KubeJWTAuthenticator struct {
 
 	// remote cluster kubeClient getter
 	remoteKubeClientGetter RemoteKubeClientGetter
+
+	// podLister caches the pods of the primary cluster, to look up the node of the callers whose token review does not
+	// include it. The node is not looked up if nil.
+	podLister listerv1.PodLister
 }
 
 var _ security.Authenticator = &KubeJWTAuthenticator{}
@@ -72,6 +77,13 @@ func NewKubeJWTAuthenticator(meshHolder mesh.Holder, client kubernetes.Interface
 	}
 }
 
+// LookupNodes makes the authenticator look up the node of the callers of the primary cluster in the pods of the lister,
+// when the token review does not include it, as before Kubernetes 1.30. It is only needed when the node of the callers
+// is used, as the node of the pods of the remote clusters is never looked up.
+func (a *KubeJWTAuthenticator) LookupNodes(podLister listerv1.PodLister) {
+	a.podLister = podLister
+}
+
 func (a *KubeJWTAuthenticator) AuthenticatorType() string {
 	return KubeJWTAuthenticatorType
 }
@@ -150,25 +162,44 @@ func (a *KubeJWTAuthenticator) authenticate(targetJWT string, clusterID cluster.
 		// is unbound and the setting to require bound tokens is off
 		aud = nil
 	}
-	id, err := tokenreview.ValidateK8sJwt(kubeClient, targetJWT, aud)
+	info, err := tokenreview.ValidateK8sJwt(kubeClient, targetJWT, aud)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate the JWT from cluster %q: %v", clusterID, err)
 	}
-	if len(id) != 2 {
-		return nil, fmt.Errorf("failed to parse the JWT. Validation result length is not 2, but %d", len(id))
+	if info.NodeName == "" && info.PodName != "" && a.podLister != nil && a.isPrimary(clusterID) {
+		info.NodeName = a.podNodeName(info)
 	}
-	callerNamespace := id[0]
-	callerServiceAccount := id[1]
 	return &security.Caller{
-		AuthSource: security.AuthSourceIDToken,
-		Identities: []string{fmt.Sprintf(authenticate.IdentityTemplate, a.meshHolder.Mesh().GetTrustDomain(), callerNamespace, callerServiceAccount)},
+		AuthSource:     security.AuthSourceIDToken,
+		Identities:     []string{fmt.Sprintf(authenticate.IdentityTemplate, a.meshHolder.Mesh().GetTrustDomain(), info.PodNamespace, info.PodServiceAccount)},
+		KubernetesInfo: info,
 	}, nil
 }
 
+// podNodeName returns the node of the pod the token is bound to, or an empty string if the pod is not cached or has
+// been replaced by another pod of the same name.
+func (a *KubeJWTAuthenticator) podNodeName(info security.KubernetesInfo) string {
+	pod, err := a.podLister.Pods(info.PodNamespace).Get(info.PodName)
+	if err != nil {
+		log.Debugf("failed to get the node of the pod %s/%s: %v", info.PodNamespace, info.PodName, err)
+		return ""
+	}
+	if info.PodUID != "" && string(pod.UID) != info.PodUID {
+		log.Debugf("the pod %s/%s the token is bound to has been replaced", info.PodNamespace, info.PodName)
+		return ""
+	}
+	return pod.Spec.NodeName
+}
+
+// isPrimary returns true for the local/primary cluster, or if clusterID is not sent (we assume that its a single cluster).
+func (a *KubeJWTAuthenticator) isPrimary(clusterID cluster.ID) bool {
+	return a.clusterID == clusterID || clusterID == ""
+}
+
 func (a *KubeJWTAuthenticator) getKubeClient(clusterID cluster.ID) kubernetes.Interface {
 	// first match local/primary cluster
 	// or if clusterID is not sent (we assume that its a single cluster)
-	if a.clusterID == clusterID || clusterID == "" {
+	if a.isPrimary(clusterID) {
 		return a.kubeClient
 	}
 
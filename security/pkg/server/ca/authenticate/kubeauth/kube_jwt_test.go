@@ -22,10 +22,14 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/metadata"
 	k8sauth "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	listerv1 "k8s.io/client-go/listers/core/v1"
 	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/cluster"
@@ -186,6 +190,10 @@ func TestAuthenticate(t *testing.T) {
 			expectedCaller := &security.Caller{
 				AuthSource: security.AuthSourceIDToken,
 				Identities: []string{tc.expectedID},
+				KubernetesInfo: security.KubernetesInfo{
+					PodNamespace:      "default",
+					PodServiceAccount: "example-pod-sa",
+				},
 			}
 
 			if !reflect.DeepEqual(actualCaller, expectedCaller) {
@@ -216,3 +224,90 @@ func TestIsAllowedKubernetesAudience(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthenticateLookupNodes(t *testing.T) {
+	const (
+		podNameKey  = "authentication.kubernetes.io/pod-name"
+		podUIDKey   = "authentication.kubernetes.io/pod-uid"
+		nodeNameKey = "authentication.kubernetes.io/node-name"
+	)
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	if err := pods.Add(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: "default", UID: "pod-uid"},
+		Spec:       corev1.PodSpec{NodeName: "example-node"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name      string
+		clusterID string
+		extra     map[string]k8sauth.ExtraValue
+		lookup    bool
+		expected  string
+	}{
+		{
+			name:     "node in token review",
+			extra:    map[string]k8sauth.ExtraValue{podNameKey: {"example-pod"}, nodeNameKey: {"other-node"}},
+			lookup:   true,
+			expected: "other-node",
+		},
+		{
+			name:     "node of the pod",
+			extra:    map[string]k8sauth.ExtraValue{podNameKey: {"example-pod"}, podUIDKey: {"pod-uid"}},
+			lookup:   true,
+			expected: "example-node",
+		},
+		{
+			name:   "lookup disabled",
+			extra:  map[string]k8sauth.ExtraValue{podNameKey: {"example-pod"}, podUIDKey: {"pod-uid"}},
+			lookup: false,
+		},
+		{
+			name:   "replaced pod",
+			extra:  map[string]k8sauth.ExtraValue{podNameKey: {"example-pod"}, podUIDKey: {"other-uid"}},
+			lookup: true,
+		},
+		{
+			name:   "missing pod",
+			extra:  map[string]k8sauth.ExtraValue{podNameKey: {"other-pod"}},
+			lookup: true,
+		},
+		{
+			name:      "remote cluster",
+			clusterID: "remote",
+			extra:     map[string]k8sauth.ExtraValue{podNameKey: {"example-pod"}, podUIDKey: {"pod-uid"}},
+			lookup:    true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("create", "tokenreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+				return true, &k8sauth.TokenReview{Status: k8sauth.TokenReviewStatus{
+					Authenticated: true,
+					User: k8sauth.UserInfo{
+						Username: "system:serviceaccount:default:example-pod-sa",
+						Groups:   []string{"system:serviceaccounts"},
+						Extra:    tc.extra,
+					},
+				}}, nil
+			})
+			authenticator := NewKubeJWTAuthenticator(mockMeshConfigHolder{"example.com"}, client, "Kubernetes",
+				func(cluster.ID) kubernetes.Interface { return client }, jwt.PolicyFirstParty)
+			if tc.lookup {
+				authenticator.LookupNodes(listerv1.NewPodLister(pods))
+			}
+			md := metadata.MD{"authorization": []string{security.BearerTokenPrefix + "bearer-token"}}
+			if tc.clusterID != "" {
+				md.Set("clusterid", tc.clusterID)
+			}
+			caller, err := authenticator.Authenticate(security.AuthContext{GrpcContext: metadata.NewIncomingContext(context.Background(), md)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if caller.KubernetesInfo.NodeName != tc.expected {
+				t.Fatalf("expected node %q, got %q", tc.expected, caller.KubernetesInfo.NodeName)
+			}
+		})
+	}
+}
@@ -107,10 +107,15 @@ func (j *JwtAuthenticator) authenticate(ctx context.Context, bearerToken string)
 	if !checkAudience(sa.Aud, j.audiences) {
 		return nil, fmt.Errorf("invalid audiences %v", sa.Aud)
 	}
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to extract claims from ID token: %v", err)
+	}
 
 	return &security.Caller{
 		AuthSource: security.AuthSourceIDToken,
 		Identities: []string{fmt.Sprintf(IdentityTemplate, j.trustDomain, ns, ksa)},
+		Attributes: security.CallerAttributes{Claims: claims},
 	}, nil
 }
 
@@ -221,6 +221,10 @@ func TestOIDCAuthenticate(t *testing.T) {
 				AuthSource: security.AuthSourceIDToken,
 				Identities: []string{tc.expectedID},
 			}
+			if sub := actualCaller.Attributes.Claims["sub"]; sub != "system:serviceaccount:bar:foo" {
+				t.Errorf("%v: expected the claims of the token in the caller attributes, got sub %v", name, sub)
+			}
+			actualCaller.Attributes.Claims = nil
 			if !reflect.DeepEqual(actualCaller, expectedCaller) {
 				t.Errorf("%v: unexpected caller (want %v but got %v)", name, expectedCaller, actualCaller)
 			}
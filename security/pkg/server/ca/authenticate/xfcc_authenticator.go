@@ -66,17 +66,23 @@ func buildSecurityCaller(xfccHeader string) (*security.Caller, error) {
 		return nil, fmt.Errorf(message)
 	}
 	ids := []string{}
+	attributes := security.CallerAttributes{}
 	for _, cc := range clientCerts {
 		ids = append(ids, cc.URI)
 		ids = append(ids, cc.DNS...)
 		if cc.Subject != nil {
 			ids = append(ids, cc.Subject.CommonName)
 		}
+		if cc.URI != "" {
+			attributes.URIs = append(attributes.URIs, cc.URI)
+		}
+		attributes.DNSNames = append(attributes.DNSNames, cc.DNS...)
 	}
 
 	return &security.Caller{
 		AuthSource: security.AuthSourceClientCertificate,
 		Identities: ids,
+		Attributes: attributes,
 	}, nil
 }
 
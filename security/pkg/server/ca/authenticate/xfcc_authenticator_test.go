@@ -113,6 +113,9 @@ func TestXfccAuthenticator(t *testing.T) {
 				Identities: []string{
 					"spiffe://mesh.example.com/ns/otherns/sa/othersa",
 				},
+				Attributes: security.CallerAttributes{
+					URIs: []string{"spiffe://mesh.example.com/ns/otherns/sa/othersa"},
+				},
 			},
 		},
 		{
@@ -128,6 +131,13 @@ func TestXfccAuthenticator(t *testing.T) {
 					"hello",
 					"spiffe://mesh.example.com/ns/otherns/sa/othersa",
 				},
+				Attributes: security.CallerAttributes{
+					DNSNames: []string{"hello.west.example.com", "hello.east.example.com"},
+					URIs: []string{
+						"spiffe://mesh.example.com/ns/firstns/sa/firstsa",
+						"spiffe://mesh.example.com/ns/otherns/sa/othersa",
+					},
+				},
 			},
 		},
 		{
@@ -151,6 +161,9 @@ func TestXfccAuthenticator(t *testing.T) {
 				Identities: []string{
 					"spiffe://mesh.example.com/ns/otherns/sa/othersa",
 				},
+				Attributes: security.CallerAttributes{
+					URIs: []string{"spiffe://mesh.example.com/ns/otherns/sa/othersa"},
+				},
 			},
 			useHttpRequest: true,
 		},
@@ -167,6 +180,13 @@ func TestXfccAuthenticator(t *testing.T) {
 					"hello",
 					"spiffe://mesh.example.com/ns/otherns/sa/othersa",
 				},
+				Attributes: security.CallerAttributes{
+					DNSNames: []string{"hello.west.example.com", "hello.east.example.com"},
+					URIs: []string{
+						"spiffe://mesh.example.com/ns/firstns/sa/firstsa",
+						"spiffe://mesh.example.com/ns/otherns/sa/othersa",
+					},
+				},
 			},
 			useHttpRequest: true,
 		},
@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+)
+
+const (
+	// IdentityMappingConfigMap is the ConfigMap of the istiod namespace holding the identity mapping of the CA.
+	IdentityMappingConfigMap = "istio-ca-identity-mapping"
+	// IdentityMappingConfigMapKey is the key of the YAML identity mapping in IdentityMappingConfigMap.
+	IdentityMappingConfigMapKey = "mapping"
+)
+
+// IdentityMapping maps the attributes of the authenticated callers, such as the SANs of their client certificate or
+// the claims of their token, to the SPIFFE identities they are allowed to request in their CSR or JWT-SVID request.
+// The callers matched by no rule get the identities derived by their authenticator.
+type IdentityMapping struct {
+	Rules []IdentityMappingRule `json:"rules"`
+}
+
+// IdentityMappingRule allows the callers it matches to request the allowed identities.
+type IdentityMappingRule struct {
+	Name string      `json:"name"`
+	From CallerMatch `json:"from"`
+	// AllowedIdentities are the SPIFFE identities the callers may request. A trailing "*" matches any suffix.
+	AllowedIdentities []string `json:"allowedIdentities"`
+}
+
+// CallerMatch matches a caller if all of its set fields match. A list matches if any of its patterns matches a
+// value of the caller. Patterns are exact, or have a leading or trailing "*" matching any prefix or suffix.
+type CallerMatch struct {
+	// Authenticators are the types of the authenticator of the caller, such as XfccAuthenticator.
+	Authenticators []string `json:"authenticators,omitempty"`
+	// DNSNames and URIs match the SANs of the client certificate of the caller.
+	DNSNames []string `json:"dnsNames,omitempty"`
+	URIs     []string `json:"uris,omitempty"`
+	// Claims match the string claims, or the strings of the list claims, of the token of the caller.
+	Claims map[string][]string `json:"claims,omitempty"`
+}
+
+// ParseIdentityMapping parses and validates a YAML identity mapping.
+func ParseIdentityMapping(b []byte) (*IdentityMapping, error) {
+	m := &IdentityMapping{}
+	if err := yaml.UnmarshalStrict(b, m); err != nil {
+		return nil, fmt.Errorf("failed to parse the identity mapping: %v", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid identity mapping: %v", err)
+	}
+	return m, nil
+}
+
+func (m *IdentityMapping) validate() error {
+	for i, rule := range m.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		from := rule.From
+		if len(from.Authenticators) == 0 && len(from.DNSNames) == 0 && len(from.URIs) == 0 && len(from.Claims) == 0 {
+			return fmt.Errorf("rule %s matches every caller", name)
+		}
+		if len(rule.AllowedIdentities) == 0 {
+			return fmt.Errorf("rule %s allows no identity", name)
+		}
+		for _, id := range rule.AllowedIdentities {
+			if !strings.HasPrefix(id, spiffe.URIPrefix) {
+				return fmt.Errorf("rule %s allows %q, which is not a SPIFFE identity", name, id)
+			}
+		}
+	}
+	return nil
+}
+
+// authorize returns the rules matching the caller. If some do, it returns an error explaining the denial unless
+// every requested identity is allowed by one of them.
+func (m *IdentityMapping) authorize(caller *security.Caller, requested []string) ([]string, error) {
+	var matched []IdentityMappingRule
+	var names []string
+	for i, rule := range m.Rules {
+		if rule.From.matches(caller.Attributes) {
+			matched = append(matched, rule)
+			if rule.Name != "" {
+				names = append(names, rule.Name)
+			} else {
+				names = append(names, fmt.Sprintf("#%d", i))
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+	if len(requested) == 0 {
+		return names, fmt.Errorf("the request has no identity, required by the identity mapping rules %v", names)
+	}
+	for _, id := range requested {
+		allowed := false
+		for _, rule := range matched {
+			if matchesAny(rule.AllowedIdentities, []string{id}) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return names, fmt.Errorf("requested identity %q is not allowed by the identity mapping rules %v", id, names)
+		}
+	}
+	return names, nil
+}
+
+func (c CallerMatch) matches(attributes security.CallerAttributes) bool {
+	if len(c.Authenticators) > 0 && !matchesAny(c.Authenticators, []string{attributes.Authenticator}) {
+		return false
+	}
+	if len(c.DNSNames) > 0 && !matchesAny(c.DNSNames, attributes.DNSNames) {
+		return false
+	}
+	if len(c.URIs) > 0 && !matchesAny(c.URIs, attributes.URIs) {
+		return false
+	}
+	for claim, patterns := range c.Claims {
+		if !matchesAny(patterns, claimValues(attributes.Claims[claim])) {
+			return false
+		}
+	}
+	return true
+}
+
+// claimValues returns the strings of a string or list claim.
+func claimValues(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var values []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// matchesAny returns whether any of the patterns matches any of the values.
+func matchesAny(patterns, values []string) bool {
+	for _, p := range patterns {
+		for _, v := range values {
+			if matchPattern(p, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchPattern(pattern, value string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "*"):
+		return strings.HasPrefix(value, strings.TrimSuffix(pattern, "*"))
+	case strings.HasPrefix(pattern, "*"):
+		return strings.HasSuffix(value, strings.TrimPrefix(pattern, "*"))
+	}
+	return pattern == value
+}
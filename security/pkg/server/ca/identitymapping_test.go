@@ -0,0 +1,233 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "istio.io/api/security/v1alpha1"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/ca"
+	"istio.io/istio/security/pkg/pki/util"
+	jwtsvidpb "istio.io/istio/security/proto/jwtsvid/v1alpha1"
+)
+
+const testIdentityMapping = `
+rules:
+- name: vms
+  from:
+    authenticators: [XfccAuthenticator]
+    dnsNames: ["*.vm.example.com"]
+  allowedIdentities: ["spiffe://cluster.local/ns/vms/sa/*"]
+- name: ci
+  from:
+    claims:
+      iss: ["https://ci.example.com"]
+      groups: ["deployers"]
+  allowedIdentities: ["spiffe://cluster.local/ns/ci/sa/runner"]
+`
+
+func TestIdentityMappingAuthorize(t *testing.T) {
+	mapping, err := ParseIdentityMapping([]byte(testIdentityMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := security.CallerAttributes{Authenticator: "XfccAuthenticator", DNSNames: []string{"db-1.vm.example.com"}}
+	cases := []struct {
+		name       string
+		attributes security.CallerAttributes
+		requested  []string
+		rules      []string
+		err        string
+	}{
+		{
+			name:       "no matching rule",
+			attributes: security.CallerAttributes{Authenticator: "KubeJWTAuthenticator"},
+			requested:  []string{"spiffe://cluster.local/ns/vms/sa/db"},
+		},
+		{
+			name:       "allowed",
+			attributes: vm,
+			requested:  []string{"spiffe://cluster.local/ns/vms/sa/db"},
+			rules:      []string{"vms"},
+		},
+		{
+			name:       "denied",
+			attributes: vm,
+			requested:  []string{"spiffe://cluster.local/ns/vms/sa/db", "spiffe://cluster.local/ns/default/sa/admin"},
+			rules:      []string{"vms"},
+			err:        `requested identity "spiffe://cluster.local/ns/default/sa/admin" is not allowed by the identity mapping rules [vms]`,
+		},
+		{
+			name:       "no requested identity",
+			attributes: vm,
+			rules:      []string{"vms"},
+			err:        "the request has no identity",
+		},
+		{
+			name:       "other authenticator",
+			attributes: security.CallerAttributes{Authenticator: "ClientCertAuthenticator", DNSNames: vm.DNSNames},
+			requested:  []string{"spiffe://cluster.local/ns/vms/sa/db"},
+		},
+		{
+			name: "list claim",
+			attributes: security.CallerAttributes{Claims: map[string]any{
+				"iss": "https://ci.example.com", "groups": []any{"readers", "deployers"},
+			}},
+			requested: []string{"spiffe://cluster.local/ns/ci/sa/runner"},
+			rules:     []string{"ci"},
+		},
+		{
+			name:       "missing claim",
+			attributes: security.CallerAttributes{Claims: map[string]any{"iss": "https://ci.example.com"}},
+			requested:  []string{"spiffe://cluster.local/ns/ci/sa/runner"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := mapping.authorize(&security.Caller{Attributes: tc.attributes}, tc.requested)
+			if tc.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+			if strings.Join(rules, ",") != strings.Join(tc.rules, ",") {
+				t.Fatalf("expected matching rules %v, got %v", tc.rules, rules)
+			}
+		})
+	}
+}
+
+func TestParseIdentityMappingInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown field":    "rules:\n- name: a\n  form: {}\n",
+		"matches everyone": "rules:\n- name: a\n  allowedIdentities: [\"spiffe://cluster.local/*\"]\n",
+		"allows nothing":   "rules:\n- name: a\n  from: {uris: [\"spiffe://example.com/*\"]}\n",
+		"not spiffe":       "rules:\n- name: a\n  from: {uris: [\"spiffe://example.com/*\"]}\n  allowedIdentities: [\"*\"]\n",
+	}
+	for name, mapping := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseIdentityMapping([]byte(mapping)); err == nil {
+				t.Fatal("expected the identity mapping to be rejected")
+			}
+		})
+	}
+}
+
+func TestCreateCertificateIdentityMapping(t *testing.T) {
+	caOpts, err := ca.NewSelfSignedDebugIstioCAOptions("", time.Hour, time.Hour, time.Hour, "cluster.local", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	istioCA, err := ca.NewIstioCA(caOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := ParseIdentityMapping([]byte(testIdentityMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		ca: istioCA,
+		Authenticators: []security.Authenticator{&mockAuthenticator{
+			identities: []string{"db-1.vm.example.com"},
+			attributes: security.CallerAttributes{DNSNames: []string{"db-1.vm.example.com"}},
+		}},
+		monitoring: newMonitoringMetrics(),
+	}
+	// The VM is authenticated by the mock authenticator.
+	mapping.Rules[0].From.Authenticators = []string{"mockAuthenticator"}
+	server.SetIdentityMapping(mapping)
+
+	for _, tc := range []struct {
+		requested string
+		code      codes.Code
+	}{
+		{requested: "spiffe://cluster.local/ns/vms/sa/db", code: codes.OK},
+		{requested: "spiffe://cluster.local/ns/default/sa/admin", code: codes.PermissionDenied},
+	} {
+		csr, _, err := util.GenCSR(util.CertOptions{Host: tc.requested, RSAKeySize: 2048})
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, err := server.CreateCertificate(context.Background(), &pb.IstioCertificateRequest{Csr: string(csr), ValidityDuration: 1800})
+		if code := status.Code(err); code != tc.code {
+			t.Fatalf("expected %v for %s, got %v", tc.code, tc.requested, err)
+		}
+		if err != nil {
+			if !strings.Contains(status.Convert(err).Message(), tc.requested) {
+				t.Fatalf("expected the denial reason to name the requested identity, got %v", err)
+			}
+			continue
+		}
+		cert, err := util.ParsePemEncodedCertificate([]byte(response.CertChain[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids, _ := util.ExtractIDs(cert.Extensions); len(ids) != 1 || ids[0] != tc.requested {
+			t.Fatalf("expected a certificate of the requested identity, got %v", ids)
+		}
+	}
+}
+
+func TestCreateJWTSVIDIdentityMapping(t *testing.T) {
+	mapping, err := ParseIdentityMapping([]byte(testIdentityMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		ca: newJWTSVIDCA(t),
+		Authenticators: []security.Authenticator{&mockAuthenticator{
+			identities: []string{"db-1.vm.example.com"},
+			attributes: security.CallerAttributes{DNSNames: []string{"db-1.vm.example.com"}},
+		}},
+		monitoring: newMonitoringMetrics(),
+	}
+	mapping.Rules[0].From.Authenticators = []string{"mockAuthenticator"}
+	server.SetIdentityMapping(mapping)
+
+	for _, tc := range []struct {
+		requested string
+		code      codes.Code
+	}{
+		{requested: "spiffe://cluster.local/ns/vms/sa/db", code: codes.OK},
+		{requested: "spiffe://cluster.local/ns/default/sa/admin", code: codes.PermissionDenied},
+		{requested: "", code: codes.PermissionDenied},
+	} {
+		response, err := server.CreateJWTSVID(context.Background(),
+			&jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}, SpiffeId: tc.requested})
+		if code := status.Code(err); code != tc.code {
+			t.Fatalf("expected %v for %q, got %v", tc.code, tc.requested, err)
+		}
+		if err == nil && response.SpiffeId != tc.requested {
+			t.Fatalf("expected a JWT-SVID of the requested identity, got %s", response.SpiffeId)
+		}
+	}
+
+	// The requested identity is ignored for the callers matched by no rule, as in the CSRs.
+	server.SetIdentityMapping(&IdentityMapping{Rules: mapping.Rules[1:]})
+	_, err = server.CreateJWTSVID(context.Background(),
+		&jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}, SpiffeId: "spiffe://cluster.local/ns/vms/sa/db"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected the caller without SPIFFE identity to be denied, got %v", err)
+	}
+}
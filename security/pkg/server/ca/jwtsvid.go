@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+	jwtsvidpb "istio.io/istio/security/proto/jwtsvid/v1alpha1"
+)
+
+const (
+	// defaultJWTSVIDTTL is the lifetime of the JWT-SVIDs whose request has no validity duration.
+	defaultJWTSVIDTTL = 5 * time.Minute
+	// maxJWTSVIDTTL is the maximum lifetime of the JWT-SVIDs.
+	maxJWTSVIDTTL = time.Hour
+)
+
+// JWTSVIDSigner is implemented by the CAs able to sign JWT-SVIDs.
+type JWTSVIDSigner interface {
+	SignJWTSVID(spiffeID string, audiences []string, ttl time.Duration) (string, time.Time, error)
+}
+
+// CreateJWTSVID signs a JWT-SVID of the SPIFFE identity of the caller, for the requested audiences. If the identity
+// mapping has rules for the caller, the JWT-SVID is of the requested SPIFFE identity, which they must allow.
+func (s *Server) CreateJWTSVID(ctx context.Context, request *jwtsvidpb.JWTSVIDRequest) (*jwtsvidpb.JWTSVIDResponse, error) {
+	signer, ok := s.ca.(JWTSVIDSigner)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "the CA does not sign JWT-SVIDs")
+	}
+	s.monitoring.JWTSVID.Increment()
+	am := security.AuthenticationManager{Authenticators: s.Authenticators}
+	caller := am.Authenticate(ctx)
+	if caller == nil {
+		s.monitoring.AuthnError.Increment()
+		return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
+	}
+	if s.rateLimiter != nil {
+		if err := s.rateLimiter.reserve(caller); err != nil {
+			s.monitoring.RateLimited.Increment()
+			return nil, err
+		}
+	}
+	identities := caller.Identities
+	if mapping, _ := s.identityMapping.Load().(*IdentityMapping); mapping != nil {
+		var requested []string
+		if request.SpiffeId != "" {
+			requested = []string{request.SpiffeId}
+		}
+		var err error
+		if identities, err = s.mappedIdentities(mapping, caller, requested, "JWT-SVID request"); err != nil {
+			return nil, err
+		}
+	}
+	var spiffeID string
+	for _, id := range identities {
+		if strings.HasPrefix(id, spiffe.URIPrefix) {
+			spiffeID = id
+			break
+		}
+	}
+	if spiffeID == "" {
+		return nil, status.Errorf(codes.PermissionDenied, "caller %v has no SPIFFE identity", caller.Identities)
+	}
+	if len(request.Audience) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "JWT-SVID requires an audience")
+	}
+	ttl := time.Duration(request.ValidityDuration) * time.Second
+	if ttl <= 0 {
+		ttl = defaultJWTSVIDTTL
+	}
+	if ttl > maxJWTSVIDTTL {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"requested TTL %s is greater than the max allowed TTL %s", ttl, maxJWTSVIDTTL)
+	}
+	token, expiry, err := signer.SignJWTSVID(spiffeID, request.Audience, ttl)
+	if err != nil {
+		serverCaLog.Errorf("JWT-SVID signing error (%v)", err)
+		return nil, status.Errorf(codes.Internal, "JWT-SVID signing error (%v)", err)
+	}
+	serverCaLog.Debugf("JWT-SVID of %s signed for %v", spiffeID, request.Audience)
+	return &jwtsvidpb.JWTSVIDResponse{SpiffeId: spiffeID, Token: token, ExpiresAt: timestamppb.New(expiry)}, nil
+}
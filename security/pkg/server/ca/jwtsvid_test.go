@@ -0,0 +1,187 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/ca"
+	mockca "istio.io/istio/security/pkg/pki/ca/mock"
+	jwtsvidpb "istio.io/istio/security/proto/jwtsvid/v1alpha1"
+)
+
+// newJWTSVIDCA returns a self-signed Istio CA signing JWT-SVIDs.
+func newJWTSVIDCA(t *testing.T) *ca.IstioCA {
+	t.Helper()
+	caOpts, err := ca.NewSelfSignedDebugIstioCAOptions("", time.Hour, time.Hour, time.Hour, "cluster.local", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caOpts.JWTSigner, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	istioCA, err := ca.NewIstioCA(caOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return istioCA
+}
+
+func TestCreateJWTSVID(t *testing.T) {
+	istioCA := newJWTSVIDCA(t)
+	caOpts, err := ca.NewSelfSignedDebugIstioCAOptions("", time.Hour, time.Hour, time.Hour, "cluster.local", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noJWTKeyCA, err := ca.NewIstioCA(caOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := "spiffe://cluster.local/ns/default/sa/example"
+
+	cases := []struct {
+		name           string
+		ca             CertificateAuthority
+		authenticators []security.Authenticator
+		request        *jwtsvidpb.JWTSVIDRequest
+		code           codes.Code
+	}{
+		{
+			name:           "CA without JWT-SVID support",
+			ca:             &mockca.FakeCA{},
+			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{identity}}},
+			request:        &jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}},
+			code:           codes.Unimplemented,
+		},
+		{
+			name:           "CA without JWT signing key",
+			ca:             noJWTKeyCA,
+			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{identity}}},
+			request:        &jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}},
+			code:           codes.Internal,
+		},
+		{
+			name:           "unauthenticated",
+			ca:             istioCA,
+			authenticators: []security.Authenticator{&mockAuthenticator{errMsg: "not authorized"}},
+			request:        &jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}},
+			code:           codes.Unauthenticated,
+		},
+		{
+			name:           "no SPIFFE identity",
+			ca:             istioCA,
+			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{"example"}}},
+			request:        &jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}},
+			code:           codes.PermissionDenied,
+		},
+		{
+			name:           "no audience",
+			ca:             istioCA,
+			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{identity}}},
+			request:        &jwtsvidpb.JWTSVIDRequest{},
+			code:           codes.InvalidArgument,
+		},
+		{
+			name:           "TTL above max",
+			ca:             istioCA,
+			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{identity}}},
+			request:        &jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}, ValidityDuration: 7200},
+			code:           codes.InvalidArgument,
+		},
+		{
+			name:           "signed",
+			ca:             istioCA,
+			authenticators: []security.Authenticator{&mockAuthenticator{identities: []string{"example", identity}}},
+			request:        &jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}, ValidityDuration: 600},
+			code:           codes.OK,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := &Server{ca: c.ca, Authenticators: c.authenticators, monitoring: newMonitoringMetrics()}
+			resp, err := server.CreateJWTSVID(context.Background(), c.request)
+			if status.Code(err) != c.code {
+				t.Fatalf("expected code %v, got %v", c.code, err)
+			}
+			if c.code != codes.OK {
+				return
+			}
+			if resp.SpiffeId != identity {
+				t.Fatalf("expected JWT-SVID of %s, got %s", identity, resp.SpiffeId)
+			}
+			if d := time.Until(resp.ExpiresAt.AsTime()); d <= 9*time.Minute || d > 10*time.Minute {
+				t.Fatalf("unexpected expiry %v", resp.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestCreateJWTSVIDOverGRPC(t *testing.T) {
+	istioCA := newJWTSVIDCA(t)
+	identity := "spiffe://cluster.local/ns/default/sa/example"
+	server, err := New(istioCA, time.Hour, []security.Authenticator{&mockAuthenticator{identities: []string{identity}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	server.Register(grpcServer)
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := jwtsvidpb.NewJWTSVIDServiceClient(conn).CreateJWTSVID(context.Background(),
+		&jwtsvidpb.JWTSVIDRequest{Audience: []string{"foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorities, err := istioCA.JWTAuthorities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.ParseSigned(resp.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.Claims{}
+	if err := token.Claims(authorities[0].Key, &claims); err != nil {
+		t.Fatalf("failed to verify the JWT-SVID: %v", err)
+	}
+	if claims.Subject != identity {
+		t.Fatalf("expected JWT-SVID of %s, got %s", identity, claims.Subject)
+	}
+}
@@ -51,6 +51,21 @@ var (
 		monitoring.WithLabels(errorTag),
 	)
 
+	rateLimitedCounts = monitoring.NewSum(
+		"citadel_server_csr_rate_limited_count",
+		"The number of CSRs rejected by the rate limits.",
+	)
+
+	identityMappingDeniedCounts = monitoring.NewSum(
+		"citadel_server_identity_mapping_denied_count",
+		"The number of CSRs denied by the identity mapping.",
+	)
+
+	jwtSVIDCounts = monitoring.NewSum(
+		"citadel_server_jwt_svid_count",
+		"The number of JWT-SVID requests received by Citadel server.",
+	)
+
 	successCounts = monitoring.NewSum(
 		"citadel_server_success_cert_issuance_count",
 		"The number of certificates issuances that have succeeded.",
@@ -75,6 +90,9 @@ func init() {
 		csrParsingErrorCounts,
 		idExtractionErrorCounts,
 		certSignErrorCounts,
+		rateLimitedCounts,
+		identityMappingDeniedCounts,
+		jwtSVIDCounts,
 		successCounts,
 		rootCertExpiryTimestamp,
 		certChainExpiryTimestamp,
@@ -89,6 +107,9 @@ type monitoringMetrics struct {
 	CSRError          monitoring.Metric
 	IDExtractionError monitoring.Metric
 	certSignErrors    monitoring.Metric
+	RateLimited       monitoring.Metric
+	MappingDenied     monitoring.Metric
+	JWTSVID           monitoring.Metric
 }
 
 // newMonitoringMetrics creates a new monitoringMetrics.
@@ -100,6 +121,9 @@ func newMonitoringMetrics() monitoringMetrics {
 		CSRError:          csrParsingErrorCounts,
 		IDExtractionError: idExtractionErrorCounts,
 		certSignErrors:    certSignErrorCounts,
+		RateLimited:       rateLimitedCounts,
+		MappingDenied:     identityMappingDeniedCounts,
+		JWTSVID:           jwtSVIDCounts,
 	}
 }
 
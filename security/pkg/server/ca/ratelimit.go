@@ -0,0 +1,184 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pkg/security"
+)
+
+// RateLimitOptions configures the token bucket limits of the CSRs signed by the CA server. A limit with a
+// non-positive QPS is disabled.
+type RateLimitOptions struct {
+	// IdentityQPS and IdentityBurst limit the CSRs of every caller identity.
+	IdentityQPS   float64
+	IdentityBurst int
+	// NodeQPS and NodeBurst limit the CSRs from every node, when the authenticator knows the node of the caller.
+	NodeQPS   float64
+	NodeBurst int
+	// RequireNode rejects the CSRs of the callers with an unknown node when the CSRs are limited per node. Otherwise,
+	// only the identity limit applies to them.
+	RequireNode bool
+}
+
+const (
+	// limiterSweepInterval is how often the limiters of the callers that have not sent a CSR for a while are dropped.
+	limiterSweepInterval = time.Minute
+	// unknownNodeWarningInterval is how often the CSRs of callers with an unknown node are logged.
+	unknownNodeWarningInterval = time.Minute
+)
+
+// csrRateLimiter applies token bucket limits to the CSRs by caller identity and by node.
+type csrRateLimiter struct {
+	identities  *keyedLimiters
+	nodes       *keyedLimiters
+	requireNode bool
+
+	mu                 sync.Mutex
+	unknownNodeWarning time.Time
+}
+
+func newCSRRateLimiter(opts RateLimitOptions) *csrRateLimiter {
+	return &csrRateLimiter{
+		identities:  newKeyedLimiters(opts.IdentityQPS, opts.IdentityBurst),
+		nodes:       newKeyedLimiters(opts.NodeQPS, opts.NodeBurst),
+		requireNode: opts.RequireNode,
+	}
+}
+
+// reserve consumes a token from the buckets of the caller. If one of them is empty, no token is consumed and an error
+// with the delay until the caller can retry is returned.
+func (l *csrRateLimiter) reserve(caller *security.Caller) error {
+	node := caller.KubernetesInfo.NodeName
+	if l.nodes != nil && node == "" {
+		if l.requireNode {
+			return status.Error(codes.PermissionDenied, "the node of the caller is unknown, the CSR cannot be rate limited per node")
+		}
+		l.warnUnknownNode(caller)
+	}
+
+	now := time.Now()
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	for _, id := range caller.Identities {
+		if r := l.identities.reserve(id, now); r != nil {
+			reservations = append(reservations, r)
+		}
+	}
+	if node != "" {
+		if r := l.nodes.reserve(node, now); r != nil {
+			reservations = append(reservations, r)
+		}
+	}
+	var delay time.Duration
+	for _, r := range reservations {
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		cancel()
+		return rateLimitedError(delay)
+	}
+	return nil
+}
+
+// warnUnknownNode logs, at most once per unknownNodeWarningInterval, that the per node limit does not apply to the
+// caller.
+func (l *csrRateLimiter) warnUnknownNode(caller *security.Caller) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Since(l.unknownNodeWarning) < unknownNodeWarningInterval {
+		return
+	}
+	l.unknownNodeWarning = time.Now()
+	serverCaLog.Warnf("the node of %v is unknown, only the per identity CSR rate limit applies to it: the token "+
+		"must be bound to a pod, and istiod must be able to get the pod before Kubernetes 1.30", caller.Identities)
+}
+
+// keyedLimiters holds a token bucket per key.
+type keyedLimiters struct {
+	limit     rate.Limit
+	burst     int
+	mu        sync.Mutex
+	limiters  map[string]*keyedLimiter
+	lastSweep time.Time
+}
+
+type keyedLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+func newKeyedLimiters(qps float64, burst int) *keyedLimiters {
+	if qps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &keyedLimiters{
+		limit:    rate.Limit(qps),
+		burst:    burst,
+		limiters: map[string]*keyedLimiter{},
+	}
+}
+
+// reserve reserves a token of the bucket of the key, or returns nil if there is no limit.
+func (k *keyedLimiters) reserve(key string, now time.Time) *rate.Reservation {
+	if k == nil {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if now.Sub(k.lastSweep) > limiterSweepInterval {
+		// A bucket is full again once it has not been used for the time to refill it, and can be dropped.
+		refill := time.Duration(float64(k.burst) / float64(k.limit) * float64(time.Second))
+		for key, l := range k.limiters {
+			if now.Sub(l.lastSeen) > refill {
+				delete(k.limiters, key)
+			}
+		}
+		k.lastSweep = now
+	}
+	l, f := k.limiters[key]
+	if !f {
+		l = &keyedLimiter{Limiter: rate.NewLimiter(k.limit, k.burst)}
+		k.limiters[key] = l
+	}
+	l.lastSeen = now
+	return l.ReserveN(now, 1)
+}
+
+// rateLimitedError returns a ResourceExhausted error, with the delay after which the caller can retry.
+func rateLimitedError(delay time.Duration) error {
+	st := status.New(codes.ResourceExhausted, "CSR rate limit exceeded")
+	if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(delay)}); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "istio.io/api/security/v1alpha1"
+	"istio.io/istio/pkg/security"
+	mockca "istio.io/istio/security/pkg/pki/ca/mock"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func TestCreateCertificateRateLimits(t *testing.T) {
+	authenticator := &mockAuthenticator{}
+	server := &Server{
+		ca: &mockca.FakeCA{
+			SignedCert:    []byte("cert"),
+			KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+		},
+		Authenticators: []security.Authenticator{authenticator},
+		monitoring:     newMonitoringMetrics(),
+	}
+	server.SetRateLimits(RateLimitOptions{IdentityQPS: 0.01, IdentityBurst: 2, NodeQPS: 0.01, NodeBurst: 3})
+	sign := func(identity, node string) error {
+		authenticator.identities = []string{identity}
+		authenticator.kubernetesInfo = security.KubernetesInfo{NodeName: node}
+		_, err := server.CreateCertificate(context.Background(), &pb.IstioCertificateRequest{Csr: "dumb CSR"})
+		return err
+	}
+	expectLimited := func(err error) {
+		t.Helper()
+		s, _ := status.FromError(err)
+		if s.Code() != codes.ResourceExhausted {
+			t.Fatalf("expected rate limited CSR, got %v", err)
+		}
+		for _, d := range s.Details() {
+			if info, ok := d.(*errdetails.RetryInfo); ok && info.RetryDelay.AsDuration() > time.Minute {
+				return
+			}
+		}
+		t.Fatalf("expected retry delay in %v", s.Details())
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := sign("a", "node-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The burst of the identity is exhausted, without consuming the one of the node.
+	expectLimited(sign("a", "node-1"))
+	if err := sign("a", ""); err == nil {
+		t.Fatal("expected identity to be rate limited on any node")
+	}
+	if err := sign("b", "node-1"); err != nil {
+		t.Fatal(err)
+	}
+	// The burst of the node is exhausted.
+	expectLimited(sign("c", "node-1"))
+	if err := sign("c", "node-2"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateCertificateRateLimitsUnknownNode(t *testing.T) {
+	for _, requireNode := range []bool{false, true} {
+		authenticator := &mockAuthenticator{identities: []string{"a"}}
+		server := &Server{
+			ca: &mockca.FakeCA{
+				SignedCert:    []byte("cert"),
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+			},
+			Authenticators: []security.Authenticator{authenticator},
+			monitoring:     newMonitoringMetrics(),
+		}
+		server.SetRateLimits(RateLimitOptions{NodeQPS: 0.01, NodeBurst: 1, RequireNode: requireNode})
+		for i := 0; i < 2; i++ {
+			_, err := server.CreateCertificate(context.Background(), &pb.IstioCertificateRequest{Csr: "dumb CSR"})
+			if requireNode && status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("expected the CSR of an unknown node to be rejected, got %v", err)
+			}
+			if !requireNode && err != nil {
+				t.Fatalf("expected the CSR of an unknown node not to be limited per node, got %v", err)
+			}
+		}
+	}
+}
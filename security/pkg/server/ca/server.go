@@ -15,6 +15,7 @@
 package ca
 
 import (
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/context"
@@ -27,6 +28,7 @@ import (
 	"istio.io/istio/security/pkg/pki/ca"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
+	jwtsvidpb "istio.io/istio/security/proto/jwtsvid/v1alpha1"
 	"istio.io/pkg/log"
 )
 
@@ -46,10 +48,16 @@ type CertificateAuthority interface {
 // specified port.
 type Server struct {
 	pb.UnimplementedIstioCertificateServiceServer
+	jwtsvidpb.UnimplementedJWTSVIDServiceServer
 	monitoring     monitoringMetrics
 	Authenticators []security.Authenticator
-	ca             CertificateAuthority
-	serverCertTTL  time.Duration
+	// AuditSinks receive the audit event of every issued certificate.
+	AuditSinks    []AuditSink
+	ca            CertificateAuthority
+	serverCertTTL time.Duration
+	rateLimiter   *csrRateLimiter
+	// identityMapping is the *IdentityMapping of the callers to the identities they may request, if any.
+	identityMapping atomic.Value
 }
 
 // CreateCertificate handles an incoming certificate signing request (CSR). It does
@@ -68,13 +76,23 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 		s.monitoring.AuthnError.Increment()
 		return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
 	}
-	// TODO: Call authorizer.
+	if s.rateLimiter != nil {
+		if err := s.rateLimiter.reserve(caller); err != nil {
+			s.monitoring.RateLimited.Increment()
+			serverCaLog.Debugf("CSR of %v rate limited: %v", caller.Identities, err)
+			return nil, err
+		}
+	}
+	subjectIDs, err := s.subjectIDs(caller, request.Csr)
+	if err != nil {
+		return nil, err
+	}
 	crMetadata := request.Metadata.GetFields()
 	certSigner := crMetadata[security.CertSigner].GetStringValue()
 	log.Debugf("cert signer from workload %s", certSigner)
 	_, _, certChainBytes, rootCertBytes := s.ca.GetCAKeyCertBundle().GetAll()
 	certOpts := ca.CertOpts{
-		SubjectIDs: caller.Identities,
+		SubjectIDs: subjectIDs,
 		TTL:        time.Duration(request.ValidityDuration) * time.Second,
 		ForCA:      false,
 		CertSigner: certSigner,
@@ -106,9 +124,85 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	}
 	s.monitoring.Success.Increment()
 	serverCaLog.Debug("CSR successfully signed.")
+	s.audit(ctx, caller, certSigner, respCertChain[0])
 	return response, nil
 }
 
+// subjectIDs returns the identities of the certificate of the caller. They are the identities requested in the CSR if
+// the identity mapping has rules for the caller, which must allow them, or the identities of the caller otherwise.
+func (s *Server) subjectIDs(caller *security.Caller, csrPEM string) ([]string, error) {
+	mapping, _ := s.identityMapping.Load().(*IdentityMapping)
+	if mapping == nil {
+		return caller.Identities, nil
+	}
+	csr, err := util.ParsePemEncodedCSR([]byte(csrPEM))
+	if err != nil {
+		s.monitoring.CSRError.Increment()
+		return nil, status.Errorf(codes.InvalidArgument, "CSR parsing error (%v)", err)
+	}
+	var requested []string
+	for _, uri := range csr.URIs {
+		requested = append(requested, uri.String())
+	}
+	return s.mappedIdentities(mapping, caller, requested, "CSR")
+}
+
+// mappedIdentities returns the requested identities if the identity mapping has rules for the caller, which must
+// allow them, or the identities of the caller otherwise. The kind of request is used in the logs and errors.
+func (s *Server) mappedIdentities(mapping *IdentityMapping, caller *security.Caller, requested []string, kind string) ([]string, error) {
+	rules, err := mapping.authorize(caller, requested)
+	if err != nil {
+		s.monitoring.MappingDenied.Increment()
+		serverCaLog.Infof("%s of %v denied: %v", kind, caller.Identities, err)
+		return nil, status.Errorf(codes.PermissionDenied, "%s denied: %v", kind, err)
+	}
+	if len(rules) == 0 {
+		return caller.Identities, nil
+	}
+	serverCaLog.Debugf("%s of %v for %v allowed by the identity mapping rules %v", kind, caller.Identities, requested, rules)
+	return requested, nil
+}
+
+// SetIdentityMapping sets the mapping of the callers to the identities they may request. A nil mapping disables it.
+func (s *Server) SetIdentityMapping(mapping *IdentityMapping) {
+	s.identityMapping.Store(mapping)
+}
+
+// audit records the issuance of the certificate to the caller in the audit sinks.
+func (s *Server) audit(ctx context.Context, caller *security.Caller, certSigner string, certPEM string) {
+	if len(s.AuditSinks) == 0 {
+		return
+	}
+	cert, err := util.ParsePemEncodedCertificate([]byte(certPEM))
+	if err != nil {
+		serverCaLog.Errorf("failed to parse issued certificate for audit (error %v)", err)
+		return
+	}
+	// The identities of the certificate differ from the ones of the caller when mapped by the identity mapping.
+	identities, err := util.ExtractIDs(cert.Extensions)
+	if err != nil {
+		identities = caller.Identities
+	}
+	now := time.Now()
+	// NotAfter is truncated to the second in the certificate, so the TTL is rounded up to the second.
+	ttl := (cert.NotAfter.Sub(now) + time.Second - 1).Truncate(time.Second)
+	event := IssuedCertificate{
+		Identities:    identities,
+		SerialNumber:  cert.SerialNumber.Text(16),
+		TTL:           ttl.String(),
+		IssuedAt:      now,
+		ExpiresAt:     cert.NotAfter,
+		CertSigner:    certSigner,
+		PodName:       caller.KubernetesInfo.PodName,
+		PodNamespace:  caller.KubernetesInfo.PodNamespace,
+		NodeName:      caller.KubernetesInfo.NodeName,
+		RemoteAddress: security.GetConnectionAddress(ctx),
+	}
+	for _, sink := range s.AuditSinks {
+		sink.Record(event)
+	}
+}
+
 func recordCertsExpiry(keyCertBundle *util.KeyCertBundle) {
 	rootCertExpiry, err := keyCertBundle.ExtractRootCertExpiryTimestamp()
 	if err != nil {
@@ -127,9 +221,17 @@ func recordCertsExpiry(keyCertBundle *util.KeyCertBundle) {
 	certChainExpiryTimestamp.Record(certChainExpiry)
 }
 
+// SetRateLimits limits the rate of the CSRs signed per caller identity and per node.
+func (s *Server) SetRateLimits(opts RateLimitOptions) {
+	s.rateLimiter = newCSRRateLimiter(opts)
+}
+
 // Register registers a GRPC server on the specified port.
 func (s *Server) Register(grpcServer *grpc.Server) {
 	pb.RegisterIstioCertificateServiceServer(grpcServer, s)
+	if _, ok := s.ca.(JWTSVIDSigner); ok {
+		jwtsvidpb.RegisterJWTSVIDServiceServer(grpcServer, s)
+	}
 }
 
 // New creates a new instance of `IstioCAServiceServer`
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
@@ -30,6 +31,7 @@ import (
 
 	pb "istio.io/api/security/v1alpha1"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/ca"
 	mockca "istio.io/istio/security/pkg/pki/ca/mock"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
@@ -37,9 +39,11 @@ import (
 )
 
 type mockAuthenticator struct {
-	authSource security.AuthSource
-	identities []string
-	errMsg     string
+	authSource     security.AuthSource
+	identities     []string
+	kubernetesInfo security.KubernetesInfo
+	attributes     security.CallerAttributes
+	errMsg         string
 }
 
 func (authn *mockAuthenticator) AuthenticatorType() string {
@@ -52,8 +56,10 @@ func (authn *mockAuthenticator) Authenticate(_ security.AuthContext) (*security.
 	}
 
 	return &security.Caller{
-		AuthSource: authn.authSource,
-		Identities: authn.identities,
+		AuthSource:     authn.authSource,
+		Identities:     authn.identities,
+		KubernetesInfo: authn.kubernetesInfo,
+		Attributes:     authn.attributes,
 	}, nil
 }
 
@@ -258,3 +264,50 @@ func TestCreateCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateCertificateAudit(t *testing.T) {
+	caOpts, err := ca.NewSelfSignedDebugIstioCAOptions("", time.Hour, time.Hour, time.Hour, "cluster.local", 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	istioCA, err := ca.NewIstioCA(caOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := "spiffe://cluster.local/ns/default/sa/example"
+	inventory := NewInventory(10)
+	server := &Server{
+		ca: istioCA,
+		Authenticators: []security.Authenticator{&mockAuthenticator{
+			identities:     []string{identity},
+			kubernetesInfo: security.KubernetesInfo{PodName: "example-pod", PodNamespace: "default", NodeName: "node-1"},
+		}},
+		AuditSinks: []AuditSink{inventory},
+		monitoring: newMonitoringMetrics(),
+	}
+	csr, _, err := util.GenCSR(util.CertOptions{Host: identity, RSAKeySize: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := server.CreateCertificate(context.Background(), &pb.IstioCertificateRequest{Csr: string(csr), ValidityDuration: 1800})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := util.ParsePemEncodedCertificate([]byte(response.CertChain[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certs := inventory.List(InventoryQuery{Identity: identity, NodeName: "node-1"})
+	if len(certs) != 1 {
+		t.Fatalf("expected a single certificate in the inventory, got %+v", certs)
+	}
+	got := certs[0]
+	if got.SerialNumber != cert.SerialNumber.Text(16) || got.TTL != "30m0s" || !got.ExpiresAt.Equal(cert.NotAfter) ||
+		got.PodName != "example-pod" || got.PodNamespace != "default" || got.RemoteAddress != "unknown" {
+		t.Fatalf("unexpected audit event %+v", got)
+	}
+	if certs := inventory.List(InventoryQuery{NodeName: "node-2"}); len(certs) != 0 {
+		t.Fatalf("expected no certificate issued from another node, got %+v", certs)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jwtsvid serves the JWT-SVIDs of the workload identity on a local endpoint of the node agent.
+package jwtsvid
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/security"
+)
+
+// Cache caches the JWT-SVIDs fetched from the CA per audience, and fetches a new JWT-SVID once half of the lifetime
+// of the cached one has elapsed.
+type Cache struct {
+	client security.JWTSVIDClient
+	ttl    time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*entry
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+type entry struct {
+	// mutex serializes the fetches of the same audience.
+	mutex     sync.Mutex
+	svid      *security.JWTSVIDResponse
+	refreshAt time.Time
+}
+
+// NewCache returns a cache of the JWT-SVIDs fetched with the client, requested with the TTL. The CA default TTL
+// applies if the TTL is 0.
+func NewCache(client security.JWTSVIDClient, ttl time.Duration) *Cache {
+	return &Cache{
+		client:  client,
+		ttl:     ttl,
+		entries: map[string]*entry{},
+		now:     time.Now,
+	}
+}
+
+// Get returns a JWT-SVID for the audience. If the JWT-SVID cannot be refreshed, the cached one is returned as long
+// as it has not expired.
+func (c *Cache) Get(audience []string) (*security.JWTSVIDResponse, error) {
+	audience = normalize(audience)
+	e := c.entry(strings.Join(audience, " "))
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	now := c.now()
+	if e.svid != nil && now.Before(e.refreshAt) {
+		return e.svid, nil
+	}
+	svid, err := c.client.FetchJWTSVID(audience, c.ttl)
+	if err != nil {
+		if e.svid != nil && now.Before(e.svid.ExpiresAt) {
+			jwtSVIDLog.Warnf("failed to refresh the JWT-SVID for %v, using the cached one: %v", audience, err)
+			return e.svid, nil
+		}
+		return nil, err
+	}
+	e.svid = svid
+	e.refreshAt = now.Add(svid.ExpiresAt.Sub(now) / 2)
+	return svid, nil
+}
+
+func (c *Cache) entry(key string) *entry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evictExpiredLocked()
+	e, f := c.entries[key]
+	if !f {
+		e = &entry{}
+		c.entries[key] = e
+	}
+	return e
+}
+
+// evictExpiredLocked removes the expired JWT-SVIDs and the entries which failed to fetch one, so that the audiences
+// no longer requested are not kept forever.
+func (c *Cache) evictExpiredLocked() {
+	now := c.now()
+	for key, e := range c.entries {
+		if !e.mutex.TryLock() {
+			continue
+		}
+		if e.svid == nil || !now.Before(e.svid.ExpiresAt) {
+			delete(c.entries, key)
+		}
+		e.mutex.Unlock()
+	}
+}
+
+// normalize sorts and deduplicates the audience, so that the same audiences share a cache entry.
+func normalize(audience []string) []string {
+	out := make([]string, 0, len(audience))
+	seen := map[string]bool{}
+	for _, a := range audience {
+		if a != "" && !seen[a] {
+			seen[a] = true
+			out = append(out, a)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
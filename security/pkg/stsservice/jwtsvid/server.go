@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwtsvid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+// JWTSVIDPath is the URL path serving the JWT-SVIDs, for the audiences of the "audience" query parameters.
+const JWTSVIDPath = "/jwtsvid"
+
+var jwtSVIDLog = log.RegisterScope("jwtsvid", "JWT-SVID service debugging", 0)
+
+// Server serves the JWT-SVIDs of the workload identity on a local HTTP endpoint.
+type Server struct {
+	cache      *Cache
+	httpServer *http.Server
+	// Port number that server listens on.
+	Port int
+}
+
+// Config for the JWT-SVID server.
+type Config struct {
+	LocalHostAddr string
+	LocalPort     int
+	// TTL is the lifetime of the JWT-SVIDs requested to the CA. The CA default applies if not set.
+	TTL time.Duration
+}
+
+// NewServer creates a new JWT-SVID server, fetching the JWT-SVIDs with the client.
+func NewServer(config Config, client security.JWTSVIDClient) (*Server, error) {
+	s := &Server{
+		cache: NewCache(client, config.TTL),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(JWTSVIDPath, s.ServeJWTSVIDRequests)
+	hostPort := net.JoinHostPort(config.LocalHostAddr, strconv.Itoa(config.LocalPort))
+	s.httpServer = &http.Server{
+		Addr:        hostPort,
+		Handler:     mux,
+		IdleTimeout: 90 * time.Second, // matches http.DefaultTransport keep-alive timeout
+		ReadTimeout: 30 * time.Second,
+	}
+	ln, err := net.Listen("tcp", hostPort)
+	if err != nil {
+		return nil, fmt.Errorf("JWT-SVID server failed to listen: %v", err)
+	}
+	// If passed in port is 0, get the actual chosen port.
+	s.Port = ln.Addr().(*net.TCPAddr).Port
+	go func() {
+		jwtSVIDLog.Infof("Start listening on %s:%d", config.LocalHostAddr, s.Port)
+		err := s.httpServer.Serve(ln)
+		// Serve always returns a non-nil error.
+		jwtSVIDLog.Error(err)
+	}()
+	return s, nil
+}
+
+// ServeJWTSVIDRequests returns a JWT-SVID for the audiences of the request.
+func (s *Server) ServeJWTSVIDRequests(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, fmt.Sprintf("request method is invalid, should be GET but get %s", req.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	var audience []string
+	for _, a := range req.URL.Query()["audience"] {
+		audience = append(audience, strings.Split(a, ",")...)
+	}
+	audience = normalize(audience)
+	if len(audience) == 0 {
+		http.Error(w, "audience is required", http.StatusBadRequest)
+		return
+	}
+	svid, err := s.cache.Get(audience)
+	if err != nil {
+		jwtSVIDLog.Warnf("failed to fetch the JWT-SVID for %v: %v", audience, err)
+		http.Error(w, fmt.Sprintf("failed to fetch the JWT-SVID: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(svid); err != nil {
+		jwtSVIDLog.Errorf("failure in sending JWT-SVID response: %v", err)
+	}
+}
+
+// Stop closes the server
+func (s *Server) Stop() {
+	if err := s.httpServer.Shutdown(context.TODO()); err != nil {
+		jwtSVIDLog.Errorf("failed to shut down JWT-SVID server: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwtsvid
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/security"
+)
+
+type fakeClient struct {
+	mutex     sync.Mutex
+	calls     int
+	audiences [][]string
+	lifetime  time.Duration
+	now       func() time.Time
+	err       error
+}
+
+func (c *fakeClient) FetchJWTSVID(audience []string, _ time.Duration) (*security.JWTSVIDResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.calls++
+	c.audiences = append(c.audiences, audience)
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &security.JWTSVIDResponse{
+		SpiffeID:  "spiffe://cluster.local/ns/default/sa/app",
+		Token:     fmt.Sprintf("token-%d", c.calls),
+		ExpiresAt: c.now().Add(c.lifetime),
+	}, nil
+}
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	client := &fakeClient{lifetime: time.Hour, now: clock}
+	c := NewCache(client, 0)
+	c.now = clock
+
+	get := func(audience ...string) *security.JWTSVIDResponse {
+		t.Helper()
+		svid, err := c.Get(audience)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return svid
+	}
+
+	first := get("b", "a")
+	if !reflect.DeepEqual(client.audiences, [][]string{{"a", "b"}}) {
+		t.Fatalf("expected sorted audiences, got %v", client.audiences)
+	}
+	if svid := get("a", "b", "a"); svid.Token != first.Token || client.calls != 1 {
+		t.Fatalf("expected the cached JWT-SVID, got %s after %d calls", svid.Token, client.calls)
+	}
+	if get("c"); client.calls != 2 {
+		t.Fatalf("expected a JWT-SVID per audience, got %d calls", client.calls)
+	}
+
+	// Refreshed once half of the lifetime has elapsed.
+	now = now.Add(31 * time.Minute)
+	second := get("a", "b")
+	if second.Token == first.Token {
+		t.Fatalf("expected the JWT-SVID to be refreshed")
+	}
+
+	// The cached JWT-SVID is used while valid if the CA is unavailable.
+	client.err = fmt.Errorf("unavailable")
+	now = now.Add(31 * time.Minute)
+	if svid := get("a", "b"); svid.Token != second.Token {
+		t.Fatalf("expected the cached JWT-SVID, got %s", svid.Token)
+	}
+	now = now.Add(30 * time.Minute)
+	if _, err := c.Get([]string{"a", "b"}); err == nil {
+		t.Fatalf("expected an error once the cached JWT-SVID expired")
+	}
+
+	// The audiences which failed to get a JWT-SVID are not kept.
+	for i := 0; i < 10; i++ {
+		if _, err := c.Get([]string{fmt.Sprintf("unknown-%d", i)}); err == nil {
+			t.Fatalf("expected an error while the CA is unavailable")
+		}
+	}
+	c.mutex.Lock()
+	entries := len(c.entries)
+	c.mutex.Unlock()
+	if entries > 1 {
+		t.Fatalf("expected the failed entries to be evicted, got %d entries", entries)
+	}
+}
+
+func TestServer(t *testing.T) {
+	client := &fakeClient{lifetime: time.Hour, now: time.Now}
+	s, err := NewServer(Config{LocalHostAddr: "localhost"}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	url := fmt.Sprintf("http://localhost:%d%s", s.Port, JWTSVIDPath)
+
+	cases := []struct {
+		name   string
+		method string
+		query  string
+		status int
+	}{
+		{name: "audiences", method: http.MethodGet, query: "?audience=b,a&audience=c", status: http.StatusOK},
+		{name: "no audience", method: http.MethodGet, status: http.StatusBadRequest},
+		{name: "post", method: http.MethodPost, query: "?audience=a", status: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, url+tc.query, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+			if tc.status != http.StatusOK {
+				return
+			}
+			svid := &security.JWTSVIDResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(svid); err != nil {
+				t.Fatal(err)
+			}
+			if svid.Token == "" || !strings.HasPrefix(svid.SpiffeID, "spiffe://") {
+				t.Fatalf("unexpected JWT-SVID %+v", svid)
+			}
+			if got := client.audiences[len(client.audiences)-1]; !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+				t.Fatalf("expected the audiences of the request, got %v", got)
+			}
+		})
+	}
+
+	client.err = fmt.Errorf("unavailable")
+	resp, err := http.Get(url + "?audience=other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+}
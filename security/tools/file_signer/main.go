@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// A reference key provider for the external signer of the Istio CA: it serves the Signer service, defined in
+// security/proto/signer/v1alpha1/signer.proto, on a Unix domain socket with a private key read from a file.
+// It is meant for tests and for the development of key providers backed by a KMS or an HSM.
+
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"istio.io/istio/security/pkg/pki/signer"
+	"istio.io/pkg/log"
+)
+
+var (
+	keyFile = flag.String("key", "ca-key.pem", "PEM encoded private key file of the CA.")
+	socket  = flag.String("socket", "/var/run/istio-signer/signer.sock",
+		"Unix domain socket to serve the Signer service on, set in the CA_EXTERNAL_SIGNER_SOCKET environment variable of istiod.")
+)
+
+func main() {
+	flag.Parse()
+
+	fileSigner, err := signer.NewFileSigner(*keyFile)
+	if err != nil {
+		log.Fatalf("Failed to read the private key: %v.", err)
+	}
+	if err := os.Remove(*socket); err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Failed to remove the socket: %v.", err)
+	}
+	lis, err := net.Listen("unix", *socket)
+	if err != nil {
+		log.Fatalf("Failed to listen on the socket: %v.", err)
+	}
+	s := signer.NewServer(fileSigner)
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		s.GracefulStop()
+	}()
+	log.Infof("Serving the Signer service on %s", *socket)
+	if err := s.Serve(lis); err != nil {
+		log.Fatalf("Failed to serve: %v.", err)
+	}
+}
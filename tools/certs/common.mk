@@ -48,7 +48,7 @@ root-ca.conf:
 	@echo "[ req_ext ]" >> $@
 	@echo "subjectKeyIdentifier = hash" >> $@
 	@echo "basicConstraints = critical, CA:true" >> $@
-	@echo "keyUsage = critical, digitalSignature, nonRepudiation, keyEncipherment, keyCertSign" >> $@
+	@echo "keyUsage = critical, digitalSignature, nonRepudiation, keyEncipherment, keyCertSign, cRLSign" >> $@
 	@echo "[ req_dn ]" >> $@
 	@echo "O = $(ROOTCA_ORG)" >> $@
 	@echo "CN = $(ROOTCA_CN)" >> $@
@@ -67,7 +67,7 @@ root-ca.conf:
 	@echo "[ req_ext ]" >> $@
 	@echo "subjectKeyIdentifier = hash" >> $@
 	@echo "basicConstraints = critical, CA:true, pathlen:0" >> $@
-	@echo "keyUsage = critical, digitalSignature, nonRepudiation, keyEncipherment, keyCertSign" >> $@
+	@echo "keyUsage = critical, digitalSignature, nonRepudiation, keyEncipherment, keyCertSign, cRLSign" >> $@
 	@echo "subjectAltName=@san" >> $@
 	@echo "[ san ]" >> $@
 	@echo "DNS.1 = $(INTERMEDIATE_SAN_DNS)" >> $@